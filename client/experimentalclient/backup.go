@@ -31,11 +31,12 @@ type backupClient struct {
 	addr string
 }
 
-func NewBackup(c *http.Client, scheme, clusterName string) Backup {
+// NewBackup returns a client of the backup service of the cluster in the given namespace.
+func NewBackup(c *http.Client, scheme, clusterName, ns string) Backup {
 	return &backupClient{
 		client: c,
 		scheme: scheme,
-		addr:   k8sutil.BackupServiceAddr(clusterName),
+		addr:   k8sutil.BackupServiceAddr(clusterName, ns),
 	}
 }
 
@@ -0,0 +1,45 @@
+package experimentalclient
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type recordingTransport struct {
+	urls []string
+}
+
+func (rt *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.urls = append(rt.urls, req.URL.String())
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(strings.NewReader("{}")),
+		Request:    req,
+	}, nil
+}
+
+func TestNewBackupURL(t *testing.T) {
+	rt := &recordingTransport{}
+	b := NewBackup(&http.Client{Transport: rt}, "http", "example-etcd-cluster", "prod")
+	if err := b.Request(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := b.ServiceStatus(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w := []string{
+		"http://example-etcd-cluster-backup-sidecar.prod.svc.cluster.local:19999/v1/backupnow",
+		"http://example-etcd-cluster-backup-sidecar.prod.svc.cluster.local:19999/v1/status",
+	}
+	if len(rt.urls) != len(w) {
+		t.Fatalf("requested urls get=%v, want=%v", rt.urls, w)
+	}
+	for i := range w {
+		if rt.urls[i] != w[i] {
+			t.Errorf("#%d: url get=%s, want=%s", i, rt.urls[i], w[i])
+		}
+	}
+}
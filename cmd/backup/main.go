@@ -48,7 +48,7 @@ func init() {
 
 	namespace = os.Getenv("MY_POD_NAMESPACE")
 	if len(namespace) == 0 {
-		namespace = "default"
+		logrus.Fatalf("must set env MY_POD_NAMESPACE")
 	}
 }
 
@@ -0,0 +1,88 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/chaos"
+	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
+	"github.com/coreos/etcd-operator/version"
+
+	"github.com/Sirupsen/logrus"
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+var (
+	namespace       string
+	clusterSelector string
+	killInterval    time.Duration
+	killProbability float64
+	killMax         int
+
+	printVersion bool
+)
+
+func init() {
+	flag.StringVar(&clusterSelector, "cluster-selector", "etcd.coreos.com/chaos=enabled", "Label selector of the cluster objects whose member pods are killed.")
+	flag.DurationVar(&killInterval, "kill-interval", 30*time.Second, "Interval between two kills of each selected cluster.")
+	flag.Float64Var(&killProbability, "kill-probability", 0.5, "Probability to kill member pods of a selected cluster at each interval.")
+	flag.IntVar(&killMax, "kill-max", 1, "Maximum number of member pods of a cluster killed at a time.")
+	flag.BoolVar(&printVersion, "version", false, "Show version and quit")
+	flag.Parse()
+
+	namespace = os.Getenv("MY_POD_NAMESPACE")
+	if len(namespace) == 0 {
+		logrus.Fatalf("must set env MY_POD_NAMESPACE")
+	}
+}
+
+func main() {
+	if printVersion {
+		fmt.Println("etcd-chaos", version.Version)
+		os.Exit(0)
+	}
+
+	sel, err := labels.Parse(clusterSelector)
+	if err != nil {
+		logrus.Fatalf("invalid cluster selector (%s): %v", clusterSelector, err)
+	}
+	if sel.Empty() {
+		logrus.Fatalf("cluster selector must not be empty: it would select all clusters")
+	}
+	if killMax < 1 || killProbability < 0 || killProbability > 1 || killInterval <= 0 {
+		logrus.Fatalf("invalid kill configuration: interval %v, probability %v, max %d", killInterval, killProbability, killMax)
+	}
+
+	rand.Seed(time.Now().UnixNano())
+	logrus.Warningf("DO NOT USE IN PRODUCTION - killing at most %d member pod(s) of clusters selected by (%s) in namespace %s every %v at %v",
+		killMax, sel, namespace, killInterval, killProbability)
+
+	m := chaos.NewMonkeys(k8sutil.MustNewKubeClient())
+	m.CrushClusters(context.Background(), &chaos.ClusterCrashConfig{
+		Namespace:       namespace,
+		ClusterSelector: sel,
+
+		KillRate:        rate.Every(killInterval),
+		KillProbability: killProbability,
+		KillMax:         killMax,
+	})
+}
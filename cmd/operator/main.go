@@ -22,6 +22,7 @@ import (
 	"os"
 	"os/signal"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/coreos/etcd-operator/pkg/analytics"
@@ -30,14 +31,17 @@ import (
 	"github.com/coreos/etcd-operator/pkg/controller"
 	"github.com/coreos/etcd-operator/pkg/garbagecollection"
 	"github.com/coreos/etcd-operator/pkg/util/constants"
+	"github.com/coreos/etcd-operator/pkg/util/featuregate"
 	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
 	"github.com/coreos/etcd-operator/pkg/util/k8sutil/election"
 	"github.com/coreos/etcd-operator/pkg/util/k8sutil/election/resourcelock"
+	"github.com/coreos/etcd-operator/pkg/util/notify"
 	"github.com/coreos/etcd-operator/pkg/util/probe"
 	"github.com/coreos/etcd-operator/pkg/util/retryutil"
 	"github.com/coreos/etcd-operator/version"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/time/rate"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
@@ -46,6 +50,7 @@ import (
 	"k8s.io/client-go/pkg/api"
 	"k8s.io/client-go/pkg/api/v1"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/flowcontrol"
 )
 
 var (
@@ -58,6 +63,23 @@ var (
 	s3Bucket         string
 	listenAddr       string
 	gcInterval       time.Duration
+	gcDryRun         bool
+	featureGates     string
+
+	exportClusterMetrics bool
+	createTPR            bool
+	podCreateQPS         float64
+	podCreateBurst       int
+	maxClusters          int
+	notifyWebhookURL     string
+	notifyMissedBackups  int
+	shards               int
+	shardIndex           int
+	defaultProfile       string
+	defaultEtcdRepo      string
+	defaultEtcdVersion   string
+	watchNamespaces      string
+	ignoreNamespaces     string
 
 	chaosLevel int
 
@@ -84,19 +106,27 @@ func init() {
 	flag.IntVar(&chaosLevel, "chaos-level", -1, "DO NOT USE IN PRODUCTION - level of chaos injected into the etcd clusters created by the operator.")
 	flag.BoolVar(&printVersion, "version", false, "Show version and quit")
 	flag.DurationVar(&gcInterval, "gc-interval", 10*time.Minute, "GC interval")
+	flag.BoolVar(&gcDryRun, "gc-dry-run", false, "Only log the orphaned resources the periodic GC would delete")
+	flag.StringVar(&featureGates, "feature-gates", "", "A set of key=value pairs that describe experimental features to enable, e.g. 'FeatureA=true,FeatureB=false'.")
+	flag.BoolVar(&exportClusterMetrics, "export-cluster-metrics", false, "Export metrics of each managed cluster (leader, leader changes, db size, raft lag, alarms) derived from polling its members")
+	flag.BoolVar(&createTPR, "create-tpr", true, "Register the cluster TPR on startup. If false, the operator waits for it to be registered, e.g. by the installer")
+	flag.Float64Var(&podCreateQPS, "pod-create-qps", 0, "Maximum member pods created per second over all clusters. 0 means no limit")
+	flag.IntVar(&podCreateBurst, "pod-create-burst", 10, "Maximum member pods created at once over all clusters, if --pod-create-qps is set")
+	flag.IntVar(&maxClusters, "max-clusters", 0, "Maximum number of clusters the operator manages in each namespace. Extra clusters are rejected until others are deleted. 0 means no limit")
+	flag.StringVar(&notifyWebhookURL, "notify-webhook-url", "", "URL to post a JSON notification to when a cluster is degraded, failed, lost its quorum or missed backups")
+	flag.IntVar(&notifyMissedBackups, "notify-missed-backups", 3, "Number of backups in a row a cluster misses before it is notified. 0 disables the notification")
+	flag.IntVar(&shards, "shards", 0, "Number of operator replicas which each manage the subset of clusters hashed to their shard. 0 or 1 disables sharding")
+	flag.IntVar(&shardIndex, "shard-index", -1, "Shard of the clusters this operator manages, if --shards is set. -1 takes the ordinal of the StatefulSet pod of the operator")
+	flag.StringVar(&defaultProfile, "default-profile", "", "Config map with the profile of new clusters which do not set spec.profile")
+	flag.StringVar(&defaultEtcdRepo, "default-etcd-repository", "", "etcd image repository of new clusters which do not set spec.etcdImage.repository")
+	flag.StringVar(&defaultEtcdVersion, "default-etcd-version", "", "etcd version of new clusters which do not set spec.version")
+	flag.StringVar(&watchNamespaces, "watch-namespaces", "", "Comma separated namespaces whose clusters the operator manages instead of its own namespace, or * for all namespaces")
+	flag.StringVar(&ignoreNamespaces, "ignore-namespaces", "", "Comma separated namespaces whose clusters the operator never manages, e.g. kube-system")
+	flag.StringVar(&k8sutil.BackupImage, "backup-image", k8sutil.BackupImage, "Image of the backup sidecars")
+	flag.StringVar(&k8sutil.BusyboxImage, "busybox-image", k8sutil.BusyboxImage, "busybox image of the DNS check and restore init containers of etcd pods")
+	flag.StringVar(&k8sutil.AlpineImage, "alpine-image", k8sutil.AlpineImage, "alpine image of the backup copy and NFS pods")
+	flag.StringVar(&k8sutil.CurlImage, "curl-image", k8sutil.CurlImage, "curl image of the seed snapshot init containers and debug pods")
 	flag.Parse()
-
-	// Workaround for watching TPR resource.
-	restCfg, err := k8sutil.InClusterConfig()
-	if err != nil {
-		panic(err)
-	}
-	controller.MasterHost = restCfg.Host
-	restcli, err := k8sutil.NewTPRClient()
-	if err != nil {
-		panic(err)
-	}
-	controller.KubeHttpCli = restcli.Client
 }
 
 func main() {
@@ -108,6 +138,13 @@ func main() {
 	if len(name) == 0 {
 		logrus.Fatalf("must set env MY_POD_NAME")
 	}
+	if shards > 1 && shardIndex < 0 {
+		var err error
+		shardIndex, err = controller.ShardIndexFromPodName(name)
+		if err != nil {
+			logrus.Fatalf("failed to get the shard index: %v", err)
+		}
+	}
 
 	c := make(chan os.Signal, 1)
 	signal.Notify(c)
@@ -140,13 +177,19 @@ func main() {
 		logrus.Fatalf("failed to get hostname: %v", err)
 	}
 
+	// The replicas of each shard elect their own leader.
+	lockName := controller.ShardLockName(shards, shardIndex)
+	if shards > 1 {
+		logrus.Infof("managing shard %d of %d", shardIndex, shards)
+	}
+
 	kubecli := k8sutil.MustNewKubeClient()
 	// TODO: replace this to client-go once leader election package is imported
 	//       https://github.com/kubernetes/client-go/issues/28
 	rl := &resourcelock.EndpointsLock{
 		EndpointsMeta: api.ObjectMeta{
 			Namespace: namespace,
-			Name:      "etcd-operator",
+			Name:      lockName,
 		},
 		Client: kubecli,
 		LockConfig: resourcelock.ResourceLockConfig{
@@ -156,6 +199,7 @@ func main() {
 	}
 
 	http.HandleFunc(probe.HTTPReadyzEndpoint, probe.ReadyzHandler)
+	http.Handle("/metrics", prometheus.Handler())
 	go http.ListenAndServe(listenAddr, nil)
 
 	election.RunOrDie(election.LeaderElectionConfig{
@@ -179,19 +223,23 @@ func run(stop <-chan struct{}) {
 		logrus.Fatalf("invalid operator config: %v", err)
 	}
 
-	go periodicFullGC(cfg.KubeCli, cfg.Namespace, gcInterval)
+	// A replica still running with another --shards may manage clusters of this shard.
+	controller.WaitForShardAgreement(cfg.KubeCli, cfg.Namespace, cfg.Shards)
+
+	// The GC covers the resources of all clusters, so only the first shard runs it.
+	if cfg.Shards <= 1 || cfg.ShardIndex == 0 {
+		go periodicFullGC(cfg, gcInterval, gcDryRun)
+	}
 
 	startChaos(context.Background(), cfg.KubeCli, cfg.Namespace, chaosLevel)
 
-	for {
-		c := controller.New(cfg)
-		err := c.Run()
-		switch err {
-		case controller.ErrVersionOutdated:
-		default:
-			logrus.Fatalf("controller Run() ended with failure: %v", err)
-		}
-	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-stop
+		cancel()
+	}()
+	err := controller.Run(ctx, cfg)
+	logrus.Fatalf("controller Run() ended with failure: %v", err)
 }
 
 func newControllerConfig() controller.Config {
@@ -202,6 +250,12 @@ func newControllerConfig() controller.Config {
 		logrus.Fatalf("fail to get my pod's service account: %v", err)
 	}
 
+	fg, err := featuregate.Parse(featureGates)
+	if err != nil {
+		logrus.Fatalf("invalid feature gates: %v", err)
+	}
+	logrus.Infof("enabled feature gates: [%v]", fg)
+
 	cfg := controller.Config{
 		Namespace:      namespace,
 		ServiceAccount: serviceAccount,
@@ -211,7 +265,25 @@ func newControllerConfig() controller.Config {
 			AWSConfig: awsConfig,
 			S3Bucket:  s3Bucket,
 		},
-		KubeCli: kubecli,
+		KubeCli:               kubecli,
+		FeatureGate:           fg,
+		ExportClusterMetrics:  exportClusterMetrics,
+		CreateTPR:             createTPR,
+		MaxClusters:           maxClusters,
+		Shards:                shards,
+		ShardIndex:            shardIndex,
+		DefaultProfile:        defaultProfile,
+		DefaultEtcdRepository: defaultEtcdRepo,
+		DefaultEtcdVersion:    defaultEtcdVersion,
+		WatchNamespaces:       splitNamespaces(watchNamespaces),
+		IgnoreNamespaces:      splitNamespaces(ignoreNamespaces),
+	}
+	if podCreateQPS > 0 {
+		cfg.PodCreateLimiter = flowcontrol.NewTokenBucketRateLimiter(float32(podCreateQPS), podCreateBurst)
+	}
+	if len(notifyWebhookURL) != 0 {
+		cfg.Notifier = notify.NewWebhook(notifyWebhookURL)
+		cfg.NotifyMissedBackups = notifyMissedBackups
 	}
 
 	return cfg
@@ -231,15 +303,34 @@ func getMyPodServiceAccount(kubecli kubernetes.Interface) (string, error) {
 	return sa, err
 }
 
-func periodicFullGC(kubecli kubernetes.Interface, ns string, d time.Duration) {
-	gc := garbagecollection.New(kubecli, ns)
-	timer := time.NewTimer(d)
-	defer timer.Stop()
+// splitNamespaces returns the namespaces of a comma separated list.
+func splitNamespaces(s string) []string {
+	var nss []string
+	for _, ns := range strings.Split(s, ",") {
+		if ns = strings.TrimSpace(ns); len(ns) != 0 {
+			nss = append(nss, ns)
+		}
+	}
+	return nss
+}
+
+// periodicFullGC collects the orphaned resources of the namespaces the operator manages.
+func periodicFullGC(cfg controller.Config, d time.Duration, dryRun bool) {
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
 	for {
-		<-timer.C
-		err := gc.FullyCollect()
+		<-ticker.C
+		nss, err := cfg.ManagedNamespaces(cfg.KubeCli)
 		if err != nil {
 			logrus.Warningf("failed to cleanup resources: %v", err)
+			continue
+		}
+		for _, ns := range nss {
+			gc := garbagecollection.New(cfg.KubeCli, ns)
+			gc.DryRun = dryRun
+			if err := gc.FullyCollect(); err != nil {
+				logrus.Warningf("failed to cleanup resources in namespace %s: %v", ns, err)
+			}
 		}
 	}
 }
@@ -41,6 +41,10 @@ const (
 	PVBackupV1 = "v1"
 
 	maxRecentBackupStatusCount = 10
+
+	// thresholdCheckInterval is how often the progress of the cluster is checked
+	// against the backup thresholds of the policy.
+	thresholdCheckInterval = 10 * time.Second
 )
 
 type Backup struct {
@@ -57,6 +61,10 @@ type Backup struct {
 
 	backupNow chan chan backupNowAck
 
+	// lastSnapDBSize is the max db size in bytes of the members when the latest backup was made,
+	// the same measure checkThresholds compares against. It is 0 if unknown, e.g. after a restart of the sidecar.
+	lastSnapDBSize int64
+
 	// recentBackupStatus keeps the statuses of 'maxRecentBackupStatusCount' recent backups.
 	recentBackupsStatus []backupapi.BackupStatus
 }
@@ -74,7 +82,7 @@ func New(kclient kubernetes.Interface, clusterName, ns string, sp spec.ClusterSp
 
 	var be backend
 	switch sp.Backup.StorageType {
-	case spec.BackupStorageTypePersistentVolume, spec.BackupStorageTypeDefault:
+	case spec.BackupStorageTypePersistentVolume, spec.BackupStorageTypeDefault, spec.BackupStorageTypeNFS:
 		be = &fileBackend{dir: bdir}
 	case spec.BackupStorageTypeS3:
 		s3cli, err := s3.New(os.Getenv(env.AWSS3Bucket), path.Join(ns, clusterName))
@@ -138,17 +146,55 @@ func (b *Backup) Run() {
 		}
 	}()
 
+	var checkc <-chan time.Time
+	if b.policy.HasBackupThreshold() {
+		ticker := time.NewTicker(thresholdCheckInterval)
+		defer ticker.Stop()
+		checkc = ticker.C
+	}
+
+	// The first backup is made right away, so that a restart of the backup sidecar,
+	// e.g. after its node failed, does not put off the next backup by another interval.
+	// It is skipped if the cluster did not change since the latest backup.
+	timer := time.NewTimer(0)
+	// After a failed backup, backup thresholds are not checked again before retryAt,
+	// so that a cluster over a threshold does not trigger a failing backup every check.
+	var (
+		failures int
+		retryAt  time.Time
+	)
 	for {
 		var ackchan chan backupNowAck
 		select {
-		case <-time.After(interval):
+		case <-timer.C:
 		case ackchan = <-b.backupNow:
 			logrus.Info("received a backup request")
+		case <-checkc:
+			if time.Now().Before(retryAt) {
+				continue
+			}
+			reason := b.checkThresholds(lastSnapRev)
+			if len(reason) == 0 {
+				continue
+			}
+			logrus.Infof("backup threshold exceeded: %s", reason)
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
 		}
+		timer.Reset(interval)
 
 		rev, err := b.saveSnap(lastSnapRev)
 		if err != nil {
+			failures++
+			retryAt = time.Now().Add(thresholdRetryDelay(failures, interval))
 			logrus.Errorf("failed to save snapshot: %v", err)
+		} else {
+			failures = 0
+			retryAt = time.Time{}
 		}
 		lastSnapRev = rev
 
@@ -162,10 +208,55 @@ func (b *Backup) Run() {
 	}
 }
 
-func (b *Backup) saveSnap(lastSnapRev int64) (int64, error) {
+// checkThresholds returns why a backup is due because of the progress of the cluster
+// since the latest backup, or an empty string if it is not.
+func (b *Backup) checkThresholds(lastSnapRev int64) string {
+	pods, err := b.runningPods()
+	if err != nil {
+		logrus.Warningf("failed to check backup thresholds: %v", err)
+		return ""
+	}
+	rev, dbSize := getMaxRevAndDBSize(pods, b.etcdTLSConfig)
+	if rev == 0 {
+		return ""
+	}
+	if b.lastSnapDBSize == 0 {
+		// The size of the latest backup is unknown, count the growth from now on.
+		b.lastSnapDBSize = dbSize
+	}
+	return exceededThreshold(b.policy, lastSnapRev, rev, b.lastSnapDBSize, dbSize)
+}
+
+// thresholdRetryDelay returns how long to wait before backup thresholds may trigger a backup again
+// after the given number of consecutive failed backups. It doubles from thresholdCheckInterval
+// with every failure, up to the backup interval.
+func thresholdRetryDelay(failures int, interval time.Duration) time.Duration {
+	d := thresholdCheckInterval
+	for i := 1; i < failures && d < interval; i++ {
+		d *= 2
+	}
+	if d > interval {
+		d = interval
+	}
+	return d
+}
+
+// exceededThreshold returns which backup threshold of the policy the cluster exceeded
+// going from (lastRev, lastDBSize) to (rev, dbSize), or an empty string if it exceeded none.
+func exceededThreshold(bp spec.BackupPolicy, lastRev, rev, lastDBSize, dbSize int64) string {
+	if n := bp.MaxRevisionsBetweenBackups; n > 0 && rev-lastRev > n {
+		return fmt.Sprintf("advanced %d revisions since the latest backup (max %d)", rev-lastRev, n)
+	}
+	if n := bp.MaxDBGrowthBetweenBackupsInMB; n > 0 && dbSize-lastDBSize > int64(n)*1024*1024 {
+		return fmt.Sprintf("db grew %.3fMB since the latest backup (max %dMB)", toMB(dbSize-lastDBSize), n)
+	}
+	return ""
+}
+
+func (b *Backup) runningPods() ([]*v1.Pod, error) {
 	podList, err := b.kclient.Core().Pods(b.namespace).List(k8sutil.ClusterListOpt(b.clusterName))
 	if err != nil {
-		return lastSnapRev, err
+		return nil, err
 	}
 
 	var pods []*v1.Pod
@@ -175,6 +266,14 @@ func (b *Backup) saveSnap(lastSnapRev int64) (int64, error) {
 			pods = append(pods, pod)
 		}
 	}
+	return pods, nil
+}
+
+func (b *Backup) saveSnap(lastSnapRev int64) (int64, error) {
+	pods, err := b.runningPods()
+	if err != nil {
+		return lastSnapRev, err
+	}
 
 	if len(pods) == 0 {
 		msg := "no running etcd pods found"
@@ -197,6 +296,9 @@ func (b *Backup) saveSnap(lastSnapRev int64) (int64, error) {
 		err = fmt.Errorf("write snapshot failed: %v", err)
 		return lastSnapRev, err
 	}
+	// Count the db growth from the largest member, as checkThresholds does.
+	// The size is 0, i.e. unknown, if no member can be reached.
+	_, b.lastSnapDBSize = getMaxRevAndDBSize(pods, b.etcdTLSConfig)
 	return rev, nil
 }
 
@@ -287,6 +389,43 @@ func getMemberWithMaxRev(pods []*v1.Pod, tc *tls.Config, selfHosted bool) (*etcd
 	return member, maxRev
 }
 
+// getMaxRevAndDBSize returns the max revision and the max db size in bytes of the members.
+func getMaxRevAndDBSize(pods []*v1.Pod, tc *tls.Config) (int64, int64) {
+	var maxRev, maxDBSize int64
+	for _, pod := range pods {
+		m := &etcdutil.Member{
+			Name:         pod.Name,
+			Namespace:    pod.Namespace,
+			SecureClient: tc != nil,
+		}
+		cfg := clientv3.Config{
+			Endpoints:   []string{m.ClientAddr()},
+			DialTimeout: constants.DefaultDialTimeout,
+			TLS:         tc,
+		}
+		etcdcli, err := clientv3.New(cfg)
+		if err != nil {
+			logrus.Warningf("failed to create etcd client for pod (%v): %v", pod.Name, err)
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), constants.DefaultRequestTimeout)
+		resp, err := etcdcli.Maintenance.Status(ctx, m.ClientAddr())
+		cancel()
+		etcdcli.Close()
+		if err != nil {
+			logrus.Warningf("failed to get status of member %s (%s): %v", m.Name, m.ClientAddr(), err)
+			continue
+		}
+		if resp.Header.Revision > maxRev {
+			maxRev = resp.Header.Revision
+		}
+		if resp.DbSize > maxDBSize {
+			maxDBSize = resp.DbSize
+		}
+	}
+	return maxRev, maxDBSize
+}
+
 func (b *Backup) getLatestBackupRev() int64 {
 	// If there is any error, we just exit backup sidecar because we can't serve the backup any way.
 	name, err := b.be.getLatest()
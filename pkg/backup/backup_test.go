@@ -21,8 +21,10 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/coreos/etcd-operator/pkg/backup/backupapi"
+	"github.com/coreos/etcd-operator/pkg/spec"
 )
 
 func TestRespHeaderHasVersionRevision(t *testing.T) {
@@ -125,6 +127,51 @@ func TestBackupVersionCompatiblity(t *testing.T) {
 	}
 }
 
+func TestExceededThreshold(t *testing.T) {
+	mb := int64(1024 * 1024)
+	tests := []struct {
+		bp         spec.BackupPolicy
+		lastRev    int64
+		rev        int64
+		lastDBSize int64
+		dbSize     int64
+		wExceeded  bool
+	}{
+		{spec.BackupPolicy{}, 10, 100000, mb, 1000 * mb, false},
+		{spec.BackupPolicy{MaxRevisionsBetweenBackups: 1000}, 10, 1010, mb, mb, false},
+		{spec.BackupPolicy{MaxRevisionsBetweenBackups: 1000}, 10, 1011, mb, mb, true},
+		{spec.BackupPolicy{MaxDBGrowthBetweenBackupsInMB: 64}, 10, 100000, mb, 65 * mb, false},
+		{spec.BackupPolicy{MaxDBGrowthBetweenBackupsInMB: 64}, 10, 100000, mb, 66 * mb, true},
+		{spec.BackupPolicy{MaxDBGrowthBetweenBackupsInMB: 64}, 10, 11, 100 * mb, 50 * mb, false},
+		{spec.BackupPolicy{MaxRevisionsBetweenBackups: 1000, MaxDBGrowthBetweenBackupsInMB: 64}, 10, 20, mb, 100 * mb, true},
+	}
+	for i, tt := range tests {
+		reason := exceededThreshold(tt.bp, tt.lastRev, tt.rev, tt.lastDBSize, tt.dbSize)
+		if get := len(reason) != 0; get != tt.wExceeded {
+			t.Errorf("#%d: exceeded get=%v (%s), want=%v", i, get, reason, tt.wExceeded)
+		}
+	}
+}
+
+func TestThresholdRetryDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		interval time.Duration
+		w        time.Duration
+	}{
+		{failures: 1, interval: time.Hour, w: thresholdCheckInterval},
+		{failures: 2, interval: time.Hour, w: 2 * thresholdCheckInterval},
+		{failures: 4, interval: time.Hour, w: 8 * thresholdCheckInterval},
+		{failures: 100, interval: time.Hour, w: time.Hour},
+		{failures: 1, interval: time.Second, w: time.Second},
+	}
+	for i, tt := range tests {
+		if get := thresholdRetryDelay(tt.failures, tt.interval); get != tt.w {
+			t.Errorf("#%d: retry delay get=%v, want=%v", i, get, tt.w)
+		}
+	}
+}
+
 func setupBackupDir(snap string) (string, error) {
 	d, err := ioutil.TempDir("", "backupdir")
 	if err != nil {
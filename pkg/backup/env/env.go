@@ -18,4 +18,5 @@ const (
 	ClusterSpec = "CLUSTER_SPEC"
 	AWSS3Bucket = "AWS_S3_BUCKET"
 	AWSConfig   = "AWS_CONFIG_FILE"
+	AWSCABundle = "AWS_CA_BUNDLE"
 )
@@ -0,0 +1,98 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// NewHTTPClient returns the HTTP client for S3 requests of the operator, which verifies the endpoint
+// with the CAs of caBundle instead of the system CAs if caBundle is not empty, and sends
+// requests through the proxy returned by proxy if it is not nil.
+func NewHTTPClient(caBundle []byte, proxy func(*http.Request) (*url.URL, error)) (*http.Client, error) {
+	tr := &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+	if len(caBundle) != 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caBundle) {
+			return nil, errors.New("no PEM encoded certificate in the S3 CA bundle")
+		}
+		tr.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	return &http.Client{Transport: tr}, nil
+}
+
+// ProxyFunc returns the proxy for S3 requests like http.ProxyFromEnvironment does
+// for the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables,
+// but with the given values, so that each cluster can use its own proxies.
+func ProxyFunc(httpProxy, httpsProxy, noProxy string) (func(*http.Request) (*url.URL, error), error) {
+	var hu, hsu *url.URL
+	var err error
+	if len(httpProxy) != 0 {
+		if hu, err = url.Parse(httpProxy); err != nil {
+			return nil, err
+		}
+	}
+	if len(httpsProxy) != 0 {
+		if hsu, err = url.Parse(httpsProxy); err != nil {
+			return nil, err
+		}
+	}
+	return func(req *http.Request) (*url.URL, error) {
+		if !useProxy(req.URL.Host, noProxy) {
+			return nil, nil
+		}
+		if req.URL.Scheme == "https" {
+			return hsu, nil
+		}
+		return hu, nil
+	}, nil
+}
+
+// useProxy returns false if the host, with an optional port, matches an entry of noProxy.
+// An entry matches the host itself and its subdomains. "*" matches all hosts.
+func useProxy(host, noProxy string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	for _, p := range strings.Split(noProxy, ",") {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if len(p) == 0 {
+			continue
+		}
+		if p == "*" {
+			return false
+		}
+		p = strings.TrimPrefix(p, ".")
+		if host == p || strings.HasSuffix(host, "."+p) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,59 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestProxyFunc(t *testing.T) {
+	proxy, err := ProxyFunc("http://proxy:3128", "http://secure-proxy:3128", "internal.example.com, .svc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		url    string
+		wProxy string
+	}{
+		{url: "http://s3.amazonaws.com/bucket", wProxy: "http://proxy:3128"},
+		{url: "https://s3.amazonaws.com/bucket", wProxy: "http://secure-proxy:3128"},
+		{url: "https://internal.example.com/bucket", wProxy: ""},
+		{url: "https://s3.internal.example.com:9000/bucket", wProxy: ""},
+		{url: "https://minio.default.svc:9000/bucket", wProxy: ""},
+		{url: "https://notinternal.example.com/bucket", wProxy: "http://secure-proxy:3128"},
+	}
+	for i, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		pu, err := proxy(&http.Request{URL: u})
+		if err != nil {
+			t.Fatalf("#%d: unexpected error: %v", i, err)
+		}
+		get := ""
+		if pu != nil {
+			get = pu.String()
+		}
+		if get != tt.wProxy {
+			t.Errorf("#%d: proxy get=%s, want=%s", i, get, tt.wProxy)
+		}
+	}
+}
+
+func TestNewHTTPClientInvalidCABundle(t *testing.T) {
+	if _, err := NewHTTPClient([]byte("not a certificate"), nil); err == nil {
+		t.Error("expected error for a CA bundle without certificates")
+	}
+}
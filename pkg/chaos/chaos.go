@@ -18,6 +18,9 @@ import (
 	"context"
 	"math/rand"
 
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
+
 	"github.com/Sirupsen/logrus"
 	"golang.org/x/time/rate"
 
@@ -65,36 +68,91 @@ func (m *Monkeys) CrushPods(ctx context.Context, c *CrashConfig) {
 			continue
 		}
 
-		pods, err := m.kubecli.CoreV1().Pods(ns).List(metav1.ListOptions{LabelSelector: ls})
+		m.killRandomPods(ns, ls, c.KillMax)
+	}
+}
+
+// ClusterCrashConfig configures killing member pods of the etcd clusters opted in for chaos testing.
+type ClusterCrashConfig struct {
+	Namespace string
+	// ClusterSelector selects the clusters by the labels of their cluster objects.
+	ClusterSelector labels.Selector
+
+	KillRate        rate.Limit
+	KillProbability float64
+	// KillMax is the maximum number of member pods of a cluster killed at a time.
+	KillMax int
+}
+
+// CrushClusters kills member pods of the clusters selected by ClusterSelector.
+// Clusters are selected again at every kill, so labeling or unlabeling a cluster takes effect without restart.
+func (m *Monkeys) CrushClusters(ctx context.Context, c *ClusterCrashConfig) {
+	limiter := rate.NewLimiter(c.KillRate, 1)
+	cs := c.ClusterSelector.String()
+	ns := c.Namespace
+	for {
+		err := limiter.Wait(ctx)
+		if err != nil { // user cancellation
+			logrus.Infof("crushClusters is canceled for cluster selector %v by the user: %v", cs, err)
+			return
+		}
+
+		cl, err := k8sutil.GetClusterList(m.kubecli.CoreV1().RESTClient(), ns)
 		if err != nil {
-			logrus.Errorf("failed to list pods for selector %v: %v", ls, err)
+			logrus.Errorf("failed to list clusters: %v", err)
 			continue
 		}
-		if len(pods.Items) == 0 {
-			logrus.Infof("no pods to kill for selector %v", ls)
-			continue
+		for _, name := range selectClusters(cl.Items, c.ClusterSelector) {
+			if p := rand.Float64(); p > c.KillProbability {
+				logrus.Infof("skip killing pods of cluster %v: probability: %v, got p: %v", name, c.KillProbability, p)
+				continue
+			}
+			m.killRandomPods(ns, labels.SelectorFromSet(k8sutil.LabelsForCluster(name)).String(), c.KillMax)
 		}
+	}
+}
 
-		max := len(pods.Items)
-		kmax := rand.Intn(c.KillMax) + 1
-		if kmax < max {
-			max = kmax
+func selectClusters(clusters []spec.Cluster, sel labels.Selector) []string {
+	var names []string
+	for _, cl := range clusters {
+		if sel.Matches(labels.Set(cl.Metadata.Labels)) {
+			names = append(names, cl.Metadata.Name)
 		}
+	}
+	return names
+}
 
-		logrus.Infof("start to kill %d pods for selector %v", max, ls)
+// killRandomPods kills between 1 and killMax random pods selected by ls.
+func (m *Monkeys) killRandomPods(ns, ls string, killMax int) {
+	pods, err := m.kubecli.CoreV1().Pods(ns).List(metav1.ListOptions{LabelSelector: ls})
+	if err != nil {
+		logrus.Errorf("failed to list pods for selector %v: %v", ls, err)
+		return
+	}
+	if len(pods.Items) == 0 {
+		logrus.Infof("no pods to kill for selector %v", ls)
+		return
+	}
 
-		tokills := make(map[string]struct{})
-		for len(tokills) < max {
-			tokills[pods.Items[rand.Intn(len(pods.Items))].Name] = struct{}{}
-		}
+	max := len(pods.Items)
+	kmax := rand.Intn(killMax) + 1
+	if kmax < max {
+		max = kmax
+	}
 
-		for tokill := range tokills {
-			err = m.kubecli.CoreV1().Pods(ns).Delete(tokill, metav1.NewDeleteOptions(0))
-			if err != nil {
-				logrus.Errorf("failed to kill pod %v: %v", tokill, err)
-				continue
-			}
-			logrus.Infof("killed pod %v for selector %v", tokill, ls)
+	logrus.Infof("start to kill %d pods for selector %v", max, ls)
+
+	tokills := make(map[string]struct{})
+	for len(tokills) < max {
+		tokills[pods.Items[rand.Intn(len(pods.Items))].Name] = struct{}{}
+	}
+
+	for tokill := range tokills {
+		err = m.kubecli.CoreV1().Pods(ns).Delete(tokill, metav1.NewDeleteOptions(0))
+		if err != nil {
+			logrus.Errorf("failed to kill pod %v: %v", tokill, err)
+			continue
 		}
+		logrus.Infof("killed pod %v for selector %v", tokill, ls)
 	}
 }
@@ -0,0 +1,55 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chaos
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestSelectClusters(t *testing.T) {
+	newCluster := func(name string, ls map[string]string) spec.Cluster {
+		cl := spec.Cluster{}
+		cl.Metadata.Name = name
+		cl.Metadata.Labels = ls
+		return cl
+	}
+	clusters := []spec.Cluster{
+		newCluster("staging", map[string]string{"etcd.coreos.com/chaos": "enabled"}),
+		newCluster("production", nil),
+		newCluster("paused", map[string]string{"etcd.coreos.com/chaos": "disabled"}),
+	}
+	tests := []struct {
+		selector string
+		wNames   []string
+	}{
+		{selector: "etcd.coreos.com/chaos=enabled", wNames: []string{"staging"}},
+		{selector: "etcd.coreos.com/chaos", wNames: []string{"staging", "paused"}},
+		{selector: "env=test", wNames: nil},
+	}
+	for i, tt := range tests {
+		sel, err := labels.Parse(tt.selector)
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if names := selectClusters(clusters, sel); !reflect.DeepEqual(names, tt.wNames) {
+			t.Errorf("#%d: names get=%v, want=%v", i, names, tt.wNames)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
+	"github.com/coreos/etcd-operator/pkg/util/retryutil"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// maxAuditRecords bounds the audit log so that the ConfigMap stays well
+// below the object size limit. The oldest records are dropped first.
+const maxAuditRecords = 1000
+
+type auditAction string
+
+const (
+	auditClusterCreated    auditAction = "ClusterCreated"
+	auditMemberAdded       auditAction = "MemberAdded"
+	auditMemberRemoved     auditAction = "MemberRemoved"
+	auditMemberUpgraded    auditAction = "MemberUpgraded"
+	auditMemberRecreated   auditAction = "MemberRecreated"
+	auditClusterRecovery   auditAction = "ClusterRecovery"
+	auditClusterRecreated  auditAction = "ClusterRecreated"
+	auditClusterHibernated auditAction = "ClusterHibernated"
+	auditClusterResumed    auditAction = "ClusterResumed"
+)
+
+// auditRecord is an operator action taken on the cluster.
+type auditRecord struct {
+	Time   string      `json:"time"`
+	Action auditAction `json:"action"`
+	Member string      `json:"member,omitempty"`
+	Reason string      `json:"reason,omitempty"`
+}
+
+// audit appends a record of an operator action to the cluster's audit ConfigMap.
+// Failing to record is logged but never fails the action itself.
+func (c *Cluster) audit(action auditAction, member, reason string) {
+	r := auditRecord{
+		Time:   time.Now().Format(time.RFC3339),
+		Action: action,
+		Member: member,
+		Reason: reason,
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		panic("unexpected json error " + err.Error())
+	}
+
+	if err := c.appendAuditLog(string(b)); err != nil {
+		c.logger.Warningf("failed to record audit (%s): %v", b, err)
+	}
+}
+
+func (c *Cluster) appendAuditLog(line string) error {
+	ns, name := c.cluster.Metadata.Namespace, k8sutil.AuditConfigMapName(c.cluster.Metadata.Name)
+	cmcli := c.config.KubeCli.CoreV1().ConfigMaps(ns)
+
+	return retryutil.Retry(time.Second, 5, func() (bool, error) {
+		cm, err := cmcli.Get(name, metav1.GetOptions{})
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				return false, err
+			}
+			cm = k8sutil.NewAuditConfigMapManifest(c.cluster.Metadata.Name, c.cluster.AsOwner())
+			cm.Data[k8sutil.AuditLogKey] = line + "\n"
+			_, err = cmcli.Create(cm)
+			if apierrors.IsAlreadyExists(err) {
+				return false, nil
+			}
+			return err == nil, err
+		}
+
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[k8sutil.AuditLogKey] = appendAuditLine(cm.Data[k8sutil.AuditLogKey], line, maxAuditRecords)
+		_, err = cmcli.Update(cm)
+		if apierrors.IsConflict(err) {
+			return false, nil
+		}
+		return err == nil, err
+	})
+}
+
+// appendAuditLine appends line to the newline separated log and keeps at most
+// max lines of it.
+func appendAuditLine(log, line string, max int) string {
+	lines := strings.Split(strings.TrimSuffix(log, "\n"), "\n")
+	if len(lines) == 1 && len(lines[0]) == 0 {
+		lines = nil
+	}
+	lines = append(lines, line)
+	if len(lines) > max {
+		lines = lines[len(lines)-max:]
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func scaleReason(from, to int) string {
+	return fmt.Sprintf("scaling from size %d to %d", from, to)
+}
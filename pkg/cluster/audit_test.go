@@ -0,0 +1,47 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import "testing"
+
+func TestAppendAuditLine(t *testing.T) {
+	tests := []struct {
+		log  string
+		line string
+		max  int
+		wlog string
+	}{{
+		log:  "",
+		line: "a",
+		max:  3,
+		wlog: "a\n",
+	}, {
+		log:  "a\nb\n",
+		line: "c",
+		max:  3,
+		wlog: "a\nb\nc\n",
+	}, {
+		log:  "a\nb\nc\n",
+		line: "d",
+		max:  3,
+		wlog: "b\nc\nd\n",
+	}}
+	for i, tt := range tests {
+		log := appendAuditLine(tt.log, tt.line, tt.max)
+		if log != tt.wlog {
+			t.Errorf("#%d: log get=%q, want=%q", i, log, tt.wlog)
+		}
+	}
+}
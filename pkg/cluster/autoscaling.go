@@ -0,0 +1,195 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
+)
+
+// autoscalingMinSamples is the number of recent probe reads needed to scale on their latency.
+const autoscalingMinSamples = 30
+
+// autoscaleIfNeeded scales the autoscaling target one step once the read latency or the db size
+// stayed past a threshold for the window of the policy. It only scales a healthy cluster which is
+// not upgrading, and waits for the cooldown after the previous step.
+func (c *Cluster) autoscaleIfNeeded() {
+	ap := c.cluster.Spec.Autoscaling
+	if ap == nil || c.latency == nil {
+		return
+	}
+	if len(c.status.Members.Unready) != 0 || c.status.Size != c.cluster.Spec.Size ||
+		len(c.status.TargetVersion) != 0 || c.status.IsDegraded() {
+		c.autoscaleDir = 0
+		return
+	}
+	if as := c.status.Autoscaling; as != nil {
+		cooldown := time.Duration(ap.GetCooldownInSecond()) * time.Second
+		if t, err := time.Parse(time.RFC3339, as.LastScaleTime); err == nil && time.Since(t) < cooldown {
+			return
+		}
+	}
+
+	target := ap.GetTarget()
+	current := c.cluster.Spec.Size
+	if target == spec.AutoscalingTargetGRPCProxy {
+		current = c.cluster.Spec.GRPCProxy.Size
+	}
+	sig := autoscalingSignals{dbSize: maxDBSize(c.status.Members.DBSize)}
+	if samples := c.latency.samples[readSerializable]; len(samples) >= autoscalingMinSamples {
+		sig.p99, sig.hasLatency = percentile(samples, 0.99), true
+	}
+	size := autoscaleSize(ap, current, sig)
+
+	// The signals must ask for the same direction for the whole window, so that a spike
+	// does not scale the target. A size out of the bounds is corrected right away.
+	now := time.Now()
+	dir := 0
+	switch {
+	case size > current:
+		dir = 1
+	case size < current:
+		dir = -1
+	}
+	if dir != c.autoscaleDir {
+		c.autoscaleDir, c.autoscaleDirSince = dir, now
+	}
+	if dir == 0 {
+		return
+	}
+	window := time.Duration(ap.GetWindowInSecond()) * time.Second
+	if current >= ap.MinSize && current <= ap.MaxSize && now.Sub(c.autoscaleDirSince) < window {
+		return
+	}
+
+	if err := c.scaleAutoscalingTarget(target, current, size, sig.String()); err != nil {
+		c.logger.Errorf("failed to autoscale %s from %d to %d: %v", target, current, size, err)
+		return
+	}
+	c.autoscaleDir = 0
+	// The latencies measured at the previous size do not apply anymore.
+	c.latency.resetSamples()
+}
+
+// autoscalingSignals are the signals the autoscaling target is scaled on.
+type autoscalingSignals struct {
+	// p99 is the 99th percentile serializable read latency, if there are enough samples.
+	p99        time.Duration
+	hasLatency bool
+	// dbSize is the largest db size of the members in bytes, 0 if unknown.
+	dbSize int64
+}
+
+func (s autoscalingSignals) String() string {
+	latency := "unknown"
+	if s.hasLatency {
+		latency = s.p99.String()
+	}
+	return fmt.Sprintf("serializable read p99 latency is %s, max db size is %.3fMB", latency, float64(s.dbSize)/1024/1024)
+}
+
+// autoscaleSize returns the size to scale the target to from the current size,
+// or the current size if the signals are within the thresholds.
+// The target is scaled up if either signal exceeds its scale up threshold, and down
+// only if the latency is below the scale down threshold and the db size below its threshold.
+func autoscaleSize(ap *spec.AutoscalingPolicy, current int, sig autoscalingSignals) int {
+	step := 1
+	// Clusters of an even size are scaled to the next odd size.
+	if ap.GetTarget() == spec.AutoscalingTargetCluster && current%2 == 1 {
+		step = 2
+	}
+	up := time.Duration(ap.ScaleUpReadLatencyInMs) * time.Millisecond
+	down := time.Duration(ap.ScaleDownReadLatencyInMs) * time.Millisecond
+	dbLimit := ap.ScaleUpDBSizeInMB * 1024 * 1024
+
+	latencyUp := sig.hasLatency && up > 0 && sig.p99 > up
+	dbUp := dbLimit > 0 && sig.dbSize > dbLimit
+	latencyDown := sig.hasLatency && sig.p99 < down
+	dbDown := dbLimit == 0 || (sig.dbSize > 0 && sig.dbSize <= dbLimit)
+
+	switch {
+	case current < ap.MinSize || ((latencyUp || dbUp) && current < ap.MaxSize):
+		return minInt(current+step, ap.MaxSize)
+	case current > ap.MaxSize || (latencyDown && dbDown && current > ap.MinSize):
+		return maxInt(current-step, ap.MinSize)
+	}
+	return current
+}
+
+func maxDBSize(sizes map[string]int64) int64 {
+	var max int64
+	for _, s := range sizes {
+		if s > max {
+			max = s
+		}
+	}
+	return max
+}
+
+// scaleAutoscalingTarget writes the new size of the target to the cluster spec,
+// like a user would do, and applies it.
+func (c *Cluster) scaleAutoscalingTarget(target spec.AutoscalingTarget, from, to int, reason string) error {
+	old := c.cluster.Spec
+	switch target {
+	case spec.AutoscalingTargetCluster:
+		c.cluster.Spec.Size = to
+	case spec.AutoscalingTargetGRPCProxy:
+		gp := *c.cluster.Spec.GRPCProxy
+		gp.Size = to
+		c.cluster.Spec.GRPCProxy = &gp
+	}
+	as := &spec.AutoscalingStatus{
+		LastScaleTime:   time.Now().Format(time.RFC3339),
+		LastScaleReason: fmt.Sprintf("scaled %s from %d to %d: %s", target, from, to, reason),
+	}
+	oldStatus := c.cluster.Status
+	st := c.status.Copy()
+	st.Autoscaling = as
+	c.cluster.Status = st
+
+	newCluster, err := k8sutil.UpdateClusterTPRObject(c.config.KubeCli.CoreV1().RESTClient(), c.cluster.Metadata.Namespace, c.cluster)
+	if err != nil {
+		// Neither the size nor the autoscaling status changed.
+		c.cluster.Spec, c.cluster.Status = old, oldStatus
+		return err
+	}
+	c.cluster = newCluster
+	c.status.Autoscaling = as
+	c.logger.Infof("autoscaling: %s", as.LastScaleReason)
+	c.createEvent(k8sutil.AutoscaledEvent(c.cluster, as.LastScaleReason))
+
+	if target == spec.AutoscalingTargetGRPCProxy {
+		return c.setupGRPCProxy()
+	}
+	// The members are added or removed by the following reconciles.
+	return c.setupPDB()
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
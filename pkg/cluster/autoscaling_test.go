@@ -0,0 +1,73 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+)
+
+func TestAutoscaleSize(t *testing.T) {
+	const mb = 1024 * 1024
+	cluster := &spec.AutoscalingPolicy{MinSize: 3, MaxSize: 7, ScaleUpReadLatencyInMs: 50, ScaleDownReadLatencyInMs: 10}
+	proxy := &spec.AutoscalingPolicy{Target: spec.AutoscalingTargetGRPCProxy, MinSize: 1, MaxSize: 4, ScaleUpReadLatencyInMs: 50}
+	db := &spec.AutoscalingPolicy{MinSize: 3, MaxSize: 7, ScaleUpReadLatencyInMs: 50, ScaleDownReadLatencyInMs: 10, ScaleUpDBSizeInMB: 512}
+	latency := func(p99 time.Duration) autoscalingSignals {
+		return autoscalingSignals{p99: p99, hasLatency: true}
+	}
+	tests := []struct {
+		ap      *spec.AutoscalingPolicy
+		current int
+		sig     autoscalingSignals
+		wSize   int
+	}{
+		// within thresholds
+		{ap: cluster, current: 3, sig: latency(20 * time.Millisecond), wSize: 3},
+		// scale up by two members
+		{ap: cluster, current: 3, sig: latency(60 * time.Millisecond), wSize: 5},
+		// at max size
+		{ap: cluster, current: 7, sig: latency(60 * time.Millisecond), wSize: 7},
+		// scale down by two members
+		{ap: cluster, current: 5, sig: latency(5 * time.Millisecond), wSize: 3},
+		// at min size
+		{ap: cluster, current: 3, sig: latency(5 * time.Millisecond), wSize: 3},
+		// even size scaled to the next odd size
+		{ap: cluster, current: 4, sig: latency(60 * time.Millisecond), wSize: 5},
+		// below min size
+		{ap: cluster, current: 1, sig: latency(20 * time.Millisecond), wSize: 3},
+		// above max size
+		{ap: cluster, current: 9, sig: latency(20 * time.Millisecond), wSize: 7},
+		// not enough latency samples
+		{ap: cluster, current: 5, sig: autoscalingSignals{}, wSize: 5},
+		// proxies are scaled by one
+		{ap: proxy, current: 2, sig: latency(60 * time.Millisecond), wSize: 3},
+		// proxies are not scaled down without a scale down threshold
+		{ap: proxy, current: 2, sig: latency(0), wSize: 2},
+		// scale up on the db size alone
+		{ap: db, current: 3, sig: autoscalingSignals{dbSize: 600 * mb}, wSize: 5},
+		// the db size above the threshold scales up even at a low latency
+		{ap: db, current: 5, sig: autoscalingSignals{p99: 5 * time.Millisecond, hasLatency: true, dbSize: 600 * mb}, wSize: 7},
+		{ap: db, current: 5, sig: autoscalingSignals{p99: 5 * time.Millisecond, hasLatency: true, dbSize: 100 * mb}, wSize: 3},
+		// no scale down while the db size is unknown
+		{ap: db, current: 5, sig: latency(5 * time.Millisecond), wSize: 5},
+	}
+	for i, tt := range tests {
+		if size := autoscaleSize(tt.ap, tt.current, tt.sig); size != tt.wSize {
+			t.Errorf("#%d: size get=%d, want=%d", i, size, tt.wSize)
+		}
+	}
+}
@@ -59,7 +59,7 @@ func newBackupManager(c Config, cl *spec.Cluster, l *logrus.Entry) (*backupManag
 		config:  c,
 		cluster: cl,
 		logger:  l,
-		bc:      experimentalclient.NewBackup(&http.Client{}, "http", cl.Metadata.GetName()),
+		bc:      experimentalclient.NewBackup(&http.Client{}, "http", cl.Metadata.GetName(), cl.Metadata.GetNamespace()),
 	}
 	var err error
 	bm.s, err = bm.setupStorage()
@@ -80,12 +80,14 @@ func (bm *backupManager) setupStorage() (s backupstorage.Storage, err error) {
 		if c.PVProvisioner == constants.PVProvisionerNone {
 			return nil, errNoPVForBackup
 		}
-		s, err = backupstorage.NewPVStorage(c.KubeCli, cl.Metadata.Name, cl.Metadata.Namespace, c.PVProvisioner, *b)
+		s, err = backupstorage.NewPVStorage(c.KubeCli, cl.Metadata.Name, cl.Metadata.Namespace, c.PVProvisioner, *b, cl.AsOwner())
 	case spec.BackupStorageTypeS3:
 		if len(c.S3Context.AWSConfig) == 0 && b.S3 == nil {
 			return nil, errNoS3ConfigForBackup
 		}
 		s, err = backupstorage.NewS3Storage(c.S3Context, c.KubeCli, cl.Metadata.Name, cl.Metadata.Namespace, *b)
+	case spec.BackupStorageTypeNFS:
+		s, err = backupstorage.NewNFSStorage(c.KubeCli, cl.Metadata.Name, cl.Metadata.Namespace, *b, cl.AsOwner())
 	}
 	return s, err
 }
@@ -159,6 +161,8 @@ func (bm *backupManager) makeSidecarDeployment() *appsv1beta1.Deployment {
 		} else {
 			k8sutil.AttachOperatorS3ToPodSpec(&podTemplate.Spec, c.S3Context)
 		}
+	case spec.BackupStorageTypeNFS:
+		k8sutil.PodSpecWithNFS(&podTemplate.Spec, *cl.Spec.Backup.NFS, cl.Metadata.Namespace)
 	}
 	name := k8sutil.BackupSidecarName(cl.Metadata.Name)
 	dplSel := k8sutil.LabelsForCluster(cl.Metadata.Name)
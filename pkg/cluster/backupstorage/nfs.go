@@ -0,0 +1,58 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backupstorage
+
+import (
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+type nfs struct {
+	clusterName  string
+	namespace    string
+	backupPolicy spec.BackupPolicy
+	kubecli      kubernetes.Interface
+	owner        metav1.OwnerReference
+}
+
+func NewNFSStorage(kubecli kubernetes.Interface, cn, ns string, backupPolicy spec.BackupPolicy, owner metav1.OwnerReference) (Storage, error) {
+	s := &nfs{
+		clusterName:  cn,
+		namespace:    ns,
+		backupPolicy: backupPolicy,
+		kubecli:      kubecli,
+		owner:        owner,
+	}
+	return s, nil
+}
+
+func (s *nfs) Create() error {
+	// The export is managed by the user. The backup sidecar creates the backup dir on it.
+	return nil
+}
+
+func (s *nfs) Clone(from string) error {
+	return k8sutil.CopyNFSBackups(s.kubecli, *s.backupPolicy.NFS, from, s.clusterName, s.namespace, s.owner)
+}
+
+func (s *nfs) Delete() error {
+	if s.backupPolicy.CleanupBackupsOnClusterDelete {
+		return k8sutil.DeleteNFSBackups(s.kubecli, *s.backupPolicy.NFS, s.clusterName, s.namespace)
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/coreos/etcd-operator/pkg/spec"
 	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -13,25 +14,32 @@ type pv struct {
 	pvProvisioner string
 	backupPolicy  spec.BackupPolicy
 	kubecli       kubernetes.Interface
+	owner         metav1.OwnerReference
 }
 
-func NewPVStorage(kubecli kubernetes.Interface, cn, ns, pvp string, backupPolicy spec.BackupPolicy) (Storage, error) {
+func NewPVStorage(kubecli kubernetes.Interface, cn, ns, pvp string, backupPolicy spec.BackupPolicy, owner metav1.OwnerReference) (Storage, error) {
 	s := &pv{
 		clusterName:   cn,
 		namespace:     ns,
 		pvProvisioner: pvp,
 		backupPolicy:  backupPolicy,
 		kubecli:       kubecli,
+		owner:         owner,
 	}
 	return s, nil
 }
 
 func (s *pv) Create() error {
-	return k8sutil.CreateAndWaitPVC(s.kubecli, s.clusterName, s.namespace, s.pvProvisioner, s.backupPolicy.PV.VolumeSizeInMB)
+	// The backup PVC outlives the cluster unless backups are cleaned up on cluster deletion.
+	var owner *metav1.OwnerReference
+	if s.backupPolicy.CleanupBackupsOnClusterDelete {
+		owner = &s.owner
+	}
+	return k8sutil.CreateAndWaitPVC(s.kubecli, s.clusterName, s.namespace, s.pvProvisioner, s.backupPolicy.PV.VolumeSizeInMB, owner)
 }
 
 func (s *pv) Clone(from string) error {
-	return k8sutil.CopyVolume(s.kubecli, from, s.clusterName, s.namespace)
+	return k8sutil.CopyVolume(s.kubecli, from, s.clusterName, s.namespace, s.owner)
 }
 
 func (s *pv) Delete() error {
@@ -1,7 +1,10 @@
 package backupstorage
 
 import (
+	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/url"
 	"os"
 	"path"
 
@@ -9,6 +12,7 @@ import (
 	"github.com/coreos/etcd-operator/pkg/backup/s3/s3config"
 	"github.com/coreos/etcd-operator/pkg/spec"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
@@ -42,10 +46,18 @@ func NewS3Storage(s3Ctx s3config.S3Context, kubecli kubernetes.Interface, cluste
 			if err != nil {
 				return nil, err
 			}
-			return backups3.NewFromSessionOpt(p.S3.S3Bucket, prefix, session.Options{
+			so := session.Options{
 				SharedConfigState: session.SharedConfigEnable,
 				SharedConfigFiles: []string{configFile, credsFile},
-			})
+			}
+			if len(p.S3.CABundleSecret) != 0 || p.S3.Proxy != nil {
+				hc, err := newS3HTTPClient(kubecli, ns, *p.S3)
+				if err != nil {
+					return nil, err
+				}
+				so.Config = aws.Config{HTTPClient: hc}
+			}
+			return backups3.NewFromSessionOpt(p.S3.S3Bucket, prefix, so)
 		} else {
 			return backups3.New(s3Ctx.S3Bucket, prefix)
 		}
@@ -115,3 +127,27 @@ func setupAWSConfig(kubecli kubernetes.Interface, ns, secret, dir string) (strin
 	}
 	return credsFile, configFile, nil
 }
+
+// newS3HTTPClient returns the HTTP client with the CA bundle and the proxies of the S3 source.
+func newS3HTTPClient(kubecli kubernetes.Interface, ns string, ss spec.S3Source) (*http.Client, error) {
+	var caBundle []byte
+	if len(ss.CABundleSecret) != 0 {
+		se, err := kubecli.CoreV1().Secrets(ns).Get(ss.CABundleSecret, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		caBundle = se.Data[spec.S3CABundleFileName]
+		if len(caBundle) == 0 {
+			return nil, fmt.Errorf("secret (%s) has no %s", ss.CABundleSecret, spec.S3CABundleFileName)
+		}
+	}
+	var proxy func(*http.Request) (*url.URL, error)
+	if p := ss.Proxy; p != nil {
+		var err error
+		proxy, err = backups3.ProxyFunc(p.HTTPProxy, p.HTTPSProxy, p.NoProxy)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return backups3.NewHTTPClient(caBundle, proxy)
+}
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"math"
 	"reflect"
+	"sort"
 	"sync"
 	"time"
 
@@ -26,15 +27,20 @@ import (
 	"github.com/coreos/etcd-operator/pkg/garbagecollection"
 	"github.com/coreos/etcd-operator/pkg/spec"
 	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+	"github.com/coreos/etcd-operator/pkg/util/featuregate"
 	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
+	"github.com/coreos/etcd-operator/pkg/util/notify"
 	"github.com/coreos/etcd-operator/pkg/util/retryutil"
+	"github.com/coreos/etcd-operator/version"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/coreos/etcd/clientv3"
 	"github.com/pborman/uuid"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/util/flowcontrol"
 )
 
 var (
@@ -59,7 +65,19 @@ type Config struct {
 	ServiceAccount string
 	s3config.S3Context
 
-	KubeCli kubernetes.Interface
+	KubeCli     kubernetes.Interface
+	FeatureGate featuregate.FeatureGate
+
+	// ExportMetrics exports metrics derived from the member statuses of the cluster.
+	ExportMetrics bool
+	// PodCreateLimiter rate limits the member pod creation of all clusters, if not nil.
+	PodCreateLimiter flowcontrol.RateLimiter
+
+	// Notifier is sent the critical conditions of the cluster, if not nil.
+	Notifier notify.Notifier
+	// NotifyMissedBackups is the number of backups in a row a cluster misses before it is notified.
+	// 0 disables the notification.
+	NotifyMissedBackups int
 }
 
 type Cluster struct {
@@ -86,6 +104,63 @@ type Cluster struct {
 
 	tlsConfig *tls.Config
 
+	// etcdcli is the client of the etcd cluster kept across reconciliations.
+	// etcdcliURLs are the sorted client URLs it was created with,
+	// and etcdcliAuthSecret the secret of the etcd user it authenticates as.
+	etcdcli           *clientv3.Client
+	etcdcliURLs       []string
+	etcdcliAuthSecret string
+
+	// unreachableSince records since when each member has been unreachable through the etcd client.
+	unreachableSince map[string]time.Time
+
+	// metrics exports the cluster metrics if Config.ExportMetrics is set.
+	metrics *metricsExporter
+	// latency keeps the latencies of the recent probe reads.
+	latency *latencyProber
+	// dbGrowth keeps the db size samples the growth in status is estimated from.
+	dbGrowth *dbGrowthTracker
+	// lastMemberUpgrade is when the operator last upgraded a member.
+	lastMemberUpgrade time.Time
+	// lastZoneRebalance is when the operator last moved a member to rebalance zones.
+	lastZoneRebalance time.Time
+	// lastRestoreJobCollection is when the operator last deleted the expired restore Jobs.
+	lastRestoreJobCollection time.Time
+	// autoscaleDir is the direction the autoscaling signals asked for at every check since autoscaleDirSince:
+	// positive to scale up, negative to scale down, 0 for neither.
+	autoscaleDir      int
+	autoscaleDirSince time.Time
+	// zoneRebalanceFrom is the zone the operator last moved a member out of,
+	// and zoneRebalanceFromCount the number of members in it before.
+	zoneRebalanceFrom      string
+	zoneRebalanceFromCount int
+	// zoneRebalanceStopped is set once a replacement member landed back in zoneRebalanceFrom.
+	zoneRebalanceStopped bool
+	// initialDataFailures is the number of times in a row loading spec.initialData failed,
+	// and initialDataRetryAt when it may be retried.
+	initialDataFailures int
+	initialDataRetryAt  time.Time
+	// selfHostedGateNotified is set once the operator posted that it leaves the self-hosted cluster unmanaged.
+	selfHostedGateNotified bool
+	// evacuationBlocked is the member whose evacuation waits for a node to take its replacement, see evacuateMember.
+	evacuationBlocked string
+
+	// nodes are the nodes listed at nodesListedAt, see listNodes.
+	nodes         []v1.Node
+	nodesListedAt time.Time
+
+	// mirror reads the heartbeats back from the mirror destination if spec.mirror is set.
+	mirror *mirrorMonitor
+
+	// notified records the types of the critical conditions notified since they last cleared.
+	notified map[string]bool
+
+	// gatewayEndpoints are the sorted member endpoints the gateway DaemonSet was last set up with.
+	gatewayEndpoints []string
+
+	// digestMismatches records the members reported to run another image digest than recorded for their version.
+	digestMismatches map[string]bool
+
 	gc *garbagecollection.GC
 }
 
@@ -100,12 +175,17 @@ func New(config Config, cl *spec.Cluster, stopC <-chan struct{}, wg *sync.WaitGr
 		status:  cl.Status.Copy(),
 		gc:      garbagecollection.New(config.KubeCli, cl.Metadata.Namespace),
 	}
+	c.memberCounter = c.status.MemberCounter
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 
 		if err := c.setup(); err != nil {
+			if k8sutil.IsNamespaceTerminatingError(err) {
+				c.logger.Infof("namespace is being terminated, not managing the cluster: %v", err)
+				return
+			}
 			c.logger.Errorf("cluster failed to setup: %v", err)
 			if c.status.Phase != spec.ClusterPhaseFailed {
 				c.status.SetReason(err.Error())
@@ -131,6 +211,9 @@ func (c *Cluster) setup() error {
 	var shouldCreateCluster bool
 	switch c.status.Phase {
 	case spec.ClusterPhaseNone:
+		if c.isSelfHostedGated() {
+			return errSelfHostedDisabled
+		}
 		shouldCreateCluster = true
 	case spec.ClusterPhaseCreating:
 		return errCreatedCluster
@@ -168,13 +251,49 @@ func (c *Cluster) setup() error {
 	if shouldCreateCluster {
 		return c.create()
 	}
+	if err := c.migrateIfNeeded(); err != nil {
+		c.logger.Errorf("failed to migrate cluster: %v", err)
+	}
+	if c.cluster.Spec.ServiceMonitor != nil {
+		if err := c.setupServiceMonitor(); err != nil {
+			c.logger.Errorf("failed to set up service monitor: %v", err)
+		}
+	}
+	if c.cluster.Spec.PrometheusRule != nil {
+		if err := c.setupPrometheusRule(); err != nil {
+			c.logger.Errorf("failed to set up prometheus rule: %v", err)
+		}
+	}
+	if c.cluster.Spec.GRPCProxy != nil {
+		if err := c.setupGRPCProxy(); err != nil {
+			c.logger.Errorf("failed to set up grpc proxy: %v", err)
+		}
+	}
+	if c.cluster.Spec.Gateway != nil {
+		if err := c.setupGateway(); err != nil {
+			c.logger.Errorf("failed to set up gateway: %v", err)
+		}
+	}
+	if c.cluster.Spec.Mirror != nil {
+		if err := c.setupMirror(); err != nil {
+			c.logger.Errorf("failed to set up mirror: %v", err)
+		}
+	}
+	if c.cluster.IsDebugEnabled() {
+		if err := c.setupDebug(); err != nil {
+			c.logger.Errorf("failed to set up debug pod: %v", err)
+		}
+	}
 	return nil
 }
 
 func (c *Cluster) create() error {
 	c.status.SetPhase(spec.ClusterPhaseCreating)
+	c.status.SetOperatorVersion(version.Version)
 
-	if err := c.updateTPRStatus(); err != nil {
+	// The spec may have been filled from a profile and the operator defaults. Writing it with the phase
+	// keeps later changes of the profile and the defaults from affecting the cluster.
+	if err := c.writeSpec(c.cluster); err != nil {
 		return fmt.Errorf("cluster create: failed to update cluster phase (%v): %v", spec.ClusterPhaseCreating, err)
 	}
 	c.logger.Infof("creating cluster with Spec (%#v), Status (%#v)", c.cluster.Spec, c.cluster.Status)
@@ -198,6 +317,53 @@ func (c *Cluster) create() error {
 	if err := c.setupServices(); err != nil {
 		return fmt.Errorf("cluster create: fail to create client service LB: %v", err)
 	}
+	if err := c.setupPDB(); err != nil {
+		return fmt.Errorf("cluster create: fail to create pod disruption budget: %v", err)
+	}
+	if c.cluster.Spec.ServiceMonitor != nil {
+		// The cluster works without being monitored, e.g. before the Prometheus Operator is installed.
+		if err := c.setupServiceMonitor(); err != nil {
+			c.logger.Errorf("cluster create: failed to create service monitor: %v", err)
+		}
+	}
+	if c.cluster.Spec.PrometheusRule != nil {
+		if err := c.setupPrometheusRule(); err != nil {
+			c.logger.Errorf("cluster create: failed to create prometheus rule: %v", err)
+		}
+	}
+	if c.cluster.Spec.GRPCProxy != nil {
+		// Clients can use the client service while the proxies are missing.
+		if err := c.setupGRPCProxy(); err != nil {
+			c.logger.Errorf("cluster create: failed to create grpc proxy: %v", err)
+		}
+	}
+	if c.cluster.Spec.Gateway != nil {
+		if err := c.setupGateway(); err != nil {
+			return fmt.Errorf("cluster create: fail to create gateway: %v", err)
+		}
+	}
+	if c.cluster.Spec.Mirror != nil {
+		if err := c.setupMirror(); err != nil {
+			return fmt.Errorf("cluster create: fail to create mirror: %v", err)
+		}
+	}
+	if c.cluster.IsDebugEnabled() {
+		// The cluster works without the debug pod.
+		if err := c.setupDebug(); err != nil {
+			c.logger.Errorf("cluster create: failed to create debug pod: %v", err)
+		}
+	}
+	reason := fmt.Sprintf("created with size %d and version %s", c.cluster.Spec.Size, c.cluster.Spec.Version)
+	if cp := c.cluster.Spec.Clone; cp != nil {
+		reason += fmt.Sprintf(", cloned from %s", k8sutil.CloneSourceURL(c.cluster.Metadata.Namespace, cp))
+	}
+	if mp := c.cluster.Spec.V2Migration; mp != nil {
+		reason += fmt.Sprintf(", migrated from v2 data in PVC %s", mp.PersistentVolumeClaimName)
+	}
+	if sp := c.cluster.Spec.SeedSnapshot; sp != nil {
+		reason += fmt.Sprintf(", seeded from snapshot in %s", k8sutil.SeedSnapshotSource(sp))
+	}
+	c.audit(auditClusterCreated, "", reason)
 	return nil
 }
 
@@ -248,6 +414,16 @@ func (c *Cluster) run(stopC <-chan struct{}) {
 			c.delete()
 		}
 
+		if c.metrics != nil {
+			c.metrics.reset()
+		}
+		deleteLatencyMetrics(c.name())
+		deleteDBGrowthMetrics(c.name())
+		mirrorLag.DeleteLabelValues(c.name())
+		lastBackupTimestamp.DeleteLabelValues(c.name())
+		deleteEtcdClientFailures(c.name())
+		c.closeEtcdClient()
+		c.closeMirrorClient()
 		close(c.stopCh)
 	}()
 
@@ -265,21 +441,102 @@ func (c *Cluster) run(stopC <-chan struct{}) {
 		case event := <-c.eventCh:
 			switch event.typ {
 			case eventModifyCluster:
+				// Annotations, e.g. the upgrade approval, change without a spec change.
+				odbg := c.cluster.IsDebugEnabled()
+				c.cluster.Metadata = event.cluster.Metadata
+				if odbg != c.cluster.IsDebugEnabled() {
+					if err := c.setupDebug(); err != nil {
+						c.logger.Errorf("failed to update debug pod: %v", err)
+					}
+				}
+				if fields := event.cluster.Spec.RevertImmutableFields(c.cluster.Spec); len(fields) != 0 {
+					c.logger.Errorf("rejecting the update of %v, which cannot be updated", fields)
+					c.createEvent(k8sutil.SpecUpdateRejectedEvent(event.cluster, fields))
+					if err := c.writeSpec(event.cluster); err != nil {
+						c.logger.Warningf("failed to write back the reverted spec: %v", err)
+					}
+				}
 				if isSpecEqual(event.cluster.Spec, c.cluster.Spec) {
 					break
 				}
+				keepPinnedDigests(&event.cluster.Spec, c.cluster.Spec)
 				// TODO: we can't handle another upgrade while an upgrade is in progress
 				c.logger.Infof("spec update: from: %v to: %v", c.cluster.Spec, event.cluster.Spec)
 
 				ob, nb := c.cluster.Spec.Backup, event.cluster.Spec.Backup
+				oldSize := c.cluster.Spec.Size
+				omu := c.cluster.Spec.MaxUnavailable
+				osm := c.cluster.Spec.ServiceMonitor
+				omp := c.cluster.Spec.Etcd.GetMetricsPort()
+				opr := c.cluster.Spec.PrometheusRule
+				ogp := c.cluster.Spec.GRPCProxy
+				ogw := c.cluster.Spec.Gateway
+				omr := c.cluster.Spec.Mirror
+				ocs := c.cluster.Spec.ClientService
 				c.cluster = event.cluster
 
+				if oldSize != c.cluster.Spec.Size || omu != c.cluster.Spec.MaxUnavailable {
+					if err := c.setupPDB(); err != nil {
+						c.logger.Errorf("failed to update pod disruption budget: %v", err)
+					}
+				}
+
+				mp := c.cluster.Spec.Etcd.GetMetricsPort()
+				if mp != omp {
+					if err := k8sutil.UpdateServicePorts(c.config.KubeCli, c.cluster.Metadata.Name, c.cluster.Metadata.Namespace, mp, c.cluster.Spec.ClientService); err != nil {
+						c.logger.Errorf("failed to update service ports: %v", err)
+					}
+				}
+
+				if !reflect.DeepEqual(ocs, c.cluster.Spec.ClientService) {
+					if err := k8sutil.UpdateClientServicePolicy(c.config.KubeCli, c.cluster.Metadata.Name, c.cluster.Metadata.Namespace, mp, c.cluster.Spec.ClientService); err != nil {
+						c.logger.Errorf("failed to update client service: %v", err)
+					}
+				}
+
+				if !reflect.DeepEqual(osm, c.cluster.Spec.ServiceMonitor) ||
+					((mp != omp || !reflect.DeepEqual(ocs, c.cluster.Spec.ClientService)) && osm != nil) {
+					if err := c.setupServiceMonitor(); err != nil {
+						c.logger.Errorf("failed to update service monitor: %v", err)
+					}
+				}
+
+				// The alerts also depend on the quota and backup interval of the cluster.
+				if opr != nil || c.cluster.Spec.PrometheusRule != nil {
+					if err := c.setupPrometheusRule(); err != nil {
+						c.logger.Errorf("failed to update prometheus rule: %v", err)
+					}
+				}
+
+				// The proxies, gateways and the mirror run the etcd image of the cluster version.
+				if ogp != nil || c.cluster.Spec.GRPCProxy != nil {
+					if err := c.setupGRPCProxy(); err != nil {
+						c.logger.Errorf("failed to update grpc proxy: %v", err)
+					}
+				}
+				if ogw != nil || c.cluster.Spec.Gateway != nil {
+					if err := c.setupGateway(); err != nil {
+						c.logger.Errorf("failed to update gateway: %v", err)
+					}
+				}
+				if omr != nil || c.cluster.Spec.Mirror != nil {
+					if err := c.setupMirror(); err != nil {
+						c.logger.Errorf("failed to update mirror: %v", err)
+					}
+				}
+				if c.cluster.IsDebugEnabled() {
+					if err := c.setupDebug(); err != nil {
+						c.logger.Errorf("failed to update debug pod: %v", err)
+					}
+				}
+
 				if !isBackupPolicyEqual(ob, nb) {
 					err := c.updateBackupPolicy(ob, nb)
 					if err != nil {
 						c.logger.Errorf("failed to update backup policy: %v", err)
 						clusterFailed = true
 						c.status.SetReason(err.Error())
+						c.notify(notify.TypeFailed, err.Error())
 						return
 					}
 				}
@@ -301,6 +558,27 @@ func (c *Cluster) run(stopC <-chan struct{}) {
 				c.status.Control()
 			}
 
+			if c.isSelfHostedGated() {
+				// Existing self-hosted clusters are left alone instead of failed, so that
+				// enabling the feature gate resumes their management.
+				if !c.selfHostedGateNotified {
+					c.logger.Warningf("skipping reconciliation: %v", errSelfHostedDisabled)
+					c.createEvent(k8sutil.SelfHostedUnmanagedEvent(c.cluster))
+					c.selfHostedGateNotified = true
+				}
+				continue
+			}
+
+			if c.cluster.Spec.Hibernated {
+				if err := c.hibernate(); err != nil {
+					c.logger.Errorf("failed to hibernate: %v", err)
+				}
+				if err := c.updateTPRStatus(); err != nil {
+					c.logger.Warningf("failed to update TPR status: %v", err)
+				}
+				continue
+			}
+
 			running, pending, err := c.pollPods()
 			if err != nil {
 				c.logger.Errorf("fail to poll pods: %v", err)
@@ -308,12 +586,35 @@ func (c *Cluster) run(stopC <-chan struct{}) {
 				continue
 			}
 
+			if len(pending) > 0 && k8sutil.HasMemberPVC(c.cluster.Spec) {
+				// A member pod on a PVC bound to a lost node never gets scheduled, so it would hold reconciliation forever.
+				if pod := pickUnschedulableMemberPod(pending, time.Now()); pod != nil {
+					rerr = c.replaceUnschedulableMember(running, pod)
+					if rerr != nil {
+						c.logger.Errorf("failed to replace unschedulable member (%s): %v", pod.Name, rerr)
+					}
+					break
+				}
+			}
 			if len(pending) > 0 {
 				// Pod startup might take long, e.g. pulling image. It would deterministically become running or succeeded/failed later.
 				c.logger.Infof("skip reconciliation: running (%v), pending (%v)", k8sutil.GetPodNames(running), k8sutil.GetPodNames(pending))
 				reconcileFailed.WithLabelValues("not all pods are running").Inc()
 				continue
 			}
+			if len(running) == 0 && c.namespaceTerminating() {
+				// The pods were deleted with the namespace. Recovering them is futile.
+				rerr = errNamespaceTerminating
+				break
+			}
+			if len(running) == 0 && c.status.Hibernated {
+				c.logger.Infof("resuming hibernated cluster")
+				rerr = c.resume()
+				if rerr != nil {
+					c.logger.Errorf("fail to resume hibernated cluster: %v", rerr)
+				}
+				break
+			}
 			if len(running) == 0 {
 				c.logger.Warningf("all etcd pods are dead. Trying to recover from a previous backup")
 				rerr = c.disasterRecovery(nil)
@@ -331,6 +632,10 @@ func (c *Cluster) run(stopC <-chan struct{}) {
 					c.logger.Errorf("failed to update members: %v", rerr)
 					break
 				}
+			} else if err := c.updateMembers(c.members); err != nil {
+				// The membership of etcd is the source of truth. Keep the last known
+				// membership if etcd cannot be reached, e.g. on quorum loss.
+				c.logger.Warningf("failed to sync members from etcd: %v", err)
 			}
 			rerr = c.reconcile(running)
 			if rerr != nil {
@@ -342,6 +647,15 @@ func (c *Cluster) run(stopC <-chan struct{}) {
 				c.logger.Warningf("failed to update local backup service status: %v", err)
 			}
 			c.updateMemberStatus(running)
+			c.updateSafeToEvict(running)
+			c.updateImageDigests(running)
+			c.updateGatewayEndpoints()
+			c.updateClientEndpoint()
+			c.probeReadLatency()
+			c.checkMirror()
+			c.autoscaleIfNeeded()
+			c.checkNotifications()
+			c.collectRestoreJobs()
 			if err := c.updateTPRStatus(); err != nil {
 				c.logger.Warningf("failed to update TPR status: %v", err)
 			}
@@ -353,9 +667,16 @@ func (c *Cluster) run(stopC <-chan struct{}) {
 			reconcileFailed.WithLabelValues(rerr.Error()).Inc()
 		}
 
+		if rerr == errNamespaceTerminating || k8sutil.IsNamespaceTerminatingError(rerr) {
+			// The cluster object is deleted with the namespace, so there is nothing to report the failure to.
+			c.logger.Infof("namespace is being terminated, stop managing the cluster: %v", rerr)
+			return
+		}
+
 		if isFatalError(rerr) {
 			clusterFailed = true
 			c.status.SetReason(rerr.Error())
+			c.notify(notify.TypeFailed, rerr.Error())
 
 			c.logger.Errorf("cluster failed: %v", rerr)
 			return
@@ -383,34 +704,68 @@ func (c *Cluster) updateBackupPolicy(ob, nb *spec.BackupPolicy) error {
 	return nil
 }
 
+// isSpecEqual tells whether the specs are equal in the fields users set.
+// The image digests the operator pins are left out, so that neither the watch event of the spec
+// the operator wrote nor an update which raced it counts as a spec change.
 func isSpecEqual(s1, s2 spec.ClusterSpec) bool {
-	if s1.Size != s2.Size || s1.Paused != s2.Paused || s1.Version != s2.Version {
-		return false
+	return reflect.DeepEqual(withoutOperatorFields(s1), withoutOperatorFields(s2))
+}
+
+func withoutOperatorFields(s spec.ClusterSpec) spec.ClusterSpec {
+	if s.EtcdImage != nil && s.EtcdImage.PinDigests {
+		ip := *s.EtcdImage
+		ip.Digests = nil
+		s.EtcdImage = &ip
+	}
+	return s
+}
+
+// keepPinnedDigests adds the image digests the operator pinned in the old spec to the new spec,
+// if the new spec still pins digests and misses them, e.g. since it was written before they were pinned.
+func keepPinnedDigests(ns *spec.ClusterSpec, old spec.ClusterSpec) {
+	if ns.EtcdImage == nil || !ns.EtcdImage.PinDigests || old.EtcdImage == nil {
+		return
+	}
+	for v, d := range old.EtcdImage.Digests {
+		if _, ok := ns.EtcdImage.Digests[v]; ok {
+			continue
+		}
+		if ns.EtcdImage.Digests == nil {
+			ns.EtcdImage.Digests = map[string]string{}
+		}
+		ns.EtcdImage.Digests[v] = d
 	}
-	return isBackupPolicyEqual(s1.Backup, s2.Backup)
 }
 
 func isBackupPolicyEqual(b1, b2 *spec.BackupPolicy) bool {
 	return reflect.DeepEqual(b1, b2)
 }
 
-func (c *Cluster) startSeedMember(recoverFromBackup bool) error {
-	m := &etcdutil.Member{
-		Name:         etcdutil.CreateMemberName(c.cluster.Metadata.Name, c.memberCounter),
-		Namespace:    c.cluster.Metadata.Namespace,
-		SecurePeer:   c.isSecurePeer(),
-		SecureClient: c.isSecureClient(),
+// startSeedMember creates the seed member of the cluster.
+// If backupVersion is not empty, the seed member restores the latest backup compatible with it.
+func (c *Cluster) startSeedMember(backupVersion string) error {
+	m, err := c.newMember()
+	if err != nil {
+		return err
 	}
 	ms := etcdutil.NewMemberSet(m)
-	if err := c.createPod(ms, m, "new", recoverFromBackup); err != nil {
+	// The cluster cannot run without its seed member, so it waits for the rate limit instead of retrying later.
+	if c.config.PodCreateLimiter != nil {
+		c.config.PodCreateLimiter.Accept()
+	}
+	if err := c.createPod(ms, m, "new", backupVersion); err != nil {
 		return fmt.Errorf("failed to create seed member (%s): %v", m.Name, err)
 	}
-	c.memberCounter++
 	c.members = ms
 	c.logger.Infof("cluster created with seed member (%s)", m.Name)
 	return nil
 }
 
+// isSelfHostedGated returns true if the cluster is self-hosted and the SelfHosted feature gate is disabled.
+func (c *Cluster) isSelfHostedGated() bool {
+	return c.cluster.Spec.SelfHosted != nil && !c.config.FeatureGate.Enabled(featuregate.SelfHosted)
+}
+
 func (c *Cluster) isSecurePeer() bool {
 	return c.cluster.Spec.TLS.IsSecurePeer()
 }
@@ -421,12 +776,22 @@ func (c *Cluster) isSecureClient() bool {
 
 // bootstrap creates the seed etcd member for a new cluster.
 func (c *Cluster) bootstrap() error {
-	return c.startSeedMember(false)
+	return c.startSeedMember("")
 }
 
 // recover recovers the cluster by creating a seed etcd member from a backup.
 func (c *Cluster) recover() error {
-	return c.startSeedMember(true)
+	return c.startSeedMember(c.recoveryBackupVersion())
+}
+
+// recoveryBackupVersion returns the version of the backup to recover from.
+// While the cluster is recreated from a backup to upgrade it, the backup has the version before the upgrade.
+func (c *Cluster) recoveryBackupVersion() string {
+	if c.cluster.Spec.GetUpgradeStrategy() == spec.UpgradeStrategyRecreateFromBackup &&
+		c.status.TargetVersion == c.cluster.Spec.Version && len(c.status.CurrentVersion) != 0 {
+		return c.status.CurrentVersion
+	}
+	return c.cluster.Spec.Version
 }
 
 func (c *Cluster) Update(cl *spec.Cluster) {
@@ -449,23 +814,149 @@ func (c *Cluster) delete() {
 }
 
 func (c *Cluster) setupServices() error {
-	err := k8sutil.CreateClientService(c.config.KubeCli, c.cluster.Metadata.Name, c.cluster.Metadata.Namespace, c.cluster.AsOwner())
+	metricsPort := c.cluster.Spec.Etcd.GetMetricsPort()
+	err := k8sutil.CreateClientService(c.config.KubeCli, c.cluster.Metadata.Name, c.cluster.Metadata.Namespace, metricsPort,
+		c.cluster.Spec.ClientService, c.cluster.AsOwner())
 	if err != nil {
 		return err
 	}
 
-	return k8sutil.CreatePeerService(c.config.KubeCli, c.cluster.Metadata.Name, c.cluster.Metadata.Namespace, c.cluster.AsOwner())
+	return k8sutil.CreatePeerService(c.config.KubeCli, c.cluster.Metadata.Name, c.cluster.Metadata.Namespace, metricsPort, c.cluster.AsOwner())
+}
+
+func (c *Cluster) setupPDB() error {
+	return k8sutil.CreateOrReplacePDB(c.config.KubeCli, c.cluster.Metadata.Name, c.cluster.Metadata.Namespace, c.cluster.Spec.Size, c.cluster.Spec.MaxUnavailable, c.cluster.AsOwner())
+}
+
+// setupServiceMonitor creates or updates the ServiceMonitor of the cluster,
+// or deletes it if spec.serviceMonitor is not set.
+func (c *Cluster) setupServiceMonitor() error {
+	restcli := c.config.KubeCli.CoreV1().RESTClient()
+	name, ns := c.cluster.Metadata.Name, c.cluster.Metadata.Namespace
+	if c.cluster.Spec.ServiceMonitor == nil {
+		return k8sutil.DeleteServiceMonitor(restcli, name, ns)
+	}
+	return k8sutil.CreateOrUpdateServiceMonitor(restcli, name, ns, c.cluster.Spec, c.cluster.AsOwner())
+}
+
+// setupPrometheusRule creates or updates the PrometheusRule of the cluster,
+// or deletes it if spec.prometheusRule is not set.
+func (c *Cluster) setupPrometheusRule() error {
+	restcli := c.config.KubeCli.CoreV1().RESTClient()
+	name, ns := c.cluster.Metadata.Name, c.cluster.Metadata.Namespace
+	if c.cluster.Spec.PrometheusRule == nil {
+		return k8sutil.DeletePrometheusRule(restcli, name, ns)
+	}
+	return k8sutil.CreateOrUpdatePrometheusRule(restcli, name, ns, c.cluster.Spec, c.cluster.AsOwner())
 }
 
-func (c *Cluster) createPod(members etcdutil.MemberSet, m *etcdutil.Member, state string, needRecovery bool) error {
+// setupGRPCProxy creates or updates the grpc proxy Deployment and Service of the cluster,
+// or deletes them if spec.grpcProxy is not set.
+func (c *Cluster) setupGRPCProxy() error {
+	name, ns := c.cluster.Metadata.Name, c.cluster.Metadata.Namespace
+	if c.cluster.Spec.GRPCProxy == nil {
+		return k8sutil.DeleteGRPCProxy(c.config.KubeCli, name, ns)
+	}
+	return k8sutil.CreateOrUpdateGRPCProxy(c.config.KubeCli, name, ns, c.cluster.Spec, c.cluster.AsOwner())
+}
+
+// updateClientEndpoint reports the published client endpoint in status.clientEndpoint.
+// The node port of a NodePort client service is assigned by Kubernetes, so it is read back from the service.
+func (c *Cluster) updateClientEndpoint() {
+	sp := c.cluster.Spec.ClientService
+	nodePort := 0
+	if sp != nil && len(sp.ExternalDNSHostname) != 0 && sp.Type == v1.ServiceTypeNodePort {
+		var err error
+		nodePort, err = k8sutil.ClientServiceNodePort(c.config.KubeCli, c.cluster.Metadata.Name, c.cluster.Metadata.Namespace)
+		if err != nil {
+			c.logger.Warningf("failed to get node port of client service: %v", err)
+			return
+		}
+	}
+	c.status.ClientEndpoint = sp.ClientEndpoint(nodePort)
+}
+
+// setupGateway creates or updates the gateway DaemonSet of the cluster,
+// or deletes it if spec.gateway is not set.
+func (c *Cluster) setupGateway() error {
+	name, ns := c.cluster.Metadata.Name, c.cluster.Metadata.Namespace
+	if c.cluster.Spec.Gateway == nil {
+		return k8sutil.DeleteGateway(c.config.KubeCli, name, ns)
+	}
+	eps := c.memberEndpoints()
+	if err := k8sutil.CreateOrUpdateGateway(c.config.KubeCli, name, ns, c.cluster.Spec, eps, c.cluster.AsOwner()); err != nil {
+		return err
+	}
+	c.gatewayEndpoints = eps
+	return nil
+}
+
+// updateGatewayEndpoints points the gateways at the current members after a membership change.
+func (c *Cluster) updateGatewayEndpoints() {
+	if c.cluster.Spec.Gateway == nil || reflect.DeepEqual(c.memberEndpoints(), c.gatewayEndpoints) {
+		return
+	}
+	if err := c.setupGateway(); err != nil {
+		c.logger.Warningf("failed to update gateway endpoints: %v", err)
+	}
+}
+
+// memberEndpoints returns the sorted client endpoints ("host:port") of the members.
+func (c *Cluster) memberEndpoints() []string {
+	var eps []string
+	for _, m := range c.members {
+		eps = append(eps, m.Addr()+":2379")
+	}
+	sort.Strings(eps)
+	return eps
+}
+
+// setupDebug creates or updates the debug Deployment of the cluster,
+// or deletes it if the debug annotation is not set.
+func (c *Cluster) setupDebug() error {
+	name, ns := c.cluster.Metadata.Name, c.cluster.Metadata.Namespace
+	if !c.cluster.IsDebugEnabled() {
+		return k8sutil.DeleteDebug(c.config.KubeCli, name, ns)
+	}
+	return k8sutil.CreateOrUpdateDebug(c.config.KubeCli, name, ns, c.cluster.Spec, c.cluster.AsOwner())
+}
+
+func (c *Cluster) createPod(members etcdutil.MemberSet, m *etcdutil.Member, state, backupVersion string) error {
 	token := ""
 	if state == "new" {
 		token = uuid.New()
 	}
 
 	pod := k8sutil.NewEtcdPod(m, members.PeerURLPairs(), c.cluster.Metadata.Name, state, token, c.cluster.Spec, c.cluster.AsOwner())
-	if needRecovery {
-		k8sutil.AddRecoveryToPod(pod, c.cluster.Metadata.Name, token, m, c.cluster.Spec)
+	// The data dir of a member on a PVC is restored by a Job before its pod is created, see runRestoreJob.
+	// restore is then the pod the restore init containers are collected in for the Job.
+	restore := pod
+	if k8sutil.HasMemberPVC(c.cluster.Spec) {
+		restore = &v1.Pod{}
+	}
+	if len(backupVersion) != 0 {
+		k8sutil.AddRecoveryToPod(restore, c.cluster.Metadata.Name, token, backupVersion, m, c.cluster.Spec)
+	} else if state == "new" {
+		// Only the seed member is created in state new. It restores the initial data of the cluster.
+		switch {
+		case c.cluster.Spec.Clone != nil:
+			k8sutil.AddCloneToPod(restore, c.cluster.Metadata.Namespace, token, m, c.cluster.Spec)
+		case c.cluster.Spec.V2Migration != nil:
+			k8sutil.AddV2MigrationToPod(restore, token, m, c.cluster.Spec)
+		case c.cluster.Spec.SeedSnapshot != nil:
+			k8sutil.AddSeedSnapshotToPod(restore, token, m, c.cluster.Spec)
+		}
+	}
+	if k8sutil.HasMemberPVC(c.cluster.Spec) {
+		pvc := k8sutil.NewMemberPVCManifest(m, c.cluster.Metadata.Name, c.cluster.Spec, c.cluster.AsOwner())
+		if err := k8sutil.CreateMemberPVC(c.config.KubeCli, c.cluster.Metadata.Namespace, pvc); err != nil {
+			return fmt.Errorf("failed to create PVC of member (%s): %v", m.Name, err)
+		}
+		if len(restore.Spec.InitContainers) != 0 {
+			if err := c.runRestoreJob(m, restore); err != nil {
+				return err
+			}
+		}
 	}
 	_, err := c.config.KubeCli.Core().Pods(c.cluster.Metadata.Namespace).Create(pod)
 	return err
@@ -473,7 +964,11 @@ func (c *Cluster) createPod(members etcdutil.MemberSet, m *etcdutil.Member, stat
 
 func (c *Cluster) removePod(name string) error {
 	ns := c.cluster.Metadata.Namespace
-	opts := metav1.NewDeleteOptions(podTerminationGracePeriod)
+	gracePeriod := podTerminationGracePeriod
+	if p := c.cluster.Spec.Pod; p != nil && p.TerminationGracePeriodSeconds != nil {
+		gracePeriod = *p.TerminationGracePeriodSeconds
+	}
+	opts := metav1.NewDeleteOptions(gracePeriod)
 	err := c.config.KubeCli.Core().Pods(ns).Delete(name, opts)
 	if err != nil {
 		if !k8sutil.IsKubernetesResourceNotFoundError(err) {
@@ -483,6 +978,35 @@ func (c *Cluster) removePod(name string) error {
 	return nil
 }
 
+// removePodAndData removes the pod of the member and its PVC if any.
+func (c *Cluster) removePodAndData(name string) error {
+	if err := c.removePod(name); err != nil {
+		return err
+	}
+	if !k8sutil.HasMemberPVC(c.cluster.Spec) {
+		return nil
+	}
+	return k8sutil.DeleteMemberPVC(c.config.KubeCli, c.cluster.Metadata.Namespace, name)
+}
+
+// removeAllPodsAndData removes the given pods and the pods and PVCs of all members,
+// before the cluster is recreated from a backup.
+func (c *Cluster) removeAllPodsAndData(pods []*v1.Pod) error {
+	names := map[string]bool{}
+	for _, pod := range pods {
+		names[pod.Name] = true
+	}
+	for name := range c.members {
+		names[name] = true
+	}
+	for name := range names {
+		if err := c.removePodAndData(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (c *Cluster) pollPods() (running, pending []*v1.Pod, err error) {
 	podList, err := c.config.KubeCli.Core().Pods(c.cluster.Metadata.Namespace).List(k8sutil.ClusterListOpt(c.cluster.Metadata.Name))
 	if err != nil {
@@ -511,16 +1035,49 @@ func (c *Cluster) pollPods() (running, pending []*v1.Pod, err error) {
 	return running, pending, nil
 }
 
+// updateMemberStatus updates the ready and unready members in status from the
+// perspective of the etcd cluster: a member is ready only if its pod is ready,
+// it has a leader, and it has caught up with the leader's raft log.
 func (c *Cluster) updateMemberStatus(pods []*v1.Pod) {
-	var ready, unready []*v1.Pod
+	statuses := make(map[string]*clientv3.StatusResponse)
+	unreachable := make(map[string]bool)
+	var leaderIndex uint64
 	for _, pod := range pods {
 		m := &etcdutil.Member{Name: pod.Name, Namespace: pod.Namespace, SecureClient: c.isSecureClient()}
 		url := m.ClientAddr()
-		healthy, err := etcdutil.CheckHealth(url, c.tlsConfig)
+		st, err := c.memberStatus(url)
 		if err != nil {
 			c.logger.Warningf("health check of etcd member (%s) failed: %v", url, err)
+			unreachable[pod.Name] = true
+			continue
 		}
-		if healthy {
+		statuses[pod.Name] = st
+		if st.Header != nil && st.Leader == st.Header.MemberId {
+			leaderIndex = st.RaftIndex
+		}
+	}
+
+	c.unreachableSince = updateUnreachableSince(c.unreachableSince, unreachable, time.Now())
+	if c.config.ExportMetrics {
+		c.exportMetrics(statuses)
+	}
+
+	quota := c.cluster.Spec.Etcd.GetQuotaBackendBytes()
+	dbSize := make(map[string]int64, len(statuses))
+	for name, st := range statuses {
+		dbSize[name] = st.DbSize
+		if st.DbSize > quota*9/10 {
+			c.logger.Warningf("db size (%d) of etcd member (%s) is above 90%% of the backend quota (%d)", st.DbSize, name, quota)
+		}
+	}
+	c.status.Members.DBSize = dbSize
+	c.status.QuotaBackendBytes = quota
+	c.updateDBGrowth(dbSize, quota)
+
+	var ready, unready []*v1.Pod
+	for _, pod := range pods {
+		st, ok := statuses[pod.Name]
+		if ok && k8sutil.IsPodReady(pod) && isMemberInSync(st.Leader != 0, st.RaftIndex, leaderIndex) {
 			ready = append(ready, pod)
 		} else {
 			unready = append(unready, pod)
@@ -528,6 +1085,63 @@ func (c *Cluster) updateMemberStatus(pods []*v1.Pod) {
 	}
 	c.status.Members.Ready = k8sutil.GetPodNames(ready)
 	c.status.Members.Unready = k8sutil.GetPodNames(unready)
+
+	readyNames := map[string]bool{}
+	for _, name := range c.status.Members.Ready {
+		readyNames[name] = true
+	}
+	c.status.Members.Details = memberDetails(k8sutil.GetPodNames(pods), statuses, readyNames, c.members, c.status.Members.Details, time.Now())
+
+	c.status.Members.Zones = nil
+	if sp := c.cluster.Spec.Pod; sp != nil && sp.SpreadAcrossZones {
+		c.status.Members.Zones = c.memberZones(pods)
+	}
+}
+
+// maxInSyncRaftIndexLag is the maximum number of raft entries a member can fall behind
+// the leader and still be considered in sync.
+const maxInSyncRaftIndexLag = 1000
+
+func isMemberInSync(hasLeader bool, raftIndex, leaderIndex uint64) bool {
+	if !hasLeader || leaderIndex == 0 {
+		return false
+	}
+	if raftIndex >= leaderIndex {
+		return true
+	}
+	return leaderIndex-raftIndex <= maxInSyncRaftIndexLag
+}
+
+// memberZones returns the zones of the nodes the given member pods run on, see k8sutil.NodeZone.
+// The nodes are read from listNodes; a node created since is read on its own.
+func (c *Cluster) memberZones(pods []*v1.Pod) map[string]string {
+	nodeZones := map[string]string{}
+	nodes, err := c.listNodes()
+	if err != nil {
+		c.logger.Warningf("failed to read the zones of members: %v", err)
+	}
+	for i := range nodes {
+		nodeZones[nodes[i].Name] = k8sutil.NodeZone(&nodes[i])
+	}
+	zones := map[string]string{}
+	for _, pod := range pods {
+		nn := pod.Spec.NodeName
+		if len(nn) == 0 {
+			continue
+		}
+		zone, ok := nodeZones[nn]
+		if !ok {
+			node, err := c.config.KubeCli.CoreV1().Nodes().Get(nn, metav1.GetOptions{})
+			if err != nil {
+				c.logger.Warningf("failed to get node (%s) of member (%s): %v", nn, pod.Name, err)
+				continue
+			}
+			zone = k8sutil.NodeZone(node)
+			nodeZones[nn] = zone
+		}
+		zones[pod.Name] = zone
+	}
+	return zones
 }
 
 func (c *Cluster) updateTPRStatus() error {
@@ -547,6 +1161,19 @@ func (c *Cluster) updateTPRStatus() error {
 	return nil
 }
 
+// writeSpec writes the cluster with a spec the operator changed and the current status.
+// cl is updated to the written cluster.
+func (c *Cluster) writeSpec(cl *spec.Cluster) error {
+	cl.Status = c.status
+	newCluster, err := k8sutil.UpdateClusterTPRObject(c.config.KubeCli.CoreV1().RESTClient(), c.cluster.Metadata.Namespace, cl)
+	if err != nil {
+		return err
+	}
+	*cl = *newCluster
+	c.cluster.Metadata = newCluster.Metadata
+	return nil
+}
+
 func (c *Cluster) updateLocalBackupStatus() error {
 	if c.bm == nil {
 		return nil
@@ -558,6 +1185,11 @@ func (c *Cluster) updateLocalBackupStatus() error {
 	}
 	c.status.BackupServiceStatus = backupServiceStatusToTPRBackupServiceStatu(bs)
 
+	if rb := c.status.BackupServiceStatus.RecentBackup; rb != nil {
+		if t, err := time.Parse(time.RFC3339, rb.CreationTime); err == nil {
+			lastBackupTimestamp.WithLabelValues(c.name()).Set(float64(t.Unix()))
+		}
+	}
 	return nil
 }
 
@@ -595,6 +1227,17 @@ func (c *Cluster) reportFailedStatus() {
 	retryutil.Retry(retryInterval, math.MaxInt64, f)
 }
 
+// namespaceTerminating returns whether the namespace of the cluster is being terminated.
+// If the namespace cannot be read, e.g. without RBAC access to namespaces, it is assumed to be active.
+func (c *Cluster) namespaceTerminating() bool {
+	t, err := k8sutil.IsNamespaceTerminating(c.config.KubeCli, c.cluster.Metadata.Namespace)
+	if err != nil {
+		c.logger.Warningf("failed to check namespace: %v", err)
+		return false
+	}
+	return t
+}
+
 func (c *Cluster) name() string {
 	return c.cluster.Metadata.GetName()
 }
@@ -0,0 +1,213 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"github.com/coreos/etcd-operator/pkg/util/constants"
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+
+	"github.com/coreos/etcd/clientv3"
+	pb "github.com/coreos/etcd/etcdserver/etcdserverpb"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+// Cluster metrics are derived from the member statuses the operator polls on
+// every reconcile, so that clusters can be alerted on without scraping each member.
+var (
+	clusterHasLeader = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "etcd_operator",
+		Subsystem: "cluster",
+		Name:      "has_leader",
+		Help:      "Whether the cluster has a leader (1) or not (0)",
+	},
+		[]string{"ClusterName"},
+	)
+
+	clusterLeaderChanges = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "etcd_operator",
+		Subsystem: "cluster",
+		Name:      "leader_changes",
+		Help:      "Total number of leader changes observed by the operator",
+	},
+		[]string{"ClusterName"},
+	)
+
+	clusterAlarms = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "etcd_operator",
+		Subsystem: "cluster",
+		Name:      "alarms",
+		Help:      "Number of members with an active alarm of the type",
+	},
+		[]string{"ClusterName", "Alarm"},
+	)
+
+	memberDBSize = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "etcd_operator",
+		Subsystem: "cluster",
+		Name:      "member_db_size_bytes",
+		Help:      "Size of the backend database of the member in bytes",
+	},
+		[]string{"ClusterName", "Member"},
+	)
+
+	memberRaftIndexLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "etcd_operator",
+		Subsystem: "cluster",
+		Name:      "member_raft_index_lag",
+		Help:      "Number of raft entries the member is behind the leader",
+	},
+		[]string{"ClusterName", "Member"},
+	)
+)
+
+// exportedAlarms are always exported, as 0 if no member raised them.
+var exportedAlarms = []pb.AlarmType{pb.AlarmType_NOSPACE, etcdutil.AlarmTypeCorrupt}
+
+func init() {
+	prometheus.MustRegister(clusterHasLeader)
+	prometheus.MustRegister(clusterLeaderChanges)
+	prometheus.MustRegister(clusterAlarms)
+	prometheus.MustRegister(memberDBSize)
+	prometheus.MustRegister(memberRaftIndexLag)
+}
+
+// metricsExporter exports the metrics of one cluster.
+type metricsExporter struct {
+	clusterName string
+	// leader is the ID of the last observed leader, 0 if none was observed yet.
+	leader uint64
+	// members are the members metrics were exported for.
+	members map[string]bool
+}
+
+func newMetricsExporter(clusterName string) *metricsExporter {
+	return &metricsExporter{clusterName: clusterName, members: map[string]bool{}}
+}
+
+// export exports the metrics derived from the given member statuses and alarms.
+func (e *metricsExporter) export(statuses map[string]*clientv3.StatusResponse, alarms []*pb.AlarmMember) {
+	leader, _ := leaderOf(statuses)
+	if leader != 0 {
+		if e.leader != 0 && e.leader != leader {
+			clusterLeaderChanges.WithLabelValues(e.clusterName).Inc()
+		}
+		e.leader = leader
+		clusterHasLeader.WithLabelValues(e.clusterName).Set(1)
+	} else {
+		clusterHasLeader.WithLabelValues(e.clusterName).Set(0)
+	}
+
+	counts := map[pb.AlarmType]int{}
+	for _, a := range alarms {
+		counts[a.Alarm]++
+	}
+	for _, t := range exportedAlarms {
+		clusterAlarms.WithLabelValues(e.clusterName, alarmName(t)).Set(float64(counts[t]))
+	}
+
+	lags := raftIndexLags(statuses)
+	for name, st := range statuses {
+		memberDBSize.WithLabelValues(e.clusterName, name).Set(float64(st.DbSize))
+		if lag, ok := lags[name]; ok {
+			memberRaftIndexLag.WithLabelValues(e.clusterName, name).Set(float64(lag))
+		} else {
+			memberRaftIndexLag.DeleteLabelValues(e.clusterName, name)
+		}
+	}
+	for name := range e.members {
+		if _, ok := statuses[name]; !ok {
+			e.deleteMember(name)
+		}
+	}
+	for name := range statuses {
+		e.members[name] = true
+	}
+}
+
+func (e *metricsExporter) deleteMember(name string) {
+	memberDBSize.DeleteLabelValues(e.clusterName, name)
+	memberRaftIndexLag.DeleteLabelValues(e.clusterName, name)
+	delete(e.members, name)
+}
+
+// reset stops exporting the metrics of the cluster.
+func (e *metricsExporter) reset() {
+	for name := range e.members {
+		e.deleteMember(name)
+	}
+	clusterHasLeader.DeleteLabelValues(e.clusterName)
+	clusterLeaderChanges.DeleteLabelValues(e.clusterName)
+	for _, t := range exportedAlarms {
+		clusterAlarms.DeleteLabelValues(e.clusterName, alarmName(t))
+	}
+}
+
+// leaderOf returns the ID and raft index of the leader as reported by the leader itself.
+// It returns 0 if the leader did not report its status.
+func leaderOf(statuses map[string]*clientv3.StatusResponse) (id, raftIndex uint64) {
+	for _, st := range statuses {
+		if st.Header != nil && st.Leader != 0 && st.Leader == st.Header.MemberId {
+			return st.Leader, st.RaftIndex
+		}
+	}
+	return 0, 0
+}
+
+// raftIndexLags returns the number of raft entries each member is behind the leader.
+func raftIndexLags(statuses map[string]*clientv3.StatusResponse) map[string]uint64 {
+	lags := map[string]uint64{}
+	leader, leaderIndex := leaderOf(statuses)
+	if leader == 0 {
+		return lags
+	}
+	for name, st := range statuses {
+		if st.RaftIndex >= leaderIndex {
+			lags[name] = 0
+		} else {
+			lags[name] = leaderIndex - st.RaftIndex
+		}
+	}
+	return lags
+}
+
+func alarmName(t pb.AlarmType) string {
+	if t == etcdutil.AlarmTypeCorrupt {
+		return "CORRUPT"
+	}
+	return t.String()
+}
+
+// exportMetrics exports the metrics of the cluster from the given member statuses.
+func (c *Cluster) exportMetrics(statuses map[string]*clientv3.StatusResponse) {
+	if c.metrics == nil {
+		c.metrics = newMetricsExporter(c.cluster.Metadata.Name)
+	}
+	var alarms []*pb.AlarmMember
+	cli, err := c.etcdClient(c.members.ClientURLs())
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), constants.DefaultRequestTimeout)
+		var resp *clientv3.AlarmResponse
+		resp, err = cli.AlarmList(ctx)
+		cancel()
+		if err == nil {
+			alarms = resp.Alarms
+		}
+	}
+	if err != nil {
+		c.logger.Warningf("failed to list alarms: %v", err)
+	}
+	c.metrics.export(statuses, alarms)
+}
@@ -0,0 +1,72 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/coreos/etcd/clientv3"
+	pb "github.com/coreos/etcd/etcdserver/etcdserverpb"
+)
+
+func newTestStatus(id, leader, raftIndex uint64) *clientv3.StatusResponse {
+	return &clientv3.StatusResponse{Header: &pb.ResponseHeader{MemberId: id}, Leader: leader, RaftIndex: raftIndex}
+}
+
+func TestRaftIndexLags(t *testing.T) {
+	tests := []struct {
+		statuses map[string]*clientv3.StatusResponse
+		wLags    map[string]uint64
+	}{{
+		statuses: map[string]*clientv3.StatusResponse{
+			"m0": newTestStatus(1, 1, 100),
+			"m1": newTestStatus(2, 1, 90),
+			"m2": newTestStatus(3, 1, 101),
+		},
+		wLags: map[string]uint64{"m0": 0, "m1": 10, "m2": 0},
+	}, {
+		// the leader did not report its status.
+		statuses: map[string]*clientv3.StatusResponse{
+			"m1": newTestStatus(2, 1, 90),
+		},
+		wLags: map[string]uint64{},
+	}}
+	for i, tt := range tests {
+		lags := raftIndexLags(tt.statuses)
+		if len(lags) != len(tt.wLags) {
+			t.Errorf("#%d: lags get=%v, want=%v", i, lags, tt.wLags)
+			continue
+		}
+		for name, w := range tt.wLags {
+			if lags[name] != w {
+				t.Errorf("#%d: lag of %s get=%d, want=%d", i, name, lags[name], w)
+			}
+		}
+	}
+}
+
+func TestMetricsExporterLeaderChanges(t *testing.T) {
+	e := newMetricsExporter("test-metrics")
+	defer e.reset()
+
+	e.export(map[string]*clientv3.StatusResponse{"m0": newTestStatus(1, 1, 10)}, nil)
+	e.export(map[string]*clientv3.StatusResponse{"m1": newTestStatus(2, 2, 20)}, nil)
+	if e.leader != 2 {
+		t.Errorf("leader get=%d, want=2", e.leader)
+	}
+	if !e.members["m1"] || e.members["m0"] {
+		t.Errorf("exported members get=%v, want only m1", e.members)
+	}
+}
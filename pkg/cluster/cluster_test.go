@@ -0,0 +1,117 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+func TestIsMemberInSync(t *testing.T) {
+	tests := []struct {
+		hasLeader   bool
+		raftIndex   uint64
+		leaderIndex uint64
+		wInSync     bool
+	}{
+		{hasLeader: false, raftIndex: 100, leaderIndex: 100, wInSync: false},
+		{hasLeader: true, raftIndex: 100, leaderIndex: 0, wInSync: false},
+		{hasLeader: true, raftIndex: 100, leaderIndex: 100, wInSync: true},
+		{hasLeader: true, raftIndex: 101, leaderIndex: 100, wInSync: true},
+		{hasLeader: true, raftIndex: 100, leaderIndex: 100 + maxInSyncRaftIndexLag, wInSync: true},
+		{hasLeader: true, raftIndex: 100, leaderIndex: 101 + maxInSyncRaftIndexLag, wInSync: false},
+	}
+	for i, tt := range tests {
+		inSync := isMemberInSync(tt.hasLeader, tt.raftIndex, tt.leaderIndex)
+		if inSync != tt.wInSync {
+			t.Errorf("#%d: in sync get=%v, want=%v", i, inSync, tt.wInSync)
+		}
+	}
+}
+
+func TestPickUnschedulableMemberPod(t *testing.T) {
+	now := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	newPod := func(age time.Duration, scheduled v1.ConditionStatus) *v1.Pod {
+		pod := &v1.Pod{}
+		pod.Name = "test-0000"
+		pod.CreationTimestamp = metav1.NewTime(now.Add(-age))
+		pod.Status.Conditions = []v1.PodCondition{{Type: v1.PodScheduled, Status: scheduled}}
+		return pod
+	}
+	tests := []struct {
+		pod  *v1.Pod
+		wPod bool
+	}{
+		{pod: newPod(time.Minute, v1.ConditionFalse), wPod: false},
+		{pod: newPod(memberUnschedulableTimeout+time.Minute, v1.ConditionFalse), wPod: true},
+		// scheduled, e.g. pulling the image
+		{pod: newPod(memberUnschedulableTimeout+time.Minute, v1.ConditionTrue), wPod: false},
+	}
+	for i, tt := range tests {
+		get := pickUnschedulableMemberPod([]*v1.Pod{tt.pod}, now)
+		if (get != nil) != tt.wPod {
+			t.Errorf("#%d: unschedulable pod get=%v, want=%v", i, get != nil, tt.wPod)
+		}
+	}
+}
+
+func TestIsSpecEqual(t *testing.T) {
+	pinned := func(digests map[string]string) *spec.EtcdImagePolicy {
+		return &spec.EtcdImagePolicy{PinDigests: true, Digests: digests}
+	}
+	tests := []struct {
+		s1, s2 spec.ClusterSpec
+		w      bool
+	}{
+		{s1: spec.ClusterSpec{Size: 3}, s2: spec.ClusterSpec{Size: 3}, w: true},
+		{s1: spec.ClusterSpec{Size: 3}, s2: spec.ClusterSpec{Size: 5}, w: false},
+		{s1: spec.ClusterSpec{Size: 3, Paused: true}, s2: spec.ClusterSpec{Size: 3}, w: false},
+		// digests pinned by the operator are not a spec change.
+		{s1: spec.ClusterSpec{EtcdImage: pinned(map[string]string{"3.1.8": "sha256:a"})}, s2: spec.ClusterSpec{EtcdImage: pinned(nil)}, w: true},
+		{
+			s1: spec.ClusterSpec{EtcdImage: &spec.EtcdImagePolicy{Digests: map[string]string{"3.1.8": "sha256:a"}}},
+			s2: spec.ClusterSpec{EtcdImage: &spec.EtcdImagePolicy{Digests: map[string]string{"3.1.8": "sha256:b"}}},
+			w:  false,
+		},
+		{s1: spec.ClusterSpec{EtcdImage: pinned(nil)}, s2: spec.ClusterSpec{EtcdImage: &spec.EtcdImagePolicy{}}, w: false},
+	}
+	for i, tt := range tests {
+		if get := isSpecEqual(tt.s1, tt.s2); get != tt.w {
+			t.Errorf("#%d: equal get=%v, want=%v", i, get, tt.w)
+		}
+	}
+}
+
+func TestKeepPinnedDigests(t *testing.T) {
+	old := spec.ClusterSpec{EtcdImage: &spec.EtcdImagePolicy{PinDigests: true, Digests: map[string]string{"3.1.8": "sha256:a", "3.2.0": "sha256:b"}}}
+	ns := spec.ClusterSpec{Size: 5, EtcdImage: &spec.EtcdImagePolicy{PinDigests: true, Digests: map[string]string{"3.2.0": "sha256:c"}}}
+	keepPinnedDigests(&ns, old)
+	w := map[string]string{"3.1.8": "sha256:a", "3.2.0": "sha256:c"}
+	if !reflect.DeepEqual(ns.EtcdImage.Digests, w) {
+		t.Errorf("digests get=%v, want=%v", ns.EtcdImage.Digests, w)
+	}
+
+	ns = spec.ClusterSpec{EtcdImage: &spec.EtcdImagePolicy{}}
+	keepPinnedDigests(&ns, old)
+	if len(ns.EtcdImage.Digests) != 0 {
+		t.Errorf("expect no digests kept without pinning, get=%v", ns.EtcdImage.Digests)
+	}
+}
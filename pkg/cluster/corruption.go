@@ -0,0 +1,54 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+)
+
+// checkCorruption returns why the cluster data is considered corrupted,
+// or an empty string if it is not. It relies on the CORRUPT alarms etcd raises
+// when its corruption checks are enabled by spec.etcd.corruptCheckTime.
+func (c *Cluster) checkCorruption() string {
+	if ep := c.cluster.Spec.Etcd; ep == nil || len(ep.CorruptCheckTime) == 0 {
+		return ""
+	}
+	alarms, err := etcdutil.ListAlarms(c.members.ClientURLs(), c.tlsConfig, etcdutil.AlarmTypeCorrupt)
+	c.countEtcdClientFailure(rpcAlarmList, err)
+	if err != nil {
+		c.logger.Warningf("failed to list alarms: %v", err)
+		return ""
+	}
+	if len(alarms) == 0 {
+		return ""
+	}
+
+	names := make(map[uint64]string, len(c.members))
+	for _, m := range c.members {
+		names[m.ID] = m.Name
+	}
+	var corrupted []string
+	for _, a := range alarms {
+		name, ok := names[a.MemberID]
+		if !ok {
+			name = fmt.Sprintf("%x", a.MemberID)
+		}
+		corrupted = append(corrupted, name)
+	}
+	return fmt.Sprintf("data corruption detected on member(s) %s", strings.Join(corrupted, ", "))
+}
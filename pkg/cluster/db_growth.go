@@ -0,0 +1,180 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// dbGrowthSampleInterval is the interval between two db size samples.
+	dbGrowthSampleInterval = 15 * time.Minute
+	// dbGrowthWindow is how far back the samples the growth rate is estimated from go.
+	dbGrowthWindow = 7 * 24 * time.Hour
+	// minDBGrowthWindow is the time the samples need to span before a growth rate is estimated.
+	minDBGrowthWindow = time.Hour
+)
+
+var (
+	dbGrowthRate = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "etcd_operator",
+		Subsystem: "cluster",
+		Name:      "db_growth_bytes_per_second",
+		Help:      "Estimated growth rate of the largest member db of the cluster",
+	},
+		[]string{"ClusterName"},
+	)
+
+	dbTimeToQuota = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "etcd_operator",
+		Subsystem: "cluster",
+		Name:      "db_time_to_quota_seconds",
+		Help:      "Projected time until the largest member db of the cluster reaches the backend quota at the estimated growth rate",
+	},
+		[]string{"ClusterName"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(dbGrowthRate)
+	prometheus.MustRegister(dbTimeToQuota)
+}
+
+type dbSizeSample struct {
+	time time.Time
+	size int64
+}
+
+// dbGrowthTracker keeps the db size samples of a cluster.
+type dbGrowthTracker struct {
+	samples []dbSizeSample
+}
+
+// record records the db size unless the latest sample is more recent than the sample interval.
+// It returns whether the size was recorded.
+// A db which shrank, e.g. after defragmentation, starts a new window, so that the growth
+// is not underestimated by the space the defragmentation reclaimed.
+func (t *dbGrowthTracker) record(size int64, now time.Time) bool {
+	if n := len(t.samples); n > 0 {
+		last := t.samples[n-1]
+		if size < last.size {
+			t.samples = nil
+		} else if now.Sub(last.time) < dbGrowthSampleInterval {
+			return false
+		}
+	}
+	t.samples = append(t.samples, dbSizeSample{time: now, size: size})
+	i := 0
+	for i < len(t.samples)-1 && now.Sub(t.samples[i].time) > dbGrowthWindow {
+		i++
+	}
+	t.samples = t.samples[i:]
+	return true
+}
+
+// rate returns the growth rate in bytes per second over the samples,
+// and false if they do not span long enough for an estimate.
+func (t *dbGrowthTracker) rate() (float64, bool) {
+	if len(t.samples) < 2 {
+		return 0, false
+	}
+	first, last := t.samples[0], t.samples[len(t.samples)-1]
+	span := last.time.Sub(first.time)
+	if span < minDBGrowthWindow {
+		return 0, false
+	}
+	return float64(last.size-first.size) / span.Seconds(), true
+}
+
+// status returns the growth of the db and the projected time it reaches the quota,
+// or nil if the samples do not span long enough for an estimate.
+func (t *dbGrowthTracker) status(quota int64, now time.Time) *spec.DBGrowthStatus {
+	r, ok := t.rate()
+	if !ok {
+		return nil
+	}
+	first, last := t.samples[0], t.samples[len(t.samples)-1]
+	st := &spec.DBGrowthStatus{
+		BytesPerDay:       int64(r*(24*time.Hour).Seconds() + 0.5),
+		WindowStartTime:   first.time.Format(time.RFC3339),
+		WindowStartDBSize: first.size,
+		UpdateTime:        now.Format(time.RFC3339),
+	}
+	if r > 0 && last.size < quota {
+		secs := int64(float64(quota-last.size)/r + 0.5)
+		st.ProjectedQuotaTime = last.time.Add(time.Duration(secs) * time.Second).Format(time.RFC3339)
+	}
+	return st
+}
+
+// newDBGrowthTracker returns a tracker seeded with the window start recorded in status,
+// so that the estimate survives a restart of the operator.
+func newDBGrowthTracker(st *spec.DBGrowthStatus) *dbGrowthTracker {
+	t := &dbGrowthTracker{}
+	if st == nil {
+		return t
+	}
+	start, err := time.Parse(time.RFC3339, st.WindowStartTime)
+	if err != nil {
+		return t
+	}
+	t.samples = []dbSizeSample{{time: start, size: st.WindowStartDBSize}}
+	return t
+}
+
+// updateDBGrowth samples the size of the largest member db of the cluster
+// and updates the estimated growth in status and metrics.
+func (c *Cluster) updateDBGrowth(dbSize map[string]int64, quota int64) {
+	if len(dbSize) == 0 {
+		return
+	}
+	if c.dbGrowth == nil {
+		c.dbGrowth = newDBGrowthTracker(c.status.DBGrowth)
+	}
+	var max int64
+	for _, s := range dbSize {
+		if s > max {
+			max = s
+		}
+	}
+	now := time.Now()
+	if !c.dbGrowth.record(max, now) {
+		return
+	}
+
+	st := c.dbGrowth.status(quota, now)
+	c.status.DBGrowth = st
+	if st == nil {
+		deleteDBGrowthMetrics(c.name())
+		return
+	}
+	r, _ := c.dbGrowth.rate()
+	dbGrowthRate.WithLabelValues(c.name()).Set(r)
+	if len(st.ProjectedQuotaTime) == 0 {
+		dbTimeToQuota.DeleteLabelValues(c.name())
+		return
+	}
+	pt, _ := time.Parse(time.RFC3339, st.ProjectedQuotaTime)
+	dbTimeToQuota.WithLabelValues(c.name()).Set(pt.Sub(now).Seconds())
+}
+
+func deleteDBGrowthMetrics(clusterName string) {
+	dbGrowthRate.DeleteLabelValues(clusterName)
+	dbTimeToQuota.DeleteLabelValues(clusterName)
+}
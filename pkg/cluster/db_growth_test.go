@@ -0,0 +1,96 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+)
+
+func TestDBGrowthTrackerStatus(t *testing.T) {
+	mb := int64(1024 * 1024)
+	start := time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		sizes []int64 // one sample per dbGrowthSampleInterval
+		quota int64
+
+		wStatus    bool
+		wPerDay    int64
+		wProjected string
+	}{
+		// less than an hour of samples
+		{sizes: []int64{mb, 2 * mb, 3 * mb}, quota: 100 * mb, wStatus: false},
+		// 4MB per hour, 96MB left to the quota
+		{sizes: []int64{0, mb, 2 * mb, 3 * mb, 4 * mb}, quota: 100 * mb, wStatus: true, wPerDay: 96 * mb, wProjected: "2017-06-02T01:00:00Z"},
+		// no growth
+		{sizes: []int64{mb, mb, mb, mb, mb}, quota: 100 * mb, wStatus: true, wPerDay: 0},
+		// the window restarts after the db shrank
+		{sizes: []int64{10 * mb, 20 * mb, 30 * mb, 40 * mb, 5 * mb, 6 * mb}, quota: 100 * mb, wStatus: false},
+	}
+	for i, tt := range tests {
+		tr := &dbGrowthTracker{}
+		now := start
+		for _, s := range tt.sizes {
+			tr.record(s, now)
+			now = now.Add(dbGrowthSampleInterval)
+		}
+		st := tr.status(tt.quota, now)
+		if (st != nil) != tt.wStatus {
+			t.Errorf("#%d: status get=%v, want status=%v", i, st, tt.wStatus)
+			continue
+		}
+		if st == nil {
+			continue
+		}
+		if st.BytesPerDay != tt.wPerDay {
+			t.Errorf("#%d: bytes per day get=%d, want=%d", i, st.BytesPerDay, tt.wPerDay)
+		}
+		if st.ProjectedQuotaTime != tt.wProjected {
+			t.Errorf("#%d: projected quota time get=%s, want=%s", i, st.ProjectedQuotaTime, tt.wProjected)
+		}
+	}
+}
+
+func TestDBGrowthTrackerWindow(t *testing.T) {
+	tr := &dbGrowthTracker{}
+	now := time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC)
+	if !tr.record(1, now) {
+		t.Fatal("expect the first sample to be recorded")
+	}
+	if tr.record(2, now.Add(time.Minute)) {
+		t.Errorf("expect no sample before the sample interval elapses")
+	}
+	for i := 1; i <= 1000; i++ {
+		tr.record(int64(i), now.Add(time.Duration(i)*dbGrowthSampleInterval))
+	}
+	last := tr.samples[len(tr.samples)-1].time
+	if span := last.Sub(tr.samples[0].time); span > dbGrowthWindow {
+		t.Errorf("samples span get=%v, want<=%v", span, dbGrowthWindow)
+	}
+}
+
+func TestNewDBGrowthTrackerFromStatus(t *testing.T) {
+	st := &spec.DBGrowthStatus{WindowStartTime: "2017-06-01T00:00:00Z", WindowStartDBSize: 1024}
+	tr := newDBGrowthTracker(st)
+	if len(tr.samples) != 1 || tr.samples[0].size != 1024 {
+		t.Fatalf("samples get=%v, want the window start", tr.samples)
+	}
+	tr.record(2048, time.Date(2017, 6, 1, 2, 0, 0, 0, time.UTC))
+	if r, ok := tr.rate(); !ok || r <= 0 {
+		t.Errorf("rate get=(%v, %v), want a positive rate", r, ok)
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/cronutil"
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
+)
+
+// minDBSizeDefragInterval is the minimum interval between two defragmentations
+// of a member triggered by its db size. Defragmentation only reclaims free pages,
+// so the db size might stay above the threshold afterwards.
+const minDBSizeDefragInterval = time.Hour
+
+// defragIfNeeded defragments the members which are due according to the defrag policy.
+// Up to spec.maxUnavailable members are defragmented at a time, followers first and the leader last.
+func (c *Cluster) defragIfNeeded() {
+	dp := c.cluster.Spec.Defrag
+	if dp == nil {
+		c.status.Members.LastDefragTime = nil
+		return
+	}
+	if c.status.Members.LastDefragTime == nil {
+		c.status.Members.LastDefragTime = map[string]string{}
+	}
+	for name := range c.status.Members.LastDefragTime {
+		if _, ok := c.members[name]; !ok {
+			delete(c.status.Members.LastDefragTime, name)
+		}
+	}
+	if len(c.status.Members.Draining) > 0 {
+		c.logger.Infof("skip defragmentation: member(s) %v are draining", c.status.Members.Draining)
+		return
+	}
+
+	now := time.Now()
+	var due []*etcdutil.Member
+	leader := ""
+	for _, m := range c.members {
+		st, err := c.memberStatus(m.ClientAddr())
+		if err != nil {
+			// Defragmenting blocks a member. Skip it unless all members are healthy.
+			c.logger.Warningf("skip defragmentation: %v", err)
+			return
+		}
+		if m.ID == st.Leader {
+			leader = m.Name
+		}
+		if isDefragDue(dp, c.status.Members.LastDefragTime[m.Name], c.cluster.Metadata.CreationTimestamp.Time, st.DbSize, now) {
+			due = append(due, m)
+		}
+	}
+
+	if err := c.defragmentMembers(leaderLast(due, leader), leader); err != nil {
+		c.logger.Errorf("%v", err)
+	}
+}
+
+// defragmentMembers defragments the given members ordered by leaderLast in batches of up to
+// spec.maxUnavailable members, see maintenanceBatches. The members of a batch are defragmented
+// at the same time. It stops after the first batch with a failure.
+func (c *Cluster) defragmentMembers(ms []*etcdutil.Member, leader string) error {
+	for _, batch := range maintenanceBatches(ms, leader, c.maxUnavailable()) {
+		errs := make([]error, len(batch))
+		var wg sync.WaitGroup
+		for i, m := range batch {
+			c.logger.Infof("defragmenting member (%s)", m.Name)
+			wg.Add(1)
+			go func(i int, m *etcdutil.Member) {
+				defer wg.Done()
+				errs[i] = etcdutil.DefragmentMember(m.ClientAddr(), c.tlsConfig)
+			}(i, m)
+		}
+		wg.Wait()
+
+		var failed error
+		for i, m := range batch {
+			err := errs[i]
+			c.countEtcdClientFailure(rpcDefragment, err)
+			c.createEvent(k8sutil.MemberDefragmentedEvent(c.cluster, m.Name, err))
+			if err != nil {
+				if failed == nil {
+					failed = fmt.Errorf("failed to defragment member (%s): %v", m.Name, err)
+				}
+				continue
+			}
+			if c.status.Members.LastDefragTime != nil {
+				c.status.Members.LastDefragTime[m.Name] = time.Now().Format(time.RFC3339)
+			}
+		}
+		if failed != nil {
+			return failed
+		}
+	}
+	return nil
+}
+
+// isDefragDue returns true if a member with the given last defragmentation time and
+// db size needs to be defragmented according to the defrag policy.
+// A member which has not been defragmented yet is due on the schedule from the creation of the cluster.
+func isDefragDue(dp *spec.DefragPolicy, lastDefrag string, created time.Time, dbSize int64, now time.Time) bool {
+	last, err := time.Parse(time.RFC3339, lastDefrag)
+	if err != nil {
+		// The member has not been defragmented by the operator yet.
+		last = time.Time{}
+	}
+	since := now.Sub(last)
+
+	if dp.DBSizeThresholdInMB > 0 && dbSize > int64(dp.DBSizeThresholdInMB)*1024*1024 && since >= minDBSizeDefragInterval {
+		return true
+	}
+	if len(dp.Schedule) != 0 {
+		from := last
+		if from.IsZero() {
+			from = created
+		}
+		if sched, err := cronutil.Parse(dp.Schedule); err == nil {
+			if next := sched.Next(from.In(now.Location())); !next.IsZero() && !next.After(now) {
+				return true
+			}
+		}
+	}
+	return dp.IntervalInSecond > 0 && since >= time.Duration(dp.IntervalInSecond)*time.Second
+}
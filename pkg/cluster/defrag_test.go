@@ -0,0 +1,58 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+)
+
+func TestIsDefragDue(t *testing.T) {
+	// 2017-06-14 is a Wednesday.
+	now := time.Date(2017, 6, 14, 10, 30, 0, 0, time.UTC)
+	ago := func(d time.Duration) string { return now.Add(-d).Format(time.RFC3339) }
+	mb := int64(1024 * 1024)
+
+	tests := []struct {
+		dp         spec.DefragPolicy
+		lastDefrag string
+		created    time.Time
+		dbSize     int64
+		wDue       bool
+	}{
+		{dp: spec.DefragPolicy{IntervalInSecond: 3600}, lastDefrag: "", wDue: true},
+		{dp: spec.DefragPolicy{IntervalInSecond: 3600}, lastDefrag: ago(30 * time.Minute), wDue: false},
+		{dp: spec.DefragPolicy{IntervalInSecond: 3600}, lastDefrag: ago(2 * time.Hour), wDue: true},
+		{dp: spec.DefragPolicy{DBSizeThresholdInMB: 100}, lastDefrag: "", dbSize: 50 * mb, wDue: false},
+		{dp: spec.DefragPolicy{DBSizeThresholdInMB: 100}, lastDefrag: "", dbSize: 200 * mb, wDue: true},
+		{dp: spec.DefragPolicy{DBSizeThresholdInMB: 100}, lastDefrag: ago(time.Minute), dbSize: 200 * mb, wDue: false},
+		{dp: spec.DefragPolicy{DBSizeThresholdInMB: 100}, lastDefrag: ago(2 * time.Hour), dbSize: 200 * mb, wDue: true},
+		{dp: spec.DefragPolicy{Schedule: "0 * * * *"}, lastDefrag: ago(20 * time.Minute), wDue: false},
+		{dp: spec.DefragPolicy{Schedule: "0 * * * *"}, lastDefrag: ago(40 * time.Minute), wDue: true},
+		{dp: spec.DefragPolicy{Schedule: "0 3 * * 6"}, lastDefrag: ago(3 * 24 * time.Hour), wDue: false},
+		{dp: spec.DefragPolicy{Schedule: "0 3 * * 6"}, lastDefrag: ago(6 * 24 * time.Hour), wDue: true},
+		// members which were never defragmented are due from the creation of the cluster.
+		{dp: spec.DefragPolicy{Schedule: "0 * * * *"}, created: now.Add(-20 * time.Minute), wDue: false},
+		{dp: spec.DefragPolicy{Schedule: "0 * * * *"}, created: now.Add(-40 * time.Minute), wDue: true},
+	}
+	for i, tt := range tests {
+		due := isDefragDue(&tt.dp, tt.lastDefrag, tt.created, tt.dbSize, now)
+		if due != tt.wDue {
+			t.Errorf("#%d: defrag due get=%v, want=%v", i, due, tt.wDue)
+		}
+	}
+}
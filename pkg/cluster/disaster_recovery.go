@@ -0,0 +1,55 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
+)
+
+func isDisasterRecoveryApproved(cl *spec.Cluster) bool {
+	return cl.Metadata.Annotations[spec.ApproveDisasterRecoveryAnnotation] == "true"
+}
+
+// disasterRecoveryAllowed returns true if the disaster recovery policy lets the operator
+// recreate the cluster from its backup now.
+// With manual approval, the approval is consumed, so that a later disaster needs a new one.
+// Otherwise the recovery is held, since the members the operator sees as dead might
+// still serve clients behind a partition.
+func (c *Cluster) disasterRecoveryAllowed(left etcdutil.MemberSet) bool {
+	if c.cluster.Spec.GetDisasterRecoveryPolicy() != spec.DisasterRecoveryManualApproval {
+		return true
+	}
+	if isDisasterRecoveryApproved(c.cluster) {
+		if err := c.removeAnnotations(spec.ApproveDisasterRecoveryAnnotation); err != nil {
+			c.logger.Warningf("failed to remove disaster recovery approval, retry on next reconcile: %v", err)
+			return false
+		}
+		c.logger.Info("disaster recovery approved")
+		return true
+	}
+
+	reason := fmt.Sprintf("%d of %d member(s) running, waiting for approval to recover from backup: annotate the cluster with %s=true",
+		left.Size(), c.cluster.Spec.Size, spec.ApproveDisasterRecoveryAnnotation)
+	c.logger.Warning(reason)
+	if !c.status.IsRecoveryPending() {
+		c.createEvent(k8sutil.RecoveryPendingEvent(c.cluster, reason))
+	}
+	c.status.SetRecoveryPendingCondition(reason)
+	return false
+}
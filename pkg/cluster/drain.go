@@ -0,0 +1,197 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+// updateDrainingMembers records the members which are being evicted, e.g. by a node drain,
+// in status.members.draining and posts an event for each member once.
+// While members are draining, the operator holds its own disruptions of the cluster,
+// so that they do not add up with the evictions. See isMemberDraining.
+// It returns the names of the draining members and the nodes of the member pods by name.
+func (c *Cluster) updateDrainingMembers(pods []*v1.Pod) ([]string, map[string]*v1.Node) {
+	notified := map[string]bool{}
+	for _, name := range c.status.Members.Draining {
+		notified[name] = true
+	}
+	nodes := c.memberNodes(pods)
+	var draining []string
+	for _, pod := range pods {
+		if !isMemberDraining(pod, nodes[pod.Name]) {
+			continue
+		}
+		draining = append(draining, pod.Name)
+		if !notified[pod.Name] {
+			c.logger.Infof("member (%s) on node (%s) is draining", pod.Name, pod.Spec.NodeName)
+			c.createEvent(k8sutil.MemberDrainingEvent(c.cluster, pod.Name, pod.Spec.NodeName))
+		}
+	}
+	sort.Strings(draining)
+	c.status.Members.Draining = draining
+	return draining, nodes
+}
+
+// memberNodes returns the nodes the member pods run on by member name.
+// Members whose node cannot be read are left out.
+func (c *Cluster) memberNodes(pods []*v1.Pod) map[string]*v1.Node {
+	byName := map[string]*v1.Node{}
+	nodes := map[string]*v1.Node{}
+	for _, pod := range pods {
+		nn := pod.Spec.NodeName
+		if len(nn) == 0 {
+			continue
+		}
+		node, ok := nodes[nn]
+		if !ok {
+			var err error
+			node, err = c.config.KubeCli.CoreV1().Nodes().Get(nn, metav1.GetOptions{})
+			if err != nil {
+				c.logger.Warningf("failed to get node (%s) of member (%s): %v", nn, pod.Name, err)
+				node = nil
+			}
+			nodes[nn] = node
+		}
+		if node != nil {
+			byName[pod.Name] = node
+		}
+	}
+	return byName
+}
+
+// isMemberDraining tells whether the member pod is going away because of a voluntary disruption:
+// it is being deleted, e.g. evicted, or its node is being evacuated, see isNodeEvacuated.
+func isMemberDraining(pod *v1.Pod, node *v1.Node) bool {
+	return pod.DeletionTimestamp != nil || (node != nil && isNodeEvacuated(pod, node))
+}
+
+// isNodeEvacuated tells whether the pod has to leave its node: the node is cordoned, which precedes a drain,
+// or it has a NoExecute taint the pod does not tolerate.
+func isNodeEvacuated(pod *v1.Pod, node *v1.Node) bool {
+	if node.Spec.Unschedulable {
+		return true
+	}
+	for i := range node.Spec.Taints {
+		taint := &node.Spec.Taints[i]
+		if taint.Effect == v1.TaintEffectNoExecute && !toleratesTaint(pod.Spec.Tolerations, taint) {
+			return true
+		}
+	}
+	return false
+}
+
+func toleratesTaint(tolerations []v1.Toleration, taint *v1.Taint) bool {
+	for _, t := range tolerations {
+		if len(t.Effect) != 0 && t.Effect != taint.Effect {
+			continue
+		}
+		if len(t.Key) == 0 && t.Operator == v1.TolerationOpExists {
+			return true
+		}
+		if t.Key != taint.Key {
+			continue
+		}
+		if t.Operator == v1.TolerationOpExists || t.Value == taint.Value {
+			return true
+		}
+	}
+	return false
+}
+
+// pickMemberToEvacuate returns a member on an evacuated node whose pod is not being deleted yet,
+// or nil if there is none. Followers are picked before the leader.
+func (c *Cluster) pickMemberToEvacuate(pods []*v1.Pod, nodes map[string]*v1.Node) *etcdutil.Member {
+	var ms []*etcdutil.Member
+	for _, pod := range pods {
+		node := nodes[pod.Name]
+		if pod.DeletionTimestamp != nil || node == nil || !isNodeEvacuated(pod, node) {
+			continue
+		}
+		if m, ok := c.members[pod.Name]; ok {
+			ms = append(ms, m)
+		}
+	}
+	if len(ms) == 0 {
+		return nil
+	}
+	leader, err := c.leaderName()
+	if err != nil {
+		c.logger.Warningf("failed to find the leader: %v", err)
+	}
+	return leaderLast(ms, leader)[0]
+}
+
+// evacuateMember removes the member from the cluster, so that the next reconciles add a new member
+// in its place, which the scheduler places on another node. The member is kept while no node can take
+// its replacement, so that the cluster does not lose a member it cannot get back.
+func (c *Cluster) evacuateMember(m *etcdutil.Member, pods []*v1.Pod, node string) error {
+	if !c.quorumGuardAllows("evacuate", m, true) {
+		return nil
+	}
+	var member *v1.Pod
+	for _, pod := range pods {
+		if pod.Name == m.Name {
+			member = pod
+		}
+	}
+	nodes, err := c.listNodes()
+	if err != nil {
+		return err
+	}
+	if member == nil || !replacementNodeExists(member, pods, nodes) {
+		if c.evacuationBlocked != m.Name {
+			reason := fmt.Sprintf("No schedulable node can take the replacement of member %s on node %s", m.Name, node)
+			c.logger.Warningf("holding the evacuation of member (%s): no schedulable node can take its replacement", m.Name)
+			c.createEvent(k8sutil.EvacuationBlockedEvent(c.cluster, reason))
+			c.evacuationBlocked = m.Name
+		}
+		return nil
+	}
+	c.evacuationBlocked = ""
+	c.logger.Infof("evacuating member (%s) from node (%s)", m.Name, node)
+	c.status.AppendEvacuatingMember(m.Name, node)
+	if err := c.removeMember(m); err != nil {
+		return err
+	}
+	c.audit(auditMemberRemoved, m.Name, fmt.Sprintf("evacuated from cordoned or tainted node %s", node))
+	return nil
+}
+
+// replacementNodeExists tells whether one of the nodes can take a new member with the spec of the member pod:
+// a ready and schedulable node other than the one of the member, which fits the pod, see podFitsNode.
+// With the required anti-affinity of members, the nodes of the other members are left out as well.
+func replacementNodeExists(member *v1.Pod, pods []*v1.Pod, nodes []v1.Node) bool {
+	taken := map[string]bool{member.Spec.NodeName: true}
+	if a := member.Spec.Affinity; a != nil && a.PodAntiAffinity != nil && len(a.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution) != 0 {
+		for _, pod := range pods {
+			taken[pod.Spec.NodeName] = true
+		}
+	}
+	for i := range nodes {
+		node := &nodes[i]
+		if !taken[node.Name] && !node.Spec.Unschedulable && isNodeReady(node) && podFitsNode(member, node) {
+			return true
+		}
+	}
+	return false
+}
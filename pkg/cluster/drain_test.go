@@ -0,0 +1,119 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+func TestIsMemberDraining(t *testing.T) {
+	now := metav1.Now()
+	tests := []struct {
+		deletion *metav1.Time
+		node     *v1.Node
+		w        bool
+	}{
+		{deletion: nil, node: nil, w: false},
+		{deletion: nil, node: &v1.Node{}, w: false},
+		{deletion: &now, node: nil, w: true},
+		{deletion: nil, node: &v1.Node{Spec: v1.NodeSpec{Unschedulable: true}}, w: true},
+	}
+	for i, tt := range tests {
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "a-0000", DeletionTimestamp: tt.deletion}}
+		if get := isMemberDraining(pod, tt.node); get != tt.w {
+			t.Errorf("#%d: draining get=%v, want=%v", i, get, tt.w)
+		}
+	}
+}
+
+func TestIsNodeEvacuated(t *testing.T) {
+	noExecute := v1.Taint{Key: "node.example.com/maintenance", Value: "true", Effect: v1.TaintEffectNoExecute}
+	noSchedule := v1.Taint{Key: "dedicated", Value: "etcd", Effect: v1.TaintEffectNoSchedule}
+	tests := []struct {
+		taints      []v1.Taint
+		tolerations []v1.Toleration
+		w           bool
+	}{
+		{taints: nil, w: false},
+		{taints: []v1.Taint{noSchedule}, w: false},
+		{taints: []v1.Taint{noExecute}, w: true},
+		{taints: []v1.Taint{noExecute}, tolerations: []v1.Toleration{
+			{Key: "node.example.com/maintenance", Operator: v1.TolerationOpEqual, Value: "true", Effect: v1.TaintEffectNoExecute},
+		}, w: false},
+		{taints: []v1.Taint{noExecute}, tolerations: []v1.Toleration{
+			{Key: "node.example.com/maintenance", Operator: v1.TolerationOpExists},
+		}, w: false},
+		{taints: []v1.Taint{noExecute}, tolerations: []v1.Toleration{{Operator: v1.TolerationOpExists}}, w: false},
+		{taints: []v1.Taint{noExecute}, tolerations: []v1.Toleration{
+			{Key: "node.example.com/maintenance", Operator: v1.TolerationOpEqual, Value: "false"},
+		}, w: true},
+		{taints: []v1.Taint{noExecute}, tolerations: []v1.Toleration{
+			{Key: "node.example.com/maintenance", Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule},
+		}, w: true},
+	}
+	for i, tt := range tests {
+		pod := &v1.Pod{Spec: v1.PodSpec{Tolerations: tt.tolerations}}
+		node := &v1.Node{Spec: v1.NodeSpec{Taints: tt.taints}}
+		if get := isNodeEvacuated(pod, node); get != tt.w {
+			t.Errorf("#%d: evacuated get=%v, want=%v", i, get, tt.w)
+		}
+	}
+}
+
+func TestReplacementNodeExists(t *testing.T) {
+	ready := v1.NodeStatus{Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}}}
+	node := func(name string, labels map[string]string, unschedulable bool, status v1.NodeStatus) v1.Node {
+		return v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+			Spec:       v1.NodeSpec{Unschedulable: unschedulable},
+			Status:     status,
+		}
+	}
+	antiAffinity := &v1.Affinity{PodAntiAffinity: &v1.PodAntiAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: []v1.PodAffinityTerm{{TopologyKey: "kubernetes.io/hostname"}},
+	}}
+	member := func(name, nodeName string, affinity *v1.Affinity) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       v1.PodSpec{NodeName: nodeName, Affinity: affinity, NodeSelector: map[string]string{"pool": "etcd"}},
+		}
+	}
+	etcdPool := map[string]string{"pool": "etcd"}
+	tests := []struct {
+		affinity *v1.Affinity
+		nodes    []v1.Node
+		w        bool
+	}{
+		{nodes: []v1.Node{node("n0", etcdPool, true, ready), node("n3", etcdPool, false, ready)}, w: true},
+		// the node of the member is cordoned.
+		{nodes: []v1.Node{node("n0", etcdPool, true, ready)}, w: false},
+		{nodes: []v1.Node{node("n3", etcdPool, true, ready)}, w: false},
+		{nodes: []v1.Node{node("n3", etcdPool, false, v1.NodeStatus{})}, w: false},
+		{nodes: []v1.Node{node("n3", map[string]string{"pool": "app"}, false, ready)}, w: false},
+		// the other members may share a node without anti-affinity, but not with it.
+		{nodes: []v1.Node{node("n1", etcdPool, false, ready)}, w: true},
+		{affinity: antiAffinity, nodes: []v1.Node{node("n1", etcdPool, false, ready)}, w: false},
+		{affinity: antiAffinity, nodes: []v1.Node{node("n1", etcdPool, false, ready), node("n3", etcdPool, false, ready)}, w: true},
+	}
+	for i, tt := range tests {
+		pods := []*v1.Pod{member("m0", "n0", tt.affinity), member("m1", "n1", tt.affinity), member("m2", "n2", tt.affinity)}
+		if get := replacementNodeExists(pods[0], pods, tt.nodes); get != tt.w {
+			t.Errorf("#%d: replacement node exists get=%v, want=%v", i, get, tt.w)
+		}
+	}
+}
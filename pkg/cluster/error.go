@@ -23,6 +23,16 @@ var (
 	errUnexpectedUnreadyMember = errors.New("unexpected unready member for selfhosted cluster")
 
 	errCreatedCluster = errors.New("cluster failed to be created")
+
+	errSelfHostedDisabled = errors.New("self-hosted clusters need the SelfHosted feature gate")
+
+	errNamespaceTerminating = errors.New("namespace of the cluster is being terminated")
+
+	errNoBackupPolicy = errors.New("cluster has no backup policy")
+	errNoExportPolicy = errors.New("cluster has no export policy")
+	errNoImportPolicy = errors.New("cluster has no import policy")
+
+	errNoS3ConfigForDump = errors.New("prefix dumps need the operator wide S3 bucket (--backup-s3-bucket)")
 )
 
 func isFatalError(err error) bool {
@@ -0,0 +1,118 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/coreos/etcd-operator/pkg/util/constants"
+	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
+
+	"github.com/coreos/etcd/clientv3"
+	"golang.org/x/net/context"
+)
+
+// etcdClient returns the client of the etcd cluster. The client is kept across
+// reconciliations and recreated when the client URLs of the members or spec.pod.etcdctlAuthSecret change.
+// With spec.pod.etcdctlAuthSecret set, the client authenticates as the etcd user of the secret.
+func (c *Cluster) etcdClient(clientURLs []string) (*clientv3.Client, error) {
+	urls := append([]string(nil), clientURLs...)
+	sort.Strings(urls)
+	authSecret := c.etcdAuthSecret()
+	if c.etcdcli != nil && reflect.DeepEqual(urls, c.etcdcliURLs) && authSecret == c.etcdcliAuthSecret {
+		return c.etcdcli, nil
+	}
+	c.closeEtcdClient()
+
+	cfg := clientv3.Config{
+		Endpoints:   urls,
+		DialTimeout: constants.DefaultDialTimeout,
+		TLS:         c.tlsConfig,
+	}
+	if len(authSecret) != 0 {
+		d, err := k8sutil.GetEtcdAuthDataFromSecret(c.config.KubeCli, c.cluster.Metadata.Namespace, authSecret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get etcd credentials: %v", err)
+		}
+		cfg.Username, cfg.Password = d.Username, d.Password
+	}
+	cli, err := clientv3.New(cfg)
+	if err != nil {
+		c.countEtcdClientFailure(rpcDial, err)
+		return nil, fmt.Errorf("failed to create etcd client: %v", err)
+	}
+	c.etcdcli, c.etcdcliURLs, c.etcdcliAuthSecret = cli, urls, authSecret
+	return cli, nil
+}
+
+// etcdAuthSecret returns spec.pod.etcdctlAuthSecret, or "" if the cluster has none.
+func (c *Cluster) etcdAuthSecret() string {
+	if c.cluster.Spec.Pod == nil {
+		return ""
+	}
+	return c.cluster.Spec.Pod.EtcdctlAuthSecret
+}
+
+func (c *Cluster) closeEtcdClient() {
+	if c.etcdcli == nil {
+		return
+	}
+	if err := c.etcdcli.Close(); err != nil {
+		c.logger.Warningf("failed to close etcd client: %v", err)
+	}
+	c.etcdcli, c.etcdcliURLs, c.etcdcliAuthSecret = nil, nil, ""
+}
+
+// memberStatus returns the status of the etcd member serving on the given client URL.
+func (c *Cluster) memberStatus(url string) (*clientv3.StatusResponse, error) {
+	urls := []string{url}
+	if c.members != nil {
+		urls = c.members.ClientURLs()
+	}
+	cli, err := c.etcdClient(urls)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), constants.DefaultRequestTimeout)
+	resp, err := cli.Status(ctx, url)
+	cancel()
+	c.countEtcdClientFailure(rpcStatus, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status of %s: %v", url, err)
+	}
+	return resp, nil
+}
+
+// memberList returns the members of the etcd cluster.
+func (c *Cluster) memberList(clientURLs []string) (*clientv3.MemberListResponse, error) {
+	cli, err := c.etcdClient(clientURLs)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), constants.DefaultRequestTimeout)
+	resp, err := cli.MemberList(ctx)
+	cancel()
+	c.countEtcdClientFailure(rpcMemberList, err)
+	return resp, err
+}
+
+// countEtcdClientFailure counts the etcd client request of the operator to the cluster if it failed.
+func (c *Cluster) countEtcdClientFailure(rpc string, err error) {
+	if err != nil {
+		etcdClientFailures.WithLabelValues(c.name(), rpc).Inc()
+	}
+}
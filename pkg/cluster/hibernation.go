@@ -0,0 +1,195 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
+
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+// hibernate deletes all etcd pods of the cluster.
+// If the members keep their data on PVCs, only the pods are deleted: the PVCs are kept, and the member names
+// are kept in status.members.unready, so that resume recreates the pods on them. A backup is made first
+// if there is a backup policy, but hibernation does not depend on it.
+// Otherwise the data volumes go with the pods and the backup is what retains the data.
+// If the backup fails then, the pods are kept and hibernation is retried on the next reconcile.
+func (c *Cluster) hibernate() error {
+	keepData := k8sutil.HasMemberPVC(c.cluster.Spec)
+	if c.bm == nil && !keepData {
+		return errNoBackupExist
+	}
+	running, pending, err := c.pollPods()
+	if err != nil {
+		return err
+	}
+	if len(running) == 0 && len(pending) == 0 {
+		if !c.status.Hibernated {
+			c.setHibernated(c.membersToKeep(keepData, nil))
+		}
+		return nil
+	}
+
+	if len(running) > 0 && c.bm != nil {
+		if err := c.bm.requestBackup(); err != nil {
+			if !keepData {
+				return fmt.Errorf("failed to back up cluster before hibernating it: %v", err)
+			}
+			c.logger.Warningf("failed to back up cluster before hibernating it, the data is kept on the member PVCs: %v", err)
+		} else {
+			c.logger.Info("made a latest backup")
+		}
+	}
+	pods := append(running, pending...)
+	members := c.membersToKeep(keepData, pods)
+	if keepData {
+		c.logger.Info("deleting all etcd pods, keeping their PVCs")
+		for _, pod := range pods {
+			if err := c.removePod(pod.Name); err != nil {
+				return err
+			}
+		}
+	} else {
+		c.logger.Info("deleting all etcd pods")
+		if err := c.removeAllPodsAndData(pods); err != nil {
+			return err
+		}
+	}
+	c.members = nil
+	c.closeEtcdClient()
+	c.setHibernated(members)
+	if keepData {
+		c.audit(auditClusterHibernated, "", fmt.Sprintf("deleted the pods of %d member(s), keeping their PVCs", len(members)))
+	} else {
+		c.audit(auditClusterHibernated, "", fmt.Sprintf("deleted %d member(s) after backup", len(pods)))
+	}
+	return nil
+}
+
+// membersToKeep returns the sorted names of the members and the given pods if the data is kept, and nil otherwise.
+func (c *Cluster) membersToKeep(keepData bool, pods []*v1.Pod) []string {
+	if !keepData {
+		return nil
+	}
+	names := map[string]bool{}
+	for name := range c.members {
+		names[name] = true
+	}
+	for _, pod := range pods {
+		names[pod.Name] = true
+	}
+	var members []string
+	for name := range names {
+		members = append(members, name)
+	}
+	sort.Strings(members)
+	return members
+}
+
+// setHibernated records the hibernation in status. members are the members whose PVCs are kept.
+func (c *Cluster) setHibernated(members []string) {
+	c.status.Hibernated = true
+	c.status.Size = 0
+	if len(members) == 0 {
+		c.status.Members = spec.MembersStatus{}
+	} else {
+		c.status.Members.Ready = nil
+		c.status.Members.Unready = members
+		c.status.Members.Draining = nil
+	}
+	c.status.SetHibernatedCondition()
+}
+
+// resume recreates the hibernated cluster.
+// If a quorum of the members kept their PVCs, their pods are recreated on them, see resumeOnPVCs.
+// Otherwise, the cluster is recreated from the backup made on hibernation and
+// grows from the seed member to its expected size as usual.
+func (c *Cluster) resume() error {
+	if names := c.status.Members.Unready; k8sutil.HasMemberPVC(c.cluster.Spec) && len(names) != 0 {
+		kept, err := membersWithPVC(names, func(name string) (bool, error) {
+			return k8sutil.MemberPVCExists(c.config.KubeCli, c.cluster.Metadata.Namespace, name)
+		})
+		if err != nil {
+			return err
+		}
+		if isQuorumOf(kept, names) {
+			return c.resumeOnPVCs(names, kept)
+		}
+		c.logger.Warningf("only members %v of %v kept their PVCs, recovering from the backup made on hibernation", kept, names)
+	}
+	c.status.AppendRecoveringCondition()
+	if err := c.recover(); err != nil {
+		return err
+	}
+	c.status.Hibernated = false
+	c.audit(auditClusterResumed, "", "recovered from the backup made on hibernation")
+	return nil
+}
+
+// membersWithPVC returns the names of the members whose PVC exists according to pvcExists.
+func membersWithPVC(names []string, pvcExists func(name string) (bool, error)) ([]string, error) {
+	var kept []string
+	for _, name := range names {
+		ok, err := pvcExists(name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			kept = append(kept, name)
+		}
+	}
+	return kept, nil
+}
+
+// isQuorumOf returns whether the members are a quorum of all members.
+func isQuorumOf(members, all []string) bool {
+	return len(members) >= len(all)/2+1
+}
+
+// resumeOnPVCs recreates the pods of the members which kept their PVCs. They rejoin the cluster
+// with their data, like a restarted cluster, since they never left the etcd membership.
+// The other members of names stay in the membership without a pod: a member ID with empty data
+// cannot rejoin, so the reconcile removes them as dead members and adds new members in their place.
+func (c *Cluster) resumeOnPVCs(names, kept []string) error {
+	ms := etcdutil.MemberSet{}
+	for _, name := range names {
+		ms.Add(&etcdutil.Member{
+			Name:         name,
+			Namespace:    c.cluster.Metadata.Namespace,
+			SecurePeer:   c.isSecurePeer(),
+			SecureClient: c.isSecureClient(),
+		})
+	}
+	for _, name := range kept {
+		if c.config.PodCreateLimiter != nil {
+			c.config.PodCreateLimiter.Accept()
+		}
+		err := c.createPod(ms, ms[name], "existing", "")
+		if err != nil && !k8sutil.IsKubernetesResourceAlreadyExistError(err) {
+			return fmt.Errorf("failed to recreate the pod of member (%s): %v", name, err)
+		}
+	}
+	c.members = ms
+	c.status.Hibernated = false
+	c.status.Size = ms.Size()
+	c.logger.Infof("recreated the pods of members %v on their PVCs", kept)
+	c.audit(auditClusterResumed, "", fmt.Sprintf("recreated the pods of %d of %d member(s) on their PVCs", len(kept), ms.Size()))
+	return nil
+}
@@ -0,0 +1,49 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResumeWithMissingPVC(t *testing.T) {
+	names := []string{"test-0000", "test-0001", "test-0002"}
+	tests := []struct {
+		pvcs map[string]bool
+
+		wKept   []string
+		wOnPVCs bool
+	}{
+		{pvcs: map[string]bool{"test-0000": true, "test-0001": true, "test-0002": true}, wKept: names, wOnPVCs: true},
+		// the member without PVC is left to the dead member replacement
+		{pvcs: map[string]bool{"test-0000": true, "test-0002": true}, wKept: []string{"test-0000", "test-0002"}, wOnPVCs: true},
+		// no quorum of PVCs, recover from the backup
+		{pvcs: map[string]bool{"test-0001": true}, wKept: []string{"test-0001"}, wOnPVCs: false},
+		{pvcs: map[string]bool{}, wKept: nil, wOnPVCs: false},
+	}
+	for i, tt := range tests {
+		kept, err := membersWithPVC(names, func(name string) (bool, error) { return tt.pvcs[name], nil })
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if !reflect.DeepEqual(kept, tt.wKept) {
+			t.Errorf("#%d: kept get=%v, want=%v", i, kept, tt.wKept)
+		}
+		if get := isQuorumOf(kept, names); get != tt.wOnPVCs {
+			t.Errorf("#%d: resume on PVCs get=%v, want=%v", i, get, tt.wOnPVCs)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"strings"
+
+	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
+
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+// updateImageDigests records the digest of the etcd image the running members of each version run,
+// and pins it in spec.etcdImage.digests if spec.etcdImage.pinDigests is set.
+// A member which runs another digest than recorded for its version gets a warning event,
+// since the tag of the version has been moved since.
+func (c *Cluster) updateImageDigests(running []*v1.Pod) {
+	for _, pod := range running {
+		d := etcdImageDigest(pod)
+		if len(d) == 0 {
+			continue
+		}
+		ver := k8sutil.GetEtcdVersion(pod)
+		recorded, ok := c.status.ImageDigests[ver]
+		if !ok {
+			if c.status.ImageDigests == nil {
+				c.status.ImageDigests = map[string]string{}
+			}
+			c.status.ImageDigests[ver] = d
+			recorded = d
+		}
+		if recorded != d && !c.digestMismatches[pod.Name] {
+			if c.digestMismatches == nil {
+				c.digestMismatches = map[string]bool{}
+			}
+			c.digestMismatches[pod.Name] = true
+			c.logger.Warningf("member (%s) runs etcd %s image %s, recorded %s", pod.Name, ver, d, recorded)
+			c.createEvent(k8sutil.ImageDigestMismatchEvent(c.cluster, pod.Name, ver, recorded, d))
+		}
+
+		ip := c.cluster.Spec.EtcdImage
+		if ip == nil || !ip.PinDigests {
+			continue
+		}
+		if _, ok := ip.Digests[ver]; !ok {
+			if ip.Digests == nil {
+				ip.Digests = map[string]string{}
+			}
+			ip.Digests[ver] = recorded
+			c.logger.Infof("pinned etcd %s image to digest %s", ver, recorded)
+		}
+	}
+}
+
+// etcdImageDigest returns the digest of the image the etcd container of the pod runs, if the
+// container runtime reports the repository digest, e.g. "docker-pullable://quay.io/coreos/etcd@sha256:...".
+// Images which were not pulled from a registry only have an image ID.
+func etcdImageDigest(pod *v1.Pod) string {
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.Name != "etcd" {
+			continue
+		}
+		i := strings.LastIndex(cs.ImageID, "@")
+		if i < 0 || !strings.HasPrefix(cs.ImageID[i+1:], "sha256:") {
+			return ""
+		}
+		return cs.ImageID[i+1:]
+	}
+	return ""
+}
@@ -0,0 +1,42 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+func TestEtcdImageDigest(t *testing.T) {
+	digest := "sha256:6c3b2d5e0a1f4e8b9c7d0a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f"
+	tests := []struct {
+		statuses []v1.ContainerStatus
+		wDigest  string
+	}{
+		{statuses: nil, wDigest: ""},
+		{statuses: []v1.ContainerStatus{{Name: "etcd", ImageID: "docker-pullable://quay.io/coreos/etcd@" + digest}}, wDigest: digest},
+		{statuses: []v1.ContainerStatus{{Name: "etcd", ImageID: "quay.io/coreos/etcd@" + digest}}, wDigest: digest},
+		// local image without repository digest
+		{statuses: []v1.ContainerStatus{{Name: "etcd", ImageID: "docker://" + digest}}, wDigest: ""},
+		{statuses: []v1.ContainerStatus{{Name: "sidecar", ImageID: "docker-pullable://example.com/sidecar@" + digest}}, wDigest: ""},
+	}
+	for i, tt := range tests {
+		pod := &v1.Pod{Status: v1.PodStatus{ContainerStatuses: tt.statuses}}
+		if d := etcdImageDigest(pod); d != tt.wDigest {
+			t.Errorf("#%d: digest get=%s, want=%s", i, d, tt.wDigest)
+		}
+	}
+}
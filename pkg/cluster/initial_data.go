@@ -0,0 +1,146 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/constants"
+	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
+
+	"github.com/coreos/etcd/clientv3"
+	"golang.org/x/net/context"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// initialDataMaxRetryDelay caps the delay between attempts to load the initial data.
+const initialDataMaxRetryDelay = 5 * time.Minute
+
+// loadInitialDataIfNeeded puts the keys of spec.initialData into the cluster once.
+// Keys which already exist are kept, so that a retry after a partial failure is safe.
+// A failure is retried with backoff, see initialDataRetryDelay. Only the first failure in a row
+// and the success are posted as events, so that a bad config map does not flood the events.
+func (c *Cluster) loadInitialDataIfNeeded() {
+	ip := c.cluster.Spec.InitialData
+	if ip == nil || c.status.InitialDataLoaded || time.Now().Before(c.initialDataRetryAt) {
+		return
+	}
+	data, err := c.initialData(ip)
+	put := 0
+	if err == nil {
+		put, err = c.putIfAbsent(data)
+	}
+	if err != nil {
+		c.initialDataFailures++
+		delay := initialDataRetryDelay(c.initialDataFailures)
+		c.initialDataRetryAt = time.Now().Add(delay)
+		if c.initialDataFailures == 1 {
+			c.createEvent(k8sutil.InitialDataLoadedEvent(c.cluster, put, len(data), err))
+		}
+		c.logger.Errorf("failed to load initial data (attempt %d), retrying in %v: %v", c.initialDataFailures, delay, err)
+		return
+	}
+	c.createEvent(k8sutil.InitialDataLoadedEvent(c.cluster, put, len(data), nil))
+	c.logger.Infof("loaded initial data: put %d of %d key(s)", put, len(data))
+	c.initialDataFailures = 0
+	c.status.InitialDataLoaded = true
+}
+
+// initialDataRetryDelay returns how long to wait before loading the initial data again
+// after the given number of failures in a row. It doubles from reconcileInterval
+// with every failure, up to initialDataMaxRetryDelay.
+func initialDataRetryDelay(failures int) time.Duration {
+	d := reconcileInterval
+	for i := 1; i < failures && d < initialDataMaxRetryDelay; i++ {
+		d *= 2
+	}
+	if d > initialDataMaxRetryDelay {
+		d = initialDataMaxRetryDelay
+	}
+	return d
+}
+
+func (c *Cluster) initialData(ip *spec.InitialDataPolicy) (map[string]string, error) {
+	raw := ""
+	if sel := ip.ConfigMap; sel != nil {
+		cm, err := c.config.KubeCli.CoreV1().ConfigMaps(c.cluster.Metadata.Namespace).Get(sel.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get config map (%s): %v", sel.Name, err)
+		}
+		var ok bool
+		if raw, ok = cm.Data[sel.Key]; !ok {
+			return nil, fmt.Errorf("config map (%s) has no key (%s)", sel.Name, sel.Key)
+		}
+	}
+	return mergeInitialData(ip.Data, raw)
+}
+
+// mergeInitialData returns the inline keys merged with the keys of the JSON object,
+// which take precedence.
+func mergeInitialData(inline map[string]string, raw string) (map[string]string, error) {
+	data := make(map[string]string, len(inline))
+	for k, v := range inline {
+		data[k] = v
+	}
+	if len(raw) == 0 {
+		return data, nil
+	}
+	var fromConfigMap map[string]string
+	if err := json.Unmarshal([]byte(raw), &fromConfigMap); err != nil {
+		return nil, fmt.Errorf("failed to decode initial data as a JSON object of strings: %v", err)
+	}
+	for k, v := range fromConfigMap {
+		if len(k) == 0 {
+			return nil, errors.New("initial data keys must not be empty")
+		}
+		data[k] = v
+	}
+	return data, nil
+}
+
+// putIfAbsent puts the keys which do not exist yet into the cluster, and returns how many it put.
+func (c *Cluster) putIfAbsent(data map[string]string) (int, error) {
+	cli, err := c.etcdClient(c.members.ClientURLs())
+	if err != nil {
+		return 0, err
+	}
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	put := 0
+	for _, k := range keys {
+		ctx, cancel := context.WithTimeout(context.Background(), constants.DefaultRequestTimeout)
+		resp, err := cli.Txn(ctx).
+			If(clientv3.Compare(clientv3.CreateRevision(k), "=", 0)).
+			Then(clientv3.OpPut(k, data[k])).
+			Commit()
+		cancel()
+		if err != nil {
+			return put, fmt.Errorf("failed to put key (%s): %v", k, err)
+		}
+		if resp.Succeeded {
+			put++
+		}
+	}
+	return put, nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMergeInitialData(t *testing.T) {
+	tests := []struct {
+		inline map[string]string
+		raw    string
+		wData  map[string]string
+		wErr   bool
+	}{
+		{
+			inline: map[string]string{"/app/mode": "prod"},
+			wData:  map[string]string{"/app/mode": "prod"},
+		},
+		{
+			inline: map[string]string{"/app/mode": "prod", "/app/replicas": "3"},
+			raw:    `{"/app/mode": "dev", "/app/region": "eu"}`,
+			wData:  map[string]string{"/app/mode": "dev", "/app/replicas": "3", "/app/region": "eu"},
+		},
+		{
+			raw:  `{"/app/replicas": 3}`,
+			wErr: true,
+		},
+		{
+			raw:  `{"": "empty key"}`,
+			wErr: true,
+		},
+	}
+	for i, tt := range tests {
+		data, err := mergeInitialData(tt.inline, tt.raw)
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(data, tt.wData) {
+			t.Errorf("#%d: data get=%v, want=%v", i, data, tt.wData)
+		}
+	}
+}
+
+func TestInitialDataRetryDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		w        time.Duration
+	}{
+		{failures: 1, w: reconcileInterval},
+		{failures: 2, w: 2 * reconcileInterval},
+		{failures: 4, w: 8 * reconcileInterval},
+		{failures: 100, w: initialDataMaxRetryDelay},
+	}
+	for i, tt := range tests {
+		if get := initialDataRetryDelay(tt.failures); get != tt.w {
+			t.Errorf("#%d: delay get=%v, want=%v", i, get, tt.w)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"sort"
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/constants"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+const (
+	readSerializable = "serializable"
+	readLinearizable = "linearizable"
+
+	// latencyProbeKey is the key the probe reads. It does not need to exist.
+	latencyProbeKey = "/etcd-operator/latency-probe"
+	// latencyWindowSize is the number of recent probes the percentiles are computed from.
+	latencyWindowSize = 100
+	// latencyStatusInterval bounds how often the latencies in status are updated.
+	latencyStatusInterval = 5 * time.Minute
+)
+
+var (
+	readLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "etcd_operator",
+		Subsystem: "cluster",
+		Name:      "read_latency_seconds",
+		Help:      "Latency of the probe reads the operator issues against the cluster",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14),
+	},
+		[]string{"ClusterName", "Read"},
+	)
+
+	readFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "etcd_operator",
+		Subsystem: "cluster",
+		Name:      "read_failed",
+		Help:      "Total number of failed probe reads",
+	},
+		[]string{"ClusterName", "Read"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(readLatency)
+	prometheus.MustRegister(readFailed)
+}
+
+// latencyProber keeps the latencies of the recent probe reads of a cluster.
+type latencyProber struct {
+	samples  map[string][]time.Duration
+	failures map[string][]bool
+	reported time.Time
+}
+
+func newLatencyProber() *latencyProber {
+	return &latencyProber{samples: map[string][]time.Duration{}, failures: map[string][]bool{}}
+}
+
+func (p *latencyProber) record(read string, d time.Duration, failed bool) {
+	if !failed {
+		p.samples[read] = appendWindow(p.samples[read], d)
+	}
+	fs := append(p.failures[read], failed)
+	if len(fs) > latencyWindowSize {
+		fs = fs[len(fs)-latencyWindowSize:]
+	}
+	p.failures[read] = fs
+}
+
+// resetSamples drops the recorded latencies, e.g. after the cluster was scaled.
+func (p *latencyProber) resetSamples() {
+	p.samples = map[string][]time.Duration{}
+	p.failures = map[string][]bool{}
+}
+
+func appendWindow(w []time.Duration, d time.Duration) []time.Duration {
+	w = append(w, d)
+	if len(w) > latencyWindowSize {
+		w = w[len(w)-latencyWindowSize:]
+	}
+	return w
+}
+
+// status returns the latencies of the recent reads, or nil if it is not time to report them.
+func (p *latencyProber) status(now time.Time) *spec.ReadLatencyStatus {
+	if now.Sub(p.reported) < latencyStatusInterval {
+		return nil
+	}
+	s, l := p.samples[readSerializable], p.samples[readLinearizable]
+	n := len(s)
+	if len(l) < n {
+		n = len(l)
+	}
+	failures := 0
+	for _, read := range []string{readSerializable, readLinearizable} {
+		for _, f := range p.failures[read] {
+			if f {
+				failures++
+			}
+		}
+	}
+	p.reported = now
+	return &spec.ReadLatencyStatus{
+		SerializableP99InMs: int64(percentile(s, 0.99) / time.Millisecond),
+		LinearizableP99InMs: int64(percentile(l, 0.99) / time.Millisecond),
+		Samples:             n,
+		Failures:            failures,
+		UpdateTime:          now.Format(time.RFC3339),
+	}
+}
+
+// percentile returns the q-th percentile of the samples, 0 if there is none.
+func percentile(samples []time.Duration, q float64) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Sort(durations(sorted))
+	i := int(float64(len(sorted))*q+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+type durations []time.Duration
+
+func (d durations) Len() int           { return len(d) }
+func (d durations) Less(i, j int) bool { return d[i] < d[j] }
+func (d durations) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+
+// probeReadLatency issues a serializable and a linearizable read against the cluster
+// and records their latencies.
+func (c *Cluster) probeReadLatency() {
+	if c.latency == nil {
+		c.latency = newLatencyProber()
+	}
+	cli, err := c.etcdClient(c.members.ClientURLs())
+	if err != nil {
+		c.logger.Warningf("failed to probe read latency: %v", err)
+		return
+	}
+	probes := []struct {
+		read string
+		opts []clientv3.OpOption
+	}{
+		{read: readSerializable, opts: []clientv3.OpOption{clientv3.WithSerializable()}},
+		{read: readLinearizable},
+	}
+	for _, pr := range probes {
+		ctx, cancel := context.WithTimeout(context.Background(), constants.DefaultRequestTimeout)
+		start := time.Now()
+		_, err := cli.Get(ctx, latencyProbeKey, pr.opts...)
+		d := time.Since(start)
+		cancel()
+		if err != nil {
+			c.logger.Warningf("%s probe read failed: %v", pr.read, err)
+			readFailed.WithLabelValues(c.name(), pr.read).Inc()
+		} else {
+			readLatency.WithLabelValues(c.name(), pr.read).Observe(d.Seconds())
+		}
+		c.latency.record(pr.read, d, err != nil)
+	}
+
+	if st := c.latency.status(time.Now()); st != nil {
+		c.status.ReadLatency = st
+	}
+}
+
+func deleteLatencyMetrics(clusterName string) {
+	for _, read := range []string{readSerializable, readLinearizable} {
+		readLatency.DeleteLabelValues(clusterName, read)
+		readFailed.DeleteLabelValues(clusterName, read)
+	}
+}
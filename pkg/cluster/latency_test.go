@@ -0,0 +1,65 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	var hundred []time.Duration
+	for i := 100; i >= 1; i-- {
+		hundred = append(hundred, time.Duration(i)*time.Millisecond)
+	}
+	tests := []struct {
+		samples []time.Duration
+		q       float64
+		w       time.Duration
+	}{
+		{samples: nil, q: 0.99, w: 0},
+		{samples: []time.Duration{time.Second}, q: 0.99, w: time.Second},
+		{samples: hundred, q: 0.99, w: 99 * time.Millisecond},
+		{samples: hundred, q: 0.5, w: 50 * time.Millisecond},
+	}
+	for i, tt := range tests {
+		if p := percentile(tt.samples, tt.q); p != tt.w {
+			t.Errorf("#%d: percentile get=%v, want=%v", i, p, tt.w)
+		}
+	}
+}
+
+func TestLatencyProberStatus(t *testing.T) {
+	p := newLatencyProber()
+	now := time.Now()
+	for i := 0; i < latencyWindowSize+10; i++ {
+		p.record(readSerializable, time.Millisecond, false)
+		p.record(readLinearizable, 10*time.Millisecond, i%10 == 0)
+	}
+
+	st := p.status(now)
+	if st == nil {
+		t.Fatal("expect status on first report")
+	}
+	if st.SerializableP99InMs != 1 || st.LinearizableP99InMs != 10 {
+		t.Errorf("p99 get=(%d, %d), want=(1, 10)", st.SerializableP99InMs, st.LinearizableP99InMs)
+	}
+	if st.Failures != 10 {
+		t.Errorf("failures get=%d, want=10", st.Failures)
+	}
+	if p.status(now.Add(time.Minute)) != nil {
+		t.Errorf("expect no status before the report interval elapses")
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+)
+
+// leaderLast orders members for disruptive maintenance, e.g. defragmentation or upgrades,
+// which the callers run on up to spec.maxUnavailable members at a time: the followers come first in name order,
+// the leader last. Disrupting the leader last causes at most one election, and the
+// followers already went through the maintenance when the new leader is elected.
+// An empty leader name keeps the name order.
+func leaderLast(ms []*etcdutil.Member, leader string) []*etcdutil.Member {
+	ordered := make([]*etcdutil.Member, 0, len(ms))
+	var lm *etcdutil.Member
+	for _, m := range ms {
+		if m.Name == leader {
+			lm = m
+			continue
+		}
+		ordered = append(ordered, m)
+	}
+	sort.Sort(membersByName(ordered))
+	if lm != nil {
+		ordered = append(ordered, lm)
+	}
+	return ordered
+}
+
+type membersByName []*etcdutil.Member
+
+func (ms membersByName) Len() int           { return len(ms) }
+func (ms membersByName) Less(i, j int) bool { return ms[i].Name < ms[j].Name }
+func (ms membersByName) Swap(i, j int)      { ms[i], ms[j] = ms[j], ms[i] }
+
+// leaderName returns the name of the member the reachable members consider the leader.
+func (c *Cluster) leaderName() (string, error) {
+	var err error
+	for _, m := range c.members {
+		st, serr := c.memberStatus(m.ClientAddr())
+		if serr != nil {
+			err = serr
+			continue
+		}
+		for _, l := range c.members {
+			if l.ID == st.Leader {
+				return l.Name, nil
+			}
+		}
+		return "", errors.New("the cluster has no leader among the known members")
+	}
+	if err == nil {
+		err = errors.New("the cluster has no members")
+	}
+	return "", err
+}
+
+// membersLeaderLast returns the members ordered by leaderLast, and the name of the leader.
+func (c *Cluster) membersLeaderLast() ([]*etcdutil.Member, string, error) {
+	leader, err := c.leaderName()
+	if err != nil {
+		return nil, "", err
+	}
+	ms := make([]*etcdutil.Member, 0, len(c.members))
+	for _, m := range c.members {
+		ms = append(ms, m)
+	}
+	return leaderLast(ms, leader), leader, nil
+}
+
+// maintenanceBatches splits the members ordered by leaderLast into batches of up to n members
+// which are disrupted at the same time. The leader is always disrupted on its own.
+func maintenanceBatches(ms []*etcdutil.Member, leader string, n int) [][]*etcdutil.Member {
+	if n < 1 {
+		n = 1
+	}
+	var batches [][]*etcdutil.Member
+	var batch []*etcdutil.Member
+	for _, m := range ms {
+		if m.Name == leader || len(batch) == n {
+			if len(batch) > 0 {
+				batches = append(batches, batch)
+			}
+			batch = nil
+		}
+		batch = append(batch, m)
+		if m.Name == leader {
+			batches = append(batches, batch)
+			batch = nil
+		}
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// maxUnavailable returns the number of members which may be disrupted at the same time.
+func (c *Cluster) maxUnavailable() int {
+	return c.cluster.Spec.GetMaxUnavailable(c.members.Size())
+}
@@ -0,0 +1,148 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+func TestLeaderLast(t *testing.T) {
+	tests := []struct {
+		names  []string
+		leader string
+		wNames []string
+	}{
+		{names: []string{"a-0002", "a-0000", "a-0001"}, leader: "a-0000", wNames: []string{"a-0001", "a-0002", "a-0000"}},
+		{names: []string{"a-0002", "a-0000", "a-0001"}, leader: "", wNames: []string{"a-0000", "a-0001", "a-0002"}},
+		// the leader is not among the members
+		{names: []string{"a-0002", "a-0001"}, leader: "a-0000", wNames: []string{"a-0001", "a-0002"}},
+		{names: []string{"a-0000"}, leader: "a-0000", wNames: []string{"a-0000"}},
+	}
+	for i, tt := range tests {
+		var ms []*etcdutil.Member
+		for _, n := range tt.names {
+			ms = append(ms, &etcdutil.Member{Name: n})
+		}
+		var names []string
+		for _, m := range leaderLast(ms, tt.leader) {
+			names = append(names, m.Name)
+		}
+		if !reflect.DeepEqual(names, tt.wNames) {
+			t.Errorf("#%d: order get=%v, want=%v", i, names, tt.wNames)
+		}
+	}
+}
+
+func TestPickOneOldMember(t *testing.T) {
+	newPod := func(name, version string) *v1.Pod {
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: map[string]string{}}}
+		k8sutil.SetEtcdVersion(pod, version)
+		return pod
+	}
+	pods := []*v1.Pod{newPod("a-0000", "3.1.8"), newPod("a-0001", "3.1.8"), newPod("a-0002", "3.2.0")}
+	tests := []struct {
+		leader string
+		wName  string
+	}{
+		{leader: "a-0000", wName: "a-0001"},
+		{leader: "a-0001", wName: "a-0000"},
+		{leader: "a-0002", wName: "a-0000"},
+		{leader: "", wName: "a-0000"},
+	}
+	for i, tt := range tests {
+		m := pickOneOldMember(pods, "3.2.0", tt.leader)
+		if m == nil || m.Name != tt.wName {
+			t.Errorf("#%d: picked get=%v, want=%s", i, m, tt.wName)
+		}
+	}
+	if m := pickOneOldMember(pods[2:], "3.2.0", ""); m != nil {
+		t.Errorf("expect no old member, get=%v", m)
+	}
+}
+
+func TestMaintenanceBatches(t *testing.T) {
+	tests := []struct {
+		names    []string
+		leader   string
+		n        int
+		wBatches [][]string
+	}{
+		{names: []string{"a-0001", "a-0002", "a-0000"}, leader: "a-0000", n: 1,
+			wBatches: [][]string{{"a-0001"}, {"a-0002"}, {"a-0000"}}},
+		{names: []string{"a-0001", "a-0002", "a-0003", "a-0004", "a-0000"}, leader: "a-0000", n: 2,
+			wBatches: [][]string{{"a-0001", "a-0002"}, {"a-0003", "a-0004"}, {"a-0000"}}},
+		{names: []string{"a-0001", "a-0002", "a-0003", "a-0000"}, leader: "a-0000", n: 2,
+			wBatches: [][]string{{"a-0001", "a-0002"}, {"a-0003"}, {"a-0000"}}},
+		// the leader is not among the members
+		{names: []string{"a-0001", "a-0002", "a-0003"}, leader: "a-0000", n: 3,
+			wBatches: [][]string{{"a-0001", "a-0002", "a-0003"}}},
+		{names: []string{"a-0000"}, leader: "a-0000", n: 2, wBatches: [][]string{{"a-0000"}}},
+	}
+	for i, tt := range tests {
+		var ms []*etcdutil.Member
+		for _, n := range tt.names {
+			ms = append(ms, &etcdutil.Member{Name: n})
+		}
+		var batches [][]string
+		for _, b := range maintenanceBatches(ms, tt.leader, tt.n) {
+			var names []string
+			for _, m := range b {
+				names = append(names, m.Name)
+			}
+			batches = append(batches, names)
+		}
+		if !reflect.DeepEqual(batches, tt.wBatches) {
+			t.Errorf("#%d: batches get=%v, want=%v", i, batches, tt.wBatches)
+		}
+	}
+}
+
+func TestPickOldMembers(t *testing.T) {
+	newPod := func(name, version string) *v1.Pod {
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: map[string]string{}}}
+		k8sutil.SetEtcdVersion(pod, version)
+		return pod
+	}
+	pods := []*v1.Pod{newPod("a-0000", "3.1.8"), newPod("a-0001", "3.1.8"), newPod("a-0002", "3.1.8"),
+		newPod("a-0003", "3.2.0"), newPod("a-0004", "3.1.8")}
+	tests := []struct {
+		leader string
+		n      int
+		wNames []string
+	}{
+		{leader: "a-0000", n: 1, wNames: []string{"a-0001"}},
+		{leader: "a-0000", n: 2, wNames: []string{"a-0001", "a-0002"}},
+		{leader: "a-0003", n: 2, wNames: []string{"a-0000", "a-0001"}},
+	}
+	for i, tt := range tests {
+		var names []string
+		for _, m := range pickOldMembers(pods, "3.2.0", tt.leader, tt.n) {
+			names = append(names, m.Name)
+		}
+		if !reflect.DeepEqual(names, tt.wNames) {
+			t.Errorf("#%d: picked get=%v, want=%v", i, names, tt.wNames)
+		}
+	}
+	if ms := pickOldMembers(pods[3:4], "3.2.0", "", 2); ms != nil {
+		t.Errorf("expect no old member, get=%v", ms)
+	}
+}
@@ -18,13 +18,14 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/coreos/etcd-operator/pkg/spec"
 	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
 
 	"k8s.io/client-go/pkg/api/v1"
 )
 
 func (c *Cluster) updateMembers(known etcdutil.MemberSet) error {
-	resp, err := etcdutil.ListMembers(known.ClientURLs(), c.tlsConfig)
+	resp, err := c.memberList(known.ClientURLs())
 	if err != nil {
 		return err
 	}
@@ -55,14 +56,14 @@ func (c *Cluster) updateMembers(known etcdutil.MemberSet) error {
 				return errInvalidMemberName
 			}
 		}
-		ct, err := etcdutil.GetCounterFromMemberName(name)
-		if err != nil {
+		if ct, err := etcdutil.GetCounterFromMemberName(name); err == nil {
+			if ct+1 > c.memberCounter {
+				c.setMemberCounter(ct + 1)
+			}
+		} else if !isRandomMemberName(c.cluster.Metadata.Name, name) {
 			c.logger.Errorf("invalid member name (%s): %v", name, err)
 			return errInvalidMemberName
 		}
-		if ct+1 > c.memberCounter {
-			c.memberCounter = ct + 1
-		}
 
 		members[name] = &etcdutil.Member{
 			Name:         name,
@@ -76,14 +77,47 @@ func (c *Cluster) updateMembers(known etcdutil.MemberSet) error {
 	return nil
 }
 
-func (c *Cluster) newMember(id int) *etcdutil.Member {
-	name := etcdutil.CreateMemberName(c.cluster.Metadata.Name, id)
+// newMember allocates the name of a new member according to spec.memberNaming.
+// A name is never handed out twice, even if creating the member fails afterwards,
+// so that a new member does not collide with the pod or the peer URL of a removed one.
+func (c *Cluster) newMember() (*etcdutil.Member, error) {
+	name, err := c.nextMemberName()
+	if err != nil {
+		return nil, err
+	}
 	return &etcdutil.Member{
 		Name:         name,
 		Namespace:    c.cluster.Metadata.Namespace,
 		SecurePeer:   c.isSecurePeer(),
 		SecureClient: c.isSecureClient(),
+	}, nil
+}
+
+func (c *Cluster) nextMemberName() (string, error) {
+	if c.cluster.Spec.GetMemberNaming() == spec.MemberNamingRandom {
+		for {
+			name, err := etcdutil.CreateRandomMemberName(c.cluster.Metadata.Name)
+			if err != nil {
+				return "", err
+			}
+			if _, ok := c.members[name]; !ok {
+				return name, nil
+			}
+		}
 	}
+	name := etcdutil.CreateMemberName(c.cluster.Metadata.Name, c.memberCounter)
+	c.setMemberCounter(c.memberCounter + 1)
+	return name, nil
+}
+
+// setMemberCounter also records the counter in status, which is the lower bound of the counter after an operator restart.
+func (c *Cluster) setMemberCounter(ct int) {
+	c.memberCounter = ct
+	c.status.MemberCounter = ct
+}
+
+func isRandomMemberName(clusterName, name string) bool {
+	return strings.HasPrefix(name, clusterName+"-") && etcdutil.IsRandomMemberSuffix(strings.TrimPrefix(name, clusterName+"-"))
 }
 
 func podsToMemberSet(pods []*v1.Pod, sc bool) etcdutil.MemberSet {
@@ -0,0 +1,74 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+
+	"github.com/coreos/etcd/clientv3"
+)
+
+// lastHealthyTimeResolution bounds how often the last healthy time of a member is
+// updated, so that the cluster status is not written on every reconcile.
+const lastHealthyTimeResolution = time.Minute
+
+// memberDetails returns the details of the given members from their statuses.
+// Members without status keep the last healthy time of prev.
+func memberDetails(names []string, statuses map[string]*clientv3.StatusResponse, ready map[string]bool,
+	members etcdutil.MemberSet, prev []spec.MemberStatus, now time.Time) []spec.MemberStatus {
+	lastHealthy := map[string]string{}
+	for _, ms := range prev {
+		lastHealthy[ms.Name] = ms.LastHealthyTime
+	}
+
+	sort.Strings(names)
+	details := make([]spec.MemberStatus, 0, len(names))
+	for _, name := range names {
+		ms := spec.MemberStatus{Name: name, LastHealthyTime: lastHealthy[name]}
+		if m, ok := members[name]; ok && m.ID != 0 {
+			ms.ID = fmt.Sprintf("%x", m.ID)
+		}
+		if st, ok := statuses[name]; ok {
+			if st.Header != nil {
+				ms.ID = fmt.Sprintf("%x", st.Header.MemberId)
+				if st.Leader == st.Header.MemberId {
+					ms.Role = spec.MemberRoleLeader
+				} else {
+					ms.Role = spec.MemberRoleFollower
+				}
+			}
+			ms.Version = st.Version
+			ms.DBSize = st.DbSize
+		}
+		if ready[name] && isHealthyTimeStale(ms.LastHealthyTime, now) {
+			ms.LastHealthyTime = now.Format(time.RFC3339)
+		}
+		details = append(details, ms)
+	}
+	return details
+}
+
+func isHealthyTimeStale(last string, now time.Time) bool {
+	t, err := time.Parse(time.RFC3339, last)
+	if err != nil {
+		return true
+	}
+	return now.Sub(t) >= lastHealthyTimeResolution
+}
@@ -0,0 +1,62 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+
+	"github.com/coreos/etcd/clientv3"
+)
+
+func TestMemberDetails(t *testing.T) {
+	now := time.Now()
+	recent := now.Add(-10 * time.Second).Format(time.RFC3339)
+	old := now.Add(-time.Hour).Format(time.RFC3339)
+
+	leader := newTestStatus(0x1a, 0x1a, 100)
+	leader.Version, leader.DbSize = "3.1.8", 1024
+	statuses := map[string]*clientv3.StatusResponse{
+		"m0": leader,
+		"m1": newTestStatus(0x2b, 0x1a, 100),
+		"m3": newTestStatus(0x4d, 0x1a, 100),
+	}
+	ready := map[string]bool{"m0": true, "m1": true, "m3": true}
+	members := etcdutil.MemberSet{"m2": &etcdutil.Member{Name: "m2", ID: 0x3c}}
+	prev := []spec.MemberStatus{
+		{Name: "m1", LastHealthyTime: recent},
+		{Name: "m2", LastHealthyTime: old},
+		{Name: "m3", LastHealthyTime: old},
+	}
+
+	details := memberDetails([]string{"m3", "m2", "m1", "m0"}, statuses, ready, members, prev, now)
+	want := []spec.MemberStatus{
+		{Name: "m0", ID: "1a", Role: spec.MemberRoleLeader, Version: "3.1.8", DBSize: 1024, LastHealthyTime: now.Format(time.RFC3339)},
+		{Name: "m1", ID: "2b", Role: spec.MemberRoleFollower, LastHealthyTime: recent},
+		{Name: "m2", ID: "3c", LastHealthyTime: old},
+		{Name: "m3", ID: "4d", Role: spec.MemberRoleFollower, LastHealthyTime: now.Format(time.RFC3339)},
+	}
+	if len(details) != len(want) {
+		t.Fatalf("details get=%v, want=%v", details, want)
+	}
+	for i := range want {
+		if details[i] != want[i] {
+			t.Errorf("#%d: member details get=%+v, want=%+v", i, details[i], want[i])
+		}
+	}
+}
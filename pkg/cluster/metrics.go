@@ -23,7 +23,55 @@ var reconcileFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
 	[]string{"Reason"},
 )
 
+var lastBackupTimestamp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "etcd_operator",
+	Subsystem: "cluster",
+	Name:      "last_backup_timestamp_seconds",
+	Help:      "Creation time of the most recent backup of the cluster in unix seconds",
+},
+	[]string{"ClusterName"},
+)
+
+var etcdClientFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "etcd_operator",
+	Subsystem: "cluster",
+	Name:      "etcd_client_failures_total",
+	Help:      "Total number of failed etcd client requests of the operator to the cluster by RPC",
+},
+	[]string{"ClusterName", "RPC"},
+)
+
+// The RPCs counted by etcdClientFailures.
+const (
+	rpcDial         = "Dial"
+	rpcStatus       = "Status"
+	rpcMemberList   = "MemberList"
+	rpcMemberAdd    = "MemberAdd"
+	rpcMemberRemove = "MemberRemove"
+	rpcAlarmList    = "AlarmList"
+	rpcDefragment   = "Defragment"
+	rpcCompact      = "Compact"
+)
+
+var etcdClientRPCs = []string{rpcDial, rpcStatus, rpcMemberList, rpcMemberAdd, rpcMemberRemove, rpcAlarmList, rpcDefragment, rpcCompact}
+
+func deleteEtcdClientFailures(clusterName string) {
+	for _, rpc := range etcdClientRPCs {
+		etcdClientFailures.DeleteLabelValues(clusterName, rpc)
+	}
+}
+
+var podCreationsThrottled = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "etcd_operator",
+	Subsystem: "cluster",
+	Name:      "pod_creations_throttled_total",
+	Help:      "Total number of member additions postponed by the pod creation rate limit",
+})
+
 func init() {
 	prometheus.MustRegister(reconcileHistogram)
 	prometheus.MustRegister(reconcileFailed)
+	prometheus.MustRegister(lastBackupTimestamp)
+	prometheus.MustRegister(podCreationsThrottled)
+	prometheus.MustRegister(etcdClientFailures)
 }
@@ -0,0 +1,203 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
+	"github.com/coreos/etcd-operator/version"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+// migrationStep is a one-off step that brings resources created by an older
+// operator version to the layout the current operator expects.
+// Every step must be idempotent: it might run again if the operator restarts
+// before the new operator version is recorded in the cluster status.
+type migrationStep struct {
+	name string
+	run  func(c *Cluster) error
+}
+
+var migrationSteps = []migrationStep{
+	{name: "ensure client and peer services", run: (*Cluster).migrateServices},
+	{name: "ensure pod disruption budget", run: (*Cluster).setupPDB},
+	{name: "delete per-member services", run: (*Cluster).deleteMemberServices},
+}
+
+// migrateIfNeeded detects version skew between the operator that last reconciled
+// the cluster and the running operator. On skew, it runs all migration steps
+// and records the current operator version in status. If any step fails, the
+// version is not recorded and migration is retried on next operator start.
+func (c *Cluster) migrateIfNeeded() error {
+	from, to := c.status.OperatorVersion, version.Version
+	if from == to {
+		return nil
+	}
+	if len(from) == 0 {
+		from = "unknown"
+	}
+	c.logger.Infof("cluster was last reconciled by operator version (%s), migrating to (%s)", from, to)
+	c.createEvent(k8sutil.OperatorUpgradedEvent(c.cluster, from, to))
+
+	for _, s := range migrationSteps {
+		err := s.run(c)
+		c.createEvent(k8sutil.OperatorMigrationEvent(c.cluster, s.name, err))
+		if err != nil {
+			return fmt.Errorf("migration step (%s) failed: %v", s.name, err)
+		}
+		c.logger.Infof("migration step (%s) finished", s.name)
+	}
+
+	c.status.SetOperatorVersion(to)
+	return nil
+}
+
+func (c *Cluster) migrateServices() error {
+	name, ns, owner := c.cluster.Metadata.Name, c.cluster.Metadata.Namespace, c.cluster.AsOwner()
+	metricsPort := c.cluster.Spec.Etcd.GetMetricsPort()
+	err := k8sutil.CreateClientService(c.config.KubeCli, name, ns, metricsPort, c.cluster.Spec.ClientService, owner)
+	if err != nil && !k8sutil.IsKubernetesResourceAlreadyExistError(err) {
+		return err
+	}
+	err = k8sutil.CreatePeerService(c.config.KubeCli, name, ns, metricsPort, owner)
+	if err == nil {
+		return nil
+	}
+	if !k8sutil.IsKubernetesResourceAlreadyExistError(err) {
+		return err
+	}
+	// Services created by older operators do not expose the metrics port.
+	if err := k8sutil.UpdateServicePorts(c.config.KubeCli, name, ns, metricsPort, c.cluster.Spec.ClientService); err != nil {
+		return err
+	}
+
+	// Peer services created by older operators publish only ready endpoints.
+	svc, err := c.config.KubeCli.CoreV1().Services(ns).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if svc.Annotations[k8sutil.TolerateUnreadyEndpointsAnnotation] == "true" {
+		return nil
+	}
+	if svc.Annotations == nil {
+		svc.Annotations = map[string]string{}
+	}
+	svc.Annotations[k8sutil.TolerateUnreadyEndpointsAnnotation] = "true"
+	_, err = c.config.KubeCli.CoreV1().Services(ns).Update(svc)
+	return err
+}
+
+// deleteMemberServices deletes the per-member services created by operators before 0.2.0.
+// Members are addressed by the DNS names of their pods in the peer service instead,
+// which do not depend on the pod IPs either.
+// A service is kept as long as the peer URL of a member still resolves through it,
+// e.g. for members which joined before 0.2.0 and have not been replaced yet.
+// If the membership cannot be read, no service is deleted and the step fails,
+// so that it is retried on the next operator start.
+func (c *Cluster) deleteMemberServices() error {
+	name, ns := c.cluster.Metadata.Name, c.cluster.Metadata.Namespace
+	opt := metav1.ListOptions{LabelSelector: "etcd_cluster=" + name}
+	svcs, err := c.config.KubeCli.CoreV1().Services(ns).List(opt)
+	if err != nil {
+		return err
+	}
+	var inUse map[string]bool
+	for _, svc := range svcs.Items {
+		if !isMemberServiceName(name, svc.Name) {
+			continue
+		}
+		if inUse == nil {
+			inUse, err = c.peerURLHosts()
+			if err != nil {
+				return fmt.Errorf("failed to list members referencing per-member services: %v", err)
+			}
+		}
+		if inUse[svc.Name] {
+			c.logger.Infof("keeping per-member service (%s): a member peer URL still refers to it", svc.Name)
+			continue
+		}
+		err := c.config.KubeCli.CoreV1().Services(ns).Delete(svc.Name, nil)
+		if err != nil && !k8sutil.IsKubernetesResourceNotFoundError(err) {
+			return err
+		}
+		c.logger.Infof("deleted per-member service (%s)", svc.Name)
+	}
+	return nil
+}
+
+// peerURLHosts returns the first DNS labels of the hosts in the peer URLs of all members of the cluster.
+func (c *Cluster) peerURLHosts() (map[string]bool, error) {
+	running, _, err := c.pollPods()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.memberList(podsToMemberSet(running, c.isSecureClient()).ClientURLs())
+	if err != nil {
+		return nil, err
+	}
+	var purls []string
+	for _, m := range resp.Members {
+		purls = append(purls, m.PeerURLs...)
+	}
+	return hostLabels(purls), nil
+}
+
+// hostLabels returns the first DNS labels of the hosts of the given URLs,
+// e.g. "example-0001" for "http://example-0001.default.svc:2380".
+func hostLabels(urls []string) map[string]bool {
+	labels := map[string]bool{}
+	for _, s := range urls {
+		u, err := url.Parse(s)
+		if err != nil || len(u.Host) == 0 {
+			continue
+		}
+		host := u.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		labels[strings.SplitN(host, ".", 2)[0]] = true
+	}
+	return labels
+}
+
+// isMemberServiceName tells whether the service is named after a member of the cluster, e.g. "example-0001".
+func isMemberServiceName(clusterName, svcName string) bool {
+	if !strings.HasPrefix(svcName, clusterName+"-") {
+		return false
+	}
+	counter := strings.TrimPrefix(svcName, clusterName+"-")
+	if len(counter) == 0 {
+		return false
+	}
+	for _, r := range counter {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Cluster) createEvent(ev *v1.Event) {
+	_, err := c.config.KubeCli.CoreV1().Events(c.cluster.Metadata.Namespace).Create(ev)
+	if err != nil {
+		c.logger.Errorf("failed to create event (%s): %v", ev.Reason, err)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsMemberServiceName(t *testing.T) {
+	tests := []struct {
+		svcName string
+		want    bool
+	}{
+		{svcName: "example-0001", want: true},
+		{svcName: "example", want: false},
+		{svcName: "example-client", want: false},
+		{svcName: "example-backup-sidecar", want: false},
+		{svcName: "example-", want: false},
+		{svcName: "example-2-0001", want: false},
+	}
+	for i, tt := range tests {
+		if get := isMemberServiceName("example", tt.svcName); get != tt.want {
+			t.Errorf("#%d: get=%v, want=%v", i, get, tt.want)
+		}
+	}
+}
+
+func TestHostLabels(t *testing.T) {
+	urls := []string{
+		"http://example-0001:2380",
+		"https://example-0002.default.svc.cluster.local:2380",
+		"http://example-0003.example.default.svc.cluster.local:2380",
+		"http://10.0.0.1:2380",
+		"example-0004",
+	}
+	want := map[string]bool{"example-0001": true, "example-0002": true, "example-0003": true, "10": true}
+	if get := hostLabels(urls); !reflect.DeepEqual(get, want) {
+		t.Errorf("host labels get=%v, want=%v", get, want)
+	}
+}
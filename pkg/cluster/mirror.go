@@ -0,0 +1,195 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"crypto/tls"
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/constants"
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+// mirrorStatusInterval bounds how often the mirror lag in status is updated.
+const mirrorStatusInterval = time.Minute
+
+var mirrorLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "etcd_operator",
+	Subsystem: "cluster",
+	Name:      "mirror_lag_seconds",
+	Help:      "Age of the most recent heartbeat of the cluster found on the mirror destination",
+},
+	[]string{"ClusterName"},
+)
+
+func init() {
+	prometheus.MustRegister(mirrorLag)
+}
+
+// mirrorMonitor keeps what is needed to read the heartbeats back from the mirror destination.
+type mirrorMonitor struct {
+	// tlsSecret is the secret tlsConfig was loaded from.
+	tlsSecret string
+	tlsConfig *tls.Config
+	// cli is the client of the destination at endpoint, reused across reconciles.
+	cli      *clientv3.Client
+	endpoint string
+	reported time.Time
+}
+
+// setupMirror creates or updates the mirror Deployment of the cluster,
+// or deletes it if spec.mirror is not set.
+func (c *Cluster) setupMirror() error {
+	name, ns := c.cluster.Metadata.Name, c.cluster.Metadata.Namespace
+	if c.cluster.Spec.Mirror == nil {
+		return k8sutil.DeleteMirror(c.config.KubeCli, name, ns)
+	}
+	return k8sutil.CreateOrUpdateMirror(c.config.KubeCli, name, ns, c.cluster.Spec, c.cluster.AsOwner())
+}
+
+// checkMirror writes a heartbeat to the cluster and reads the most recent heartbeat
+// that reached the mirror destination. Their difference is the lag of the mirror.
+// It only runs if spec.mirror.heartbeatKey is set.
+func (c *Cluster) checkMirror() {
+	mp := c.cluster.Spec.Mirror
+	if mp == nil || len(mp.HeartbeatKey) == 0 {
+		if c.mirror != nil {
+			c.closeMirrorClient()
+			c.mirror = nil
+			c.status.Mirror = nil
+			mirrorLag.DeleteLabelValues(c.name())
+		}
+		return
+	}
+	if c.mirror == nil {
+		c.mirror = &mirrorMonitor{}
+	}
+
+	now := time.Now()
+	if err := c.putMirrorHeartbeat(mp, now); err != nil {
+		c.logger.Warningf("failed to write mirror heartbeat: %v", err)
+	}
+	last, err := c.lastMirrorHeartbeat(mp)
+	if err != nil {
+		c.logger.Warningf("failed to read mirror heartbeat from destination: %v", err)
+		return
+	}
+	if last.IsZero() {
+		c.logger.Infof("no mirror heartbeat on destination yet")
+		return
+	}
+
+	lag := now.Sub(last)
+	mirrorLag.WithLabelValues(c.name()).Set(lag.Seconds())
+	if now.Sub(c.mirror.reported) < mirrorStatusInterval {
+		return
+	}
+	c.mirror.reported = now
+	c.status.Mirror = &spec.MirrorStatus{
+		LastSyncTime: last.Format(time.RFC3339),
+		LagInSeconds: int64(lag / time.Second),
+		UpdateTime:   now.Format(time.RFC3339),
+	}
+}
+
+func (c *Cluster) putMirrorHeartbeat(mp *spec.MirrorPolicy, now time.Time) error {
+	cli, err := c.etcdClient(c.members.ClientURLs())
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), constants.DefaultRequestTimeout)
+	_, err = cli.Put(ctx, mp.HeartbeatKey, now.Format(time.RFC3339Nano))
+	cancel()
+	return err
+}
+
+// lastMirrorHeartbeat returns the most recent heartbeat found on the mirror destination,
+// or the zero time if there is none.
+func (c *Cluster) lastMirrorHeartbeat(mp *spec.MirrorPolicy) (time.Time, error) {
+	cli, err := c.mirrorDestinationClient(mp)
+	if err != nil {
+		return time.Time{}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), constants.DefaultRequestTimeout)
+	resp, err := cli.Get(ctx, mp.DestinationKey(mp.HeartbeatKey))
+	cancel()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(resp.Kvs) == 0 {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339Nano, string(resp.Kvs[0].Value))
+}
+
+// mirrorDestinationClient returns the client of the mirror destination. It is created once
+// and reused until the destination or its TLS secret changes.
+func (c *Cluster) mirrorDestinationClient(mp *spec.MirrorPolicy) (*clientv3.Client, error) {
+	endpoint := k8sutil.MirrorDestinationURL(c.cluster.Metadata.Namespace, mp)
+	if c.mirror.cli != nil && c.mirror.endpoint == endpoint && c.mirror.tlsSecret == mp.DestinationTLSSecret {
+		return c.mirror.cli, nil
+	}
+	c.closeMirrorClient()
+	tc, err := c.mirrorDestinationTLSConfig(mp.DestinationTLSSecret)
+	if err != nil {
+		return nil, err
+	}
+	cli, err := clientv3.New(clientv3.Config{
+		Endpoints:   []string{endpoint},
+		DialTimeout: constants.DefaultDialTimeout,
+		TLS:         tc,
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.mirror.cli, c.mirror.endpoint = cli, endpoint
+	return cli, nil
+}
+
+func (c *Cluster) closeMirrorClient() {
+	if c.mirror == nil || c.mirror.cli == nil {
+		return
+	}
+	if err := c.mirror.cli.Close(); err != nil {
+		c.logger.Warningf("failed to close mirror destination client: %v", err)
+	}
+	c.mirror.cli = nil
+}
+
+func (c *Cluster) mirrorDestinationTLSConfig(secret string) (*tls.Config, error) {
+	if len(secret) == 0 {
+		c.mirror.tlsSecret, c.mirror.tlsConfig = "", nil
+		return nil, nil
+	}
+	if c.mirror.tlsConfig != nil && c.mirror.tlsSecret == secret {
+		return c.mirror.tlsConfig, nil
+	}
+	d, err := k8sutil.GetTLSDataFromSecret(c.config.KubeCli, c.cluster.Metadata.Namespace, secret)
+	if err != nil {
+		return nil, err
+	}
+	tc, err := etcdutil.NewTLSConfig(d.CertData, d.KeyData, d.CAData)
+	if err != nil {
+		return nil, err
+	}
+	c.mirror.tlsSecret, c.mirror.tlsConfig = secret, tc
+	return tc, nil
+}
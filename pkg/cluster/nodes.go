@@ -0,0 +1,125 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+// nodeListInterval is how long the operator reuses the nodes it listed,
+// so that large clusters are not listed on every reconcile.
+const nodeListInterval = time.Minute
+
+// listNodes returns the nodes of the Kubernetes cluster, listed at most once per nodeListInterval.
+func (c *Cluster) listNodes() ([]v1.Node, error) {
+	if c.nodes != nil && time.Since(c.nodesListedAt) < nodeListInterval {
+		return c.nodes, nil
+	}
+	nodes, err := c.config.KubeCli.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %v", err)
+	}
+	c.nodes = nodes.Items
+	c.nodesListedAt = time.Now()
+	return c.nodes, nil
+}
+
+// podFitsNode tells whether the scheduler may place a pod with the spec of the given pod on the node:
+// the node matches its node selector and required node affinity, and it tolerates the NoSchedule and
+// NoExecute taints of the node. Resources and pod (anti-)affinity are not considered.
+func podFitsNode(pod *v1.Pod, node *v1.Node) bool {
+	for k, v := range pod.Spec.NodeSelector {
+		if node.Labels[k] != v {
+			return false
+		}
+	}
+	for i := range node.Spec.Taints {
+		taint := &node.Spec.Taints[i]
+		if taint.Effect == v1.TaintEffectPreferNoSchedule {
+			continue
+		}
+		if !toleratesTaint(pod.Spec.Tolerations, taint) {
+			return false
+		}
+	}
+	a := pod.Spec.Affinity
+	if a == nil || a.NodeAffinity == nil || a.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return true
+	}
+	// The terms are ORed.
+	for _, term := range a.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		if matchNodeSelectorTerm(term, node.Labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchNodeSelectorTerm tells whether the node labels match all requirements of the term.
+func matchNodeSelectorTerm(term v1.NodeSelectorTerm, labels map[string]string) bool {
+	for _, r := range term.MatchExpressions {
+		v, ok := labels[r.Key]
+		switch r.Operator {
+		case v1.NodeSelectorOpIn:
+			if !ok || !containsString(r.Values, v) {
+				return false
+			}
+		case v1.NodeSelectorOpNotIn:
+			if ok && containsString(r.Values, v) {
+				return false
+			}
+		case v1.NodeSelectorOpExists:
+			if !ok {
+				return false
+			}
+		case v1.NodeSelectorOpDoesNotExist:
+			if ok {
+				return false
+			}
+		case v1.NodeSelectorOpGt, v1.NodeSelectorOpLt:
+			if !ok || len(r.Values) != 1 {
+				return false
+			}
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return false
+			}
+			bound, err := strconv.ParseInt(r.Values[0], 10, 64)
+			if err != nil {
+				return false
+			}
+			if (r.Operator == v1.NodeSelectorOpGt && n <= bound) || (r.Operator == v1.NodeSelectorOpLt && n >= bound) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
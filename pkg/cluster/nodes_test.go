@@ -0,0 +1,62 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+func TestPodFitsNode(t *testing.T) {
+	zoneIn := func(zones ...string) *v1.Affinity {
+		return &v1.Affinity{NodeAffinity: &v1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+				NodeSelectorTerms: []v1.NodeSelectorTerm{{
+					MatchExpressions: []v1.NodeSelectorRequirement{{Key: "zone", Operator: v1.NodeSelectorOpIn, Values: zones}},
+				}},
+			},
+		}}
+	}
+	noSchedule := []v1.Taint{{Key: "dedicated", Value: "db", Effect: v1.TaintEffectNoSchedule}}
+	tests := []struct {
+		podSpec v1.PodSpec
+		labels  map[string]string
+		taints  []v1.Taint
+		w       bool
+	}{
+		{podSpec: v1.PodSpec{}, w: true},
+		{podSpec: v1.PodSpec{NodeSelector: map[string]string{"role": "etcd"}}, labels: map[string]string{"role": "etcd"}, w: true},
+		{podSpec: v1.PodSpec{NodeSelector: map[string]string{"role": "etcd"}}, labels: map[string]string{"role": "app"}, w: false},
+		{podSpec: v1.PodSpec{}, taints: noSchedule, w: false},
+		{
+			podSpec: v1.PodSpec{Tolerations: []v1.Toleration{{Key: "dedicated", Operator: v1.TolerationOpEqual, Value: "db"}}},
+			taints:  noSchedule,
+			w:       true,
+		},
+		{podSpec: v1.PodSpec{}, taints: []v1.Taint{{Key: "dedicated", Effect: v1.TaintEffectPreferNoSchedule}}, w: true},
+		{podSpec: v1.PodSpec{Affinity: zoneIn("a", "b")}, labels: map[string]string{"zone": "b"}, w: true},
+		{podSpec: v1.PodSpec{Affinity: zoneIn("a", "b")}, labels: map[string]string{"zone": "c"}, w: false},
+		{podSpec: v1.PodSpec{Affinity: zoneIn("a")}, w: false},
+	}
+	for i, tt := range tests {
+		pod := &v1.Pod{Spec: tt.podSpec}
+		node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Labels: tt.labels}, Spec: v1.NodeSpec{Taints: tt.taints}}
+		if get := podFitsNode(pod, node); get != tt.w {
+			t.Errorf("#%d: fits get=%v, want=%v", i, get, tt.w)
+		}
+	}
+}
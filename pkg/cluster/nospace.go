@@ -0,0 +1,92 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
+
+	pb "github.com/coreos/etcd/etcdserver/etcdserverpb"
+)
+
+// remediateNoSpaceIfNeeded compacts, defragments and disarms the NOSPACE alarms
+// of the cluster if remediation is enabled. Each step posts an event.
+func (c *Cluster) remediateNoSpaceIfNeeded() {
+	if !c.cluster.Spec.RemediateNoSpace {
+		return
+	}
+	urls := c.members.ClientURLs()
+	alarms, err := etcdutil.ListAlarms(urls, c.tlsConfig, pb.AlarmType_NOSPACE)
+	c.countEtcdClientFailure(rpcAlarmList, err)
+	if err != nil {
+		c.logger.Warningf("failed to list alarms: %v", err)
+		return
+	}
+	if len(alarms) == 0 {
+		return
+	}
+	c.logger.Warningf("NOSPACE alarm raised by %d member(s), remediating", len(alarms))
+
+	rev, err := etcdutil.CompactToLatest(urls, c.tlsConfig)
+	c.countEtcdClientFailure(rpcCompact, err)
+	c.createEvent(k8sutil.NoSpaceRemediationEvent(c.cluster, fmt.Sprintf("compact to revision %d", rev), err))
+	if err != nil {
+		c.logger.Errorf("failed to compact: %v", err)
+		return
+	}
+
+	ms, leader, err := c.membersLeaderLast()
+	if err == nil {
+		err = c.defragmentMembers(ms, leader)
+	}
+	if err != nil {
+		c.createEvent(k8sutil.NoSpaceRemediationEvent(c.cluster, "defragment", err))
+		c.logger.Errorf("failed to defragment: %v", err)
+		return
+	}
+
+	err = c.checkDBSizeBelowQuota()
+	if err == nil {
+		for _, a := range alarms {
+			if err = etcdutil.DisarmAlarm(urls, c.tlsConfig, a); err != nil {
+				break
+			}
+		}
+	}
+	c.createEvent(k8sutil.NoSpaceRemediationEvent(c.cluster, "disarm alarm", err))
+	if err != nil {
+		c.logger.Errorf("failed to disarm NOSPACE alarm: %v", err)
+		return
+	}
+	c.logger.Infof("NOSPACE alarm remediated")
+}
+
+// checkDBSizeBelowQuota returns an error if the db size of any member is not below the backend quota.
+// Disarming the alarm before is pointless: the member would raise it again right away.
+func (c *Cluster) checkDBSizeBelowQuota() error {
+	quota := c.cluster.Spec.Etcd.GetQuotaBackendBytes()
+	for _, m := range c.members {
+		st, err := c.memberStatus(m.ClientAddr())
+		if err != nil {
+			return err
+		}
+		if st.DbSize >= quota {
+			return fmt.Errorf("db size (%d) of member (%s) still exceeds the backend quota (%d), increase spec.etcd.quotaBackendBytes or delete data", st.DbSize, m.Name, quota)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/constants"
+	"github.com/coreos/etcd-operator/pkg/util/notify"
+)
+
+// notify sends a notification of the type unless one was sent since the condition last cleared.
+// It is sent in the background, so that a slow receiver does not hold up the reconciliation.
+func (c *Cluster) notify(typ, message string) {
+	if c.config.Notifier == nil || c.notified[typ] {
+		return
+	}
+	if c.notified == nil {
+		c.notified = make(map[string]bool)
+	}
+	c.notified[typ] = true
+	n := notify.Notification{
+		Cluster:   c.name(),
+		Namespace: c.cluster.Metadata.Namespace,
+		Type:      typ,
+		Message:   message,
+		Time:      time.Now().Format(time.RFC3339),
+	}
+	go func() {
+		if err := c.config.Notifier.Notify(n); err != nil {
+			c.logger.Warningf("failed to send %s notification: %v", n.Type, err)
+		}
+	}()
+}
+
+// clearNotification lets the condition of the type be notified again.
+func (c *Cluster) clearNotification(typ string) {
+	delete(c.notified, typ)
+}
+
+// checkNotifications notifies the critical conditions found in the status after a reconciliation,
+// and clears those which are gone.
+func (c *Cluster) checkNotifications() {
+	if c.config.Notifier == nil {
+		return
+	}
+	if c.status.IsDegraded() {
+		c.notify(notify.TypeDegraded, c.status.Conditions[len(c.status.Conditions)-1].Reason)
+	} else {
+		c.clearNotification(notify.TypeDegraded)
+	}
+	if c.status.IsReady() {
+		c.clearNotification(notify.TypeQuorumLost)
+	}
+
+	if bp := c.cluster.Spec.Backup; bp != nil && c.config.NotifyMissedBackups > 0 {
+		reason := checkMissedBackups(c.status.BackupServiceStatus, bp, c.config.NotifyMissedBackups, c.cluster.Metadata.CreationTimestamp.Time, time.Now())
+		if len(reason) != 0 {
+			c.notify(notify.TypeBackupsMissed, reason)
+		} else {
+			c.clearNotification(notify.TypeBackupsMissed)
+		}
+	}
+}
+
+// checkMissedBackups returns why the cluster created at the given time missed the given number
+// of backups in a row, or an empty string if it did not.
+func checkMissedBackups(bs *spec.BackupServiceStatus, bp *spec.BackupPolicy, missed int, created, now time.Time) string {
+	interval := constants.DefaultSnapshotInterval
+	if bp.BackupIntervalInSecond != 0 {
+		interval = time.Duration(bp.BackupIntervalInSecond) * time.Second
+	}
+	maxAge := time.Duration(missed) * interval
+	if bs == nil || bs.RecentBackup == nil {
+		if age := now.Sub(created); age > maxAge {
+			return fmt.Sprintf("missed %d backup(s) in a row: no backup was made since the cluster was created %v ago", missed, age-age%time.Second)
+		}
+		return ""
+	}
+	if reason := checkBackupAge(bs, maxAge, now); len(reason) != 0 {
+		return fmt.Sprintf("missed %d backup(s) in a row: %s", missed, reason)
+	}
+	return ""
+}
@@ -0,0 +1,47 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+)
+
+func TestCheckMissedBackups(t *testing.T) {
+	now := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	bp := &spec.BackupPolicy{BackupIntervalInSecond: 600}
+	newStatus := func(created time.Time) *spec.BackupServiceStatus {
+		return &spec.BackupServiceStatus{RecentBackup: &spec.BackupStatus{CreationTime: created.Format(time.RFC3339)}}
+	}
+	tests := []struct {
+		bs       *spec.BackupServiceStatus
+		created  time.Time
+		wMissing bool
+	}{
+		{bs: newStatus(now.Add(-20 * time.Minute)), created: now.Add(-24 * time.Hour), wMissing: false},
+		{bs: newStatus(now.Add(-40 * time.Minute)), created: now.Add(-24 * time.Hour), wMissing: true},
+		// new cluster without a backup yet
+		{bs: nil, created: now.Add(-10 * time.Minute), wMissing: false},
+		{bs: nil, created: now.Add(-time.Hour), wMissing: true},
+	}
+	for i, tt := range tests {
+		reason := checkMissedBackups(tt.bs, bp, 3, tt.created, now)
+		if missing := len(reason) != 0; missing != tt.wMissing {
+			t.Errorf("#%d: missing get=%v (%s), want=%v", i, missing, reason, tt.wMissing)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	backups3 "github.com/coreos/etcd-operator/pkg/backup/s3"
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/constants"
+
+	"github.com/coreos/etcd/clientv3"
+	"golang.org/x/net/context"
+)
+
+const (
+	// dumpPageSize is the number of keys an export reads from etcd at a time.
+	dumpPageSize = 1000
+	// dumpObjectPrefix is the folder of the dumps in the operator wide bucket. It is not a valid
+	// namespace name, so that dumps never collide with the backups stored under their namespace.
+	dumpObjectPrefix = "_exports"
+)
+
+// dumpedKV is a key of a prefix dump. A dump is a stream of these as JSON objects, one per line.
+type dumpedKV struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// dumpStore returns the operator wide S3 bucket, which holds the prefix dumps of all clusters,
+// so that a dump of one cluster can be imported into another.
+func (c *Cluster) dumpStore() (*backups3.S3, error) {
+	if len(c.config.S3Context.S3Bucket) == 0 {
+		return nil, errNoS3ConfigForDump
+	}
+	return backups3.New(c.config.S3Context.S3Bucket, dumpObjectPrefix)
+}
+
+// triggerExport dumps the keys under the prefixes of spec.export to S3.
+// The dump is spooled to a temporary file of the operator, so that large prefixes are not held in memory.
+func (c *Cluster) triggerExport() (string, error) {
+	ep := c.cluster.Spec.Export
+	if ep == nil {
+		return "", errNoExportPolicy
+	}
+	store, err := c.dumpStore()
+	if err != nil {
+		return "", err
+	}
+	cli, err := c.etcdClient(c.members.ClientURLs())
+	if err != nil {
+		return "", err
+	}
+	f, err := ioutil.TempFile("", "etcd-export-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create dump file: %v", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	n, rev, err := exportPrefixes(cli, ep.Prefixes, f)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Seek(0, os.SEEK_SET); err != nil {
+		return "", fmt.Errorf("failed to rewind dump file: %v", err)
+	}
+	if err := store.Put(ep.Object, f); err != nil {
+		return "", fmt.Errorf("failed to upload dump (%s): %v", ep.Object, err)
+	}
+	return fmt.Sprintf("exported %d key(s) at revision %d to %s", n, rev, ep.Object), nil
+}
+
+// triggerImport loads the dump of spec.import from S3 into the cluster.
+func (c *Cluster) triggerImport() (string, error) {
+	ip := c.cluster.Spec.Import
+	if ip == nil {
+		return "", errNoImportPolicy
+	}
+	store, err := c.dumpStore()
+	if err != nil {
+		return "", err
+	}
+	cli, err := c.etcdClient(c.members.ClientURLs())
+	if err != nil {
+		return "", err
+	}
+	r, err := store.Get(ip.Object)
+	if err != nil {
+		return "", fmt.Errorf("failed to download dump (%s): %v", ip.Object, err)
+	}
+	defer r.Close()
+
+	put, kept, err := importDump(cli, r, ip)
+	if err != nil {
+		return "", fmt.Errorf("failed after importing %d key(s): %v", put, err)
+	}
+	return fmt.Sprintf("imported %d key(s) from %s, kept %d existing key(s)", put, ip.Object, kept), nil
+}
+
+// exportPrefixes writes the keys under the prefixes to w, page by page at the revision of the first page,
+// and returns the number of keys and that revision.
+func exportPrefixes(kv clientv3.KV, prefixes []string, w io.Writer) (int, int64, error) {
+	enc := json.NewEncoder(w)
+	n := 0
+	var rev int64
+	for _, prefix := range outermostPrefixes(prefixes) {
+		key, end := prefix, prefixRangeEnd(prefix)
+		for {
+			opts := []clientv3.OpOption{clientv3.WithRange(end), clientv3.WithLimit(dumpPageSize)}
+			if rev != 0 {
+				opts = append(opts, clientv3.WithRev(rev))
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), constants.DefaultRequestTimeout)
+			resp, err := kv.Get(ctx, key, opts...)
+			cancel()
+			if err != nil {
+				return n, rev, fmt.Errorf("failed to get keys under prefix (%s): %v", prefix, err)
+			}
+			if rev == 0 {
+				rev = resp.Header.Revision
+			}
+			for _, ev := range resp.Kvs {
+				if err := enc.Encode(dumpedKV{Key: ev.Key, Value: ev.Value}); err != nil {
+					return n, rev, fmt.Errorf("failed to write dump: %v", err)
+				}
+				n++
+			}
+			if !resp.More || len(resp.Kvs) == 0 {
+				break
+			}
+			// Continue right after the last key of the page.
+			key = string(resp.Kvs[len(resp.Kvs)-1].Key) + "\x00"
+		}
+	}
+	return n, rev, nil
+}
+
+// importDump puts the keys of the dump read from r which match the prefixes of the policy,
+// and returns how many it put and how many existing keys it kept.
+func importDump(kv clientv3.KV, r io.Reader, ip *spec.ImportPolicy) (int, int, error) {
+	dec := json.NewDecoder(r)
+	put, kept := 0, 0
+	for {
+		var d dumpedKV
+		if err := dec.Decode(&d); err != nil {
+			if err == io.EOF {
+				return put, kept, nil
+			}
+			return put, kept, fmt.Errorf("failed to read dump: %v", err)
+		}
+		k := string(d.Key)
+		if len(ip.Prefixes) != 0 && !hasAnyPrefix(k, ip.Prefixes) {
+			continue
+		}
+		ok, err := putDumpedKey(kv, k, string(d.Value), ip.Overwrite)
+		if err != nil {
+			return put, kept, fmt.Errorf("failed to put key (%s): %v", k, err)
+		}
+		if ok {
+			put++
+		} else {
+			kept++
+		}
+	}
+}
+
+// putDumpedKey puts the key, unless it exists and overwrite is false. It returns whether it put the key.
+func putDumpedKey(kv clientv3.KV, k, v string, overwrite bool) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), constants.DefaultRequestTimeout)
+	defer cancel()
+	if overwrite {
+		_, err := kv.Put(ctx, k, v)
+		return err == nil, err
+	}
+	resp, err := kv.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(k), "=", 0)).
+		Then(clientv3.OpPut(k, v)).
+		Commit()
+	if err != nil {
+		return false, err
+	}
+	return resp.Succeeded, nil
+}
+
+// outermostPrefixes returns the sorted prefixes without the ones under another prefix,
+// so that no key is exported twice.
+func outermostPrefixes(prefixes []string) []string {
+	sorted := append([]string(nil), prefixes...)
+	sort.Strings(sorted)
+	var out []string
+	for _, p := range sorted {
+		if len(out) != 0 && strings.HasPrefix(p, out[len(out)-1]) {
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// prefixRangeEnd returns the end of the key range of all keys with the prefix.
+// If no key follows the range, it returns "\x00", which makes the range open ended.
+func prefixRangeEnd(prefix string) string {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1])
+		}
+	}
+	return "\x00"
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
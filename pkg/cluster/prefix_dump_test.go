@@ -0,0 +1,90 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+
+	"github.com/coreos/etcd/clientv3"
+	"golang.org/x/net/context"
+)
+
+func TestPrefixRangeEnd(t *testing.T) {
+	tests := []struct {
+		prefix string
+		w      string
+	}{
+		{prefix: "/a/", w: "/a0"},
+		{prefix: "a", w: "b"},
+		{prefix: "a\xff", w: "b"},
+		{prefix: "\xff\xff", w: "\x00"},
+	}
+	for i, tt := range tests {
+		if get := prefixRangeEnd(tt.prefix); get != tt.w {
+			t.Errorf("#%d: range end get=%q, want=%q", i, get, tt.w)
+		}
+	}
+}
+
+func TestOutermostPrefixes(t *testing.T) {
+	tests := []struct {
+		prefixes []string
+		w        []string
+	}{
+		{prefixes: []string{"/b/", "/a/"}, w: []string{"/a/", "/b/"}},
+		{prefixes: []string{"/a/b/", "/a/", "/a/c"}, w: []string{"/a/"}},
+		{prefixes: []string{"/a", "/ab", "/b"}, w: []string{"/a", "/b"}},
+		{prefixes: []string{"/a/", "/a/"}, w: []string{"/a/"}},
+	}
+	for i, tt := range tests {
+		if get := outermostPrefixes(tt.prefixes); !reflect.DeepEqual(get, tt.w) {
+			t.Errorf("#%d: prefixes get=%v, want=%v", i, get, tt.w)
+		}
+	}
+}
+
+// putRecorder is a clientv3.KV which records the keys put into it.
+type putRecorder struct {
+	clientv3.KV
+	puts map[string]string
+}
+
+func (r *putRecorder) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	r.puts[key] = val
+	return &clientv3.PutResponse{}, nil
+}
+
+func TestImportDumpPrefixes(t *testing.T) {
+	// the keys /a/1 and /b/1 with the values a and b, base64 encoded as JSON encodes []byte
+	dump := `{"key":"L2EvMQ==","value":"YQ=="}
+{"key":"L2IvMQ==","value":"Yg=="}
+`
+	kv := &putRecorder{puts: map[string]string{}}
+	ip := &spec.ImportPolicy{Object: "dump", Prefixes: []string{"/a/"}, Overwrite: true}
+	put, kept, err := importDump(kv, strings.NewReader(dump), ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if put != 1 || kept != 0 {
+		t.Errorf("put get=%d, want=1; kept get=%d, want=0", put, kept)
+	}
+	if w := map[string]string{"/a/1": "a"}; !reflect.DeepEqual(kv.puts, w) {
+		t.Errorf("puts get=%v, want=%v", kv.puts, w)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
+)
+
+// quorumGuardAllows tells whether the cluster keeps a quorum of healthy members after the operation on the member.
+// The operation removes the member from the cluster if remove is set, or takes it down for a while otherwise,
+// e.g. to restart it at a new version. A refused operation sets a Degraded condition, posts an event,
+// and is retried on the next reconcile.
+//
+// Removing dead or unreachable members never takes a healthy member away and is not guarded.
+// Disaster recovery, hibernation and recreating the cluster from a backup take all members down on purpose
+// and are not guarded either.
+func (c *Cluster) quorumGuardAllows(op string, m *etcdutil.Member, remove bool) bool {
+	return c.quorumGuardAllowsIn(c.healthyMembers(), op, m, remove)
+}
+
+// quorumGuardAllowsAll returns the leading members of ms which can be taken down at the same time
+// while the cluster keeps a quorum of healthy members. If not even the first member can be taken down,
+// it refuses the operation like quorumGuardAllows and returns none.
+func (c *Cluster) quorumGuardAllowsAll(op string, ms []*etcdutil.Member) []*etcdutil.Member {
+	healthy := c.healthyMembers()
+	var allowed []*etcdutil.Member
+	for i, m := range ms {
+		if i == 0 {
+			if !c.quorumGuardAllowsIn(healthy, op, m, false) {
+				return nil
+			}
+		} else if !quorumKept(healthy, c.members.Size(), m.Name, false) {
+			c.logger.Infof("quorum guard: %s member %s with the next batch", op, m.Name)
+			break
+		}
+		allowed = append(allowed, m)
+		delete(healthy, m.Name)
+	}
+	return allowed
+}
+
+func (c *Cluster) quorumGuardAllowsIn(healthy map[string]bool, op string, m *etcdutil.Member, remove bool) bool {
+	if quorumKept(healthy, c.members.Size(), m.Name, remove) {
+		return true
+	}
+	after, size := quorumAfter(healthy, c.members.Size(), m.Name, remove)
+	reason := fmt.Sprintf("refused to %s member %s: it would leave %d of %d members healthy, below the quorum of %d",
+		op, m.Name, after, size, size/2+1)
+	c.logger.Warning(reason)
+	if !c.status.IsDegraded() {
+		c.createEvent(k8sutil.QuorumGuardEvent(c.cluster, reason))
+	}
+	c.status.SetDegradedCondition(reason)
+	return false
+}
+
+// quorumKept tells whether the operation on the member leaves a quorum of healthy members.
+// Operations on unhealthy members, e.g. removing a dead member, do not take a healthy member away and are always allowed.
+func quorumKept(healthy map[string]bool, size int, name string, remove bool) bool {
+	if !healthy[name] {
+		return true
+	}
+	if !remove && size < 3 {
+		// A cluster of one or two members loses quorum whenever a member restarts.
+		return true
+	}
+	after, size := quorumAfter(healthy, size, name, remove)
+	return after >= size/2+1
+}
+
+// quorumAfter returns the number of healthy members and the size of the cluster after the operation on the member.
+func quorumAfter(healthy map[string]bool, size int, name string, remove bool) (int, int) {
+	after := len(healthy)
+	if healthy[name] {
+		after--
+	}
+	if remove {
+		size--
+	}
+	return after, size
+}
+
+// healthyMembers returns the members which respond to status requests and have a leader.
+func (c *Cluster) healthyMembers() map[string]bool {
+	healthy := map[string]bool{}
+	for name, m := range c.members {
+		st, err := c.memberStatus(m.ClientAddr())
+		if err != nil {
+			c.logger.Warningf("quorum guard: health check of member (%s) failed: %v", name, err)
+			continue
+		}
+		if st.Leader != 0 {
+			healthy[name] = true
+		}
+	}
+	return healthy
+}
@@ -0,0 +1,51 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import "testing"
+
+func TestQuorumKept(t *testing.T) {
+	tests := []struct {
+		healthy []string
+		size    int
+		name    string
+		remove  bool
+		want    bool
+	}{
+		// scale down a healthy cluster from 3 to 2
+		{healthy: []string{"a", "b", "c"}, size: 3, name: "a", remove: true, want: true},
+		// scale down from 3 to 2 with one member down
+		{healthy: []string{"a", "b"}, size: 3, name: "a", remove: true, want: false},
+		// remove the unhealthy member instead
+		{healthy: []string{"a", "b"}, size: 3, name: "c", remove: true, want: true},
+		// upgrade a healthy cluster of 3
+		{healthy: []string{"a", "b", "c"}, size: 3, name: "a", remove: false, want: true},
+		// upgrade a cluster of 3 with one member down
+		{healthy: []string{"a", "b"}, size: 3, name: "a", remove: false, want: false},
+		// upgrade a cluster of 5 with one member down
+		{healthy: []string{"a", "b", "c", "d"}, size: 5, name: "a", remove: false, want: true},
+		// upgrade a single member cluster
+		{healthy: []string{"a"}, size: 1, name: "a", remove: false, want: true},
+	}
+	for i, tt := range tests {
+		healthy := map[string]bool{}
+		for _, n := range tt.healthy {
+			healthy[n] = true
+		}
+		if get := quorumKept(healthy, tt.size, tt.name, tt.remove); get != tt.want {
+			t.Errorf("#%d: get=%v, want=%v", i, get, tt.want)
+		}
+	}
+}
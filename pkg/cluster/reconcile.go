@@ -16,15 +16,19 @@ package cluster
 
 import (
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/coreos/etcd-operator/pkg/spec"
 	"github.com/coreos/etcd-operator/pkg/util/constants"
 	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
 	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
+	"github.com/coreos/etcd-operator/pkg/util/notify"
 
 	"github.com/coreos/etcd/clientv3"
 	"github.com/coreos/etcd/etcdserver/api/v3rpc/rpctypes"
 	"golang.org/x/net/context"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/pkg/api/v1"
 )
 
@@ -45,15 +49,84 @@ func (c *Cluster) reconcile(pods []*v1.Pod) error {
 		return c.reconcileMembers(running)
 	}
 
+	if m := c.pickUnreachableMemberToRemove(); m != nil {
+		return c.removeUnreachableMember(m)
+	}
+
+	draining, nodes := c.updateDrainingMembers(pods)
+	if sp.EvacuateNodes {
+		if m := c.pickMemberToEvacuate(pods, nodes); m != nil {
+			return c.evacuateMember(m, pods, nodes[m.Name].Name)
+		}
+	}
+
 	if needUpgrade(pods, sp) {
+		if sp.GetUpgradeStrategy() == spec.UpgradeStrategyRecreateFromBackup {
+			return c.recreateFromBackup(pods)
+		}
+		// Upgrading a member while others are evicted might take the cluster below its quorum,
+		// since the PodDisruptionBudget does not account for restarts by the operator.
+		if len(draining) > 0 {
+			c.holdUpgrade(fmt.Sprintf("waiting for the drain of member(s) %v", draining))
+			return nil
+		}
+		// The members already upgraded are the canary of the upgrade.
+		if reason := c.checkUpgradedMembers(pods, sp.Version); len(reason) != 0 {
+			c.pauseUpgrade(reason)
+			return nil
+		}
+		if c.status.TargetVersion != sp.Version {
+			if reason := c.checkUpgradePreconditions(sp.Version); len(reason) != 0 {
+				c.holdUpgrade(reason)
+				return nil
+			}
+		}
 		c.status.UpgradeVersionTo(sp.Version)
 
-		m := pickOneOldMember(pods, sp.Version)
-		return c.upgradeOneMember(m.Name)
+		n := c.maxUnavailable()
+		if sp.GetUpgradeStrategy() == spec.UpgradeStrategyManualApproval {
+			n = 1
+		}
+		leader, err := c.leaderName()
+		if err != nil {
+			c.logger.Warningf("failed to find the leader, upgrading members one at a time in name order: %v", err)
+			n = 1
+		}
+		ms := pickOldMembers(pods, sp.Version, leader, n)
+		if sp.GetUpgradeStrategy() == spec.UpgradeStrategyManualApproval && !isUpgradeApproved(c.cluster, ms[0].Name) {
+			c.waitForUpgradeApproval(ms[0].Name)
+			return nil
+		}
+		for _, m := range c.quorumGuardAllowsAll("upgrade", ms) {
+			if err := c.upgradeOneMember(m.Name); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
 
 	c.status.SetVersion(sp.Version)
-	c.status.SetReadyCondition()
+	if reason := c.checkCorruption(); len(reason) != 0 {
+		if !c.status.IsDegraded() {
+			c.createEvent(k8sutil.DataCorruptionEvent(c.cluster, reason))
+		}
+		c.status.SetDegradedCondition(reason)
+	} else {
+		c.status.SetReadyCondition()
+	}
+
+	if sp.RebalanceZones {
+		if err := c.rebalanceZonesIfNeeded(pods); err != nil {
+			return err
+		}
+	} else {
+		c.status.Members.UnavailableZones = nil
+	}
+
+	c.remediateNoSpaceIfNeeded()
+	c.defragIfNeeded()
+	c.loadInitialDataIfNeeded()
+	c.runTriggeredOperations()
 
 	return nil
 }
@@ -65,8 +138,9 @@ func (c *Cluster) reconcile(pods []*v1.Pod) error {
 // 1. Remove all pods from running set that does not belong to member set.
 // 2. L consist of remaining pods of runnings
 // 3. If L = members, the current state matches the membership state. END.
-// 4. If len(L) < len(members)/2 + 1, quorum lost. Go to recovery process.
-// 5. Add one missing member. END.
+// 4. If a dead member still has its PVC, recreate its pod on it. END.
+// 5. If len(L) < len(members)/2 + 1, quorum lost. Go to recovery process.
+// 6. Remove one dead member. END.
 func (c *Cluster) reconcileMembers(running etcdutil.MemberSet) error {
 	c.logger.Infof("running members: %s", running)
 	c.logger.Infof("cluster membership: %s", c.members)
@@ -75,7 +149,7 @@ func (c *Cluster) reconcileMembers(running etcdutil.MemberSet) error {
 	if unknownMembers.Size() > 0 {
 		c.logger.Infof("removing unexpected pods: %v", unknownMembers)
 		for _, m := range unknownMembers {
-			if err := c.removePod(m.Name); err != nil {
+			if err := c.removePodAndData(m.Name); err != nil {
 				return err
 			}
 		}
@@ -86,6 +160,16 @@ func (c *Cluster) reconcileMembers(running etcdutil.MemberSet) error {
 		return c.resize()
 	}
 
+	if k8sutil.HasMemberPVC(c.cluster.Spec) {
+		m, err := c.pickDeadMemberWithPVC(c.members.Diff(L))
+		if err != nil {
+			return err
+		}
+		if m != nil {
+			return c.recreateMemberPod(m)
+		}
+	}
+
 	if L.Size() < c.members.Size()/2+1 {
 		c.logger.Infof("Disaster recovery")
 		return c.disasterRecovery(L)
@@ -113,6 +197,14 @@ func (c *Cluster) resize() error {
 }
 
 func (c *Cluster) addOneMember() error {
+	// The rate limit is checked before adding the member to etcd,
+	// since a member without pod widens the quorum.
+	if l := c.config.PodCreateLimiter; l != nil && !l.TryAccept() {
+		c.logger.Infof("pod creation throttled, adding member on next reconcile")
+		c.status.SetThrottledCondition(c.members.Size(), c.cluster.Spec.Size)
+		podCreationsThrottled.Inc()
+		return nil
+	}
 	c.status.AppendScalingUpCondition(c.members.Size(), c.cluster.Spec.Size)
 
 	cfg := clientv3.Config{
@@ -122,13 +214,18 @@ func (c *Cluster) addOneMember() error {
 	}
 	etcdcli, err := clientv3.New(cfg)
 	if err != nil {
+		c.countEtcdClientFailure(rpcDial, err)
 		return err
 	}
 	defer etcdcli.Close()
 
-	newMember := c.newMember(c.memberCounter)
+	newMember, err := c.newMember()
+	if err != nil {
+		return err
+	}
 	ctx, _ := context.WithTimeout(context.Background(), constants.DefaultRequestTimeout)
 	resp, err := etcdcli.MemberAdd(ctx, []string{newMember.PeerURL()})
+	c.countEtcdClientFailure(rpcMemberAdd, err)
 	if err != nil {
 		c.logger.Errorf("fail to add new member (%s): %v", newMember.Name, err)
 		return err
@@ -136,26 +233,135 @@ func (c *Cluster) addOneMember() error {
 	newMember.ID = resp.Member.ID
 	c.members.Add(newMember)
 
-	if err := c.createPod(c.members, newMember, "existing", false); err != nil {
+	if err := c.createPod(c.members, newMember, "existing", ""); err != nil {
 		c.logger.Errorf("fail to create member (%s): %v", newMember.Name, err)
 		return err
 	}
-	c.memberCounter++
 	c.logger.Infof("added member (%s)", newMember.Name)
+	c.audit(auditMemberAdded, newMember.Name, scaleReason(c.members.Size()-1, c.cluster.Spec.Size))
 	return nil
 }
 
 func (c *Cluster) removeOneMember() error {
+	toRemove := c.pickMemberToRemove()
+	if !c.quorumGuardAllows("remove", toRemove, true) {
+		return nil
+	}
 	c.status.AppendScalingDownCondition(c.members.Size(), c.cluster.Spec.Size)
 
-	return c.removeMember(c.members.PickOne())
+	reason := scaleReason(c.members.Size(), c.cluster.Spec.Size)
+	if err := c.removeMember(toRemove); err != nil {
+		return err
+	}
+	c.audit(auditMemberRemoved, toRemove.Name, reason)
+	return nil
+}
+
+// pickMemberToRemove prefers a follower over the leader, so that removing
+// a member does not stall clients on a leader election.
+func (c *Cluster) pickMemberToRemove() *etcdutil.Member {
+	for _, m := range c.members {
+		st, err := c.memberStatus(m.ClientAddr())
+		if err != nil {
+			continue
+		}
+		for _, f := range c.members {
+			if f.ID != st.Leader {
+				return f
+			}
+		}
+		break
+	}
+	return c.members.PickOne()
+}
+
+// pickDeadMemberWithPVC returns a dead member whose PVC still exists, or nil if there is none.
+func (c *Cluster) pickDeadMemberWithPVC(dead etcdutil.MemberSet) (*etcdutil.Member, error) {
+	for _, m := range dead {
+		ok, err := k8sutil.MemberPVCExists(c.config.KubeCli, c.cluster.Metadata.Namespace, m.Name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return m, nil
+		}
+	}
+	return nil, nil
+}
+
+// recreateMemberPod recreates the pod of the dead member on its PVC.
+// The member rejoins with its name, ID and data and only catches up on the raft log,
+// instead of being replaced by a new member which needs a snapshot transfer.
+// A pod left over by the member, e.g. a failed one, is removed first and the pod is recreated on a later reconcile.
+func (c *Cluster) recreateMemberPod(m *etcdutil.Member) error {
+	_, err := c.config.KubeCli.CoreV1().Pods(c.cluster.Metadata.Namespace).Get(m.Name, metav1.GetOptions{})
+	if err == nil {
+		c.logger.Infof("removing the pod of dead member (%s) before recreating it", m.Name)
+		return c.removePod(m.Name)
+	}
+	if !k8sutil.IsKubernetesResourceNotFoundError(err) {
+		return err
+	}
+	if err := c.createPod(c.members, m, "existing", ""); err != nil {
+		return fmt.Errorf("failed to recreate the pod of member (%s): %v", m.Name, err)
+	}
+	c.logger.Infof("recreated the pod of dead member (%s) on its PVC", m.Name)
+	c.audit(auditMemberRecreated, m.Name, "pod recreated on the PVC of the member")
+	return nil
+}
+
+// memberUnschedulableTimeout is how long the pod of a member with a PVC may stay unscheduled,
+// e.g. because its volume is bound to a lost node or zone, before the member is replaced.
+const memberUnschedulableTimeout = 5 * time.Minute
+
+// pickUnschedulableMemberPod returns a pending pod the scheduler has not placed for longer than
+// memberUnschedulableTimeout, or nil if there is none.
+func pickUnschedulableMemberPod(pods []*v1.Pod, now time.Time) *v1.Pod {
+	for _, pod := range pods {
+		if now.Sub(pod.CreationTimestamp.Time) <= memberUnschedulableTimeout {
+			continue
+		}
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type == v1.PodScheduled && cond.Status == v1.ConditionFalse {
+				return pod
+			}
+		}
+	}
+	return nil
+}
+
+// replaceUnschedulableMember removes the member of the pod from the cluster and deletes its PVC,
+// so that the next reconciles add a new member on a new PVC, which the scheduler can place on any node.
+func (c *Cluster) replaceUnschedulableMember(running []*v1.Pod, pod *v1.Pod) error {
+	if c.members == nil {
+		if err := c.updateMembers(podsToMemberSet(running, c.isSecureClient())); err != nil {
+			return err
+		}
+	}
+	m, ok := c.members[pod.Name]
+	if !ok {
+		c.logger.Infof("removing unschedulable pod (%s) and its PVC", pod.Name)
+		return c.removePodAndData(pod.Name)
+	}
+	reason := fmt.Sprintf("pod not scheduled for %v, e.g. its volume is bound to a lost node", memberUnschedulableTimeout)
+	c.logger.Infof("replacing member (%s): %s", m.Name, reason)
+	c.status.AppendRemovingDeadMember(m.Name)
+	if err := c.removeMember(m); err != nil {
+		return err
+	}
+	c.audit(auditMemberRemoved, m.Name, reason)
+	return nil
 }
 
 func (c *Cluster) removeDeadMember(toRemove *etcdutil.Member) error {
 	c.logger.Infof("removing dead member %q", toRemove.Name)
 	c.status.AppendRemovingDeadMember(toRemove.Name)
 
-	return c.removeMember(toRemove)
+	if err := c.removeMember(toRemove); err != nil {
+		return err
+	}
+	c.audit(auditMemberRemoved, toRemove.Name, "member is dead")
+	return nil
 }
 
 func (c *Cluster) removeMember(toRemove *etcdutil.Member) error {
@@ -165,12 +371,13 @@ func (c *Cluster) removeMember(toRemove *etcdutil.Member) error {
 		case rpctypes.ErrMemberNotFound:
 			c.logger.Infof("etcd member (%v) has been removed", toRemove.Name)
 		default:
+			c.countEtcdClientFailure(rpcMemberRemove, err)
 			c.logger.Errorf("fail to remove etcd member (%v): %v", toRemove.Name, err)
 			return err
 		}
 	}
 	c.members.Remove(toRemove.Name)
-	if err := c.removePod(toRemove.Name); err != nil {
+	if err := c.removePodAndData(toRemove.Name); err != nil {
 		return err
 	}
 	c.logger.Infof("removed member (%v) with ID (%d)", toRemove.Name, toRemove.ID)
@@ -178,17 +385,24 @@ func (c *Cluster) removeMember(toRemove *etcdutil.Member) error {
 }
 
 func (c *Cluster) disasterRecovery(left etcdutil.MemberSet) error {
-	c.status.AppendRecoveringCondition()
+	c.notify(notify.TypeQuorumLost, fmt.Sprintf("%d of %d member(s) running", left.Size(), c.cluster.Spec.Size))
 
 	if c.cluster.Spec.SelfHosted != nil {
+		c.status.AppendRecoveringCondition()
 		return errors.New("self-hosted cluster cannot be recovered from disaster")
 	}
 
 	if c.cluster.Spec.Backup == nil {
+		c.status.AppendRecoveringCondition()
 		c.logger.Errorf("fail to do disaster recovery: no backup policy has been defined.")
 		return errNoBackupExist
 	}
 
+	if !c.disasterRecoveryAllowed(left) {
+		return nil
+	}
+	c.status.AppendRecoveringCondition()
+
 	backupNow := false
 	if len(left) > 0 {
 		c.logger.Infof("pods are still running (%v). Will try to make a latest backup from one of them.", left)
@@ -204,7 +418,7 @@ func (c *Cluster) disasterRecovery(left etcdutil.MemberSet) error {
 	} else {
 		// We don't return error if backupnow failed. Instead, we ask if there is previous backup.
 		// If so, we can still continue. Otherwise, it's fatal error.
-		exist, err := c.bm.checkBackupExist(c.cluster.Spec.Version)
+		exist, err := c.bm.checkBackupExist(c.recoveryBackupVersion())
 		if err != nil {
 			c.logger.Errorln(err)
 			return err
@@ -214,25 +428,47 @@ func (c *Cluster) disasterRecovery(left etcdutil.MemberSet) error {
 		}
 	}
 
-	for _, m := range left {
-		err := c.removePod(m.Name)
-		if err != nil {
-			return err
-		}
+	if err := c.removeAllPodsAndData(nil); err != nil {
+		return err
+	}
+	if err := c.recover(); err != nil {
+		return err
 	}
-	return c.recover()
+	c.audit(auditClusterRecovery, "", fmt.Sprintf("recovered from backup with %d member(s) left running", len(left)))
+	return nil
 }
 
 func needUpgrade(pods []*v1.Pod, cs spec.ClusterSpec) bool {
-	return len(pods) == cs.Size && pickOneOldMember(pods, cs.Version) != nil
+	return len(pods) == cs.Size && pickOneOldMember(pods, cs.Version, "") != nil
 }
 
-func pickOneOldMember(pods []*v1.Pod, newVersion string) *etcdutil.Member {
+// pickOldMembers picks the next batch of up to n members to upgrade to the new version
+// in leaderLast order, see maintenanceBatches.
+func pickOldMembers(pods []*v1.Pod, newVersion, leader string, n int) []*etcdutil.Member {
+	old := oldMembers(pods, newVersion)
+	if len(old) == 0 {
+		return nil
+	}
+	return maintenanceBatches(leaderLast(old, leader), leader, n)[0]
+}
+
+// pickOneOldMember picks the next member to upgrade to the new version in leaderLast order.
+func pickOneOldMember(pods []*v1.Pod, newVersion, leader string) *etcdutil.Member {
+	old := oldMembers(pods, newVersion)
+	if len(old) == 0 {
+		return nil
+	}
+	return leaderLast(old, leader)[0]
+}
+
+// oldMembers returns the members which do not run the new version.
+func oldMembers(pods []*v1.Pod, newVersion string) []*etcdutil.Member {
+	var old []*etcdutil.Member
 	for _, pod := range pods {
 		if k8sutil.GetEtcdVersion(pod) == newVersion {
 			continue
 		}
-		return &etcdutil.Member{Name: pod.Name, Namespace: pod.Namespace}
+		old = append(old, &etcdutil.Member{Name: pod.Name, Namespace: pod.Namespace})
 	}
-	return nil
+	return old
 }
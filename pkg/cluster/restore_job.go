@@ -0,0 +1,85 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
+
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+const (
+	// restoreJobDeadline is how long a restore Job may run, including fetching the snapshot.
+	restoreJobDeadline = 10 * time.Minute
+	// restoreJobTTL is how long finished restore Jobs are kept to inspect them.
+	restoreJobTTL = 24 * time.Hour
+	// restoreJobCollectionInterval is how often the expired restore Jobs are deleted.
+	restoreJobCollectionInterval = 10 * time.Minute
+)
+
+// runRestoreJob restores the data dir of the member on its PVC in a Job, which runs the restore
+// init containers of the given pod, and waits for the Job to finish.
+// If the Job fails, its reason is appended as a RestoreFailed condition and the PVC is deleted,
+// so that the next recovery restores a new member from scratch. The Job is kept for restoreJobTTL.
+func (c *Cluster) runRestoreJob(m *etcdutil.Member, restore *v1.Pod) error {
+	ns := c.cluster.Metadata.Namespace
+	job := k8sutil.NewRestoreJobManifest(m, c.cluster.Metadata.Name, restore, restoreJobDeadline, c.cluster.Spec, c.cluster.AsOwner())
+	if _, err := c.config.KubeCli.BatchV1().Jobs(ns).Create(job); err != nil {
+		return fmt.Errorf("failed to create restore job (%s): %v", job.Name, err)
+	}
+	c.logger.Infof("restoring the data of member (%s) in job (%s)", m.Name, job.Name)
+
+	// The Job fails by itself at its deadline. The wait allows for the Job controller to notice.
+	failure, err := k8sutil.WaitRestoreJob(c.config.KubeCli, ns, job.Name, restoreJobDeadline+time.Minute)
+	if err != nil {
+		failure = err.Error()
+	}
+	if ferr := k8sutil.FinishRestoreJob(c.config.KubeCli, ns, job.Name, len(failure) != 0, time.Now()); ferr != nil {
+		c.logger.Warningf("failed to finish restore job (%s): %v", job.Name, ferr)
+	}
+	if len(failure) == 0 {
+		c.logger.Infof("restored the data of member (%s) in job (%s)", m.Name, job.Name)
+		return nil
+	}
+
+	c.status.SetRestoreFailedCondition(fmt.Sprintf("restore job (%s) failed: %s", job.Name, failure))
+	if err := c.updateTPRStatus(); err != nil {
+		c.logger.Warningf("failed to update TPR status: %v", err)
+	}
+	if err := k8sutil.DeleteMemberPVC(c.config.KubeCli, ns, m.Name); err != nil {
+		c.logger.Warningf("failed to delete PVC of member (%s) after its restore failed: %v", m.Name, err)
+	}
+	return fmt.Errorf("failed to restore member (%s) in job (%s): %s", m.Name, job.Name, failure)
+}
+
+// collectRestoreJobs deletes the restore Jobs which finished more than restoreJobTTL ago,
+// at most every restoreJobCollectionInterval.
+func (c *Cluster) collectRestoreJobs() {
+	if !k8sutil.HasMemberPVC(c.cluster.Spec) || time.Since(c.lastRestoreJobCollection) < restoreJobCollectionInterval {
+		return
+	}
+	c.lastRestoreJobCollection = time.Now()
+	deleted, err := k8sutil.DeleteExpiredRestoreJobs(c.config.KubeCli, c.cluster.Metadata.Name, c.cluster.Metadata.Namespace, restoreJobTTL, time.Now())
+	if len(deleted) != 0 {
+		c.logger.Infof("deleted expired restore jobs %v", deleted)
+	}
+	if err != nil {
+		c.logger.Warningf("failed to delete expired restore jobs: %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+// updateSafeToEvict annotates the member pods with spec.SafeToEvictAnnotation if spec.manageSafeToEvict is set,
+// following the ready members in status. Otherwise, it removes the annotation unless spec.pod.annotations sets it.
+func (c *Cluster) updateSafeToEvict(pods []*v1.Pod) {
+	sp := c.cluster.Spec
+	want := ""
+	if sp.ManageSafeToEvict {
+		want = safeToEvictValue(len(c.status.Members.Ready), sp.Size)
+	} else if sp.Pod != nil {
+		if _, ok := sp.Pod.Annotations[spec.SafeToEvictAnnotation]; ok {
+			return
+		}
+	}
+
+	for _, pod := range pods {
+		cur, ok := pod.Annotations[spec.SafeToEvictAnnotation]
+		if (ok && cur == want) || (!ok && len(want) == 0) {
+			continue
+		}
+		if err := c.patchSafeToEvict(pod, want); err != nil {
+			c.logger.Warningf("failed to update %s annotation of member (%s): %v", spec.SafeToEvictAnnotation, pod.Name, err)
+			continue
+		}
+		c.logger.Infof("set %s annotation of member (%s) to %q", spec.SafeToEvictAnnotation, pod.Name, want)
+	}
+}
+
+// safeToEvictValue returns "true" if the cluster keeps a quorum of ready members
+// after losing any one of them, and "false" otherwise.
+func safeToEvictValue(ready, size int) string {
+	if ready-1 >= size/2+1 {
+		return "true"
+	}
+	return "false"
+}
+
+// patchSafeToEvict sets the annotation of the pod to the value, or removes it if the value is empty.
+func (c *Cluster) patchSafeToEvict(pod *v1.Pod, value string) error {
+	newpod := k8sutil.ClonePod(pod)
+	if len(value) == 0 {
+		delete(newpod.Annotations, spec.SafeToEvictAnnotation)
+	} else {
+		if newpod.Annotations == nil {
+			newpod.Annotations = map[string]string{}
+		}
+		newpod.Annotations[spec.SafeToEvictAnnotation] = value
+	}
+	patchdata, err := k8sutil.CreatePatch(pod, newpod, v1.Pod{})
+	if err != nil {
+		return err
+	}
+	_, err = c.config.KubeCli.CoreV1().Pods(pod.Namespace).Patch(pod.Name, types.StrategicMergePatchType, patchdata)
+	return err
+}
@@ -0,0 +1,37 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import "testing"
+
+func TestSafeToEvictValue(t *testing.T) {
+	tests := []struct {
+		ready int
+		size  int
+		w     string
+	}{
+		{ready: 1, size: 1, w: "false"},
+		{ready: 3, size: 3, w: "true"},
+		{ready: 2, size: 3, w: "false"},
+		{ready: 4, size: 5, w: "true"},
+		{ready: 3, size: 5, w: "false"},
+		{ready: 2, size: 2, w: "false"},
+	}
+	for i, tt := range tests {
+		if get := safeToEvictValue(tt.ready, tt.size); get != tt.w {
+			t.Errorf("#%d: safe to evict get=%s, want=%s", i, get, tt.w)
+		}
+	}
+}
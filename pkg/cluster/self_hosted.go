@@ -15,6 +15,7 @@
 package cluster
 
 import (
+	"crypto/tls"
 	"fmt"
 	"math"
 	"time"
@@ -58,8 +59,10 @@ func (c *Cluster) addOneSelfHostedMember() error {
 
 	c.status.AppendScalingUpCondition(c.members.Size(), c.cluster.Spec.Size)
 
-	newMember := c.newMember(c.memberCounter)
-	c.memberCounter++
+	newMember, err := c.newMember()
+	if err != nil {
+		return err
+	}
 	peerURL := newMember.PeerURL()
 	initialCluster := append(c.members.PeerURLPairs(), newMember.Name+"="+peerURL)
 
@@ -104,12 +107,14 @@ func (c *Cluster) addOneSelfHostedMember() error {
 }
 
 func (c *Cluster) newSelfHostedSeedMember() error {
-	newMember := c.newMember(c.memberCounter)
-	c.memberCounter++
+	newMember, err := c.newMember()
+	if err != nil {
+		return err
+	}
 	initialCluster := []string{newMember.Name + "=" + newMember.PeerURL()}
 
 	pod := k8sutil.NewSelfHostedEtcdPod(newMember, initialCluster, nil, c.cluster.Metadata.Name, "new", uuid.New(), c.cluster.Spec, c.cluster.AsOwner())
-	_, err := k8sutil.CreateAndWaitPod(c.config.KubeCli, c.cluster.Metadata.Namespace, pod, 30*time.Second)
+	_, err = k8sutil.CreateAndWaitPod(c.config.KubeCli, c.cluster.Metadata.Namespace, pod, 30*time.Second)
 	if err != nil {
 		return err
 	}
@@ -118,6 +123,23 @@ func (c *Cluster) newSelfHostedSeedMember() error {
 	return nil
 }
 
+// bootMemberSyncRetries bounds the wait for the first member to catch up with the boot member, about 10 minutes.
+const bootMemberSyncRetries = 60
+
+// isCaughtUpWith returns true if the member serving on url has a leader and is
+// in sync with the raft log of the boot member serving on bootURL.
+func isCaughtUpWith(url, bootURL string, tc *tls.Config) (bool, error) {
+	bst, err := etcdutil.MemberStatus(bootURL, tc)
+	if err != nil {
+		return false, err
+	}
+	st, err := etcdutil.MemberStatus(url, tc)
+	if err != nil {
+		return false, err
+	}
+	return isMemberInSync(st.Leader != 0, st.RaftIndex, bst.RaftIndex), nil
+}
+
 func (c *Cluster) migrateBootMember() error {
 	endpoint := c.cluster.Spec.SelfHosted.BootMemberClientEndpoint
 
@@ -138,8 +160,10 @@ func (c *Cluster) migrateBootMember() error {
 	}
 
 	// create the member inside Kubernetes for migration
-	newMember := c.newMember(c.memberCounter)
-	c.memberCounter++
+	newMember, err := c.newMember()
+	if err != nil {
+		return err
+	}
 
 	peerURL := newMember.PeerURL()
 	initialCluster = append(initialCluster, newMember.Name+"="+peerURL)
@@ -152,13 +176,23 @@ func (c *Cluster) migrateBootMember() error {
 	}
 
 	go func() {
-		// TODO: a shorter timeout?
-		// Waiting here for cluster to get stable:
-		// - etcd data are replicated;
-		// - cluster TPR state has switched to "Running"
-		delay := 60 * time.Second
-		c.logger.Infof("wait %v before removing the boot member", delay)
-		time.Sleep(delay)
+		// The boot member might be the only member which has all the data of the control plane.
+		// Remove it only once the new member caught up with it.
+		err := retryutil.Retry(10*time.Second, bootMemberSyncRetries, func() (bool, error) {
+			synced, err := isCaughtUpWith(newMember.ClientAddr(), endpoint, c.tlsConfig)
+			if err != nil {
+				c.logger.Warningf("boot member migration: %v", err)
+				return false, nil
+			}
+			if !synced {
+				c.logger.Infof("boot member migration: waiting for member (%s) to catch up with the boot member", newMember.Name)
+			}
+			return synced, nil
+		})
+		if err != nil {
+			c.logger.Errorf("boot member migration: member (%s) did not catch up with the boot member, keeping the boot member: %v", newMember.Name, err)
+			return
+		}
 
 		err = etcdutil.RemoveMember([]string{newMember.ClientAddr()}, c.tlsConfig, bootMember.ID)
 		if err != nil {
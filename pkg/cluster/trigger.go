@@ -0,0 +1,126 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
+)
+
+// triggeredOperation is a one-off operation triggered by a cluster annotation.
+type triggeredOperation struct {
+	annotation string
+	name       string
+	// run returns a description of what was done.
+	run func() (string, error)
+}
+
+// triggeredOperations returns the operations triggered by the annotations of the cluster,
+// in the order they run: a backup is made before the keyspace is changed, an export
+// dumps the keys before an import loads others, the keyspace is compacted after them,
+// and members are defragmented after the compaction freed their pages.
+func (c *Cluster) triggeredOperations() []triggeredOperation {
+	all := []triggeredOperation{
+		{annotation: spec.TriggerBackupAnnotation, name: "backup", run: c.triggerBackup},
+		{annotation: spec.TriggerExportAnnotation, name: "export", run: c.triggerExport},
+		{annotation: spec.TriggerImportAnnotation, name: "import", run: c.triggerImport},
+		{annotation: spec.TriggerCompactionAnnotation, name: "compaction", run: c.triggerCompaction},
+		{annotation: spec.TriggerDefragAnnotation, name: "defragmentation", run: c.triggerDefrag},
+	}
+	var ops []triggeredOperation
+	for _, op := range all {
+		if _, ok := c.cluster.Metadata.Annotations[op.annotation]; ok {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+// runTriggeredOperations runs the operations triggered by annotations once.
+// The annotations are removed first, so that an operation is not repeated
+// if the operator fails or restarts while running it.
+func (c *Cluster) runTriggeredOperations() {
+	ops := c.triggeredOperations()
+	if len(ops) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(ops))
+	for _, op := range ops {
+		keys = append(keys, op.annotation)
+	}
+	if err := c.removeAnnotations(keys...); err != nil {
+		c.logger.Warningf("failed to remove trigger annotations, retry on next reconcile: %v", err)
+		return
+	}
+	for _, op := range ops {
+		c.logger.Infof("running %s triggered by annotation (%s)", op.name, op.annotation)
+		result, err := op.run()
+		c.createEvent(k8sutil.TriggeredOperationEvent(c.cluster, op.name, result, err))
+		if err != nil {
+			c.logger.Errorf("triggered %s failed: %v", op.name, err)
+		}
+	}
+}
+
+// removeAnnotations removes the given annotations from the cluster object.
+func (c *Cluster) removeAnnotations(keys ...string) error {
+	cl := *c.cluster
+	cl.Metadata.Annotations = make(map[string]string, len(c.cluster.Metadata.Annotations))
+	for k, v := range c.cluster.Metadata.Annotations {
+		cl.Metadata.Annotations[k] = v
+	}
+	for _, k := range keys {
+		delete(cl.Metadata.Annotations, k)
+	}
+	newCluster, err := k8sutil.UpdateClusterTPRObject(c.config.KubeCli.CoreV1().RESTClient(), c.cluster.Metadata.Namespace, &cl)
+	if err != nil {
+		return err
+	}
+	c.cluster = newCluster
+	return nil
+}
+
+func (c *Cluster) triggerBackup() (string, error) {
+	if c.bm == nil {
+		return "", errNoBackupPolicy
+	}
+	if err := c.bm.requestBackup(); err != nil {
+		return "", err
+	}
+	return "made a backup", nil
+}
+
+func (c *Cluster) triggerCompaction() (string, error) {
+	rev, err := etcdutil.CompactToLatest(c.members.ClientURLs(), c.tlsConfig)
+	c.countEtcdClientFailure(rpcCompact, err)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("compacted to revision %d", rev), nil
+}
+
+func (c *Cluster) triggerDefrag() (string, error) {
+	ms, leader, err := c.membersLeaderLast()
+	if err != nil {
+		return "", err
+	}
+	if err := c.defragmentMembers(ms, leader); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("defragmented %d member(s)", len(ms)), nil
+}
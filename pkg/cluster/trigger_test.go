@@ -0,0 +1,65 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTriggeredOperations(t *testing.T) {
+	tests := []struct {
+		annotations map[string]string
+		wOps        []string
+	}{
+		{annotations: nil, wOps: nil},
+		{annotations: map[string]string{spec.ApproveUpgradeAnnotation: "test-0000"}, wOps: nil},
+		// the value does not matter
+		{annotations: map[string]string{spec.TriggerBackupAnnotation: ""}, wOps: []string{"backup"}},
+		// backup first, defragmentation after compaction
+		{
+			annotations: map[string]string{
+				spec.TriggerDefragAnnotation:     "true",
+				spec.TriggerCompactionAnnotation: "true",
+				spec.TriggerBackupAnnotation:     "true",
+			},
+			wOps: []string{"backup", "compaction", "defragmentation"},
+		},
+		// export before import, both between the backup and the compaction
+		{
+			annotations: map[string]string{
+				spec.TriggerImportAnnotation:     "",
+				spec.TriggerCompactionAnnotation: "",
+				spec.TriggerExportAnnotation:     "",
+				spec.TriggerBackupAnnotation:     "",
+			},
+			wOps: []string{"backup", "export", "import", "compaction"},
+		},
+	}
+	for i, tt := range tests {
+		c := &Cluster{cluster: &spec.Cluster{Metadata: metav1.ObjectMeta{Annotations: tt.annotations}}}
+		var ops []string
+		for _, op := range c.triggeredOperations() {
+			ops = append(ops, op.name)
+		}
+		if !reflect.DeepEqual(ops, tt.wOps) {
+			t.Errorf("#%d: operations get=%v, want=%v", i, ops, tt.wOps)
+		}
+	}
+}
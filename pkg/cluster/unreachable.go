@@ -0,0 +1,83 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+)
+
+// updateUnreachableSince returns the times since when members have been unreachable.
+// Members that are reachable again, or are not checked anymore, are dropped.
+func updateUnreachableSince(since map[string]time.Time, unreachable map[string]bool, now time.Time) map[string]time.Time {
+	updated := make(map[string]time.Time, len(unreachable))
+	for name := range unreachable {
+		if t, ok := since[name]; ok {
+			updated[name] = t
+		} else {
+			updated[name] = now
+		}
+	}
+	return updated
+}
+
+// pickUnreachableMemberToRemove returns a member which has been unreachable for longer
+// than spec.memberUnreachableTimeoutInSecond, or nil if there is none.
+// It returns nil if removing the member would leave the cluster without a quorum of
+// reachable members.
+func (c *Cluster) pickUnreachableMemberToRemove() *etcdutil.Member {
+	timeout := time.Duration(c.cluster.Spec.MemberUnreachableTimeoutInSecond) * time.Second
+	if timeout == 0 {
+		return nil
+	}
+	name, ok := unreachableMemberToRemove(c.unreachableSince, c.members.Size(), timeout, time.Now())
+	if !ok {
+		return nil
+	}
+	return c.members[name]
+}
+
+func unreachableMemberToRemove(since map[string]time.Time, size int, timeout time.Duration, now time.Time) (string, bool) {
+	// The remaining members must keep a quorum of the shrunk cluster.
+	if reachable := size - len(since); reachable < (size-1)/2+1 {
+		return "", false
+	}
+	var name string
+	var oldest time.Time
+	for n, t := range since {
+		if now.Sub(t) < timeout {
+			continue
+		}
+		if len(name) == 0 || t.Before(oldest) {
+			name, oldest = n, t
+		}
+	}
+	return name, len(name) != 0
+}
+
+func (c *Cluster) removeUnreachableMember(toRemove *etcdutil.Member) error {
+	since := c.unreachableSince[toRemove.Name]
+	c.logger.Infof("removing member %q unreachable since %v", toRemove.Name, since)
+	c.status.AppendRemovingDeadMember(toRemove.Name)
+
+	if err := c.removeMember(toRemove); err != nil {
+		return err
+	}
+	delete(c.unreachableSince, toRemove.Name)
+	c.audit(auditMemberRemoved, toRemove.Name, fmt.Sprintf("member is unreachable since %s", since.Format(time.RFC3339)))
+	return nil
+}
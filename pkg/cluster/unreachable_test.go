@@ -0,0 +1,63 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUpdateUnreachableSince(t *testing.T) {
+	now := time.Now()
+	before := now.Add(-time.Minute)
+	since := map[string]time.Time{"m0": before, "m1": before}
+
+	updated := updateUnreachableSince(since, map[string]bool{"m1": true, "m2": true}, now)
+	if len(updated) != 2 {
+		t.Fatalf("unreachable members get=%v, want m1 and m2", updated)
+	}
+	if !updated["m1"].Equal(before) {
+		t.Errorf("m1 unreachable since get=%v, want=%v", updated["m1"], before)
+	}
+	if !updated["m2"].Equal(now) {
+		t.Errorf("m2 unreachable since get=%v, want=%v", updated["m2"], now)
+	}
+}
+
+func TestUnreachableMemberToRemove(t *testing.T) {
+	now := time.Now()
+	ago := func(d time.Duration) time.Time { return now.Add(-d) }
+
+	tests := []struct {
+		since map[string]time.Time
+		size  int
+		wName string
+		wOK   bool
+	}{
+		{since: map[string]time.Time{}, size: 3, wOK: false},
+		{since: map[string]time.Time{"m0": ago(time.Minute)}, size: 3, wOK: false},
+		{since: map[string]time.Time{"m0": ago(time.Hour)}, size: 3, wName: "m0", wOK: true},
+		{since: map[string]time.Time{"m0": ago(time.Hour), "m1": ago(2 * time.Hour)}, size: 5, wName: "m1", wOK: true},
+		// removing one of two unreachable members would leave 1 of 2 members reachable.
+		{since: map[string]time.Time{"m0": ago(time.Hour), "m1": ago(time.Hour)}, size: 3, wOK: false},
+		{since: map[string]time.Time{"m0": ago(time.Hour)}, size: 1, wOK: false},
+	}
+	for i, tt := range tests {
+		name, ok := unreachableMemberToRemove(tt.since, tt.size, 10*time.Minute, now)
+		if ok != tt.wOK || name != tt.wName {
+			t.Errorf("#%d: member to remove get=(%s, %v), want=(%s, %v)", i, name, ok, tt.wName, tt.wOK)
+		}
+	}
+}
@@ -15,10 +15,16 @@
 package cluster
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
 	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
 
+	"github.com/coreos/etcd/clientv3"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/pkg/api/v1"
@@ -36,7 +42,7 @@ func (c *Cluster) upgradeOneMember(memberName string) error {
 	oldpod := k8sutil.ClonePod(pod)
 
 	c.logger.Infof("upgrading the etcd member %v from %s to %s", memberName, k8sutil.GetEtcdVersion(pod), c.cluster.Spec.Version)
-	pod.Spec.Containers[0].Image = k8sutil.EtcdImageName(c.cluster.Spec.Version)
+	pod.Spec.Containers[0].Image = k8sutil.EtcdImageName(c.cluster.Spec)
 	k8sutil.SetEtcdVersion(pod, c.cluster.Spec.Version)
 
 	patchdata, err := k8sutil.CreatePatch(oldpod, pod, v1.Pod{})
@@ -49,5 +55,117 @@ func (c *Cluster) upgradeOneMember(memberName string) error {
 		return fmt.Errorf("fail to update the etcd member (%s): %v", memberName, err)
 	}
 	c.logger.Infof("finished upgrading the etcd member %v", memberName)
+	c.lastMemberUpgrade = time.Now()
+	c.audit(auditMemberUpgraded, memberName, fmt.Sprintf("upgraded from %s to %s", k8sutil.GetEtcdVersion(oldpod), c.cluster.Spec.Version))
+	return nil
+}
+
+// upgradeVerifyTimeout is how long an upgraded member has to pass its checks
+// before the upgrade is paused.
+const upgradeVerifyTimeout = 5 * time.Minute
+
+// checkUpgradedMembers returns why the members already upgraded to the version fail
+// their checks, or an empty string if they pass. An upgraded member must be ready,
+// run the new version, and be in sync with the leader. The cluster must not have a
+// data corruption alarm.
+func (c *Cluster) checkUpgradedMembers(pods []*v1.Pod, version string) string {
+	statuses := make(map[string]*clientv3.StatusResponse)
+	var leaderIndex uint64
+	for _, pod := range pods {
+		m := &etcdutil.Member{Name: pod.Name, Namespace: pod.Namespace, SecureClient: c.isSecureClient()}
+		st, err := c.memberStatus(m.ClientAddr())
+		if err != nil {
+			continue
+		}
+		statuses[pod.Name] = st
+		if st.Header != nil && st.Leader == st.Header.MemberId {
+			leaderIndex = st.RaftIndex
+		}
+	}
+	for _, pod := range pods {
+		if k8sutil.GetEtcdVersion(pod) != version {
+			continue
+		}
+		if reason := checkUpgradedMember(pod.Name, k8sutil.IsPodReady(pod), statuses[pod.Name], version, leaderIndex); len(reason) != 0 {
+			return reason
+		}
+	}
+	return c.checkCorruption()
+}
+
+func checkUpgradedMember(name string, podReady bool, st *clientv3.StatusResponse, version string, leaderIndex uint64) string {
+	switch {
+	case !podReady:
+		return fmt.Sprintf("upgraded member %s is not ready", name)
+	case st == nil:
+		return fmt.Sprintf("upgraded member %s is unreachable", name)
+	case st.Version != version:
+		return fmt.Sprintf("upgraded member %s runs etcd %s instead of %s", name, st.Version, version)
+	case !isMemberInSync(st.Leader != 0, st.RaftIndex, leaderIndex):
+		return fmt.Sprintf("upgraded member %s is not in sync with the leader", name)
+	}
+	return ""
+}
+
+// pauseUpgrade holds the upgrade until the upgraded members pass their checks.
+// Upgraded members get upgradeVerifyTimeout to pass them before the upgrade is reported as paused.
+func (c *Cluster) pauseUpgrade(reason string) {
+	if time.Since(c.lastMemberUpgrade) < upgradeVerifyTimeout {
+		c.logger.Infof("waiting for the upgraded members: %s", reason)
+		return
+	}
+	c.logger.Warningf("upgrade paused: %s", reason)
+	if !c.status.IsUpgradePaused() {
+		c.createEvent(k8sutil.UpgradePausedEvent(c.cluster, reason))
+	}
+	c.status.SetUpgradePausedCondition(reason)
+}
+
+func isUpgradeApproved(cl *spec.Cluster, memberName string) bool {
+	return cl.Metadata.Annotations[spec.ApproveUpgradeAnnotation] == memberName
+}
+
+// waitForUpgradeApproval holds the upgrade until the user approves the upgrade of the member.
+func (c *Cluster) waitForUpgradeApproval(memberName string) {
+	c.holdUpgrade(fmt.Sprintf("waiting for approval to upgrade member %s: annotate the cluster with %s=%s",
+		memberName, spec.ApproveUpgradeAnnotation, memberName))
+}
+
+// holdUpgrade reports that the upgrade does not continue for the reason.
+func (c *Cluster) holdUpgrade(reason string) {
+	c.logger.Info(reason)
+	if !c.status.IsUpgradePaused() {
+		c.createEvent(k8sutil.UpgradePausedEvent(c.cluster, reason))
+	}
+	c.status.SetUpgradePausedCondition(reason)
+}
+
+// recreateFromBackup upgrades the cluster by backing it up, deleting all members
+// and creating a seed member at the new version from the backup.
+// The cluster is then resized to the expected size as usual.
+func (c *Cluster) recreateFromBackup(pods []*v1.Pod) error {
+	from, to := c.status.CurrentVersion, c.cluster.Spec.Version
+	if c.bm == nil {
+		return errNoBackupExist
+	}
+	if len(from) == 0 {
+		return errors.New("failed to recreate cluster from backup: current cluster version is unknown")
+	}
+	c.status.UpgradeVersionTo(to)
+	c.status.AppendRecreatingCondition(from, to)
+
+	// Unlike disaster recovery, an older backup is no fallback: writes since then would be lost.
+	if err := c.bm.requestBackup(); err != nil {
+		return fmt.Errorf("failed to back up cluster before recreating it: %v", err)
+	}
+	c.logger.Infof("made a latest backup, recreating the cluster from version %s to %s", from, to)
+
+	if err := c.removeAllPodsAndData(pods); err != nil {
+		return err
+	}
+	if err := c.startSeedMember(from); err != nil {
+		return err
+	}
+	c.audit(auditClusterRecreated, "", fmt.Sprintf("recreated from backup to upgrade from %s to %s", from, to))
 	return nil
 }
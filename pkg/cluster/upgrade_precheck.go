@@ -0,0 +1,93 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+
+	pb "github.com/coreos/etcd/etcdserver/etcdserverpb"
+	"github.com/coreos/go-semver/semver"
+)
+
+// checkUpgradePreconditions returns why the cluster must not start a rolling upgrade
+// to the version, or an empty string if it may. The version must be at most one minor
+// version away from the current one, no alarm may be active, all members must be healthy,
+// and the most recent backup must be recent enough if the backup policy asks for it.
+func (c *Cluster) checkUpgradePreconditions(version string) string {
+	if reason := checkVersionJump(c.status.CurrentVersion, version); len(reason) != 0 {
+		return reason
+	}
+
+	alarms, err := etcdutil.ListAlarms(c.members.ClientURLs(), c.tlsConfig, pb.AlarmType_NONE)
+	c.countEtcdClientFailure(rpcAlarmList, err)
+	if err != nil {
+		return fmt.Sprintf("failed to list alarms: %v", err)
+	}
+	if len(alarms) != 0 {
+		return fmt.Sprintf("%d alarm(s) active, e.g. %s on member %x", len(alarms), alarmName(alarms[0].Alarm), alarms[0].MemberID)
+	}
+
+	if healthy := c.healthyMembers(); len(healthy) != c.members.Size() {
+		return fmt.Sprintf("%d of %d members are healthy", len(healthy), c.members.Size())
+	}
+
+	if bp := c.cluster.Spec.Backup; bp != nil && bp.MaxBackupAgeForUpgradeInSecond > 0 {
+		maxAge := time.Duration(bp.MaxBackupAgeForUpgradeInSecond) * time.Second
+		return checkBackupAge(c.status.BackupServiceStatus, maxAge, time.Now())
+	}
+	return ""
+}
+
+// checkVersionJump returns why upgrading from one version to the other is not supported
+// by a rolling upgrade, or an empty string if it is.
+// Unknown versions are not checked, e.g. the current version of a cluster created by an older operator.
+func checkVersionJump(from, to string) string {
+	fv, err := semver.NewVersion(from)
+	if err != nil {
+		return ""
+	}
+	tv, err := semver.NewVersion(to)
+	if err != nil {
+		return ""
+	}
+	minors := tv.Minor - fv.Minor
+	if minors < 0 {
+		minors = -minors
+	}
+	if tv.Major != fv.Major || minors > 1 {
+		return fmt.Sprintf("version %s is more than one minor version away from %s", to, from)
+	}
+	return ""
+}
+
+// checkBackupAge returns why the most recent backup is missing or older than maxAge,
+// or an empty string if it is recent enough.
+func checkBackupAge(bs *spec.BackupServiceStatus, maxAge time.Duration, now time.Time) string {
+	if bs == nil || bs.RecentBackup == nil {
+		return "no backup of the cluster exists"
+	}
+	t, err := time.Parse(time.RFC3339, bs.RecentBackup.CreationTime)
+	if err != nil {
+		return fmt.Sprintf("failed to parse the creation time of the most recent backup: %v", err)
+	}
+	if age := now.Sub(t); age > maxAge {
+		return fmt.Sprintf("the most recent backup is %v old, older than %v", age-age%time.Second, maxAge)
+	}
+	return ""
+}
@@ -0,0 +1,145 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+
+	"github.com/coreos/etcd/clientv3"
+)
+
+func TestCheckUpgradedMember(t *testing.T) {
+	newStatus := func(version string, leader, raftIndex uint64) *clientv3.StatusResponse {
+		st := newTestStatus(2, leader, raftIndex)
+		st.Version = version
+		return st
+	}
+	tests := []struct {
+		podReady bool
+		st       *clientv3.StatusResponse
+		wPass    bool
+	}{
+		{podReady: true, st: newStatus("3.2.0", 1, 2000), wPass: true},
+		// pod not ready
+		{podReady: false, st: newStatus("3.2.0", 1, 2000), wPass: false},
+		// unreachable
+		{podReady: true, st: nil, wPass: false},
+		// still runs the old version
+		{podReady: true, st: newStatus("3.1.8", 1, 2000), wPass: false},
+		// no leader
+		{podReady: true, st: newStatus("3.2.0", 0, 2000), wPass: false},
+		// slightly behind the leader
+		{podReady: true, st: newStatus("3.2.0", 1, 1500), wPass: true},
+		// far behind the leader
+		{podReady: true, st: newStatus("3.2.0", 1, 500), wPass: false},
+	}
+	for i, tt := range tests {
+		reason := checkUpgradedMember("test-0000", tt.podReady, tt.st, "3.2.0", 2000)
+		if pass := len(reason) == 0; pass != tt.wPass {
+			t.Errorf("#%d: pass get=%v (%s), want=%v", i, pass, reason, tt.wPass)
+		}
+	}
+}
+
+func TestRecoveryBackupVersion(t *testing.T) {
+	tests := []struct {
+		strategy       spec.UpgradeStrategyType
+		current        string
+		target         string
+		wBackupVersion string
+	}{
+		{strategy: spec.UpgradeStrategyRecreateFromBackup, current: "3.0.17", target: "3.2.0", wBackupVersion: "3.0.17"},
+		// not upgrading
+		{strategy: spec.UpgradeStrategyRecreateFromBackup, current: "3.2.0", target: "", wBackupVersion: "3.2.0"},
+		// upgrading to an outdated target
+		{strategy: spec.UpgradeStrategyRecreateFromBackup, current: "3.0.17", target: "3.1.8", wBackupVersion: "3.2.0"},
+		// rolling upgrade
+		{strategy: spec.UpgradeStrategyRolling, current: "3.1.8", target: "3.2.0", wBackupVersion: "3.2.0"},
+	}
+	for i, tt := range tests {
+		c := &Cluster{
+			cluster: &spec.Cluster{Spec: spec.ClusterSpec{Version: "3.2.0", UpgradeStrategy: tt.strategy}},
+			status:  spec.ClusterStatus{CurrentVersion: tt.current, TargetVersion: tt.target},
+		}
+		if v := c.recoveryBackupVersion(); v != tt.wBackupVersion {
+			t.Errorf("#%d: backup version get=%s, want=%s", i, v, tt.wBackupVersion)
+		}
+	}
+}
+
+func TestIsUpgradeApproved(t *testing.T) {
+	tests := []struct {
+		annotations map[string]string
+		wApproved   bool
+	}{
+		{annotations: map[string]string{spec.ApproveUpgradeAnnotation: "test-0001"}, wApproved: true},
+		// another member approved
+		{annotations: map[string]string{spec.ApproveUpgradeAnnotation: "test-0000"}, wApproved: false},
+		{annotations: nil, wApproved: false},
+	}
+	for i, tt := range tests {
+		cl := &spec.Cluster{}
+		cl.Metadata.Annotations = tt.annotations
+		if approved := isUpgradeApproved(cl, "test-0001"); approved != tt.wApproved {
+			t.Errorf("#%d: approved get=%v, want=%v", i, approved, tt.wApproved)
+		}
+	}
+}
+
+func TestCheckVersionJump(t *testing.T) {
+	tests := []struct {
+		from, to string
+		wPass    bool
+	}{
+		{from: "3.0.16", to: "3.1.8", wPass: true},
+		{from: "3.1.8", to: "3.1.9", wPass: true},
+		{from: "3.1.8", to: "3.0.16", wPass: true},
+		{from: "3.0.16", to: "3.2.0", wPass: false},
+		{from: "2.3.8", to: "3.0.0", wPass: false},
+		// unknown current version
+		{from: "", to: "3.2.0", wPass: true},
+	}
+	for i, tt := range tests {
+		reason := checkVersionJump(tt.from, tt.to)
+		if pass := len(reason) == 0; pass != tt.wPass {
+			t.Errorf("#%d: pass get=%v (%s), want=%v", i, pass, reason, tt.wPass)
+		}
+	}
+}
+
+func TestCheckBackupAge(t *testing.T) {
+	now := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	newStatus := func(created time.Time) *spec.BackupServiceStatus {
+		return &spec.BackupServiceStatus{RecentBackup: &spec.BackupStatus{CreationTime: created.Format(time.RFC3339)}}
+	}
+	tests := []struct {
+		bs    *spec.BackupServiceStatus
+		wPass bool
+	}{
+		{bs: newStatus(now.Add(-10 * time.Minute)), wPass: true},
+		{bs: newStatus(now.Add(-2 * time.Hour)), wPass: false},
+		{bs: &spec.BackupServiceStatus{}, wPass: false},
+		{bs: nil, wPass: false},
+	}
+	for i, tt := range tests {
+		reason := checkBackupAge(tt.bs, time.Hour, now)
+		if pass := len(reason) == 0; pass != tt.wPass {
+			t.Errorf("#%d: pass get=%v (%s), want=%v", i, pass, reason, tt.wPass)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
+
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+// zoneRebalanceCooldown is the minimum interval between two members moved to rebalance zones,
+// so that the scheduler placed the new member and it caught up before the next one is moved.
+const zoneRebalanceCooldown = 10 * time.Minute
+
+// rebalanceZonesIfNeeded moves one member out of the zone with the most members if the members
+// are skewed across the available zones, e.g. after a zone outage during which the members of the zone
+// were replaced in the other zones. The new member is placed by the scheduler, which prefers the zone
+// with the fewest members of the cluster. Members are moved one at a time, only while all members are ready.
+// Only zones with a node the member pods can be scheduled on count as available.
+// If a replacement lands back in the zone it was moved out of, e.g. because the scheduler prefers other
+// nodes there, rebalancing stops with an event until the members move across zones otherwise.
+func (c *Cluster) rebalanceZonesIfNeeded(pods []*v1.Pod) error {
+	if len(pods) == 0 {
+		return nil
+	}
+	available, err := c.availableZones(pods[0])
+	if err != nil {
+		c.logger.Warningf("skip zone rebalancing: %v", err)
+		return nil
+	}
+	zones := c.memberZones(pods)
+	c.updateUnavailableZones(zones, available)
+
+	// Wait for the zones to recover before members are moved into them.
+	if len(c.status.Members.UnavailableZones) != 0 {
+		return nil
+	}
+	if len(c.status.Members.Ready) != c.members.Size() || len(c.status.Members.Draining) != 0 {
+		return nil
+	}
+	if time.Since(c.lastZoneRebalance) < zoneRebalanceCooldown {
+		return nil
+	}
+	from, to, ok := zoneToRebalance(zones, available)
+	if !ok {
+		c.zoneRebalanceFrom, c.zoneRebalanceStopped = "", false
+		return nil
+	}
+	count := zoneCounts(zones)[from]
+	if from == c.zoneRebalanceFrom && count >= c.zoneRebalanceFromCount {
+		if !c.zoneRebalanceStopped {
+			reason := fmt.Sprintf("stopped rebalancing zones: the member moved out of zone %s was replaced in zone %s again", from, from)
+			c.logger.Warning(reason)
+			c.createEvent(k8sutil.ZoneRebalanceStoppedEvent(c.cluster, reason))
+			c.zoneRebalanceStopped = true
+		}
+		return nil
+	}
+	c.zoneRebalanceStopped = false
+
+	var ms []*etcdutil.Member
+	for name, zone := range zones {
+		if m, ok := c.members[name]; ok && zone == from {
+			ms = append(ms, m)
+		}
+	}
+	if len(ms) == 0 {
+		return nil
+	}
+	leader, err := c.leaderName()
+	if err != nil {
+		c.logger.Warningf("failed to find the leader: %v", err)
+	}
+	m := leaderLast(ms, leader)[0]
+	if !c.quorumGuardAllows("rebalance", m, true) {
+		return nil
+	}
+
+	reason := fmt.Sprintf("moving member %s out of zone %s, which has more members than zone %s", m.Name, from, to)
+	c.logger.Info(reason)
+	c.status.AppendRebalancingZonesCondition(reason)
+	if err := c.removeMember(m); err != nil {
+		return err
+	}
+	c.lastZoneRebalance = time.Now()
+	c.zoneRebalanceFrom, c.zoneRebalanceFromCount = from, count
+	c.createEvent(k8sutil.ZoneRebalancedEvent(c.cluster, reason))
+	c.audit(auditMemberRemoved, m.Name, reason)
+	return nil
+}
+
+// zoneToRebalance returns the zone to move a member out of and the available zone with the fewest members,
+// if they differ by more than one member.
+// Members in zones without a ready node are counted, since they might come back with their zone.
+func zoneToRebalance(memberZones map[string]string, available map[string]bool) (from, to string, ok bool) {
+	counts := zoneCounts(memberZones)
+	for zone := range available {
+		if _, ok := counts[zone]; !ok {
+			counts[zone] = 0
+		}
+	}
+	names := make([]string, 0, len(counts))
+	for zone := range counts {
+		names = append(names, zone)
+	}
+	sort.Strings(names)
+
+	for _, zone := range names {
+		if len(from) == 0 || counts[zone] > counts[from] {
+			from = zone
+		}
+		if available[zone] && (len(to) == 0 || counts[zone] < counts[to]) {
+			to = zone
+		}
+	}
+	if len(from) == 0 || len(to) == 0 || counts[from]-counts[to] <= 1 {
+		return "", "", false
+	}
+	return from, to, true
+}
+
+// zoneCounts returns the number of members in each zone.
+func zoneCounts(memberZones map[string]string) map[string]int {
+	counts := map[string]int{}
+	for _, zone := range memberZones {
+		if len(zone) != 0 {
+			counts[zone]++
+		}
+	}
+	return counts
+}
+
+// availableZones returns the zones with at least one ready and schedulable node
+// the given member pod fits on, see podFitsNode.
+func (c *Cluster) availableZones(member *v1.Pod) (map[string]bool, error) {
+	nodes, err := c.listNodes()
+	if err != nil {
+		return nil, err
+	}
+	zones := map[string]bool{}
+	for i := range nodes {
+		node := &nodes[i]
+		zone := k8sutil.NodeZone(node)
+		if len(zone) != 0 && !node.Spec.Unschedulable && isNodeReady(node) && podFitsNode(member, node) {
+			zones[zone] = true
+		}
+	}
+	return zones, nil
+}
+
+func isNodeReady(node *v1.Node) bool {
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == v1.NodeReady {
+			return cond.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// updateUnavailableZones records the zones of members without a ready node in status.members.unavailableZones,
+// and posts an event for each zone once.
+func (c *Cluster) updateUnavailableZones(memberZones map[string]string, available map[string]bool) {
+	notified := map[string]bool{}
+	for _, zone := range c.status.Members.UnavailableZones {
+		notified[zone] = true
+	}
+	unavailable := map[string]bool{}
+	for _, zone := range memberZones {
+		if len(zone) != 0 && !available[zone] {
+			unavailable[zone] = true
+		}
+	}
+	var zones []string
+	for zone := range unavailable {
+		zones = append(zones, zone)
+		if !notified[zone] {
+			c.logger.Warningf("zone (%s) of members has no ready node", zone)
+			c.createEvent(k8sutil.ZoneUnavailableEvent(c.cluster, zone))
+		}
+	}
+	sort.Strings(zones)
+	c.status.Members.UnavailableZones = zones
+}
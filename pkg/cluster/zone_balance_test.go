@@ -0,0 +1,46 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import "testing"
+
+func TestZoneToRebalance(t *testing.T) {
+	abc := map[string]bool{"a": true, "b": true, "c": true}
+	tests := []struct {
+		memberZones map[string]string
+		available   map[string]bool
+		wFrom       string
+		wTo         string
+		wOK         bool
+	}{
+		// balanced
+		{memberZones: map[string]string{"m0": "a", "m1": "b", "m2": "c"}, available: abc, wOK: false},
+		// zone c recovered after its member was replaced in zone a
+		{memberZones: map[string]string{"m0": "a", "m1": "b", "m3": "a"}, available: abc, wFrom: "a", wTo: "c", wOK: true},
+		// zone c is still down
+		{memberZones: map[string]string{"m0": "a", "m1": "b", "m3": "a"}, available: map[string]bool{"a": true, "b": true}, wOK: false},
+		// more members than zones
+		{memberZones: map[string]string{"m0": "a", "m1": "b", "m2": "c", "m3": "a", "m4": "b"}, available: abc, wOK: false},
+		{memberZones: map[string]string{"m0": "a", "m1": "b", "m2": "a", "m3": "a", "m4": "b"}, available: abc, wFrom: "a", wTo: "c", wOK: true},
+		// members on nodes without zone label
+		{memberZones: map[string]string{"m0": "", "m1": "", "m2": ""}, available: abc, wOK: false},
+	}
+	for i, tt := range tests {
+		from, to, ok := zoneToRebalance(tt.memberZones, tt.available)
+		if ok != tt.wOK || from != tt.wFrom || to != tt.wTo {
+			t.Errorf("#%d: rebalance get=(%s, %s, %v), want=(%s, %s, %v)", i, from, to, ok, tt.wFrom, tt.wTo, tt.wOK)
+		}
+	}
+}
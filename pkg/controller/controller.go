@@ -15,6 +15,7 @@
 package controller
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -28,7 +29,9 @@ import (
 	"github.com/coreos/etcd-operator/pkg/cluster"
 	"github.com/coreos/etcd-operator/pkg/spec"
 	"github.com/coreos/etcd-operator/pkg/util/constants"
+	"github.com/coreos/etcd-operator/pkg/util/featuregate"
 	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
+	"github.com/coreos/etcd-operator/pkg/util/notify"
 	"github.com/coreos/etcd-operator/pkg/util/probe"
 
 	"github.com/Sirupsen/logrus"
@@ -36,6 +39,7 @@ import (
 	kwatch "k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	v1beta1extensions "k8s.io/client-go/pkg/apis/extensions/v1beta1"
+	"k8s.io/client-go/util/flowcontrol"
 )
 
 var (
@@ -48,11 +52,6 @@ var (
 	ErrVersionOutdated = errors.New("requested version is outdated in apiserver")
 
 	initRetryWaitTime = 30 * time.Second
-
-	// Workaround for watching TPR resource.
-	// client-go has encoding issue and we want something more predictable.
-	KubeHttpCli *http.Client
-	MasterHost  string
 )
 
 type Event struct {
@@ -69,19 +68,64 @@ type Controller struct {
 	// Kubernetes resource version of the clusters
 	clusterRVs map[string]string
 	stopChMap  map[string]chan struct{}
+	// rejected clusters exceed Config.MaxClusters in their namespace. They are managed once other clusters of it are deleted.
+	rejected map[string]*spec.Cluster
 
 	waitCluster sync.WaitGroup
 }
 
 type Config struct {
+	// MasterHost and KubeHTTPClient are used to watch the clusters, which is a workaround for
+	// watching the TPR: client-go has encoding issue and we want something more predictable.
+	// Run sets them from the in-cluster config if MasterHost is empty.
+	MasterHost     string
+	KubeHTTPClient *http.Client
+
+	// Namespace is the namespace of the operator. Its clusters are managed unless WatchNamespaces is set.
 	Namespace      string
 	ServiceAccount string
 	PVProvisioner  string
 	s3config.S3Context
-	KubeCli kubernetes.Interface
+	KubeCli     kubernetes.Interface
+	FeatureGate featuregate.FeatureGate
+	// ExportClusterMetrics exports metrics of each managed cluster derived from its member statuses.
+	ExportClusterMetrics bool
+	// PodCreateLimiter rate limits the member pod creation of all clusters, if not nil.
+	PodCreateLimiter flowcontrol.RateLimiter
+	// MaxClusters is the maximum number of clusters the operator manages in each namespace. 0 means no limit.
+	MaxClusters int
+	// Notifier is sent the critical conditions of all clusters, if not nil.
+	Notifier notify.Notifier
+	// NotifyMissedBackups is the number of backups in a row a cluster misses before it is notified.
+	NotifyMissedBackups int
+	// Shards is the number of operator replicas which each manage a subset of the clusters.
+	// 0 or 1 means the operator manages all clusters.
+	Shards int
+	// ShardIndex is the shard of the clusters this operator manages, in [0, Shards).
+	ShardIndex int
+	// DefaultProfile is the profile of new clusters which do not name one, if not empty.
+	DefaultProfile string
+	// DefaultEtcdRepository is the etcd image repository of new clusters which omit it, if not empty.
+	DefaultEtcdRepository string
+	// DefaultEtcdVersion is the version of new clusters which omit it, if not empty.
+	DefaultEtcdVersion string
+	// CreateTPR registers the cluster TPR on startup.
+	// Otherwise the operator waits for it to be registered, e.g. by the installer.
+	CreateTPR bool
+	// WatchNamespaces are the namespaces whose clusters the operator manages instead of Namespace,
+	// or only AllNamespaces.
+	WatchNamespaces []string
+	// IgnoreNamespaces are the namespaces whose clusters the operator never manages, e.g. kube-system.
+	IgnoreNamespaces []string
 }
 
 func (c *Config) Validate() error {
+	if c.KubeCli == nil {
+		return errors.New("no Kubernetes client")
+	}
+	if len(c.MasterHost) != 0 && c.KubeHTTPClient == nil {
+		return errors.New("no HTTP client to watch the clusters")
+	}
 	if _, ok := supportedPVProvisioners[c.PVProvisioner]; !ok {
 		return fmt.Errorf(
 			"persistent volume provisioner %s is not supported: options = %v",
@@ -93,7 +137,10 @@ func (c *Config) Validate() error {
 	if !(allEmpty || allSet) {
 		return errors.New("AWS/S3 related configs should be all set or all empty")
 	}
-	return nil
+	if c.Shards < 0 || (c.Shards > 1 && (c.ShardIndex < 0 || c.ShardIndex >= c.Shards)) {
+		return fmt.Errorf("shard index %d is out of %d shards", c.ShardIndex, c.Shards)
+	}
+	return c.validateNamespaces()
 }
 
 func New(cfg Config) *Controller {
@@ -104,10 +151,14 @@ func New(cfg Config) *Controller {
 		clusters:   make(map[string]*cluster.Cluster),
 		clusterRVs: make(map[string]string),
 		stopChMap:  map[string]chan struct{}{},
+		rejected:   make(map[string]*spec.Cluster),
 	}
 }
 
-func (c *Controller) Run() error {
+// Run manages the clusters until ctx is done or the watch of the clusters fails.
+// On return, the clusters are no longer managed. ErrVersionOutdated means the watch fell
+// behind and the controller has to be recreated to rebuild its state.
+func (c *Controller) Run(ctx context.Context) error {
 	var (
 		watchVersion string
 		err          error
@@ -129,7 +180,11 @@ func (c *Controller) Run() error {
 		}
 		c.logger.Errorf("initialization failed: %v", err)
 		c.logger.Infof("retry in %v...", initRetryWaitTime)
-		time.Sleep(initRetryWaitTime)
+		select {
+		case <-time.After(initRetryWaitTime):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 		// todo: add max retry?
 	}
 
@@ -144,9 +199,13 @@ func (c *Controller) Run() error {
 
 	probe.SetReady()
 
-	eventCh, errCh := c.watch(watchVersion)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	eventCh, errCh := c.watch(ctx, watchVersion)
 
+	handled := make(chan struct{})
 	go func() {
+		defer close(handled)
 		pt := newPanicTimer(time.Minute, "unexpected long blocking (> 1 Minute) when handling cluster event")
 
 		for ev := range eventCh {
@@ -157,82 +216,139 @@ func (c *Controller) Run() error {
 			pt.stop()
 		}
 	}()
-	return <-errCh
+
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	// Stop the watch and wait for the event in flight, so that no cluster is managed after return.
+	cancel()
+	<-handled
+	return err
 }
 
 func (c *Controller) handleClusterEvent(event *Event) error {
 	clus := event.Object
+	if !c.owns(clus) {
+		return nil
+	}
+	key := clusterKey(clus)
 
 	if clus.Status.IsFailed() {
 		clustersFailed.Inc()
 		if event.Type == kwatch.Deleted {
-			delete(c.clusters, clus.Metadata.Name)
-			delete(c.clusterRVs, clus.Metadata.Name)
+			delete(c.clusters, key)
+			delete(c.clusterRVs, key)
+			delete(c.stopChMap, key)
 			return nil
 		}
 		return fmt.Errorf("ignore failed cluster (%s). Please delete its TPR", clus.Metadata.Name)
 	}
 
+	if event.Type != kwatch.Deleted {
+		if err := c.applyProfile(clus); err != nil {
+			c.failProfile(clus, err)
+			return nil
+		}
+	}
+
 	// TODO: add validation to spec update.
 	clus.Spec.Cleanup()
 
 	switch event.Type {
 	case kwatch.Added:
-		stopC := make(chan struct{})
-		nc := cluster.New(c.makeClusterConfig(), clus, stopC, &c.waitCluster)
-
-		c.stopChMap[clus.Metadata.Name] = stopC
-		c.clusters[clus.Metadata.Name] = nc
-		c.clusterRVs[clus.Metadata.Name] = clus.Metadata.ResourceVersion
+		if !c.canManageMore(clus.Metadata.Namespace) {
+			c.reject(clus)
+			return nil
+		}
+		c.manage(clus)
 
 		analytics.ClusterCreated()
 		clustersCreated.Inc()
 		clustersTotal.Inc()
 
 	case kwatch.Modified:
-		if _, ok := c.clusters[clus.Metadata.Name]; !ok {
+		if _, ok := c.rejected[key]; ok {
+			c.rejected[key] = clus
+			c.clusterRVs[key] = clus.Metadata.ResourceVersion
+			return nil
+		}
+		if _, ok := c.clusters[key]; !ok {
 			return fmt.Errorf("unsafe state. cluster was never created but we received event (%s)", event.Type)
 		}
-		c.clusters[clus.Metadata.Name].Update(clus)
-		c.clusterRVs[clus.Metadata.Name] = clus.Metadata.ResourceVersion
+		c.clusters[key].Update(clus)
+		c.clusterRVs[key] = clus.Metadata.ResourceVersion
 		clustersModified.Inc()
 
 	case kwatch.Deleted:
-		if _, ok := c.clusters[clus.Metadata.Name]; !ok {
+		if _, ok := c.rejected[key]; ok {
+			delete(c.rejected, key)
+			delete(c.clusterRVs, key)
+			return nil
+		}
+		if _, ok := c.clusters[key]; !ok {
 			return fmt.Errorf("unsafe state. cluster was never created but we received event (%s)", event.Type)
 		}
-		c.clusters[clus.Metadata.Name].Delete()
-		delete(c.clusters, clus.Metadata.Name)
-		delete(c.clusterRVs, clus.Metadata.Name)
+		c.clusters[key].Delete()
+		delete(c.clusters, key)
+		delete(c.clusterRVs, key)
+		delete(c.stopChMap, key)
 		analytics.ClusterDeleted()
 		clustersDeleted.Inc()
 		clustersTotal.Dec()
+
+		c.admitRejected(clus.Metadata.Namespace)
 	}
 	return nil
 }
 
+// manage starts managing the cluster.
+func (c *Controller) manage(clus *spec.Cluster) {
+	key := clusterKey(clus)
+	stopC := make(chan struct{})
+	nc := cluster.New(c.makeClusterConfig(), clus, stopC, &c.waitCluster)
+
+	c.stopChMap[key] = stopC
+	c.clusters[key] = nc
+	c.clusterRVs[key] = clus.Metadata.ResourceVersion
+}
+
 func (c *Controller) findAllClusters() (string, error) {
 	c.logger.Info("finding existing clusters...")
-	clusterList, err := k8sutil.GetClusterList(c.Config.KubeCli.CoreV1().RESTClient(), c.Config.Namespace)
+	clusterList, err := k8sutil.GetClusterList(c.Config.KubeCli.CoreV1().RESTClient(), c.watchNamespace())
 	if err != nil {
 		return "", err
 	}
 
+	pending := make(map[string]*spec.Cluster)
 	for i := range clusterList.Items {
-		clus := clusterList.Items[i]
+		clus := &clusterList.Items[i]
+		if !c.owns(clus) {
+			continue
+		}
 
 		if clus.Status.IsFailed() {
 			c.logger.Infof("ignore failed cluster (%s). Please delete its TPR", clus.Metadata.Name)
 			continue
 		}
 
+		if err := c.applyProfile(clus); err != nil {
+			c.failProfile(clus, err)
+			continue
+		}
 		clus.Spec.Cleanup()
+		pending[clusterKey(clus)] = clus
+	}
 
-		stopC := make(chan struct{})
-		nc := cluster.New(c.makeClusterConfig(), &clus, stopC, &c.waitCluster)
-		c.stopChMap[clus.Metadata.Name] = stopC
-		c.clusters[clus.Metadata.Name] = nc
-		c.clusterRVs[clus.Metadata.Name] = clus.Metadata.ResourceVersion
+	// The oldest clusters are managed first, so that the same clusters are kept within the quota across restarts.
+	for clus := oldestCluster(pending); clus != nil; clus = oldestCluster(pending) {
+		delete(pending, clusterKey(clus))
+		if !c.canManageMore(clus.Metadata.Namespace) {
+			c.reject(clus)
+			continue
+		}
+		c.manage(clus)
 	}
 
 	return clusterList.Metadata.ResourceVersion, nil
@@ -244,22 +360,46 @@ func (c *Controller) makeClusterConfig() cluster.Config {
 		ServiceAccount: c.Config.ServiceAccount,
 		S3Context:      c.S3Context,
 
-		KubeCli: c.KubeCli,
+		KubeCli:       c.KubeCli,
+		FeatureGate:   c.FeatureGate,
+		ExportMetrics: c.ExportClusterMetrics,
+
+		PodCreateLimiter: c.PodCreateLimiter,
+
+		Notifier:            c.Notifier,
+		NotifyMissedBackups: c.NotifyMissedBackups,
 	}
 }
 
 func (c *Controller) initResource() (string, error) {
+	if err := c.verifyPermissions(); err != nil {
+		return "", err
+	}
+
 	watchVersion := "0"
-	err := c.createTPR()
-	if err != nil {
-		if k8sutil.IsKubernetesResourceAlreadyExistError(err) {
-			// TPR has been initialized before. We need to recover existing cluster.
-			watchVersion, err = c.findAllClusters()
-			if err != nil {
-				return "", err
+	var err error
+	if c.Config.CreateTPR {
+		err = c.createTPR()
+		if err != nil {
+			if k8sutil.IsKubernetesResourceAlreadyExistError(err) {
+				// TPR has been initialized before. We need to recover existing cluster.
+				watchVersion, err = c.findAllClusters()
+				if err != nil {
+					return "", err
+				}
+			} else {
+				return "", fmt.Errorf("fail to create TPR: %v", err)
 			}
-		} else {
-			return "", fmt.Errorf("fail to create TPR: %v", err)
+		}
+	} else {
+		c.logger.Infof("waiting for TPR (%s) to be registered", spec.TPRName())
+		err = k8sutil.WaitEtcdTPRReady(c.KubeCli.CoreV1().RESTClient(), 3*time.Second, 30*time.Second, c.watchNamespace())
+		if err != nil {
+			return "", fmt.Errorf("TPR (%s) is not registered: %v", spec.TPRName(), err)
+		}
+		watchVersion, err = c.findAllClusters()
+		if err != nil {
+			return "", err
 		}
 	}
 	if c.Config.PVProvisioner != constants.PVProvisionerNone {
@@ -288,14 +428,14 @@ func (c *Controller) createTPR() error {
 		return err
 	}
 
-	return k8sutil.WaitEtcdTPRReady(c.KubeCli.CoreV1().RESTClient(), 3*time.Second, 30*time.Second, c.Namespace)
+	return k8sutil.WaitEtcdTPRReady(c.KubeCli.CoreV1().RESTClient(), 3*time.Second, 30*time.Second, c.watchNamespace())
 }
 
 // watch creates a go routine, and watches the cluster.etcd kind resources from
 // the given watch version. It emits events on the resources through the returned
 // event chan. Errors will be reported through the returned error chan. The go routine
 // exits on any error.
-func (c *Controller) watch(watchVersion string) (<-chan *Event, <-chan error) {
+func (c *Controller) watch(ctx context.Context, watchVersion string) (<-chan *Event, <-chan error) {
 	eventCh := make(chan *Event)
 	// On unexpected error case, controller should exit
 	errCh := make(chan error, 1)
@@ -304,13 +444,22 @@ func (c *Controller) watch(watchVersion string) (<-chan *Event, <-chan error) {
 		defer close(eventCh)
 
 		for {
-			resp, err := k8sutil.WatchClusters(MasterHost, c.Config.Namespace, KubeHttpCli, watchVersion)
+			resp, err := k8sutil.WatchClusters(ctx, c.MasterHost, c.watchNamespace(), c.KubeHTTPClient, watchVersion)
+			if ctx.Err() != nil {
+				if err == nil {
+					resp.Body.Close()
+				}
+				errCh <- ctx.Err()
+				return
+			}
 			if err != nil {
+				watchDisconnects.WithLabelValues("error").Inc()
 				errCh <- err
 				return
 			}
 			if resp.StatusCode != http.StatusOK {
 				resp.Body.Close()
+				watchDisconnects.WithLabelValues("error").Inc()
 				errCh <- errors.New("invalid status code: " + resp.Status)
 				return
 			}
@@ -320,13 +469,21 @@ func (c *Controller) watch(watchVersion string) (<-chan *Event, <-chan error) {
 			decoder := json.NewDecoder(resp.Body)
 			for {
 				ev, st, err := pollEvent(decoder)
+				if ctx.Err() != nil {
+					resp.Body.Close()
+					errCh <- ctx.Err()
+					return
+				}
 				if err != nil {
 					if err == io.EOF { // apiserver will close stream periodically
 						c.logger.Debug("apiserver closed stream")
+						watchDisconnects.WithLabelValues("closed").Inc()
 						break
 					}
 
 					c.logger.Errorf("received invalid event from API server: %v", err)
+					watchDecodeFailures.Inc()
+					watchDisconnects.WithLabelValues("error").Inc()
 					errCh <- err
 					return
 				}
@@ -335,9 +492,10 @@ func (c *Controller) watch(watchVersion string) (<-chan *Event, <-chan error) {
 					resp.Body.Close()
 
 					if st.Code == http.StatusGone {
+						watchDisconnects.WithLabelValues("gone").Inc()
 						// event history is outdated.
 						// if nothing has changed, we can go back to watch again.
-						clusterList, err := k8sutil.GetClusterList(c.Config.KubeCli.CoreV1().RESTClient(), c.Config.Namespace)
+						clusterList, err := k8sutil.GetClusterList(c.Config.KubeCli.CoreV1().RESTClient(), c.watchNamespace())
 						if err == nil && !c.isClustersCacheStale(clusterList.Items) {
 							watchVersion = clusterList.Metadata.ResourceVersion
 							break
@@ -366,12 +524,21 @@ func (c *Controller) watch(watchVersion string) (<-chan *Event, <-chan error) {
 }
 
 func (c *Controller) isClustersCacheStale(currentClusters []spec.Cluster) bool {
-	if len(c.clusterRVs) != len(currentClusters) {
+	owned := 0
+	for i := range currentClusters {
+		if c.owns(&currentClusters[i]) {
+			owned++
+		}
+	}
+	if len(c.clusterRVs) != owned {
 		return true
 	}
 
 	for _, cc := range currentClusters {
-		rv, ok := c.clusterRVs[cc.Metadata.Name]
+		if !c.owns(&cc) {
+			continue
+		}
+		rv, ok := c.clusterRVs[clusterKey(&cc)]
 		if !ok || rv != cc.Metadata.ResourceVersion {
 			return true
 		}
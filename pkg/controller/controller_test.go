@@ -17,6 +17,7 @@ package controller
 import (
 	"strings"
 	"testing"
+	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/watch"
@@ -63,14 +64,92 @@ func TestHandleClusterEventDeleteFailedCluster(t *testing.T) {
 		Object: clus,
 	}
 
-	c.clusters[name] = &cluster.Cluster{}
-	c.clusterRVs[name] = "123"
+	key := clusterKey(clus)
+	c.clusters[key] = &cluster.Cluster{}
+	c.clusterRVs[key] = "123"
 
 	if err := c.handleClusterEvent(e); err != nil {
 		t.Fatal(err)
 	}
 
-	if c.clusters[name] != nil || c.clusterRVs[name] != "" {
-		t.Errorf("failed cluster not cleaned up after delete event, cluster struct: %v, RV: %s", c.clusters[name], c.clusterRVs[name])
+	if c.clusters[key] != nil || c.clusterRVs[key] != "" {
+		t.Errorf("failed cluster not cleaned up after delete event, cluster struct: %v, RV: %s", c.clusters[key], c.clusterRVs[key])
+	}
+}
+
+func TestOldestCluster(t *testing.T) {
+	now := time.Now()
+	newCluster := func(name string, created time.Time) *spec.Cluster {
+		return &spec.Cluster{Metadata: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(created)}}
+	}
+
+	tests := []struct {
+		clusters map[string]*spec.Cluster
+		want     string
+	}{{
+		clusters: map[string]*spec.Cluster{},
+		want:     "",
+	}, {
+		clusters: map[string]*spec.Cluster{
+			"b": newCluster("b", now),
+			"a": newCluster("a", now.Add(time.Minute)),
+		},
+		want: "b",
+	}, {
+		clusters: map[string]*spec.Cluster{
+			"b": newCluster("b", now),
+			"a": newCluster("a", now),
+			"c": newCluster("c", now),
+		},
+		want: "a",
+	}}
+
+	for i, tt := range tests {
+		get := ""
+		if clus := oldestCluster(tt.clusters); clus != nil {
+			get = clus.Metadata.Name
+		}
+		if get != tt.want {
+			t.Errorf("#%d: oldest cluster get=%s, want=%s", i, get, tt.want)
+		}
+	}
+}
+
+func TestHandleClusterEventRejectedCluster(t *testing.T) {
+	c := New(Config{MaxClusters: 1})
+	clus := &spec.Cluster{Metadata: metav1.ObjectMeta{Name: "rejected", ResourceVersion: "2"}}
+	key := clusterKey(clus)
+	c.clusters["/managed"] = &cluster.Cluster{}
+	c.rejected[key] = &spec.Cluster{Metadata: metav1.ObjectMeta{Name: "rejected"}}
+
+	if err := c.handleClusterEvent(&Event{Type: watch.Modified, Object: clus}); err != nil {
+		t.Fatal(err)
+	}
+	if c.rejected[key] != clus || c.clusterRVs[key] != "2" {
+		t.Errorf("rejected cluster not updated on modify event: %v", c.rejected[key])
+	}
+
+	if err := c.handleClusterEvent(&Event{Type: watch.Deleted, Object: clus}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.rejected[key]; ok || c.clusterRVs[key] != "" {
+		t.Errorf("rejected cluster not cleaned up after delete event")
+	}
+}
+
+func TestQuotaPerNamespace(t *testing.T) {
+	c := New(Config{MaxClusters: 1})
+	c.clusters["a/full"] = &cluster.Cluster{}
+	c.rejected["a/waiting"] = &spec.Cluster{Metadata: metav1.ObjectMeta{Namespace: "a", Name: "waiting"}}
+	c.rejected["b/waiting"] = &spec.Cluster{Metadata: metav1.ObjectMeta{Namespace: "b", Name: "waiting"}}
+
+	if c.canManageMore("a") {
+		t.Errorf("namespace a with %d cluster(s) can manage more, want full", len(c.clusters))
+	}
+	if !c.canManageMore("b") {
+		t.Errorf("namespace b cannot manage more, want it to admit clusters")
+	}
+	if clus := oldestCluster(clustersIn(c.rejected, "b")); clus == nil || clus.Metadata.Namespace != "b" {
+		t.Errorf("oldest rejected cluster of namespace b get=%v, want b/waiting", clus)
 	}
 }
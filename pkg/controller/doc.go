@@ -0,0 +1,31 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package controller manages the etcd clusters of a namespace, or of a set of namespaces.
+//
+// Other operators can embed it to manage etcd clusters without running the etcd-operator binary:
+//
+//	cfg := controller.Config{
+//		Namespace:      ns,
+//		ServiceAccount: sa,
+//		PVProvisioner:  constants.PVProvisionerNone,
+//		KubeCli:        k8sutil.MustNewKubeClient(),
+//	}
+//	err := controller.Run(ctx, cfg)
+//
+// Run blocks until ctx is done. It does not elect a leader: the embedding operator must make
+// sure that only one Run manages the clusters of a namespace, or of a shard of them, at a time.
+// Unlike the binary, Run neither serves metrics and probes nor runs the periodic garbage collection
+// of orphaned resources; see garbagecollection.GC.FullyCollect for the latter.
+package controller
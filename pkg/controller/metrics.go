@@ -37,6 +37,22 @@ var (
 		Name:      "clusters_failed",
 		Help:      "Total number of clusters failed",
 	})
+
+	watchDisconnects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "etcd_operator",
+		Subsystem: "controller",
+		Name:      "watch_disconnects_total",
+		Help:      "Total number of times the watch of clusters ended by reason: closed, gone or error",
+	},
+		[]string{"Reason"},
+	)
+
+	watchDecodeFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd_operator",
+		Subsystem: "controller",
+		Name:      "watch_decode_failures_total",
+		Help:      "Total number of cluster watch events which failed to decode",
+	})
 )
 
 func init() {
@@ -45,4 +61,6 @@ func init() {
 	prometheus.MustRegister(clustersDeleted)
 	prometheus.MustRegister(clustersModified)
 	prometheus.MustRegister(clustersFailed)
+	prometheus.MustRegister(watchDisconnects)
+	prometheus.MustRegister(watchDecodeFailures)
 }
@@ -0,0 +1,128 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// AllNamespaces in Config.WatchNamespaces makes the operator manage the clusters of all namespaces.
+const AllNamespaces = "*"
+
+func (c *Config) validateNamespaces() error {
+	for _, ns := range c.WatchNamespaces {
+		if len(ns) == 0 {
+			return errors.New("empty namespace to watch")
+		}
+		if ns == AllNamespaces && len(c.WatchNamespaces) != 1 {
+			return fmt.Errorf("%s cannot be combined with other namespaces to watch", AllNamespaces)
+		}
+	}
+	if c.watchesAllNamespaces() {
+		return nil
+	}
+	for _, ns := range c.watchedNamespaces() {
+		if c.ManagesNamespace(ns) {
+			return nil
+		}
+	}
+	return errors.New("all namespaces to watch are ignored")
+}
+
+func (c *Config) watchesAllNamespaces() bool {
+	return len(c.WatchNamespaces) == 1 && c.WatchNamespaces[0] == AllNamespaces
+}
+
+// watchedNamespaces returns the namespaces listed to watch, or the namespace of the operator by default.
+func (c *Config) watchedNamespaces() []string {
+	if len(c.WatchNamespaces) == 0 {
+		return []string{c.Namespace}
+	}
+	return c.WatchNamespaces
+}
+
+// ManagesNamespace returns whether the operator manages the clusters of the namespace.
+func (c *Config) ManagesNamespace(ns string) bool {
+	if containsString(c.IgnoreNamespaces, ns) {
+		return false
+	}
+	return c.watchesAllNamespaces() || containsString(c.watchedNamespaces(), ns)
+}
+
+// watchNamespace returns the namespace whose clusters the operator lists and watches,
+// or metav1.NamespaceAll if it manages the clusters of several namespaces.
+func (c *Config) watchNamespace() string {
+	if c.watchesAllNamespaces() {
+		return metav1.NamespaceAll
+	}
+	if nss := c.watchedNamespaces(); len(nss) == 1 {
+		return nss[0]
+	}
+	return metav1.NamespaceAll
+}
+
+// clusterNamespaces returns the namespaces in which the operator needs access to the resources of clusters,
+// metav1.NamespaceAll standing for all namespaces.
+func (c *Config) clusterNamespaces() []string {
+	if c.watchesAllNamespaces() {
+		return []string{metav1.NamespaceAll}
+	}
+	var nss []string
+	for _, ns := range c.watchedNamespaces() {
+		if c.ManagesNamespace(ns) {
+			nss = append(nss, ns)
+		}
+	}
+	return nss
+}
+
+// ManagedNamespaces returns the namespaces whose clusters the operator manages.
+// If it watches all namespaces, they are listed from the Kubernetes cluster.
+func (c *Config) ManagedNamespaces(kubecli kubernetes.Interface) ([]string, error) {
+	if !c.watchesAllNamespaces() {
+		return c.clusterNamespaces(), nil
+	}
+	nsl, err := kubecli.CoreV1().Namespaces().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %v", err)
+	}
+	var nss []string
+	for _, ns := range nsl.Items {
+		if c.ManagesNamespace(ns.Name) {
+			nss = append(nss, ns.Name)
+		}
+	}
+	return nss, nil
+}
+
+// clusterKey identifies a cluster among the clusters of all watched namespaces.
+func clusterKey(clus *spec.Cluster) string {
+	return clus.Metadata.Namespace + "/" + clus.Metadata.Name
+}
+
+func containsString(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
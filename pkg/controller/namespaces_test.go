@@ -0,0 +1,72 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestManagesNamespace(t *testing.T) {
+	tests := []struct {
+		cfg Config
+		ns  string
+
+		w      bool
+		wwatch string
+	}{
+		{cfg: Config{Namespace: "op"}, ns: "op", w: true, wwatch: "op"},
+		{cfg: Config{Namespace: "op"}, ns: "a", w: false, wwatch: "op"},
+		{cfg: Config{Namespace: "op", WatchNamespaces: []string{"a"}}, ns: "a", w: true, wwatch: "a"},
+		{cfg: Config{Namespace: "op", WatchNamespaces: []string{"a"}}, ns: "op", w: false, wwatch: "a"},
+		{cfg: Config{Namespace: "op", WatchNamespaces: []string{"a", "b"}}, ns: "b", w: true, wwatch: metav1.NamespaceAll},
+		{cfg: Config{Namespace: "op", WatchNamespaces: []string{"a", "b"}, IgnoreNamespaces: []string{"b"}}, ns: "b", w: false, wwatch: metav1.NamespaceAll},
+		{cfg: Config{Namespace: "op", WatchNamespaces: []string{AllNamespaces}}, ns: "a", w: true, wwatch: metav1.NamespaceAll},
+		{
+			cfg: Config{Namespace: "op", WatchNamespaces: []string{AllNamespaces}, IgnoreNamespaces: []string{"kube-system"}},
+			ns:  "kube-system", w: false, wwatch: metav1.NamespaceAll,
+		},
+	}
+	for i, tt := range tests {
+		if get := tt.cfg.ManagesNamespace(tt.ns); get != tt.w {
+			t.Errorf("#%d: manages %s get=%v, want=%v", i, tt.ns, get, tt.w)
+		}
+		if get := tt.cfg.watchNamespace(); get != tt.wwatch {
+			t.Errorf("#%d: watch namespace get=%q, want=%q", i, get, tt.wwatch)
+		}
+	}
+}
+
+func TestValidateNamespaces(t *testing.T) {
+	tests := []struct {
+		cfg Config
+		w   bool
+	}{
+		{cfg: Config{Namespace: "op"}, w: true},
+		{cfg: Config{Namespace: "op", WatchNamespaces: []string{"a", "b"}, IgnoreNamespaces: []string{"a"}}, w: true},
+		{cfg: Config{Namespace: "op", WatchNamespaces: []string{AllNamespaces}, IgnoreNamespaces: []string{"op"}}, w: true},
+		{cfg: Config{Namespace: "op", IgnoreNamespaces: []string{"op"}}, w: false},
+		{cfg: Config{Namespace: "op", WatchNamespaces: []string{"a"}, IgnoreNamespaces: []string{"a"}}, w: false},
+		{cfg: Config{Namespace: "op", WatchNamespaces: []string{AllNamespaces, "a"}}, w: false},
+		{cfg: Config{Namespace: "op", WatchNamespaces: []string{""}}, w: false},
+	}
+	for i, tt := range tests {
+		err := tt.cfg.validateNamespaces()
+		if (err == nil) != tt.w {
+			t.Errorf("#%d: valid get=%v (%v), want=%v", i, err == nil, err, tt.w)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/constants"
+	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type permission struct {
+	k8sutil.ResourceAccess
+	// optional permissions are only needed by some cluster features.
+	// The operator keeps working without them.
+	optional bool
+}
+
+func (p permission) String() string {
+	group := p.Group
+	if len(group) == 0 {
+		group = "core"
+	}
+	return fmt.Sprintf("%s %s/%s", p.Verb, group, p.Resource)
+}
+
+// requiredPermissions returns the permissions the operator needs with the given config.
+// The permissions on the resources of clusters are needed in each managed namespace.
+func requiredPermissions(cfg Config) []permission {
+	var ps []permission
+	add := func(optional bool, namespace, group, resource string, verbs ...string) {
+		for _, v := range verbs {
+			ps = append(ps, permission{
+				ResourceAccess: k8sutil.ResourceAccess{Namespace: namespace, Group: group, Resource: resource, Verb: v},
+				optional:       optional,
+			})
+		}
+	}
+
+	add(false, cfg.watchNamespace(), spec.TPRGroup, spec.TPRKindPlural, "list", "watch")
+	if cfg.CreateTPR {
+		add(false, "", "extensions", "thirdpartyresources", "create")
+	}
+	// Backups on persistent volumes are claimed from the storage class of the provisioner.
+	// Otherwise only clusters with spec.pod.persistentVolumeClaimSpec create claims.
+	pvp := cfg.PVProvisioner != constants.PVProvisionerNone
+	if pvp {
+		add(false, "", "storage.k8s.io", "storageclasses", "create")
+	}
+	for _, ns := range cfg.clusterNamespaces() {
+		add(false, ns, spec.TPRGroup, spec.TPRKindPlural, "update")
+		add(false, ns, "", "pods", "create", "delete", "list")
+		add(false, ns, "", "services", "create", "delete")
+		add(false, ns, "", "configmaps", "create", "update")
+		add(false, ns, "policy", "poddisruptionbudgets", "create")
+		add(!pvp, ns, "", "persistentvolumeclaims", "create")
+		// Deployments run the backup sidecars, gRPC proxies, mirrors and debug pods of the clusters which set them.
+		add(true, ns, "apps", "deployments", "create")
+		add(true, ns, "", "events", "create")
+		add(true, ns, "extensions", "daemonsets", "create")
+		// Jobs restore the data of seed members on PVCs.
+		add(true, ns, "batch", "jobs", "create")
+		add(true, ns, "monitoring.coreos.com", "servicemonitors", "create")
+		add(true, ns, "monitoring.coreos.com", "prometheusrules", "create")
+	}
+	add(true, "", "", "nodes", "list")
+	add(true, "", "", "namespaces", "get")
+	// The garbage collection of an operator which watches all namespaces lists them.
+	if cfg.watchesAllNamespaces() {
+		add(true, "", "", "namespaces", "list")
+	}
+	if len(cfg.S3Context.AWSSecret) != 0 {
+		add(false, cfg.Namespace, "", "secrets", "get")
+	}
+	return ps
+}
+
+// verifyPermissions checks the operator's own permissions before it starts watching.
+// It fails if a required permission is missing, and warns about missing optional ones.
+// If the apiserver does not serve access reviews, the check is skipped.
+func (c *Controller) verifyPermissions() error {
+	var missing []string
+	for _, p := range requiredPermissions(c.Config) {
+		ok, err := k8sutil.CanI(c.KubeCli, p.ResourceAccess)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				c.logger.Warningf("skip verifying operator permissions: access reviews are not supported: %v", err)
+				return nil
+			}
+			return fmt.Errorf("failed to review access (%v): %v", p, err)
+		}
+		if ok {
+			continue
+		}
+		if p.optional {
+			c.logger.Warningf("operator is not allowed to %v, features relying on it will not work", p)
+			continue
+		}
+		// With several managed namespaces, tell in which one the permission is missing.
+		if len(p.Namespace) != 0 && c.watchNamespace() == metav1.NamespaceAll {
+			missing = append(missing, fmt.Sprintf("%v in namespace %s", p, p.Namespace))
+			continue
+		}
+		missing = append(missing, p.String())
+	}
+	if len(missing) != 0 {
+		return fmt.Errorf("operator is missing permissions (see doc/user/rbac.md): %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"testing"
+
+	"github.com/coreos/etcd-operator/pkg/backup/s3/s3config"
+	"github.com/coreos/etcd-operator/pkg/util/constants"
+)
+
+func TestRequiredPermissions(t *testing.T) {
+	tests := []struct {
+		cfg Config
+
+		perm     string
+		want     bool
+		optional bool
+	}{{
+		cfg:  Config{Namespace: "ns", PVProvisioner: constants.PVProvisionerNone},
+		perm: "watch etcd.coreos.com/clusters",
+		want: true,
+	}, {
+		cfg:  Config{Namespace: "ns", PVProvisioner: constants.PVProvisionerNone},
+		perm: "create extensions/thirdpartyresources",
+		want: false,
+	}, {
+		cfg:  Config{Namespace: "ns", PVProvisioner: constants.PVProvisionerNone, CreateTPR: true},
+		perm: "create extensions/thirdpartyresources",
+		want: true,
+	}, {
+		cfg:  Config{Namespace: "ns", PVProvisioner: constants.PVProvisionerGCEPD},
+		perm: "create storage.k8s.io/storageclasses",
+		want: true,
+	}, {
+		cfg:  Config{Namespace: "ns", PVProvisioner: constants.PVProvisionerNone},
+		perm: "get core/secrets",
+		want: false,
+	}, {
+		cfg:  Config{Namespace: "ns", PVProvisioner: constants.PVProvisionerNone, S3Context: s3config.S3Context{AWSSecret: "aws"}},
+		perm: "get core/secrets",
+		want: true,
+	}, {
+		cfg:      Config{Namespace: "ns", PVProvisioner: constants.PVProvisionerNone},
+		perm:     "create core/events",
+		want:     true,
+		optional: true,
+	}, {
+		cfg:  Config{Namespace: "ns", PVProvisioner: constants.PVProvisionerGCEPD},
+		perm: "create core/persistentvolumeclaims",
+		want: true,
+	}, {
+		cfg:      Config{Namespace: "ns", PVProvisioner: constants.PVProvisionerNone},
+		perm:     "create core/persistentvolumeclaims",
+		want:     true,
+		optional: true,
+	}, {
+		cfg:      Config{Namespace: "ns", PVProvisioner: constants.PVProvisionerGCEPD},
+		perm:     "create apps/deployments",
+		want:     true,
+		optional: true,
+	}, {
+		cfg:      Config{Namespace: "ns", PVProvisioner: constants.PVProvisionerNone},
+		perm:     "create batch/jobs",
+		want:     true,
+		optional: true,
+	}, {
+		cfg:  Config{Namespace: "ns", PVProvisioner: constants.PVProvisionerNone},
+		perm: "list core/namespaces",
+		want: false,
+	}, {
+		cfg:      Config{Namespace: "ns", PVProvisioner: constants.PVProvisionerNone, WatchNamespaces: []string{AllNamespaces}},
+		perm:     "list core/namespaces",
+		want:     true,
+		optional: true,
+	}}
+
+	for i, tt := range tests {
+		var found *permission
+		for _, p := range requiredPermissions(tt.cfg) {
+			if p.String() == tt.perm {
+				p := p
+				found = &p
+			}
+		}
+		if (found != nil) != tt.want {
+			t.Errorf("#%d: permission %q required get=%v, want=%v", i, tt.perm, found != nil, tt.want)
+			continue
+		}
+		if found == nil {
+			continue
+		}
+		if found.optional != tt.optional {
+			t.Errorf("#%d: permission %q optional get=%v, want=%v", i, tt.perm, found.optional, tt.optional)
+		}
+	}
+}
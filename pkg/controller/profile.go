@@ -0,0 +1,80 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"fmt"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// applyProfile fills the spec of a new cluster from its profile, or the default profile
+// of the operator, and then from the etcd repository and version defaults of the operator.
+// Clusters which have been set up already keep their spec, so that profile and default
+// changes do not roll out to existing clusters. The cluster writes the resolved spec when it is created.
+func (c *Controller) applyProfile(clus *spec.Cluster) error {
+	if clus.Status.Phase != spec.ClusterPhaseNone {
+		return nil
+	}
+	name := clus.Spec.Profile
+	if len(name) == 0 {
+		name = c.DefaultProfile
+	}
+	if len(name) != 0 {
+		cm, err := c.KubeCli.CoreV1().ConfigMaps(c.Namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get profile (%s): %v", name, err)
+		}
+		data, ok := cm.Data[spec.ProfileSpecKey]
+		if !ok {
+			return fmt.Errorf("profile (%s) has no %s key", name, spec.ProfileSpecKey)
+		}
+		if err := clus.ApplyProfile(data); err != nil {
+			return fmt.Errorf("profile (%s): %v", name, err)
+		}
+	}
+	c.applyEtcdDefaults(&clus.Spec)
+	return nil
+}
+
+// applyEtcdDefaults sets the etcd repository and version of the spec to the defaults
+// of the operator if it omits them.
+func (c *Controller) applyEtcdDefaults(sp *spec.ClusterSpec) {
+	if len(sp.Version) == 0 {
+		sp.Version = c.DefaultEtcdVersion
+	}
+	if len(c.DefaultEtcdRepository) == 0 {
+		return
+	}
+	if sp.EtcdImage == nil {
+		sp.EtcdImage = &spec.EtcdImagePolicy{}
+	}
+	if len(sp.EtcdImage.Repository) == 0 {
+		sp.EtcdImage.Repository = c.DefaultEtcdRepository
+	}
+}
+
+// failProfile marks a cluster whose profile cannot be applied as failed.
+func (c *Controller) failProfile(clus *spec.Cluster, err error) {
+	c.logger.Errorf("cluster (%s) failed to apply its profile: %v", clus.Metadata.Name, err)
+	clus.Status.SetReason(err.Error())
+	clus.Status.SetPhase(spec.ClusterPhaseFailed)
+	if _, err := k8sutil.UpdateClusterTPRObject(c.KubeCli.CoreV1().RESTClient(), clus.Metadata.Namespace, clus); err != nil {
+		c.logger.Errorf("failed to update cluster phase (%v): %v", spec.ClusterPhaseFailed, err)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+)
+
+func TestApplyEtcdDefaults(t *testing.T) {
+	tests := []struct {
+		cfg Config
+		sp  spec.ClusterSpec
+		w   spec.ClusterSpec
+	}{{
+		cfg: Config{},
+		sp:  spec.ClusterSpec{Size: 3},
+		w:   spec.ClusterSpec{Size: 3},
+	}, {
+		cfg: Config{DefaultEtcdRepository: "registry.local/etcd", DefaultEtcdVersion: "3.1.10"},
+		sp:  spec.ClusterSpec{Size: 3},
+		w:   spec.ClusterSpec{Size: 3, Version: "3.1.10", EtcdImage: &spec.EtcdImagePolicy{Repository: "registry.local/etcd"}},
+	}, {
+		// settings of the cluster win.
+		cfg: Config{DefaultEtcdRepository: "registry.local/etcd", DefaultEtcdVersion: "3.1.10"},
+		sp:  spec.ClusterSpec{Version: "3.2.0", EtcdImage: &spec.EtcdImagePolicy{Repository: "mirror.local/etcd", Architecture: "arm64"}},
+		w:   spec.ClusterSpec{Version: "3.2.0", EtcdImage: &spec.EtcdImagePolicy{Repository: "mirror.local/etcd", Architecture: "arm64"}},
+	}, {
+		cfg: Config{DefaultEtcdRepository: "registry.local/etcd"},
+		sp:  spec.ClusterSpec{EtcdImage: &spec.EtcdImagePolicy{Architecture: "arm64"}},
+		w:   spec.ClusterSpec{EtcdImage: &spec.EtcdImagePolicy{Repository: "registry.local/etcd", Architecture: "arm64"}},
+	}}
+	for i, tt := range tests {
+		c := New(tt.cfg)
+		c.applyEtcdDefaults(&tt.sp)
+		if !reflect.DeepEqual(tt.sp, tt.w) {
+			t.Errorf("#%d: spec get=%+v, want=%+v", i, tt.sp, tt.w)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
+)
+
+// canManageMore returns whether the operator can manage another cluster in the namespace within Config.MaxClusters.
+func (c *Controller) canManageMore(ns string) bool {
+	if c.MaxClusters <= 0 {
+		return true
+	}
+	n := 0
+	for key := range c.clusters {
+		if strings.HasPrefix(key, ns+"/") {
+			n++
+		}
+	}
+	return n < c.MaxClusters
+}
+
+// reject keeps the cluster aside until the operator can manage it,
+// and reports the rejection in the cluster status.
+func (c *Controller) reject(clus *spec.Cluster) {
+	reason := fmt.Sprintf("the operator manages at most %d clusters in namespace %s", c.MaxClusters, clus.Metadata.Namespace)
+	key := clusterKey(clus)
+	c.logger.Warningf("rejecting cluster (%s): %s", clus.Metadata.Name, reason)
+	c.rejected[key] = clus
+	c.clusterRVs[key] = clus.Metadata.ResourceVersion
+
+	clus.Status.SetReason(reason)
+	clus.Status.SetRejectedCondition(reason)
+	updated, err := k8sutil.UpdateClusterTPRObject(c.KubeCli.CoreV1().RESTClient(), clus.Metadata.Namespace, clus)
+	if err != nil {
+		c.logger.Warningf("failed to report rejection of cluster (%s): %v", clus.Metadata.Name, err)
+		return
+	}
+	c.rejected[key] = updated
+	c.clusterRVs[key] = updated.Metadata.ResourceVersion
+}
+
+// admitRejected starts managing the oldest rejected clusters of the namespace while its quota allows.
+func (c *Controller) admitRejected(ns string) {
+	for c.canManageMore(ns) {
+		clus := oldestCluster(clustersIn(c.rejected, ns))
+		if clus == nil {
+			return
+		}
+		delete(c.rejected, clusterKey(clus))
+		c.logger.Infof("admitting previously rejected cluster (%s)", clus.Metadata.Name)
+		clus.Status.SetReason("")
+		c.manage(clus)
+	}
+}
+
+// clustersIn returns the clusters of the namespace.
+func clustersIn(clusters map[string]*spec.Cluster, ns string) map[string]*spec.Cluster {
+	in := make(map[string]*spec.Cluster)
+	for key, clus := range clusters {
+		if clus.Metadata.Namespace == ns {
+			in[key] = clus
+		}
+	}
+	return in
+}
+
+// oldestCluster returns the first created cluster, by namespace and name on equal creation times.
+func oldestCluster(clusters map[string]*spec.Cluster) *spec.Cluster {
+	var oldest *spec.Cluster
+	for _, clus := range clusters {
+		if oldest == nil {
+			oldest = clus
+			continue
+		}
+		ct, ot := clus.Metadata.CreationTimestamp, oldest.Metadata.CreationTimestamp
+		if ct.Before(ot) || (ct.Equal(ot) && clusterKey(clus) < clusterKey(oldest)) {
+			oldest = clus
+		}
+	}
+	return oldest
+}
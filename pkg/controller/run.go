@@ -0,0 +1,48 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+
+	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
+)
+
+// Run manages the clusters of the namespaces of cfg until ctx is done, and returns ctx.Err() then.
+// It recreates the controller whenever its watch falls behind, and returns any other error.
+// If cfg.MasterHost is empty, the clusters are watched with the in-cluster config.
+func Run(ctx context.Context, cfg Config) error {
+	if len(cfg.MasterHost) == 0 {
+		restCfg, err := k8sutil.InClusterConfig()
+		if err != nil {
+			return err
+		}
+		restcli, err := k8sutil.NewTPRClient()
+		if err != nil {
+			return err
+		}
+		cfg.MasterHost = restCfg.Host
+		cfg.KubeHTTPClient = restcli.Client
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	for {
+		err := New(cfg).Run(ctx)
+		if err != ErrVersionOutdated {
+			return err
+		}
+	}
+}
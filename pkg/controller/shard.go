@@ -0,0 +1,152 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/k8sutil/election/resourcelock"
+
+	"github.com/Sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+const (
+	operatorLockName = "etcd-operator"
+	shardLockPrefix  = "etcd-operator-shard-"
+
+	// shardAgreementRetryInterval is how often a replica checks again the locks of the other replicas
+	// while they run with another number of shards.
+	shardAgreementRetryInterval = 10 * time.Second
+)
+
+// ShardOf returns the shard out of shards which owns the cluster of the given namespace and name.
+// The shard is derived from the FNV-1a hash of "namespace/name", so that all replicas
+// agree on it without coordination.
+func ShardOf(ns, name string, shards int) int {
+	h := fnv.New32a()
+	h.Write([]byte(ns + "/" + name))
+	return int(h.Sum32() % uint32(shards))
+}
+
+// ShardIndexFromPodName returns the ordinal of a StatefulSet pod name, e.g. 2 for "etcd-operator-2".
+func ShardIndexFromPodName(name string) (int, error) {
+	i := strings.LastIndex(name, "-")
+	if i < 0 {
+		return 0, fmt.Errorf("pod name (%s) has no ordinal", name)
+	}
+	return strconv.Atoi(name[i+1:])
+}
+
+// ShardLockName returns the name of the endpoints lock the replicas of a shard elect their leader on.
+// The name records the number of shards, so that replicas which disagree on it see each other's locks.
+func ShardLockName(shards, index int) string {
+	if shards <= 1 {
+		return operatorLockName
+	}
+	return fmt.Sprintf("%s%d-of-%d", shardLockPrefix, index, shards)
+}
+
+// shardsOfLock returns the number of shards recorded in the name of an operator lock,
+// or 0 if the name is not one of an operator lock.
+func shardsOfLock(name string) int {
+	if name == operatorLockName {
+		return 1
+	}
+	if !strings.HasPrefix(name, shardLockPrefix) {
+		return 0
+	}
+	i := strings.LastIndex(name, "-of-")
+	if i < 0 {
+		return 0
+	}
+	n, err := strconv.Atoi(name[i+len("-of-"):])
+	if err != nil || n < 2 {
+		return 0
+	}
+	return n
+}
+
+// lockHeld returns whether the leader of the endpoints lock renewed it within its lease.
+func lockHeld(ep *v1.Endpoints, now time.Time) bool {
+	raw := ep.Annotations[resourcelock.LeaderElectionRecordAnnotationKey]
+	if len(raw) == 0 {
+		return false
+	}
+	var r resourcelock.LeaderElectionRecord
+	if err := json.Unmarshal([]byte(raw), &r); err != nil {
+		return false
+	}
+	lease := time.Duration(r.LeaseDurationSeconds) * time.Second
+	return len(r.HolderIdentity) != 0 && now.Before(r.RenewTime.Add(lease))
+}
+
+// conflictingShardLocks returns the names of the held operator locks which record another number of shards.
+func conflictingShardLocks(eps []v1.Endpoints, shards int, now time.Time) []string {
+	if shards <= 1 {
+		shards = 1
+	}
+	var names []string
+	for i := range eps {
+		n := shardsOfLock(eps[i].Name)
+		if n == 0 || n == shards {
+			continue
+		}
+		if lockHeld(&eps[i], now) {
+			names = append(names, eps[i].Name)
+		}
+	}
+	return names
+}
+
+// WaitForShardAgreement blocks until no replica in the namespace holds an operator lock for another
+// number of shards. Replicas which disagree on --shards would otherwise manage the same clusters at once,
+// e.g. during a rolling update which changes it.
+func WaitForShardAgreement(kubecli kubernetes.Interface, ns string, shards int) {
+	for {
+		eps, err := kubecli.CoreV1().Endpoints(ns).List(metav1.ListOptions{})
+		if err != nil {
+			logrus.Warningf("failed to list the locks of the other operator replicas: %v", err)
+		} else {
+			conflicts := conflictingShardLocks(eps.Items, shards, time.Now())
+			if len(conflicts) == 0 {
+				return
+			}
+			logrus.Warningf("operator replicas with another number of shards hold the locks %v; "+
+				"waiting for them to stop before managing clusters", conflicts)
+		}
+		time.Sleep(shardAgreementRetryInterval)
+	}
+}
+
+// owns returns whether the cluster is in a managed namespace and belongs to the shard of this operator.
+// Without sharding, the operator owns all clusters of the managed namespaces.
+func (c *Controller) owns(clus *spec.Cluster) bool {
+	if !c.ManagesNamespace(clus.Metadata.Namespace) {
+		return false
+	}
+	if c.Shards <= 1 {
+		return true
+	}
+	return ShardOf(clus.Metadata.Namespace, clus.Metadata.Name, c.Shards) == c.ShardIndex
+}
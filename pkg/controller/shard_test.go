@@ -0,0 +1,139 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/k8sutil/election/resourcelock"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+func TestShardOf(t *testing.T) {
+	shards := 3
+	counts := make([]int, shards)
+	for i := 0; i < 300; i++ {
+		name := "cluster-" + strconv.Itoa(i)
+		s := ShardOf("default", name, shards)
+		if s < 0 || s >= shards {
+			t.Fatalf("#%d: shard get=%d, want in [0, %d)", i, s, shards)
+		}
+		if ShardOf("default", name, shards) != s {
+			t.Fatalf("#%d: shard of %s is not deterministic", i, name)
+		}
+		counts[s]++
+	}
+	for s, n := range counts {
+		if n == 0 {
+			t.Errorf("shard %d owns no cluster out of 300", s)
+		}
+	}
+}
+
+func TestShardIndexFromPodName(t *testing.T) {
+	tests := []struct {
+		name   string
+		wIndex int
+		wErr   bool
+	}{
+		{name: "etcd-operator-0", wIndex: 0},
+		{name: "etcd-operator-12", wIndex: 12},
+		{name: "etcd-operator-5d8f7b9c4-x2k9p", wErr: true},
+		{name: "operator", wErr: true},
+	}
+	for i, tt := range tests {
+		index, err := ShardIndexFromPodName(tt.name)
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+			continue
+		}
+		if err == nil && index != tt.wIndex {
+			t.Errorf("#%d: index get=%d, want=%d", i, index, tt.wIndex)
+		}
+	}
+}
+
+func TestShardsOfLock(t *testing.T) {
+	tests := []struct {
+		name string
+		w    int
+	}{
+		{name: ShardLockName(0, -1), w: 1},
+		{name: ShardLockName(1, 0), w: 1},
+		{name: ShardLockName(3, 2), w: 3},
+		{name: "etcd-operator-shard-2", w: 0},
+		{name: "etcd-operator-shard-2-of-x", w: 0},
+		{name: "example-etcd-cluster", w: 0},
+	}
+	for i, tt := range tests {
+		if get := shardsOfLock(tt.name); get != tt.w {
+			t.Errorf("#%d: shards of %s get=%d, want=%d", i, tt.name, get, tt.w)
+		}
+	}
+}
+
+func TestConflictingShardLocks(t *testing.T) {
+	now := time.Now()
+	lock := func(name string, renewed time.Time) v1.Endpoints {
+		record := fmt.Sprintf(`{"holderIdentity":"etcd-operator-0","leaseDurationSeconds":15,"renewTime":%q}`,
+			renewed.UTC().Format(time.RFC3339))
+		return v1.Endpoints{ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Annotations: map[string]string{resourcelock.LeaderElectionRecordAnnotationKey: record},
+		}}
+	}
+	eps := []v1.Endpoints{
+		lock(ShardLockName(1, 0), now),
+		lock(ShardLockName(2, 0), now.Add(-time.Minute)),
+		lock(ShardLockName(2, 1), now),
+		lock(ShardLockName(3, 0), now),
+		{ObjectMeta: metav1.ObjectMeta{Name: "example-etcd-cluster"}},
+	}
+	tests := []struct {
+		shards int
+		w      []string
+	}{
+		{shards: 0, w: []string{"etcd-operator-shard-1-of-2", "etcd-operator-shard-0-of-3"}},
+		{shards: 2, w: []string{"etcd-operator", "etcd-operator-shard-0-of-3"}},
+		{shards: 3, w: []string{"etcd-operator", "etcd-operator-shard-1-of-2"}},
+	}
+	for i, tt := range tests {
+		if get := conflictingShardLocks(eps, tt.shards, now); !reflect.DeepEqual(get, tt.w) {
+			t.Errorf("#%d: conflicts get=%v, want=%v", i, get, tt.w)
+		}
+	}
+}
+
+func TestHandleClusterEventOtherShard(t *testing.T) {
+	clus := &spec.Cluster{Metadata: metav1.ObjectMeta{Name: "other", Namespace: "default", ResourceVersion: "1"}}
+	c := New(Config{Shards: 2, ShardIndex: 1 - ShardOf("default", "other", 2)})
+	if c.owns(clus) {
+		t.Fatal("cluster of the other shard is owned")
+	}
+	if err := c.handleClusterEvent(&Event{Type: watch.Modified, Object: clus}); err != nil {
+		t.Errorf("event of a cluster of the other shard failed: %v", err)
+	}
+	if c.isClustersCacheStale([]spec.Cluster{*clus}) {
+		t.Error("cache is stale because of a cluster of the other shard")
+	}
+}
@@ -36,6 +36,9 @@ type GC struct {
 
 	kubecli kubernetes.Interface
 	ns      string
+
+	// DryRun only logs the resources which would be deleted.
+	DryRun bool
 }
 
 func New(kubecli kubernetes.Interface, ns string) *GC {
@@ -46,23 +49,50 @@ func New(kubecli kubernetes.Interface, ns string) *GC {
 	}
 }
 
+// clusterSet is the set of clusters whose resources are kept.
+type clusterSet struct {
+	uids map[types.UID]bool
+	// names of the clusters, to check resources without owner by their cluster label.
+	// If nil, resources without owner are kept.
+	names map[string]bool
+}
+
+// isOrphan returns whether the resource belongs to no cluster of the set.
+func (s clusterSet) isOrphan(o metav1.Object) bool {
+	refs := o.GetOwnerReferences()
+	if len(refs) != 0 {
+		return !s.uids[refs[0].UID]
+	}
+	if s.names == nil {
+		return false
+	}
+	name, ok := o.GetLabels()["etcd_cluster"]
+	return ok && !s.names[name]
+}
+
 // CollectCluster collects resources that matches cluster lable, but
 // does not belong to the cluster with given clusterUID
 func (gc *GC) CollectCluster(cluster string, clusterUID types.UID) {
-	gc.collectResources(k8sutil.ClusterListOpt(cluster), map[types.UID]bool{clusterUID: true})
+	gc.collectResources(k8sutil.ClusterListOpt(cluster), clusterSet{uids: map[types.UID]bool{clusterUID: true}})
 }
 
 // FullyCollect collects resources that were created before,
 // but does not belong to any current running clusters.
+// Resources without owner, e.g. left over by an operator crash mid-create,
+// are collected if no cluster of their cluster label exists.
 func (gc *GC) FullyCollect() error {
 	clusters, err := k8sutil.GetClusterList(gc.kubecli.CoreV1().RESTClient(), gc.ns)
 	if err != nil {
 		return err
 	}
 
-	clusterUIDSet := make(map[types.UID]bool)
+	running := clusterSet{
+		uids:  make(map[types.UID]bool),
+		names: make(map[string]bool),
+	}
 	for _, c := range clusters.Items {
-		clusterUIDSet[c.Metadata.UID] = true
+		running.uids[c.Metadata.UID] = true
+		running.names[c.Metadata.Name] = true
 	}
 
 	option := metav1.ListOptions{
@@ -71,88 +101,219 @@ func (gc *GC) FullyCollect() error {
 		}).String(),
 	}
 
-	gc.collectResources(option, clusterUIDSet)
+	gc.collectResources(option, running)
 	return nil
 }
 
-func (gc *GC) collectResources(option metav1.ListOptions, runningSet map[types.UID]bool) {
-	if err := gc.collectPods(option, runningSet); err != nil {
+func (gc *GC) collectResources(option metav1.ListOptions, running clusterSet) {
+	if err := gc.collectPods(option, running); err != nil {
 		gc.logger.Errorf("gc pods failed: %v", err)
 	}
-	if err := gc.collectServices(option, runningSet); err != nil {
+	if err := gc.collectServices(option, running); err != nil {
 		gc.logger.Errorf("gc services failed: %v", err)
 	}
-	if err := gc.collectDeployment(option, runningSet); err != nil {
+	if err := gc.collectDeployment(option, running); err != nil {
 		gc.logger.Errorf("gc deployments failed: %v", err)
 	}
+	if err := gc.collectPodDisruptionBudgets(option, running); err != nil {
+		gc.logger.Errorf("gc pod disruption budgets failed: %v", err)
+	}
+	if err := gc.collectDaemonSets(option, running); err != nil {
+		gc.logger.Errorf("gc daemon sets failed: %v", err)
+	}
+	if err := gc.collectConfigMaps(option, running); err != nil {
+		gc.logger.Errorf("gc config maps failed: %v", err)
+	}
+	if err := gc.collectPersistentVolumeClaims(option, running); err != nil {
+		gc.logger.Errorf("gc persistent volume claims failed: %v", err)
+	}
+}
+
+// delete deletes the resource of the given kind and name with del, unless in dry run.
+func (gc *GC) delete(kind, name string, del func() error) error {
+	if gc.DryRun {
+		gc.logger.Infof("dry run: would delete %s (%s)", kind, name)
+		return nil
+	}
+	err := del()
+	if err != nil && !k8sutil.IsKubernetesResourceNotFoundError(err) {
+		return err
+	}
+	gc.logger.Infof("deleted %s (%s)", kind, name)
+	return nil
 }
 
-func (gc *GC) collectPods(option metav1.ListOptions, runningSet map[types.UID]bool) error {
+func (gc *GC) collectPods(option metav1.ListOptions, running clusterSet) error {
 	pods, err := gc.kubecli.CoreV1().Pods(gc.ns).List(option)
 	if err != nil {
 		return err
 	}
 
-	for _, p := range pods.Items {
-		if len(p.OwnerReferences) == 0 {
+	for i := range pods.Items {
+		p := &pods.Items[i]
+		if len(p.OwnerReferences) == 0 && running.names == nil {
 			gc.logger.Warningf("failed to check pod %s: no owner", p.GetName())
 			continue
 		}
 		// Pods failed due to liveness probe are also collected
-		if !runningSet[p.OwnerReferences[0].UID] || p.Status.Phase == v1.PodFailed {
+		if running.isOrphan(p) || p.Status.Phase == v1.PodFailed {
 			// kill bad pods without grace period to kill it immediately
-			err = gc.kubecli.CoreV1().Pods(gc.ns).Delete(p.GetName(), metav1.NewDeleteOptions(0))
-			if err != nil && !k8sutil.IsKubernetesResourceNotFoundError(err) {
+			err = gc.delete("pod", p.GetName(), func() error {
+				return gc.kubecli.CoreV1().Pods(gc.ns).Delete(p.GetName(), metav1.NewDeleteOptions(0))
+			})
+			if err != nil {
 				return err
 			}
-			gc.logger.Infof("deleted pod (%v)", p.GetName())
 		}
 	}
 	return nil
 }
 
-func (gc *GC) collectServices(option metav1.ListOptions, runningSet map[types.UID]bool) error {
+func (gc *GC) collectServices(option metav1.ListOptions, running clusterSet) error {
 	srvs, err := gc.kubecli.CoreV1().Services(gc.ns).List(option)
 	if err != nil {
 		return err
 	}
 
-	for _, srv := range srvs.Items {
-		if len(srv.OwnerReferences) == 0 {
+	for i := range srvs.Items {
+		srv := &srvs.Items[i]
+		if len(srv.OwnerReferences) == 0 && running.names == nil {
 			gc.logger.Warningf("failed to check service %s: no owner", srv.GetName())
 			continue
 		}
-		if !runningSet[srv.OwnerReferences[0].UID] {
-			err = gc.kubecli.CoreV1().Services(gc.ns).Delete(srv.GetName(), nil)
-			if err != nil && !k8sutil.IsKubernetesResourceNotFoundError(err) {
+		if running.isOrphan(srv) {
+			err = gc.delete("service", srv.GetName(), func() error {
+				return gc.kubecli.CoreV1().Services(gc.ns).Delete(srv.GetName(), nil)
+			})
+			if err != nil {
 				return err
 			}
-			gc.logger.Infof("deleted service (%v)", srv.GetName())
 		}
 	}
 
 	return nil
 }
 
-func (gc *GC) collectDeployment(option metav1.ListOptions, runningSet map[types.UID]bool) error {
+func (gc *GC) collectDeployment(option metav1.ListOptions, running clusterSet) error {
 	ds, err := gc.kubecli.AppsV1beta1().Deployments(gc.ns).List(option)
 	if err != nil {
 		return err
 	}
 
-	for _, d := range ds.Items {
-		if len(d.OwnerReferences) == 0 {
+	for i := range ds.Items {
+		d := &ds.Items[i]
+		if len(d.OwnerReferences) == 0 && running.names == nil {
 			gc.logger.Warningf("failed to GC deployment (%s): no owner", d.GetName())
 			continue
 		}
-		if !runningSet[d.OwnerReferences[0].UID] {
-			err = gc.kubecli.AppsV1beta1().Deployments(gc.ns).Delete(d.GetName(), k8sutil.CascadeDeleteOptions(0))
+		if running.isOrphan(d) {
+			err = gc.delete("deployment", d.GetName(), func() error {
+				return gc.kubecli.AppsV1beta1().Deployments(gc.ns).Delete(d.GetName(), k8sutil.CascadeDeleteOptions(0))
+			})
 			if err != nil {
-				if !k8sutil.IsKubernetesResourceNotFoundError(err) {
-					return err
-				}
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (gc *GC) collectPodDisruptionBudgets(option metav1.ListOptions, running clusterSet) error {
+	pdbs, err := gc.kubecli.PolicyV1beta1().PodDisruptionBudgets(gc.ns).List(option)
+	if err != nil {
+		return err
+	}
+
+	for i := range pdbs.Items {
+		pdb := &pdbs.Items[i]
+		if len(pdb.OwnerReferences) == 0 && running.names == nil {
+			gc.logger.Warningf("failed to GC pod disruption budget (%s): no owner", pdb.GetName())
+			continue
+		}
+		if running.isOrphan(pdb) {
+			err = gc.delete("pod disruption budget", pdb.GetName(), func() error {
+				return gc.kubecli.PolicyV1beta1().PodDisruptionBudgets(gc.ns).Delete(pdb.GetName(), nil)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (gc *GC) collectDaemonSets(option metav1.ListOptions, running clusterSet) error {
+	dss, err := gc.kubecli.ExtensionsV1beta1().DaemonSets(gc.ns).List(option)
+	if err != nil {
+		return err
+	}
+
+	for i := range dss.Items {
+		ds := &dss.Items[i]
+		if len(ds.OwnerReferences) == 0 && running.names == nil {
+			gc.logger.Warningf("failed to GC daemon set (%s): no owner", ds.GetName())
+			continue
+		}
+		if running.isOrphan(ds) {
+			err = gc.delete("daemon set", ds.GetName(), func() error {
+				return gc.kubecli.ExtensionsV1beta1().DaemonSets(gc.ns).Delete(ds.GetName(), k8sutil.CascadeDeleteOptions(0))
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (gc *GC) collectConfigMaps(option metav1.ListOptions, running clusterSet) error {
+	cms, err := gc.kubecli.CoreV1().ConfigMaps(gc.ns).List(option)
+	if err != nil {
+		return err
+	}
+
+	for i := range cms.Items {
+		cm := &cms.Items[i]
+		if len(cm.OwnerReferences) == 0 && running.names == nil {
+			gc.logger.Warningf("failed to GC config map (%s): no owner", cm.GetName())
+			continue
+		}
+		if running.isOrphan(cm) {
+			err = gc.delete("config map", cm.GetName(), func() error {
+				return gc.kubecli.CoreV1().ConfigMaps(gc.ns).Delete(cm.GetName(), nil)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// collectPersistentVolumeClaims only collects owned backup PVCs.
+// A backup PVC without owner is kept on purpose to restore the cluster from it later.
+func (gc *GC) collectPersistentVolumeClaims(option metav1.ListOptions, running clusterSet) error {
+	pvcs, err := gc.kubecli.CoreV1().PersistentVolumeClaims(gc.ns).List(option)
+	if err != nil {
+		return err
+	}
+
+	for i := range pvcs.Items {
+		pvc := &pvcs.Items[i]
+		if len(pvc.OwnerReferences) == 0 {
+			continue
+		}
+		if running.isOrphan(pvc) {
+			err = gc.delete("persistent volume claim", pvc.GetName(), func() error {
+				return gc.kubecli.CoreV1().PersistentVolumeClaims(gc.ns).Delete(pvc.GetName(), nil)
+			})
+			if err != nil {
+				return err
 			}
-			gc.logger.Infof("deleted deployment (%s)", d.GetName())
 		}
 	}
 
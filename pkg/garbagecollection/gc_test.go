@@ -0,0 +1,57 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package garbagecollection
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+func TestIsOrphan(t *testing.T) {
+	byUID := clusterSet{uids: map[types.UID]bool{"uid-a": true}}
+	byName := clusterSet{uids: map[types.UID]bool{"uid-a": true}, names: map[string]bool{"a": true}}
+	owned := func(uid types.UID) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{UID: uid}}
+	}
+
+	tests := []struct {
+		set    clusterSet
+		owners []metav1.OwnerReference
+		labels map[string]string
+
+		want bool
+	}{
+		{set: byUID, owners: owned("uid-a"), want: false},
+		{set: byUID, owners: owned("uid-b"), want: true},
+		// no owner and unknown cluster names: keep
+		{set: byUID, labels: map[string]string{"etcd_cluster": "b"}, want: false},
+		{set: byName, labels: map[string]string{"etcd_cluster": "a"}, want: false},
+		{set: byName, labels: map[string]string{"etcd_cluster": "b"}, want: true},
+		// no owner and no cluster label: keep
+		{set: byName, labels: map[string]string{"app": "etcd"}, want: false},
+		// owner takes precedence over the cluster label
+		{set: byName, owners: owned("uid-b"), labels: map[string]string{"etcd_cluster": "a"}, want: true},
+	}
+
+	for i, tt := range tests {
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{OwnerReferences: tt.owners, Labels: tt.labels}}
+		if get := tt.set.isOrphan(pod); get != tt.want {
+			t.Errorf("#%d: isOrphan get=%v, want=%v", i, get, tt.want)
+		}
+	}
+}
@@ -14,7 +14,12 @@
 
 package spec
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+)
 
 type BackupStorageType string
 
@@ -22,12 +27,18 @@ const (
 	BackupStorageTypeDefault          = ""
 	BackupStorageTypePersistentVolume = "PersistentVolume"
 	BackupStorageTypeS3               = "S3"
+	BackupStorageTypeNFS              = "NFS"
 
 	AWSSecretCredentialsFileName = "credentials"
 	AWSSecretConfigFileName      = "config"
+	// S3CABundleFileName is the file name of the CA bundle in the secret named by S3Source.CABundleSecret.
+	S3CABundleFileName = "ca-bundle.crt"
 )
 
-var errPVZeroSize = errors.New("PV backup should not have 0 size volume")
+var (
+	errPVZeroSize = errors.New("PV backup should not have 0 size volume")
+	errNFSUnset   = errors.New("NFS backup needs the server and the absolute path of an NFS export")
+)
 
 type BackupPolicy struct {
 	// Pod defines the policy to create the backup pod.
@@ -51,23 +62,60 @@ type BackupPolicy struct {
 	// CleanupBackupsOnClusterDelete tells whether to cleanup backup data if cluster is deleted.
 	// By default, operator will keep the backup data.
 	CleanupBackupsOnClusterDelete bool `json:"cleanupBackupsOnClusterDelete"`
+
+	// If greater than 0, MaxBackupAgeForUpgradeInSecond holds a rolling upgrade of the cluster
+	// until its most recent backup is younger than the given number of seconds.
+	MaxBackupAgeForUpgradeInSecond int `json:"maxBackupAgeForUpgradeInSecond,omitempty"`
+
+	// If greater than 0, a backup is made as soon as the cluster advanced more than
+	// MaxRevisionsBetweenBackups revisions since the latest backup, in addition to the interval.
+	MaxRevisionsBetweenBackups int64 `json:"maxRevisionsBetweenBackups,omitempty"`
+
+	// If greater than 0, a backup is made as soon as the db of the cluster grew more than
+	// MaxDBGrowthBetweenBackupsInMB since the latest backup, in addition to the interval.
+	MaxDBGrowthBetweenBackupsInMB int `json:"maxDBGrowthBetweenBackupsInMB,omitempty"`
+}
+
+// HasBackupThreshold tells whether backups are also triggered by the progress of the cluster.
+func (bp *BackupPolicy) HasBackupThreshold() bool {
+	return bp.MaxRevisionsBetweenBackups > 0 || bp.MaxDBGrowthBetweenBackupsInMB > 0
 }
 
 func (bp *BackupPolicy) Validate() error {
 	if bp.MaxBackups < 0 {
 		return errors.New("MaxBackups value should be >= 0")
 	}
+	if bp.MaxBackupAgeForUpgradeInSecond < 0 {
+		return errors.New("MaxBackupAgeForUpgradeInSecond value should be >= 0")
+	}
+	if bp.MaxRevisionsBetweenBackups < 0 {
+		return errors.New("MaxRevisionsBetweenBackups value should be >= 0")
+	}
+	if bp.MaxDBGrowthBetweenBackupsInMB < 0 {
+		return errors.New("MaxDBGrowthBetweenBackupsInMB value should be >= 0")
+	}
 	if bp.StorageType == BackupStorageTypePersistentVolume {
 		if pv := bp.StorageSource.PV; pv == nil || pv.VolumeSizeInMB <= 0 {
 			return errPVZeroSize
 		}
 	}
+	if bp.StorageType == BackupStorageTypeNFS {
+		if nfs := bp.StorageSource.NFS; nfs == nil || len(nfs.Server) == 0 || !path.IsAbs(nfs.Path) {
+			return errNFSUnset
+		}
+	}
+	if bp.StorageType == BackupStorageTypeS3 && bp.StorageSource.S3 != nil {
+		if err := bp.StorageSource.S3.Validate(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 type StorageSource struct {
-	PV *PVSource `json:"pv,omitempty"`
-	S3 *S3Source `json:"s3,omitempty"`
+	PV  *PVSource  `json:"pv,omitempty"`
+	S3  *S3Source  `json:"s3,omitempty"`
+	NFS *NFSSource `json:"nfs,omitempty"`
 }
 
 type PVSource struct {
@@ -78,6 +126,17 @@ type PVSource struct {
 	VolumeSizeInMB int `json:"volumeSizeInMB"`
 }
 
+// NFSSource is an NFS export to store backups in.
+// Backups of a cluster are stored under "${path}/${namespace}/v1/${cluster-name}" of the export,
+// so that clusters in different namespaces can share it.
+type NFSSource struct {
+	// Server is the hostname or IP address of the NFS server.
+	Server string `json:"server"`
+
+	// Path is the absolute path of the export on the NFS server.
+	Path string `json:"path"`
+}
+
 // TODO: support per cluster S3 Source configuration.
 type S3Source struct {
 	// The name of the AWS S3 bucket to store backups in.
@@ -92,6 +151,46 @@ type S3Source struct {
 	//
 	// AWSSecret overwrites the default etcd operator wide AWS credential and config.
 	AWSSecret string `json:"awsSecret,omitempty"`
+
+	// CABundleSecret is the name of the secret that stores the PEM encoded CA bundle to verify
+	// the S3 endpoint with, e.g. for an internally signed endpoint.
+	// The file name of the bundle MUST be 'ca-bundle.crt'.
+	// It replaces the system CAs.
+	CABundleSecret string `json:"caBundleSecret,omitempty"`
+
+	// Proxy defines the HTTP(S) proxies to reach the S3 endpoint through if not nil.
+	Proxy *S3Proxy `json:"proxy,omitempty"`
+}
+
+// S3Proxy defines the proxies for S3 requests with the semantics of the
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+type S3Proxy struct {
+	// HTTPProxy is the URL of the proxy for plain HTTP requests, e.g. "http://proxy.example.com:3128".
+	HTTPProxy string `json:"httpProxy,omitempty"`
+
+	// HTTPSProxy is the URL of the proxy for HTTPS requests.
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+
+	// NoProxy is a comma separated list of hosts and domain suffixes to reach without a proxy.
+	// The backup sidecar also reaches the etcd members and the operator, so it should
+	// cover the cluster domain, e.g. ".svc,.cluster.local".
+	NoProxy string `json:"noProxy,omitempty"`
+}
+
+func (ss *S3Source) Validate() error {
+	if ss.Proxy == nil {
+		return nil
+	}
+	for _, p := range []string{ss.Proxy.HTTPProxy, ss.Proxy.HTTPSProxy} {
+		if len(p) == 0 {
+			continue
+		}
+		u, err := url.Parse(p)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+			return fmt.Errorf("spec: invalid S3 proxy URL (%s)", p)
+		}
+	}
+	return nil
 }
 
 type BackupServiceStatus struct {
@@ -18,6 +18,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
 	"strings"
 	"time"
 
@@ -48,6 +49,9 @@ type Cluster struct {
 	Metadata        metav1.ObjectMeta `json:"metadata,omitempty"`
 	Spec            ClusterSpec       `json:"spec"`
 	Status          ClusterStatus     `json:"status"`
+
+	// rawSpec is the JSON the spec was decoded from, see ApplyProfile.
+	rawSpec json.RawMessage
 }
 
 func (c *Cluster) AsOwner() metav1.OwnerReference {
@@ -80,14 +84,125 @@ type ClusterSpec struct {
 	// If version is not set, default is "3.1.8".
 	Version string `json:"version,omitempty"`
 
+	// Profile is the name of the config map in the namespace of the operator whose "spec" key
+	// holds a cluster spec in YAML or JSON, e.g. with the version, TLS, backup and pod policies
+	// shared by many clusters. The fields of a new cluster which are not set, or set to their
+	// zero value, are taken from the profile once, when the operator first sees the cluster.
+	// Later changes to the profile do not affect existing clusters.
+	Profile string `json:"profile,omitempty"`
+
 	// Paused is to pause the control of the operator for the etcd cluster.
 	Paused bool `json:"paused,omitempty"`
 
+	// Hibernated lets the operator delete all etcd pods.
+	// If the members keep their data on PVCs (pod.persistentVolumeClaimSpec), the PVCs are kept,
+	// and once it is unset, the operator recreates the pods on them.
+	// Otherwise, the operator backs the cluster up first, and once it is unset, recreates the
+	// cluster from the backup at its expected size; it needs a backup policy then.
+	// The services, the backup and the backup sidecar are kept.
+	Hibernated bool `json:"hibernated,omitempty"`
+
+	// UpgradeStrategy is how the operator upgrades the members to a new version:
+	// "Rolling", "RecreateFromBackup" or "ManualApproval".
+	// Default: "Rolling"
+	UpgradeStrategy UpgradeStrategyType `json:"upgradeStrategy,omitempty"`
+
+	// MaxUnavailable is the number of members rolling upgrades and defragmentations
+	// may disrupt at the same time. If set, the PodDisruptionBudget allows as many evictions.
+	// It is capped so that a quorum of members is always available.
+	// Default: 1
+	MaxUnavailable int `json:"maxUnavailable,omitempty"`
+
+	// ManageSafeToEvict makes the operator annotate the member pods with SafeToEvictAnnotation:
+	// "true" while the cluster keeps a quorum of ready members without any one of them,
+	// "false" otherwise, so that the cluster autoscaler never removes a node the quorum depends on.
+	// The annotation is removed once it is unset.
+	ManageSafeToEvict bool `json:"manageSafeToEvict,omitempty"`
+
+	// EvacuateNodes makes the operator replace members on cordoned nodes, or on nodes with
+	// NoExecute taints the member pods do not tolerate, with new members on other nodes
+	// before the pods are killed. Members are replaced one at a time, keeping a quorum.
+	// It is not supported for self-hosted clusters.
+	EvacuateNodes bool `json:"evacuateNodes,omitempty"`
+
+	// RebalanceZones makes the operator move members one at a time out of the zone with the most members
+	// while another available zone has at least two members less, e.g. after a zone outage.
+	// It needs spec.pod.spreadAcrossZones.
+	RebalanceZones bool `json:"rebalanceZones,omitempty"`
+
+	// MemberNaming is how the operator names new members: "Ordinal" or "Random".
+	// Either way, a new member never takes the name of a removed member.
+	// Changing it only affects members added afterwards.
+	// Default: "Ordinal"
+	MemberNaming MemberNamingScheme `json:"memberNaming,omitempty"`
+
+	// DisasterRecovery is what the operator does once a majority of the members is dead:
+	// "RestoreFromBackup" or "ManualApproval".
+	// The operator never replaces a majority of the members with new members,
+	// since they could form a second cluster next to the members it cannot see.
+	// Default: "RestoreFromBackup"
+	DisasterRecovery DisasterRecoveryPolicyType `json:"disasterRecovery,omitempty"`
+
+	// EtcdImage defines the etcd image if not nil. By default, the image is "quay.io/coreos/etcd:v${version}".
+	//
+	// Updating EtcdImage takes effect on existing etcd pods on the next upgrade.
+	EtcdImage *EtcdImagePolicy `json:"etcdImage,omitempty"`
+
 	// Pod defines the policy to create pod for the etcd pod.
 	//
 	// Updating Pod does not take effect on any existing etcd pods.
 	Pod *PodPolicy `json:"pod,omitempty"`
 
+	// Etcd defines the configuration of the etcd process.
+	//
+	// Updating Etcd does not take effect on any existing etcd pods.
+	Etcd *EtcdPolicy `json:"etcd,omitempty"`
+
+	// Defrag defines the policy to defragment the etcd members if not nil.
+	Defrag *DefragPolicy `json:"defrag,omitempty"`
+
+	// RemediateNoSpace lets the operator remediate NOSPACE alarms: it compacts the
+	// key space to the latest revision, defragments all members and disarms the
+	// alarm once the db size of all members is below the backend quota.
+	// Compaction drops the revision history, e.g. watchers cannot resume from older revisions.
+	RemediateNoSpace bool `json:"remediateNoSpace,omitempty"`
+
+	// MemberUnreachableTimeoutInSecond is the time after which a member that is
+	// continuously unreachable through the etcd client is removed from the cluster
+	// and replaced by a new member.
+	// A member is only removed if the remaining members keep a quorum.
+	// If it is 0, unreachable members are not replaced.
+	MemberUnreachableTimeoutInSecond int `json:"memberUnreachableTimeoutInSecond,omitempty"`
+
+	// GRPCProxy defines the etcd gRPC proxy tier to deploy in front of the cluster if not nil.
+	GRPCProxy *GRPCProxyPolicy `json:"grpcProxy,omitempty"`
+
+	// Autoscaling defines how the operator scales the cluster or its gRPC proxies on read latency if not nil.
+	// The operator then updates spec.size or spec.grpcProxy.size itself.
+	Autoscaling *AutoscalingPolicy `json:"autoscaling,omitempty"`
+
+	// Gateway defines the etcd gateway DaemonSet to deploy for the cluster if not nil.
+	Gateway *GatewayPolicy `json:"gateway,omitempty"`
+
+	// Mirror defines the continuous replication of the keys of the cluster to another etcd cluster if not nil.
+	Mirror *MirrorPolicy `json:"mirror,omitempty"`
+
+	// ClientService defines the routing and publishing options of the client service of the cluster if not nil.
+	ClientService *ClientServicePolicy `json:"clientService,omitempty"`
+
+	// ServiceMonitor defines the ServiceMonitor of the Prometheus Operator to create
+	// for the cluster if not nil. It needs the Prometheus Operator to be installed.
+	ServiceMonitor *ServiceMonitorPolicy `json:"serviceMonitor,omitempty"`
+
+	// ServiceMesh defines how the etcd pods get along with the service mesh of the namespace if not nil.
+	// It only applies to pods created after the update.
+	ServiceMesh *ServiceMeshPolicy `json:"serviceMesh,omitempty"`
+
+	// PrometheusRule defines the PrometheusRule of the Prometheus Operator to create
+	// for the cluster if not nil. Its alerts select the member metrics scraped
+	// through the ServiceMonitor of the cluster.
+	PrometheusRule *PrometheusRulePolicy `json:"prometheusRule,omitempty"`
+
 	// Backup defines the policy to backup data of etcd cluster if not nil.
 	// If backup policy is set but restore policy not, and if a previous backup exists,
 	// this cluster would face conflict and fail to start.
@@ -99,6 +214,37 @@ type ClusterSpec struct {
 	// Restore is a cluster initialization configuration. It cannot be updated.
 	Restore *RestorePolicy `json:"restore,omitempty"`
 
+	// Clone defines the running etcd cluster to clone the data from if not nil.
+	// It's not allowed together with restore or self-hosted.
+	//
+	// Clone is a cluster initialization configuration. It cannot be updated.
+	Clone *ClonePolicy `json:"clone,omitempty"`
+
+	// V2Migration defines the etcd2 data to migrate to the v3 API and bootstrap the cluster from if not nil.
+	// It's not allowed together with restore, clone or self-hosted.
+	//
+	// V2Migration is a cluster initialization configuration. It cannot be updated.
+	V2Migration *V2MigrationPolicy `json:"v2Migration,omitempty"`
+
+	// SeedSnapshot defines the etcd v3 snapshot in a secret, config map or at a URL
+	// to bootstrap the cluster from if not nil.
+	// It's not allowed together with restore, clone, v2 migration or self-hosted.
+	//
+	// SeedSnapshot is a cluster initialization configuration. It cannot be updated.
+	SeedSnapshot *SeedSnapshotPolicy `json:"seedSnapshot,omitempty"`
+
+	// InitialData defines keys the operator puts into the cluster once, after it first became ready.
+	// Existing keys are kept. It can be combined with any way of bootstrapping the cluster.
+	//
+	// Adding it to an existing cluster loads it once as well. Updates after status.initialDataLoaded is set are not applied.
+	InitialData *InitialDataPolicy `json:"initialData,omitempty"`
+
+	// Export defines the keys the etcd.coreos.com/trigger-export annotation dumps to S3 if not nil.
+	Export *ExportPolicy `json:"export,omitempty"`
+
+	// Import defines the dump the etcd.coreos.com/trigger-import annotation loads from S3 if not nil.
+	Import *ImportPolicy `json:"import,omitempty"`
+
 	// SelfHosted determines if the etcd cluster is used for a self-hosted
 	// Kubernetes cluster.
 	//
@@ -128,6 +274,11 @@ type PodPolicy struct {
 	// Do not overwrite them.
 	Labels map[string]string `json:"labels,omitempty"`
 
+	// Annotations specifies the annotations to attach to pods the operator creates for the
+	// etcd cluster, e.g. "prometheus.io/scrape" or service mesh sidecar injection settings.
+	// Annotations set by the operator take precedence.
+	Annotations map[string]string `json:"annotations,omitempty"`
+
 	// NodeSelector specifies a map of key-value pairs. For the pod to be eligible
 	// to run on a node, the node must have each of the indicated key-value pairs as
 	// labels.
@@ -135,8 +286,54 @@ type PodPolicy struct {
 
 	// AntiAffinity determines if the etcd-operator tries to avoid putting
 	// the etcd members in the same cluster onto the same node.
+	//
+	// Deprecated: members of the same cluster are now always required to run on
+	// different nodes unless AntiAffinityMode or Affinity is set.
 	AntiAffinity bool `json:"antiAffinity,omitempty"`
 
+	// PreferredAntiAffinity relaxes the default anti-affinity of etcd members from
+	// required to preferred. The scheduler then tries to spread the members of
+	// the same cluster onto different nodes, but still schedules members
+	// onto the same node if there are not enough nodes.
+	// This is useful for small test environments.
+	//
+	// Deprecated: use AntiAffinityMode "Preferred" instead.
+	PreferredAntiAffinity bool `json:"preferredAntiAffinity,omitempty"`
+
+	// AntiAffinityMode is how strictly the members of the same cluster are kept
+	// on different nodes: "Required", "Preferred" or "Disabled".
+	// "Preferred" and "Disabled" let clusters with more members than nodes schedule.
+	// It has no effect if Affinity is set.
+	// Default: "Required"
+	AntiAffinityMode AntiAffinityMode `json:"antiAffinityMode,omitempty"`
+
+	// LivenessFailurePolicy is what happens to an etcd pod whose liveness probe fails:
+	// "ReplaceMember" or "RestartContainer".
+	// "RestartContainer" suits members with a PersistentVolumeClaimSpec as well as
+	// ephemeral members whose data is worth keeping across etcd restarts.
+	// Updating it takes effect on pods created afterwards.
+	// Default: "ReplaceMember"
+	LivenessFailurePolicy LivenessFailurePolicy `json:"livenessFailurePolicy,omitempty"`
+
+	// EtcdctlAuthSecret is the name of a secret with the `username` and `password` of an etcd user,
+	// for clusters with etcd authentication enabled. etcdctl in the etcd container, which runs the
+	// liveness and readiness probes and the pre-stop hook, authenticates as that user. The user needs
+	// to read the keys "foo" and "health". The etcd client of the operator authenticates as that user too.
+	// Updating it takes effect on pods created afterwards, and on the operator client right away.
+	EtcdctlAuthSecret string `json:"etcdctlAuthSecret,omitempty"`
+
+	// Affinity overrides the affinity settings the etcd-operator generates for the etcd pods,
+	// including the default pod anti-affinity.
+	Affinity *v1.Affinity `json:"affinity,omitempty"`
+
+	// SpreadAcrossZones asks the scheduler to spread the etcd members of the cluster
+	// across availability zones, so that a single zone failure is less likely
+	// to take out quorum. Zones are identified by the
+	// "failure-domain.beta.kubernetes.io/zone" node label.
+	// The zone of each member is reported in status.members.zones.
+	// It has no effect if Affinity is set.
+	SpreadAcrossZones bool `json:"spreadAcrossZones,omitempty"`
+
 	// Resources is the resource requirements for the etcd container.
 	// This field cannot be updated once the cluster is created.
 	Resources v1.ResourceRequirements `json:"resources,omitempty"`
@@ -144,10 +341,55 @@ type PodPolicy struct {
 	// Tolerations specifies the pod's tolerations.
 	Tolerations []v1.Toleration `json:"tolerations,omitempty"`
 
+	// PersistentVolumeClaimSpec is the spec of the PVC each etcd member keeps its data dir on,
+	// instead of an emptyDir volume. A member whose pod is lost rejoins the cluster on its PVC
+	// with the same name and ID, instead of being replaced by a new member with a snapshot transfer.
+	// The PVC of a member is deleted when the member is removed.
+	// It is not allowed for self-hosted clusters. This field cannot be updated once the cluster is created:
+	// the operator reverts updates of it.
+	PersistentVolumeClaimSpec *v1.PersistentVolumeClaimSpec `json:"persistentVolumeClaimSpec,omitempty"`
+
+	// ImagePullSecrets is a list of references to secrets in the same namespace
+	// used to pull the images of the pods the operator creates for the etcd cluster.
+	// It is needed if the etcd image is hosted in a private registry.
+	ImagePullSecrets []v1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
+	// ImagePullPolicy is the pull policy of the containers in the pods the operator
+	// creates for the etcd cluster. One of "Always", "IfNotPresent" or "Never".
+	// Defaults to the Kubernetes default if not set.
+	ImagePullPolicy v1.PullPolicy `json:"imagePullPolicy,omitempty"`
+
+	// InitContainers are run in order before the etcd container starts, after
+	// the init containers of the operator. The operator's init containers wait for
+	// the member's DNS record and restore the data directory from backup.
+	// Their names "check-dns", "fetch-backup", "restore-datadir" and "append-hosts" are reserved.
+	InitContainers []v1.Container `json:"initContainers,omitempty"`
+
+	// Sidecars are extra containers added to the etcd pods, e.g. log shippers or metrics exporters.
+	// The container name "etcd" is reserved. Sidecars can mount the etcd data volume "etcd-data".
+	Sidecars []v1.Container `json:"sidecars,omitempty"`
+
+	// SidecarVolumes are extra volumes added to the etcd pods for the use of sidecars.
+	// The volume names used by the etcd container are reserved.
+	SidecarVolumes []v1.Volume `json:"sidecarVolumes,omitempty"`
+
+	// AutomountServiceAccountToken indicates whether a service account token should be
+	// mounted into the etcd pods. etcd does not access the Kubernetes API, so it
+	// defaults to false. Set it to true for sidecars which need the Kubernetes API.
+	AutomountServiceAccountToken *bool `json:"automountServiceAccountToken,omitempty"`
+
+	// TerminationGracePeriodSeconds is the time given to etcd to shut down gracefully
+	// before it is killed, e.g. during node drains, upgrades and member removal.
+	// Large databases might need longer to shut down cleanly.
+	// Defaults to the Kubernetes default for pod evictions and 5 seconds for member removal.
+	TerminationGracePeriodSeconds *int64 `json:"terminationGracePeriodSeconds,omitempty"`
+
 	// List of environment variables to set in the etcd container.
 	// This is used to configure etcd process. etcd cluster cannot be created, when
 	// bad environement variables are provided. Do not overwrite any flags used to
-	// bootstrap the cluster (for example `--initial-cluster` flag).
+	// bootstrap the cluster (for example `--initial-cluster` flag); the environment
+	// variables of the flags set by the operator are rejected.
+	// The variables are added after the ones set by the operator.
 	// This field cannot be updated.
 	EtcdEnv []v1.EnvVar `json:"etcdEnv,omitempty"`
 }
@@ -161,16 +403,126 @@ func (c *ClusterSpec) Validate() error {
 			return errors.New("spec: backup and restore storage types are different")
 		}
 	}
+	if c.Clone != nil {
+		if c.Restore != nil || c.SelfHosted != nil {
+			return errors.New("spec: clone is not allowed together with restore or self-hosted")
+		}
+		if err := c.Clone.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.V2Migration != nil {
+		if c.Restore != nil || c.Clone != nil || c.SelfHosted != nil {
+			return errors.New("spec: v2 migration is not allowed together with restore, clone or self-hosted")
+		}
+		if err := c.V2Migration.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.SeedSnapshot != nil {
+		if c.Restore != nil || c.Clone != nil || c.V2Migration != nil || c.SelfHosted != nil {
+			return errors.New("spec: seed snapshot is not allowed together with restore, clone, v2 migration or self-hosted")
+		}
+		if err := c.SeedSnapshot.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.InitialData != nil {
+		if err := c.InitialData.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Export != nil {
+		if err := c.Export.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Import != nil {
+		if err := c.Import.Validate(); err != nil {
+			return err
+		}
+	}
 	if c.Backup != nil {
 		if err := c.Backup.Validate(); err != nil {
 			return err
 		}
 	}
+	if err := c.validateMemberNaming(); err != nil {
+		return err
+	}
+	if err := c.validateUpgradeStrategy(); err != nil {
+		return err
+	}
+	if err := c.validateDisruption(); err != nil {
+		return err
+	}
+	if err := c.validateDisasterRecovery(); err != nil {
+		return err
+	}
+	if c.Hibernated && c.SelfHosted != nil {
+		return errors.New("spec: hibernation is not allowed for self-hosted clusters")
+	}
+	if c.Hibernated && c.Backup == nil && (c.Pod == nil || c.Pod.PersistentVolumeClaimSpec == nil) {
+		return errors.New("spec: hibernation needs a backup policy or member PVCs")
+	}
+	if c.EtcdImage != nil {
+		if err := c.EtcdImage.Validate(); err != nil {
+			return err
+		}
+	}
 	if c.TLS != nil {
 		if err := c.TLS.Validate(); err != nil {
 			return err
 		}
 	}
+	if c.Etcd != nil {
+		if err := c.Etcd.Validate(c.Version); err != nil {
+			return err
+		}
+	}
+	if c.Defrag != nil {
+		if err := c.Defrag.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.GRPCProxy != nil {
+		if err := c.GRPCProxy.Validate(c.Version, c.TLS); err != nil {
+			return err
+		}
+	}
+	if c.Autoscaling != nil {
+		if err := c.Autoscaling.Validate(c.GRPCProxy); err != nil {
+			return err
+		}
+	}
+	if c.Gateway != nil {
+		if err := c.Gateway.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Mirror != nil {
+		if err := c.Mirror.Validate(c.Version); err != nil {
+			return err
+		}
+	}
+	if c.ClientService != nil {
+		if err := c.ClientService.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.ServiceMonitor != nil {
+		if err := c.ServiceMonitor.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.ServiceMesh != nil {
+		if err := c.ServiceMesh.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.MemberUnreachableTimeoutInSecond < 0 {
+		return errors.New("spec: member unreachable timeout must not be negative")
+	}
 
 	if c.Pod != nil {
 		for k := range c.Pod.Labels {
@@ -178,6 +530,142 @@ func (c *ClusterSpec) Validate() error {
 				return errors.New("spec: pod labels contains reserved label")
 			}
 		}
+		if err := validateResources(c.Pod.Resources); err != nil {
+			return err
+		}
+		switch c.Pod.ImagePullPolicy {
+		case "", v1.PullAlways, v1.PullIfNotPresent, v1.PullNever:
+		default:
+			return fmt.Errorf("spec: unknown pod image pull policy (%s)", c.Pod.ImagePullPolicy)
+		}
+		if err := c.Pod.validateAntiAffinityMode(); err != nil {
+			return err
+		}
+		if err := c.Pod.validateLivenessFailurePolicy(); err != nil {
+			return err
+		}
+		if err := c.Pod.validateContainers(); err != nil {
+			return err
+		}
+		if pvc := c.Pod.PersistentVolumeClaimSpec; pvc != nil {
+			if c.SelfHosted != nil {
+				return errors.New("spec: pod persistent volume claim spec is not allowed for self-hosted clusters")
+			}
+			if q, ok := pvc.Resources.Requests[v1.ResourceStorage]; !ok || q.Sign() <= 0 {
+				return errors.New("spec: pod persistent volume claim spec must request storage")
+			}
+		}
+		if g := c.Pod.TerminationGracePeriodSeconds; g != nil && *g < 0 {
+			return errors.New("spec: pod termination grace period must not be negative")
+		}
+		for _, e := range c.Pod.EtcdEnv {
+			if reservedEtcdEnv[e.Name] {
+				return fmt.Errorf("spec: etcd env (%s) is set by the operator", e.Name)
+			}
+			if len(c.Pod.EtcdctlAuthSecret) != 0 && etcdctlAuthEnv[e.Name] {
+				return fmt.Errorf("spec: etcd env (%s) is set by the operator with pod.etcdctlAuthSecret", e.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// RevertImmutableFields resets the fields of the updated spec which cannot be updated to their values in
+// the old spec, and returns the names of the fields it reset.
+// Changing pod.persistentVolumeClaimSpec would strand the PVCs of the existing members.
+func (c *ClusterSpec) RevertImmutableFields(old ClusterSpec) []string {
+	var oldPVC, newPVC *v1.PersistentVolumeClaimSpec
+	if old.Pod != nil {
+		oldPVC = old.Pod.PersistentVolumeClaimSpec
+	}
+	if c.Pod != nil {
+		newPVC = c.Pod.PersistentVolumeClaimSpec
+	}
+	if reflect.DeepEqual(oldPVC, newPVC) {
+		return nil
+	}
+	if c.Pod == nil {
+		c.Pod = &PodPolicy{}
+	}
+	c.Pod.PersistentVolumeClaimSpec = oldPVC
+	return []string{"pod.persistentVolumeClaimSpec"}
+}
+
+// validateResources checks that no resource request exceeds its limit,
+// which Kubernetes would reject at pod creation time.
+func validateResources(r v1.ResourceRequirements) error {
+	for name, req := range r.Requests {
+		limit, ok := r.Limits[name]
+		if !ok {
+			continue
+		}
+		if req.Cmp(limit) > 0 {
+			return fmt.Errorf("spec: pod resource request of %s (%s) must be less than or equal to its limit (%s)", name, req.String(), limit.String())
+		}
+	}
+	return nil
+}
+
+// reservedEtcdEnv are the environment variables of the etcd flags the operator sets.
+var reservedEtcdEnv = map[string]bool{
+	"ETCD_NAME":                        true,
+	"ETCD_DATA_DIR":                    true,
+	"ETCD_INITIAL_ADVERTISE_PEER_URLS": true,
+	"ETCD_LISTEN_PEER_URLS":            true,
+	"ETCD_LISTEN_CLIENT_URLS":          true,
+	"ETCD_ADVERTISE_CLIENT_URLS":       true,
+	"ETCD_INITIAL_CLUSTER":             true,
+	"ETCD_INITIAL_CLUSTER_STATE":       true,
+	"ETCD_INITIAL_CLUSTER_TOKEN":       true,
+	"ETCD_PEER_CLIENT_CERT_AUTH":       true,
+	"ETCD_PEER_TRUSTED_CA_FILE":        true,
+	"ETCD_PEER_CERT_FILE":              true,
+	"ETCD_PEER_KEY_FILE":               true,
+	"ETCD_CLIENT_CERT_AUTH":            true,
+	"ETCD_TRUSTED_CA_FILE":             true,
+	"ETCD_CERT_FILE":                   true,
+	"ETCD_KEY_FILE":                    true,
+}
+
+// etcdctlAuthEnv are the environment variables the operator sets to pass the credentials
+// of pod.etcdctlAuthSecret to etcdctl.
+var etcdctlAuthEnv = map[string]bool{
+	"ETCDCTL_USER":     true,
+	"ETCDCTL_PASSWORD": true,
+	"AUTH_USERNAME":    true,
+	"AUTH_PASSWORD":    true,
+}
+
+// reservedVolumeNames are the names of the volumes the operator adds to etcd pods.
+var reservedVolumeNames = map[string]bool{
+	"etcd-data":         true,
+	"member-peer-tls":   true,
+	"member-client-tls": true,
+	"operator-etcd-tls": true,
+}
+
+// reservedContainerNames are the names of the containers and init containers the operator adds to etcd pods.
+var reservedContainerNames = []string{"etcd", "check-dns", "fetch-backup", "restore-datadir", "append-hosts"}
+
+func (pp *PodPolicy) validateContainers() error {
+	names := map[string]bool{}
+	for _, n := range reservedContainerNames {
+		names[n] = true
+	}
+	for _, cs := range [][]v1.Container{pp.InitContainers, pp.Sidecars} {
+		for _, c := range cs {
+			if names[c.Name] {
+				return fmt.Errorf("spec: container name (%s) is reserved or duplicated", c.Name)
+			}
+			names[c.Name] = true
+		}
+	}
+	volumes := map[string]bool{}
+	for _, v := range pp.SidecarVolumes {
+		if reservedVolumeNames[v.Name] || volumes[v.Name] {
+			return fmt.Errorf("spec: sidecar volume name (%s) is reserved or duplicated", v.Name)
+		}
+		volumes[v.Name] = true
 	}
 	return nil
 }
@@ -214,13 +702,37 @@ const (
 	ClusterConditionReady = "Ready"
 
 	ClusterConditionRemovingDeadMember = "RemovingDeadMember"
+	// ClusterConditionEvacuatingMember means a member is replaced since its node is cordoned or tainted.
+	ClusterConditionEvacuatingMember = "EvacuatingMember"
+	// ClusterConditionRebalancingZones means a member is moved out of the zone with the most members.
+	ClusterConditionRebalancingZones = "RebalancingZones"
 
 	ClusterConditionRecovering = "Recovering"
+	// ClusterConditionRecoveryPending means a majority of the members is dead
+	// and the disaster recovery waits for the user's approval.
+	ClusterConditionRecoveryPending = "RecoveryPending"
+	// ClusterConditionRestoreFailed means the Job restoring the data of the seed member on its PVC failed.
+	ClusterConditionRestoreFailed = "RestoreFailed"
 
 	ClusterConditionScalingUp   = "ScalingUp"
 	ClusterConditionScalingDown = "ScalingDown"
 
 	ClusterConditionUpgrading = "Upgrading"
+	// ClusterConditionUpgradePaused means the upgraded members failed their checks
+	// and the upgrade does not continue with the other members.
+	ClusterConditionUpgradePaused = "UpgradePaused"
+
+	ClusterConditionDegraded = "Degraded"
+
+	ClusterConditionHibernated = "Hibernated"
+
+	// ClusterConditionThrottled means the operator postponed creating a member pod
+	// because of the operator wide pod creation rate limit.
+	ClusterConditionThrottled = "Throttled"
+
+	// ClusterConditionRejected means the operator does not manage the cluster,
+	// because it already manages the maximum number of clusters.
+	ClusterConditionRejected = "Rejected"
 )
 
 type ClusterStatus struct {
@@ -231,6 +743,11 @@ type ClusterStatus struct {
 	// ControlPuased indicates the operator pauses the control of the cluster.
 	ControlPaused bool `json:"controlPaused"`
 
+	// Hibernated indicates all etcd pods are deleted after spec.hibernated was set.
+	// The cluster is resumed on the member PVCs, listed in members.unready, or recreated
+	// from its backup once spec.hibernated is unset.
+	Hibernated bool `json:"hibernated,omitempty"`
+
 	// Condition keeps ten most recent cluster conditions
 	Conditions []ClusterCondition `json:"conditions"`
 
@@ -238,24 +755,147 @@ type ClusterStatus struct {
 	Size int `json:"size"`
 	// Members are the etcd members in the cluster
 	Members MembersStatus `json:"members"`
+	// MemberCounter is the counter of the next ordinal member name.
+	// It only grows, so that the names of removed members are not reused after an operator restart.
+	MemberCounter int `json:"memberCounter,omitempty"`
+	// ImageDigests maps the etcd versions of the members to the digest of the image they run,
+	// as reported by the container runtime.
+	ImageDigests map[string]string `json:"imageDigests,omitempty"`
+	// ClientEndpoint is the client endpoint the cluster is published under by external-dns,
+	// if spec.clientService.externalDNSHostname is set.
+	ClientEndpoint string `json:"clientEndpoint,omitempty"`
+	// InitialDataLoaded indicates the keys of spec.initialData were put into the cluster.
+	InitialDataLoaded bool `json:"initialDataLoaded,omitempty"`
 	// CurrentVersion is the current cluster version
 	CurrentVersion string `json:"currentVersion"`
 	// TargetVersion is the version the cluster upgrading to.
 	// If the cluster is not upgrading, TargetVersion is empty.
 	TargetVersion string `json:"targetVersion"`
 
+	// QuotaBackendBytes is the backend quota of the etcd members.
+	// Writes fail once the db size of a member in members.dbSize exceeds it.
+	QuotaBackendBytes int64 `json:"quotaBackendBytes,omitempty"`
+
+	// DBGrowth is the estimated growth of the largest member db in members.dbSize.
+	DBGrowth *DBGrowthStatus `json:"dbGrowth,omitempty"`
+
+	// ReadLatency is the latency of the reads the operator issues against the cluster.
+	ReadLatency *ReadLatencyStatus `json:"readLatency,omitempty"`
+
+	// Autoscaling is the status of the autoscaler if spec.autoscaling is set.
+	Autoscaling *AutoscalingStatus `json:"autoscaling,omitempty"`
+
+	// Mirror is the status of the mirror if spec.mirror is set.
+	Mirror *MirrorStatus `json:"mirror,omitempty"`
+
+	// OperatorVersion is the version of the operator that last reconciled
+	// the cluster. On operator upgrade it is used to detect clusters that
+	// were set up by an older operator and need migration.
+	OperatorVersion string `json:"operatorVersion,omitempty"`
+
 	// BackupServiceStatus is the status of the backup service.
 	// BackupServiceStatus only exists when backup is enabled in the
 	// cluster spec.
 	BackupServiceStatus *BackupServiceStatus `json:"backupServiceStatus,omitempty"`
 }
 
+// ReadLatencyStatus reports the latency of the probe reads the operator issues
+// against the cluster on every reconcile. It is updated every few minutes.
+type ReadLatencyStatus struct {
+	// SerializableP99InMs is the 99th percentile latency of recent serializable reads,
+	// which are served by a single member.
+	SerializableP99InMs int64 `json:"serializableP99InMs"`
+	// LinearizableP99InMs is the 99th percentile latency of recent linearizable reads,
+	// which go through the raft consensus of the cluster.
+	LinearizableP99InMs int64 `json:"linearizableP99InMs"`
+	// Samples is the number of reads of each kind the percentiles are computed from.
+	Samples int `json:"samples"`
+	// Failures is the number of reads that failed in the same window.
+	Failures int `json:"failures"`
+	// UpdateTime is the time the latencies were computed.
+	UpdateTime string `json:"updateTime"`
+}
+
+// DBGrowthStatus reports the growth of the largest member db of the cluster, estimated from
+// db size samples the operator takes every 15 minutes over up to a week.
+// It is reported once the samples span an hour. The window restarts when the db shrinks, e.g. on defragmentation.
+type DBGrowthStatus struct {
+	// BytesPerDay is the estimated growth of the db per day.
+	BytesPerDay int64 `json:"bytesPerDay"`
+	// ProjectedQuotaTime is when the db is projected to reach quotaBackendBytes at this growth.
+	// It is empty if the db does not grow.
+	ProjectedQuotaTime string `json:"projectedQuotaTime,omitempty"`
+	// WindowStartTime is the time of the oldest sample the growth is estimated from.
+	WindowStartTime string `json:"windowStartTime"`
+	// WindowStartDBSize is the db size in bytes of the oldest sample.
+	WindowStartDBSize int64 `json:"windowStartDBSize"`
+	// UpdateTime is the time the growth was estimated.
+	UpdateTime string `json:"updateTime"`
+}
+
+// AutoscalingStatus reports the most recent scaling step of the autoscaler.
+type AutoscalingStatus struct {
+	// LastScaleTime is the time of the most recent scaling step.
+	// The next step waits for the cooldown since then.
+	LastScaleTime string `json:"lastScaleTime,omitempty"`
+	// LastScaleReason explains the most recent scaling step.
+	LastScaleReason string `json:"lastScaleReason,omitempty"`
+}
+
+// MirrorStatus reports how far the destination of the mirror lags behind the cluster.
+// The operator writes a heartbeat key under the mirrored prefix on every reconcile and
+// reads it back from the destination. It is updated at most once a minute.
+type MirrorStatus struct {
+	// LastSyncTime is the time of the most recent heartbeat found on the destination.
+	LastSyncTime string `json:"lastSyncTime,omitempty"`
+	// LagInSeconds is the age of the most recent heartbeat found on the destination.
+	LagInSeconds int64 `json:"lagInSeconds"`
+	// UpdateTime is the time the lag was computed.
+	UpdateTime string `json:"updateTime"`
+}
+
 type MembersStatus struct {
 	// Ready are the etcd members that are ready to serve requests
 	// The member names are the same as the etcd pod names
 	Ready []string `json:"ready,omitempty"`
 	// Unready are the etcd members not ready to serve requests
 	Unready []string `json:"unready,omitempty"`
+	// Zones maps the etcd members to the availability zones they run in.
+	// It is only reported if spec.pod.spreadAcrossZones is set.
+	Zones map[string]string `json:"zones,omitempty"`
+	// DBSize maps the etcd members to the size of their backend database in bytes.
+	DBSize map[string]int64 `json:"dbSize,omitempty"`
+	// Draining are the etcd members which are being evicted or run on a cordoned node.
+	Draining []string `json:"draining,omitempty"`
+	// UnavailableZones are the zones in members.zones without a ready node.
+	// It is only reported if spec.rebalanceZones is set.
+	UnavailableZones []string `json:"unavailableZones,omitempty"`
+	// LastDefragTime maps the etcd members to the time they were last defragmented by the operator.
+	// It is only reported if spec.defrag is set.
+	LastDefragTime map[string]string `json:"lastDefragTime,omitempty"`
+	// Details are the details of each etcd member, sorted by member name.
+	Details []MemberStatus `json:"details,omitempty"`
+}
+
+const (
+	MemberRoleLeader   = "leader"
+	MemberRoleFollower = "follower"
+)
+
+type MemberStatus struct {
+	// Name is the name of the member, the same as its pod name.
+	Name string `json:"name"`
+	// ID is the etcd member ID in hex, as printed by etcdctl.
+	ID string `json:"id,omitempty"`
+	// Role is "leader" or "follower". It is empty if the member is unreachable.
+	Role string `json:"role,omitempty"`
+	// Version is the etcd version the member runs.
+	Version string `json:"version,omitempty"`
+	// DBSize is the size of the backend database of the member in bytes.
+	DBSize int64 `json:"dbSize,omitempty"`
+	// LastHealthyTime is the last time the member was ready.
+	// It is updated at most once a minute.
+	LastHealthyTime string `json:"lastHealthyTime,omitempty"`
 }
 
 func (cs ClusterStatus) Copy() ClusterStatus {
@@ -299,6 +939,10 @@ func (cs *ClusterStatus) SetVersion(v string) {
 	cs.CurrentVersion = v
 }
 
+func (cs *ClusterStatus) SetOperatorVersion(v string) {
+	cs.OperatorVersion = v
+}
+
 func (cs *ClusterStatus) SetReason(r string) {
 	cs.Reason = r
 }
@@ -340,6 +984,17 @@ func (cs *ClusterStatus) AppendUpgradingCondition(to string, member string) {
 	cs.appendCondition(c)
 }
 
+func (cs *ClusterStatus) AppendRecreatingCondition(from, to string) {
+	reason := fmt.Sprintf("recreating cluster from backup to upgrade version from %v to %v", from, to)
+
+	c := ClusterCondition{
+		Type:           ClusterConditionUpgrading,
+		Reason:         reason,
+		TransitionTime: time.Now().Format(time.RFC3339),
+	}
+	cs.appendCondition(c)
+}
+
 func (cs *ClusterStatus) AppendRemovingDeadMember(name string) {
 	reason := fmt.Sprintf("removing dead member %s", name)
 
@@ -351,6 +1006,26 @@ func (cs *ClusterStatus) AppendRemovingDeadMember(name string) {
 	cs.appendCondition(c)
 }
 
+func (cs *ClusterStatus) AppendEvacuatingMember(name, node string) {
+	reason := fmt.Sprintf("replacing member %s on cordoned or tainted node %s", name, node)
+
+	c := ClusterCondition{
+		Type:           ClusterConditionEvacuatingMember,
+		Reason:         reason,
+		TransitionTime: time.Now().Format(time.RFC3339),
+	}
+	cs.appendCondition(c)
+}
+
+func (cs *ClusterStatus) AppendRebalancingZonesCondition(reason string) {
+	c := ClusterCondition{
+		Type:           ClusterConditionRebalancingZones,
+		Reason:         reason,
+		TransitionTime: time.Now().Format(time.RFC3339),
+	}
+	cs.appendCondition(c)
+}
+
 func (cs *ClusterStatus) SetReadyCondition() {
 	c := ClusterCondition{
 		Type:           ClusterConditionReady,
@@ -369,6 +1044,136 @@ func (cs *ClusterStatus) SetReadyCondition() {
 	cs.appendCondition(c)
 }
 
+// SetDegradedCondition appends a degraded condition unless the cluster
+// is already degraded for the same reason.
+func (cs *ClusterStatus) SetDegradedCondition(reason string) {
+	if n := len(cs.Conditions); n > 0 {
+		lastc := cs.Conditions[n-1]
+		if lastc.Type == ClusterConditionDegraded && lastc.Reason == reason {
+			return
+		}
+	}
+	cs.appendCondition(ClusterCondition{
+		Type:           ClusterConditionDegraded,
+		Reason:         reason,
+		TransitionTime: time.Now().Format(time.RFC3339),
+	})
+}
+
+// SetUpgradePausedCondition appends an upgrade paused condition unless the upgrade
+// is already paused for the same reason.
+func (cs *ClusterStatus) SetUpgradePausedCondition(reason string) {
+	if n := len(cs.Conditions); n > 0 {
+		lastc := cs.Conditions[n-1]
+		if lastc.Type == ClusterConditionUpgradePaused && lastc.Reason == reason {
+			return
+		}
+	}
+	cs.appendCondition(ClusterCondition{
+		Type:           ClusterConditionUpgradePaused,
+		Reason:         reason,
+		TransitionTime: time.Now().Format(time.RFC3339),
+	})
+}
+
+// IsUpgradePaused returns true if the most recent condition is upgrade paused.
+func (cs *ClusterStatus) IsUpgradePaused() bool {
+	n := len(cs.Conditions)
+	return n > 0 && cs.Conditions[n-1].Type == ClusterConditionUpgradePaused
+}
+
+// SetRecoveryPendingCondition appends a recovery pending condition unless the recovery
+// is already pending for the same reason.
+func (cs *ClusterStatus) SetRecoveryPendingCondition(reason string) {
+	if n := len(cs.Conditions); n > 0 {
+		lastc := cs.Conditions[n-1]
+		if lastc.Type == ClusterConditionRecoveryPending && lastc.Reason == reason {
+			return
+		}
+	}
+	cs.appendCondition(ClusterCondition{
+		Type:           ClusterConditionRecoveryPending,
+		Reason:         reason,
+		TransitionTime: time.Now().Format(time.RFC3339),
+	})
+}
+
+// IsRecoveryPending returns true if the most recent condition is recovery pending.
+func (cs *ClusterStatus) IsRecoveryPending() bool {
+	n := len(cs.Conditions)
+	return n > 0 && cs.Conditions[n-1].Type == ClusterConditionRecoveryPending
+}
+
+// SetRestoreFailedCondition appends a restore failed condition unless the restore
+// already failed for the same reason.
+func (cs *ClusterStatus) SetRestoreFailedCondition(reason string) {
+	if n := len(cs.Conditions); n > 0 {
+		lastc := cs.Conditions[n-1]
+		if lastc.Type == ClusterConditionRestoreFailed && lastc.Reason == reason {
+			return
+		}
+	}
+	cs.appendCondition(ClusterCondition{
+		Type:           ClusterConditionRestoreFailed,
+		Reason:         reason,
+		TransitionTime: time.Now().Format(time.RFC3339),
+	})
+}
+
+func (cs *ClusterStatus) SetHibernatedCondition() {
+	if n := len(cs.Conditions); n > 0 && cs.Conditions[n-1].Type == ClusterConditionHibernated {
+		return
+	}
+	cs.appendCondition(ClusterCondition{
+		Type:           ClusterConditionHibernated,
+		TransitionTime: time.Now().Format(time.RFC3339),
+	})
+}
+
+// SetThrottledCondition appends a throttled condition reporting the scaling progress,
+// unless the cluster is already throttled at the same progress.
+func (cs *ClusterStatus) SetThrottledCondition(from, to int) {
+	reason := "pod creation throttled: " + scalingReason(from, to)
+	if n := len(cs.Conditions); n > 0 {
+		lastc := cs.Conditions[n-1]
+		if lastc.Type == ClusterConditionThrottled && lastc.Reason == reason {
+			return
+		}
+	}
+	cs.appendCondition(ClusterCondition{
+		Type:           ClusterConditionThrottled,
+		Reason:         reason,
+		TransitionTime: time.Now().Format(time.RFC3339),
+	})
+}
+
+// SetRejectedCondition appends a rejected condition unless the cluster is already rejected for the same reason.
+func (cs *ClusterStatus) SetRejectedCondition(reason string) {
+	if n := len(cs.Conditions); n > 0 {
+		lastc := cs.Conditions[n-1]
+		if lastc.Type == ClusterConditionRejected && lastc.Reason == reason {
+			return
+		}
+	}
+	cs.appendCondition(ClusterCondition{
+		Type:           ClusterConditionRejected,
+		Reason:         reason,
+		TransitionTime: time.Now().Format(time.RFC3339),
+	})
+}
+
+// IsReady returns true if the most recent condition is ready.
+func (cs *ClusterStatus) IsReady() bool {
+	n := len(cs.Conditions)
+	return n > 0 && cs.Conditions[n-1].Type == ClusterConditionReady
+}
+
+// IsDegraded returns true if the most recent condition is degraded.
+func (cs *ClusterStatus) IsDegraded() bool {
+	n := len(cs.Conditions)
+	return n > 0 && cs.Conditions[n-1].Type == ClusterConditionDegraded
+}
+
 func (cs *ClusterStatus) appendCondition(c ClusterCondition) {
 	cs.Conditions = append(cs.Conditions, c)
 	if len(cs.Conditions) > 10 {
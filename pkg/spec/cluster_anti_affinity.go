@@ -0,0 +1,61 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"errors"
+	"fmt"
+)
+
+type AntiAffinityMode string
+
+const (
+	// AntiAffinityRequired requires the members of a cluster to run on different nodes.
+	AntiAffinityRequired AntiAffinityMode = "Required"
+	// AntiAffinityPreferred asks the scheduler to spread the members of a cluster
+	// onto different nodes, but lets them share a node if there are not enough nodes.
+	AntiAffinityPreferred AntiAffinityMode = "Preferred"
+	// AntiAffinityDisabled lets the scheduler place the members without regard to each other.
+	AntiAffinityDisabled AntiAffinityMode = "Disabled"
+)
+
+// GetAntiAffinityMode returns the anti-affinity mode of the etcd pods.
+// Default: "Preferred" if the deprecated PreferredAntiAffinity is set, "Required" otherwise.
+func (pp *PodPolicy) GetAntiAffinityMode() AntiAffinityMode {
+	if pp == nil {
+		return AntiAffinityRequired
+	}
+	if len(pp.AntiAffinityMode) != 0 {
+		return pp.AntiAffinityMode
+	}
+	if pp.PreferredAntiAffinity {
+		return AntiAffinityPreferred
+	}
+	return AntiAffinityRequired
+}
+
+func (pp *PodPolicy) validateAntiAffinityMode() error {
+	switch pp.GetAntiAffinityMode() {
+	case AntiAffinityRequired, AntiAffinityDisabled:
+		if pp.PreferredAntiAffinity {
+			return errors.New("spec: preferredAntiAffinity conflicts with antiAffinityMode " + string(pp.AntiAffinityMode))
+		}
+		return nil
+	case AntiAffinityPreferred:
+		return nil
+	default:
+		return fmt.Errorf("spec: unknown anti-affinity mode (%s)", pp.AntiAffinityMode)
+	}
+}
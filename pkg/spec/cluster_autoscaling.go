@@ -0,0 +1,123 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"errors"
+	"fmt"
+)
+
+type AutoscalingTarget string
+
+const (
+	// AutoscalingTargetCluster scales spec.size, two members at a time to keep the size odd.
+	AutoscalingTargetCluster AutoscalingTarget = "cluster"
+	// AutoscalingTargetGRPCProxy scales spec.grpcProxy.size, one proxy at a time.
+	AutoscalingTargetGRPCProxy AutoscalingTarget = "grpcProxy"
+
+	defaultAutoscalingCooldownInSecond = 600
+	defaultAutoscalingWindowInSecond   = 300
+)
+
+// AutoscalingPolicy defines how the operator scales the cluster or its gRPC proxies
+// on the client load, measured as the 99th percentile latency of the serializable probe reads
+// (see status.readLatency), and on the largest db size of the members (see status.members.dbSize).
+// A signal must stay past its threshold for the whole window before the operator scales.
+// The operator only scales a healthy cluster which is not upgrading, one step at a time,
+// and waits for the cooldown between two steps.
+type AutoscalingPolicy struct {
+	// Target is what the operator scales.
+	// Default: "cluster"
+	Target AutoscalingTarget `json:"target,omitempty"`
+
+	// MinSize and MaxSize bound the size of the target.
+	// For the cluster target, both must be odd.
+	MinSize int `json:"minSize"`
+	MaxSize int `json:"maxSize"`
+
+	// ScaleUpReadLatencyInMs scales the target up once the read latency exceeds it.
+	// If it is 0, the target is not scaled on the read latency.
+	ScaleUpReadLatencyInMs int64 `json:"scaleUpReadLatencyInMs,omitempty"`
+	// ScaleDownReadLatencyInMs scales the target down once the read latency falls below it,
+	// and the db size is below ScaleUpDBSizeInMB if set.
+	// If it is 0, the target is not scaled down.
+	ScaleDownReadLatencyInMs int64 `json:"scaleDownReadLatencyInMs,omitempty"`
+
+	// ScaleUpDBSizeInMB scales the target up once the db of a member exceeds it,
+	// e.g. because range reads over a growing key space load the members more.
+	// The target is not scaled down on the db size, which barely shrinks.
+	// If it is 0, the target is not scaled on the db size.
+	ScaleUpDBSizeInMB int64 `json:"scaleUpDBSizeInMB,omitempty"`
+
+	// WindowInSecond is how long a signal must stay past its threshold before the target is scaled.
+	// Default: 300
+	WindowInSecond int `json:"windowInSecond,omitempty"`
+
+	// CooldownInSecond is the minimum time between two scaling steps.
+	// Default: 600
+	CooldownInSecond int `json:"cooldownInSecond,omitempty"`
+}
+
+func (ap *AutoscalingPolicy) Validate(grpcProxy *GRPCProxyPolicy) error {
+	switch ap.GetTarget() {
+	case AutoscalingTargetCluster:
+		if ap.MinSize%2 == 0 || ap.MaxSize%2 == 0 {
+			return errors.New("spec: autoscaling min and max size of the cluster must be odd")
+		}
+	case AutoscalingTargetGRPCProxy:
+		if grpcProxy == nil {
+			return errors.New("spec: autoscaling of grpc proxies needs spec.grpcProxy")
+		}
+	default:
+		return fmt.Errorf("spec: unknown autoscaling target (%s)", ap.Target)
+	}
+	if ap.MinSize < 1 || ap.MaxSize < ap.MinSize {
+		return fmt.Errorf("spec: invalid autoscaling size range [%d, %d]", ap.MinSize, ap.MaxSize)
+	}
+	if ap.ScaleUpReadLatencyInMs < 0 || ap.ScaleUpDBSizeInMB < 0 {
+		return errors.New("spec: autoscaling thresholds must not be negative")
+	}
+	if ap.ScaleUpReadLatencyInMs == 0 && ap.ScaleUpDBSizeInMB == 0 {
+		return errors.New("spec: autoscaling needs a scale up read latency or db size")
+	}
+	if ap.ScaleDownReadLatencyInMs < 0 || (ap.ScaleDownReadLatencyInMs > 0 && ap.ScaleDownReadLatencyInMs >= ap.ScaleUpReadLatencyInMs) {
+		return errors.New("spec: autoscaling scale down read latency must be between 0 and the scale up read latency")
+	}
+	if ap.CooldownInSecond < 0 || ap.WindowInSecond < 0 {
+		return errors.New("spec: autoscaling cooldown and window must not be negative")
+	}
+	return nil
+}
+
+func (ap *AutoscalingPolicy) GetTarget() AutoscalingTarget {
+	if len(ap.Target) == 0 {
+		return AutoscalingTargetCluster
+	}
+	return ap.Target
+}
+
+func (ap *AutoscalingPolicy) GetCooldownInSecond() int {
+	if ap.CooldownInSecond == 0 {
+		return defaultAutoscalingCooldownInSecond
+	}
+	return ap.CooldownInSecond
+}
+
+func (ap *AutoscalingPolicy) GetWindowInSecond() int {
+	if ap.WindowInSecond == 0 {
+		return defaultAutoscalingWindowInSecond
+	}
+	return ap.WindowInSecond
+}
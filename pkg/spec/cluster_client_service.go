@@ -0,0 +1,92 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+// ClientServicePolicy defines the routing options of the client service of the cluster.
+type ClientServicePolicy struct {
+	// SessionAffinity is the session affinity of the client service, "None" or "ClientIP".
+	// With "ClientIP", the connections of a client go to the same member, which keeps
+	// its reads consistent across connections while the member is up.
+	// Default: "None"
+	SessionAffinity v1.ServiceAffinity `json:"sessionAffinity,omitempty"`
+
+	// TopologyAwareHints enables topology aware hints on the client service,
+	// so that clients are routed to members in their zone if the cluster supports it.
+	// Kubernetes versions before 1.21 ignore it. See spec.pod.spreadAcrossZones
+	// to have members in every zone.
+	TopologyAwareHints bool `json:"topologyAwareHints,omitempty"`
+
+	// Type is the type of the client service: "ClusterIP", "NodePort" or "LoadBalancer".
+	// "NodePort" and "LoadBalancer" publish the cluster outside of Kubernetes,
+	// which should be combined with client TLS.
+	// Default: "ClusterIP"
+	Type v1.ServiceType `json:"type,omitempty"`
+
+	// ExternalDNSHostname is the DNS name external-dns maintains for the client service.
+	// It needs Type "NodePort" or "LoadBalancer", and external-dns watching the services
+	// of the Kubernetes cluster. The client endpoint is reported in status.clientEndpoint.
+	// The operator does not issue member certificates: with client TLS, the certificates in
+	// spec.TLS.static.member.clientSecret must list the hostname for clients to verify it.
+	ExternalDNSHostname string `json:"externalDNSHostname,omitempty"`
+}
+
+func (sp *ClientServicePolicy) Validate() error {
+	switch sp.SessionAffinity {
+	case "", v1.ServiceAffinityNone, v1.ServiceAffinityClientIP:
+	default:
+		return fmt.Errorf("spec: unknown client service session affinity (%s)", sp.SessionAffinity)
+	}
+	switch sp.Type {
+	case "", v1.ServiceTypeClusterIP, v1.ServiceTypeNodePort, v1.ServiceTypeLoadBalancer:
+	default:
+		return fmt.Errorf("spec: unsupported client service type (%s)", sp.Type)
+	}
+	if len(sp.ExternalDNSHostname) != 0 {
+		if sp.Type != v1.ServiceTypeNodePort && sp.Type != v1.ServiceTypeLoadBalancer {
+			return errors.New("spec: client service external DNS hostname needs type NodePort or LoadBalancer")
+		}
+		if errs := validation.IsDNS1123Subdomain(sp.ExternalDNSHostname); len(errs) != 0 {
+			return fmt.Errorf("spec: invalid client service external DNS hostname (%s): %v", sp.ExternalDNSHostname, errs)
+		}
+	}
+	return nil
+}
+
+// IsPublished tells whether the client service is reachable from outside of Kubernetes.
+func (sp *ClientServicePolicy) IsPublished() bool {
+	return sp != nil && (sp.Type == v1.ServiceTypeNodePort || sp.Type == v1.ServiceTypeLoadBalancer)
+}
+
+// ClientEndpoint returns the published client endpoint of the cluster, or an empty string
+// if the client service is not published under a DNS name. nodePort is the node port
+// of the client port, which clients connect to on a NodePort service.
+func (sp *ClientServicePolicy) ClientEndpoint(nodePort int) string {
+	if sp == nil || len(sp.ExternalDNSHostname) == 0 {
+		return ""
+	}
+	port := 2379
+	if sp.Type == v1.ServiceTypeNodePort {
+		port = nodePort
+	}
+	return fmt.Sprintf("%s:%d", sp.ExternalDNSHostname, port)
+}
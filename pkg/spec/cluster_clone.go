@@ -0,0 +1,39 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import "errors"
+
+// ClonePolicy defines the running etcd cluster a new cluster is cloned from.
+// The seed member of the new cluster takes a snapshot of the source cluster
+// and restores its data from it, so the source cluster does not need backup.
+type ClonePolicy struct {
+	// SourceCluster is the name of the etcd cluster to clone.
+	SourceCluster string `json:"sourceCluster"`
+	// SourceNamespace is the namespace of the source cluster.
+	// Default: the namespace of the new cluster
+	SourceNamespace string `json:"sourceNamespace,omitempty"`
+	// SourceTLSSecret is the secret with the client certificate to talk to a source
+	// cluster which serves clients over TLS. It has the same layout as the operator
+	// secret and must be in the namespace of the new cluster.
+	SourceTLSSecret string `json:"sourceTLSSecret,omitempty"`
+}
+
+func (cp *ClonePolicy) Validate() error {
+	if len(cp.SourceCluster) == 0 {
+		return errors.New("spec: clone source cluster must be set")
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+// DebugAnnotation is the cluster annotation to run a debug pod with etcdctl and curl
+// next to the cluster, if set to "true". It is an annotation instead of a spec field,
+// so that on-call engineers can toggle it without changing the desired state of the cluster.
+const DebugAnnotation = "etcd.coreos.com/debug"
+
+// IsDebugEnabled returns whether the cluster should have a debug pod.
+func (c *Cluster) IsDebugEnabled() bool {
+	return c.Metadata.Annotations[DebugAnnotation] == "true"
+}
@@ -0,0 +1,62 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/util/cronutil"
+)
+
+// DefragPolicy defines when the operator defragments the etcd members.
+// Members are defragmented one at a time, followers first and the leader last,
+// since a member does not serve requests while it is defragmented.
+type DefragPolicy struct {
+	// IntervalInSecond is the interval between two defragmentations of all members.
+	// If it is 0, members are not defragmented periodically.
+	IntervalInSecond int `json:"intervalInSecond,omitempty"`
+
+	// DBSizeThresholdInMB triggers defragmentation of a member once the size of its
+	// backend database exceeds the threshold.
+	// If it is 0, members are not defragmented on database size.
+	DBSizeThresholdInMB int `json:"dbSizeThresholdInMB,omitempty"`
+
+	// Schedule is a cron schedule of five fields, e.g. "0 3 * * 6" for Saturdays at 3am,
+	// in the time zone of the operator. Members are defragmented on the first reconcile
+	// after each scheduled time, the first time after the cluster was created.
+	// If it is empty, members are not defragmented on a schedule.
+	Schedule string `json:"schedule,omitempty"`
+}
+
+func (dp *DefragPolicy) Validate() error {
+	if dp.IntervalInSecond < 0 || dp.DBSizeThresholdInMB < 0 {
+		return errors.New("spec: defrag interval and db size threshold must not be negative")
+	}
+	if dp.IntervalInSecond == 0 && dp.DBSizeThresholdInMB == 0 && len(dp.Schedule) == 0 {
+		return errors.New("spec: defrag policy needs an interval, a db size threshold or a schedule")
+	}
+	if len(dp.Schedule) != 0 {
+		sched, err := cronutil.Parse(dp.Schedule)
+		if err != nil {
+			return fmt.Errorf("spec: invalid defrag schedule: %v", err)
+		}
+		if sched.Next(time.Now()).IsZero() {
+			return fmt.Errorf("spec: defrag schedule (%s) never fires", dp.Schedule)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import "fmt"
+
+type DisasterRecoveryPolicyType string
+
+const (
+	// DisasterRecoveryRestoreFromBackup recreates the cluster from its backup
+	// as soon as a majority of the members is dead.
+	DisasterRecoveryRestoreFromBackup DisasterRecoveryPolicyType = "RestoreFromBackup"
+	// DisasterRecoveryManualApproval recreates the cluster from its backup
+	// only after the user approved it with the ApproveDisasterRecoveryAnnotation.
+	// Until then, the members still running are left alone.
+	DisasterRecoveryManualApproval DisasterRecoveryPolicyType = "ManualApproval"
+
+	// ApproveDisasterRecoveryAnnotation is the cluster annotation to approve a pending
+	// disaster recovery. Its value must be "true". The operator removes it
+	// before recovering, so each approval recovers the cluster once.
+	ApproveDisasterRecoveryAnnotation = "etcd.coreos.com/approve-disaster-recovery"
+)
+
+// GetDisasterRecoveryPolicy returns the disaster recovery policy of the cluster.
+// Default: "RestoreFromBackup"
+func (c *ClusterSpec) GetDisasterRecoveryPolicy() DisasterRecoveryPolicyType {
+	if len(c.DisasterRecovery) == 0 {
+		return DisasterRecoveryRestoreFromBackup
+	}
+	return c.DisasterRecovery
+}
+
+func (c *ClusterSpec) validateDisasterRecovery() error {
+	switch c.GetDisasterRecoveryPolicy() {
+	case DisasterRecoveryRestoreFromBackup, DisasterRecoveryManualApproval:
+		return nil
+	default:
+		return fmt.Errorf("spec: unknown disaster recovery policy (%s)", c.DisasterRecovery)
+	}
+}
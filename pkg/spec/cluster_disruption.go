@@ -0,0 +1,61 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SafeToEvictAnnotation tells the cluster autoscaler whether it may evict a pod to remove its node.
+// Pods with emptyDir volumes, such as etcd members, are not evicted without it.
+const SafeToEvictAnnotation = "cluster-autoscaler.kubernetes.io/safe-to-evict"
+
+// GetMaxUnavailable returns the number of members which may be disrupted at the same time
+// in a cluster of the given size. It is capped at the number of members the cluster can lose
+// while keeping its quorum, but always allows one member to be disrupted.
+// Default: 1
+func (c *ClusterSpec) GetMaxUnavailable(size int) int {
+	n := c.MaxUnavailable
+	if n == 0 {
+		n = 1
+	}
+	if max := (size - 1) / 2; n > max {
+		n = max
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+func (c *ClusterSpec) validateDisruption() error {
+	if c.MaxUnavailable < 0 {
+		return errors.New("spec: maxUnavailable should be >= 0")
+	}
+	if c.EvacuateNodes && c.SelfHosted != nil {
+		return errors.New("spec: evacuateNodes is not supported for self-hosted clusters")
+	}
+	if c.RebalanceZones && (c.Pod == nil || !c.Pod.SpreadAcrossZones) {
+		return errors.New("spec: rebalanceZones needs pod.spreadAcrossZones")
+	}
+	if !c.ManageSafeToEvict || c.Pod == nil {
+		return nil
+	}
+	if _, ok := c.Pod.Annotations[SafeToEvictAnnotation]; ok {
+		return fmt.Errorf("spec: pod annotation %s is managed by the operator if manageSafeToEvict is set", SafeToEvictAnnotation)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/coreos/go-semver/semver"
+)
+
+const (
+	AutoCompactionModePeriodic = "periodic"
+	AutoCompactionModeRevision = "revision"
+)
+
+const (
+	MetricsLevelBasic     = "basic"
+	MetricsLevelExtensive = "extensive"
+)
+
+// EtcdPolicy defines the configuration of the etcd process.
+//
+// Updating EtcdPolicy only takes effect on new members.
+type EtcdPolicy struct {
+	// AutoCompactionMode is the auto compaction mode of etcd, "periodic" or "revision".
+	// It needs etcd 3.3 or above. If not set, etcd defaults to "periodic".
+	AutoCompactionMode string `json:"autoCompactionMode,omitempty"`
+
+	// AutoCompactionRetention bounds the revision history etcd keeps.
+	// In periodic mode, it is a number of hours (e.g. "1") or, since etcd 3.3, a duration (e.g. "30m").
+	// In revision mode, it is the number of revisions to keep (e.g. "10000").
+	// If not set, auto compaction is disabled.
+	AutoCompactionRetention string `json:"autoCompactionRetention,omitempty"`
+
+	// QuotaBackendBytes is the size limit of the backend database of each member.
+	// Once a member exceeds it, etcd raises a NOSPACE alarm and rejects writes.
+	// If not set, etcd defaults to 2GB.
+	QuotaBackendBytes int64 `json:"quotaBackendBytes,omitempty"`
+
+	// CorruptCheckTime enables the data corruption checks of etcd: members check
+	// their data against their peers on start, and the leader checks the data of
+	// all members every CorruptCheckTime, e.g. "1h". On data inconsistency, etcd
+	// raises a CORRUPT alarm and the operator marks the cluster as degraded.
+	// It needs etcd 3.3 or above.
+	CorruptCheckTime string `json:"corruptCheckTime,omitempty"`
+
+	// SnapshotCount is the number of committed transactions after which a member
+	// snapshots its raft log. Lower values bound memory and WAL size at the cost of
+	// more frequent snapshots. If not set, etcd defaults to 10000 before 3.2 and
+	// 100000 since 3.2.
+	SnapshotCount int64 `json:"snapshotCount,omitempty"`
+
+	// HeartbeatIntervalInMs is the interval of the leader heartbeats in milliseconds.
+	// It should be around the round trip time between members.
+	// If not set, etcd defaults to 100.
+	HeartbeatIntervalInMs int `json:"heartbeatIntervalInMs,omitempty"`
+
+	// ElectionTimeoutInMs is the time in milliseconds a follower waits for a heartbeat
+	// before it starts a leader election. It must be at least 5 times the heartbeat
+	// interval and at most 50000. If not set, etcd defaults to 1000.
+	ElectionTimeoutInMs int `json:"electionTimeoutInMs,omitempty"`
+
+	// MetricsPort is a separate port on which etcd serves /metrics and /health over
+	// plain HTTP, e.g. 2381, so that monitoring does not need client certificates.
+	// It is exposed on the client and peer services.
+	// It needs etcd 3.3 or above. If not set, metrics are served on the client port.
+	MetricsPort int `json:"metricsPort,omitempty"`
+
+	// MetricsLevel is the verbosity of the etcd metrics, "basic" or "extensive".
+	// "extensive" adds the gRPC handling time histograms of every method, which help
+	// debugging latency, at the cost of many more series.
+	// It needs etcd 3.3 or above. If not set, etcd defaults to "basic".
+	MetricsLevel string `json:"metricsLevel,omitempty"`
+}
+
+// DefaultQuotaBackendBytes is the backend quota etcd uses if none is configured.
+const DefaultQuotaBackendBytes = int64(2 * 1024 * 1024 * 1024)
+
+const (
+	defaultHeartbeatIntervalInMs = 100
+	defaultElectionTimeoutInMs   = 1000
+	maxElectionTimeoutInMs       = 50000
+)
+
+// GetQuotaBackendBytes returns the backend quota of the etcd members.
+func (ep *EtcdPolicy) GetQuotaBackendBytes() int64 {
+	if ep == nil || ep.QuotaBackendBytes == 0 {
+		return DefaultQuotaBackendBytes
+	}
+	return ep.QuotaBackendBytes
+}
+
+// GetMetricsPort returns the separate metrics port of the etcd members, or 0 if
+// metrics are served on the client port.
+func (ep *EtcdPolicy) GetMetricsPort() int {
+	if ep == nil {
+		return 0
+	}
+	return ep.MetricsPort
+}
+
+// Validate checks the etcd policy against the given etcd version.
+func (ep *EtcdPolicy) Validate(version string) error {
+	switch ep.AutoCompactionMode {
+	case "":
+	case AutoCompactionModePeriodic, AutoCompactionModeRevision:
+		if !versionAtLeast(version, "3.3.0") {
+			return fmt.Errorf("spec: etcd auto compaction mode needs etcd 3.3 or above, got version (%s)", version)
+		}
+	default:
+		return fmt.Errorf("spec: unknown etcd auto compaction mode (%s)", ep.AutoCompactionMode)
+	}
+
+	if ep.QuotaBackendBytes < 0 {
+		return errors.New("spec: etcd quota backend bytes must not be negative")
+	}
+
+	if len(ep.CorruptCheckTime) != 0 {
+		if !versionAtLeast(version, "3.3.0") {
+			return fmt.Errorf("spec: etcd corruption check needs etcd 3.3 or above, got version (%s)", version)
+		}
+		if d, err := time.ParseDuration(ep.CorruptCheckTime); err != nil || d <= 0 {
+			return fmt.Errorf("spec: invalid etcd corruption check time (%s)", ep.CorruptCheckTime)
+		}
+	}
+
+	if ep.MetricsPort != 0 {
+		if !versionAtLeast(version, "3.3.0") {
+			return fmt.Errorf("spec: etcd metrics port needs etcd 3.3 or above, got version (%s)", version)
+		}
+		if ep.MetricsPort < 0 || ep.MetricsPort > 65535 || ep.MetricsPort == 2379 || ep.MetricsPort == 2380 {
+			return fmt.Errorf("spec: invalid etcd metrics port (%d)", ep.MetricsPort)
+		}
+	}
+
+	switch ep.MetricsLevel {
+	case "":
+	case MetricsLevelBasic, MetricsLevelExtensive:
+		if !versionAtLeast(version, "3.3.0") {
+			return fmt.Errorf("spec: etcd metrics level needs etcd 3.3 or above, got version (%s)", version)
+		}
+	default:
+		return fmt.Errorf("spec: unknown etcd metrics level (%s)", ep.MetricsLevel)
+	}
+
+	if err := ep.validateRaftTiming(); err != nil {
+		return err
+	}
+
+	r := ep.AutoCompactionRetention
+	if len(r) == 0 {
+		return nil
+	}
+	if _, err := strconv.ParseUint(r, 10, 64); err == nil {
+		return nil
+	}
+	if _, err := time.ParseDuration(r); err == nil && ep.AutoCompactionMode != AutoCompactionModeRevision && versionAtLeast(version, "3.3.0") {
+		return nil
+	}
+	return fmt.Errorf("spec: invalid etcd auto compaction retention (%s)", r)
+}
+
+// validateRaftTiming checks the snapshot count, heartbeat interval and election timeout
+// against the bounds etcd enforces on start.
+func (ep *EtcdPolicy) validateRaftTiming() error {
+	if ep.SnapshotCount < 0 {
+		return errors.New("spec: etcd snapshot count must not be negative")
+	}
+	if ep.HeartbeatIntervalInMs < 0 || ep.ElectionTimeoutInMs < 0 {
+		return errors.New("spec: etcd heartbeat interval and election timeout must not be negative")
+	}
+
+	heartbeat, election := ep.HeartbeatIntervalInMs, ep.ElectionTimeoutInMs
+	if heartbeat == 0 {
+		heartbeat = defaultHeartbeatIntervalInMs
+	}
+	if election == 0 {
+		election = defaultElectionTimeoutInMs
+	}
+	if election < 5*heartbeat {
+		return fmt.Errorf("spec: etcd election timeout (%dms) must be at least 5 times the heartbeat interval (%dms)", election, heartbeat)
+	}
+	if election > maxElectionTimeoutInMs {
+		return fmt.Errorf("spec: etcd election timeout (%dms) must not exceed %dms", election, maxElectionTimeoutInMs)
+	}
+	return nil
+}
+
+// versionAtLeast returns true if the etcd version is not lower than min.
+func versionAtLeast(version, min string) bool {
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false
+	}
+	return !v.LessThan(*semver.New(min))
+}
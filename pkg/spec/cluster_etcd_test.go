@@ -0,0 +1,135 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import "testing"
+
+func TestEtcdPolicyValidateAutoCompaction(t *testing.T) {
+	tests := []struct {
+		version   string
+		mode      string
+		retention string
+		wErr      bool
+	}{
+		{version: "3.1.8", retention: "1", wErr: false},
+		{version: "3.1.8", retention: "30m", wErr: true},
+		{version: "3.1.8", mode: AutoCompactionModePeriodic, retention: "1", wErr: true},
+		{version: "3.3.0", mode: AutoCompactionModePeriodic, retention: "30m", wErr: false},
+		{version: "3.3.0", mode: AutoCompactionModeRevision, retention: "10000", wErr: false},
+		{version: "3.3.0", mode: AutoCompactionModeRevision, retention: "30m", wErr: true},
+		{version: "3.3.0", mode: "hourly", wErr: true},
+		{version: "3.3.0", retention: "-1", wErr: true},
+	}
+	for i, tt := range tests {
+		ep := &EtcdPolicy{AutoCompactionMode: tt.mode, AutoCompactionRetention: tt.retention}
+		err := ep.Validate(tt.version)
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+		}
+	}
+}
+
+func TestEtcdPolicyQuotaBackendBytes(t *testing.T) {
+	var ep *EtcdPolicy
+	if q := ep.GetQuotaBackendBytes(); q != DefaultQuotaBackendBytes {
+		t.Errorf("default quota get=%d, want=%d", q, DefaultQuotaBackendBytes)
+	}
+	ep = &EtcdPolicy{QuotaBackendBytes: 8 * 1024 * 1024 * 1024}
+	if q := ep.GetQuotaBackendBytes(); q != ep.QuotaBackendBytes {
+		t.Errorf("quota get=%d, want=%d", q, ep.QuotaBackendBytes)
+	}
+	if err := (&EtcdPolicy{QuotaBackendBytes: -1}).Validate("3.1.8"); err == nil {
+		t.Errorf("expect error on negative quota")
+	}
+}
+
+func TestEtcdPolicyValidateCorruptCheckTime(t *testing.T) {
+	tests := []struct {
+		version          string
+		corruptCheckTime string
+		wErr             bool
+	}{
+		{version: "3.3.0", corruptCheckTime: "1h", wErr: false},
+		{version: "3.1.8", corruptCheckTime: "1h", wErr: true},
+		{version: "3.3.0", corruptCheckTime: "1", wErr: true},
+		{version: "3.3.0", corruptCheckTime: "-1h", wErr: true},
+	}
+	for i, tt := range tests {
+		err := (&EtcdPolicy{CorruptCheckTime: tt.corruptCheckTime}).Validate(tt.version)
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+		}
+	}
+}
+
+func TestEtcdPolicyValidateRaftTiming(t *testing.T) {
+	tests := []struct {
+		ep   EtcdPolicy
+		wErr bool
+	}{
+		{ep: EtcdPolicy{SnapshotCount: 50000}, wErr: false},
+		{ep: EtcdPolicy{SnapshotCount: -1}, wErr: true},
+		{ep: EtcdPolicy{HeartbeatIntervalInMs: 200}, wErr: false},
+		{ep: EtcdPolicy{HeartbeatIntervalInMs: 500}, wErr: true},
+		{ep: EtcdPolicy{HeartbeatIntervalInMs: 500, ElectionTimeoutInMs: 5000}, wErr: false},
+		{ep: EtcdPolicy{ElectionTimeoutInMs: 400}, wErr: true},
+		{ep: EtcdPolicy{ElectionTimeoutInMs: 60000}, wErr: true},
+		{ep: EtcdPolicy{HeartbeatIntervalInMs: -1}, wErr: true},
+	}
+	for i, tt := range tests {
+		err := tt.ep.Validate("3.1.8")
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+		}
+	}
+}
+
+func TestEtcdPolicyValidateMetricsPort(t *testing.T) {
+	tests := []struct {
+		version string
+		port    int
+		wErr    bool
+	}{
+		{version: "3.3.0", port: 2381, wErr: false},
+		{version: "3.1.8", port: 2381, wErr: true},
+		{version: "3.3.0", port: 2379, wErr: true},
+		{version: "3.3.0", port: 70000, wErr: true},
+	}
+	for i, tt := range tests {
+		err := (&EtcdPolicy{MetricsPort: tt.port}).Validate(tt.version)
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+		}
+	}
+}
+
+func TestEtcdPolicyValidateMetricsLevel(t *testing.T) {
+	tests := []struct {
+		version string
+		level   string
+		wErr    bool
+	}{
+		{version: "3.3.0", level: MetricsLevelBasic, wErr: false},
+		{version: "3.3.0", level: MetricsLevelExtensive, wErr: false},
+		{version: "3.2.9", level: MetricsLevelExtensive, wErr: true},
+		{version: "3.3.0", level: "verbose", wErr: true},
+	}
+	for i, tt := range tests {
+		err := (&EtcdPolicy{MetricsLevel: tt.level}).Validate(tt.version)
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+		}
+	}
+}
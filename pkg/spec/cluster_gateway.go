@@ -0,0 +1,46 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import "fmt"
+
+const defaultGatewayPort = 23790
+
+// GatewayPolicy defines the etcd gateway DaemonSet deployed for the cluster.
+// The gateway runs in the host network of every node and forwards TCP connections
+// to the members of the cluster, so that clients on a node can use a fixed local endpoint.
+type GatewayPolicy struct {
+	// Port is the port the gateway listens on at 127.0.0.1 of each node.
+	// It is declared as host port, so gateways of clusters sharing nodes need distinct ports.
+	// Default: 23790
+	Port int `json:"port,omitempty"`
+
+	// Pod defines the policy to create the gateway pods.
+	Pod *PodPolicy `json:"pod,omitempty"`
+}
+
+func (gp *GatewayPolicy) Validate() error {
+	if gp.Port < 0 || gp.Port > 65535 {
+		return fmt.Errorf("spec: invalid gateway port (%d)", gp.Port)
+	}
+	return nil
+}
+
+func (gp *GatewayPolicy) GetPort() int {
+	if gp.Port == 0 {
+		return defaultGatewayPort
+	}
+	return gp.Port
+}
@@ -0,0 +1,53 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"errors"
+	"fmt"
+)
+
+// GRPCProxyPolicy defines the etcd gRPC proxy tier deployed in front of the cluster.
+// The proxies serve the etcd v3 API on the `${clusterName}-grpc-proxy` service. They
+// coalesce watches and cache serializable reads, so that read heavy clients scale
+// without adding voting members.
+type GRPCProxyPolicy struct {
+	// Size is the number of proxy replicas.
+	Size int `json:"size"`
+
+	// Pod defines the policy to create the proxy pods.
+	Pod *PodPolicy `json:"pod,omitempty"`
+
+	// ServerSecret is the secret with the certificate the proxies serve clients with,
+	// if the cluster serves clients over TLS. It has the layout of spec.TLS.static.member.clientSecret,
+	// and the certificate must be valid for `${clusterName}-grpc-proxy.${namespace}.svc.cluster.local`.
+	// It is required with client TLS, since the member certificates are not issued for the proxy service.
+	ServerSecret string `json:"serverSecret,omitempty"`
+}
+
+// Validate checks the proxy policy against the given etcd version and TLS policy.
+func (gp *GRPCProxyPolicy) Validate(version string, tls *TLSPolicy) error {
+	if gp.Size < 1 {
+		return errors.New("spec: grpc proxy size must be at least 1")
+	}
+	// The proxy serves TLS since etcd 3.2.
+	if tls.IsSecureClient() && !versionAtLeast(version, "3.2.0") {
+		return fmt.Errorf("spec: grpc proxy of a cluster with client TLS needs etcd 3.2 or above, got version (%s)", version)
+	}
+	if tls.IsSecureClient() && len(gp.ServerSecret) == 0 {
+		return errors.New("spec: grpc proxy of a cluster with client TLS needs a server secret for the proxy service")
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+const (
+	defaultEtcdRepository = "quay.io/coreos/etcd"
+
+	// archAMD64 images of the etcd releases have no architecture tag suffix.
+	archAMD64 = "amd64"
+)
+
+var imageDigestRegexp = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+
+// EtcdImagePolicy defines the image of the etcd containers and of the gRPC proxy, gateway and mirror pods.
+// The tag of the image is "v" followed by the cluster version.
+type EtcdImagePolicy struct {
+	// Repository is the repository of the etcd image.
+	// It must be a multi-arch image to run on nodes of different architectures.
+	// Default: "quay.io/coreos/etcd"
+	Repository string `json:"repository,omitempty"`
+
+	// Architecture is set if the etcd image is single-arch, e.g. "arm64".
+	// Pods are then required to run on nodes of the architecture.
+	// Unless ArchitectureRepositories overrides the repository, the image tag gets
+	// the architecture suffix of the etcd release images, e.g. "v3.2.0-arm64". amd64 images have no suffix.
+	Architecture string `json:"architecture,omitempty"`
+
+	// ArchitectureRepositories maps architectures to the repository of their single-arch etcd image.
+	// The repository of Architecture is used as is, without tag suffix.
+	ArchitectureRepositories map[string]string `json:"architectureRepositories,omitempty"`
+
+	// Digests maps etcd versions to the digest of their image, e.g. "sha256:...".
+	// Pods of a version with a digest pull the image by digest instead of by tag,
+	// so that a changed tag does not change the members.
+	Digests map[string]string `json:"digests,omitempty"`
+
+	// PinDigests lets the operator add the digest of the image the members of a version run
+	// to Digests once it observes one, so that later members pull the same image.
+	// The observed digests are reported in status.imageDigests either way.
+	PinDigests bool `json:"pinDigests,omitempty"`
+}
+
+func (ip *EtcdImagePolicy) Validate() error {
+	if len(ip.ArchitectureRepositories) != 0 && len(ip.Architecture) == 0 {
+		return errors.New("spec: etcd image architecture repositories need an architecture")
+	}
+	for v, d := range ip.Digests {
+		if !imageDigestRegexp.MatchString(d) {
+			return fmt.Errorf("spec: invalid etcd image digest (%s) of version %s", d, v)
+		}
+	}
+	return nil
+}
+
+// ImageName returns the etcd image of the given version.
+func (ip *EtcdImagePolicy) ImageName(version string) string {
+	if ip == nil {
+		return fmt.Sprintf("%s:v%s", defaultEtcdRepository, version)
+	}
+	repo, ok := ip.ArchitectureRepositories[ip.Architecture]
+	archRepo := ok && len(ip.Architecture) != 0
+	if !archRepo {
+		repo = ip.Repository
+		if len(repo) == 0 {
+			repo = defaultEtcdRepository
+		}
+	}
+	if d, ok := ip.Digests[version]; ok {
+		return fmt.Sprintf("%s@%s", repo, d)
+	}
+	if archRepo {
+		return fmt.Sprintf("%s:v%s", repo, version)
+	}
+	if len(ip.Architecture) == 0 || ip.Architecture == archAMD64 {
+		return fmt.Sprintf("%s:v%s", repo, version)
+	}
+	return fmt.Sprintf("%s:v%s-%s", repo, version, ip.Architecture)
+}
+
+// GetArchitecture returns the architecture of a single-arch etcd image, or an empty string.
+func (ip *EtcdImagePolicy) GetArchitecture() string {
+	if ip == nil {
+		return ""
+	}
+	return ip.Architecture
+}
@@ -0,0 +1,94 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEtcdImageName(t *testing.T) {
+	tests := []struct {
+		ip     *EtcdImagePolicy
+		wImage string
+	}{
+		{ip: nil, wImage: "quay.io/coreos/etcd:v3.2.0"},
+		{ip: &EtcdImagePolicy{}, wImage: "quay.io/coreos/etcd:v3.2.0"},
+		// multi-arch image
+		{ip: &EtcdImagePolicy{Repository: "example.com/etcd"}, wImage: "example.com/etcd:v3.2.0"},
+		{ip: &EtcdImagePolicy{Architecture: "amd64"}, wImage: "quay.io/coreos/etcd:v3.2.0"},
+		{ip: &EtcdImagePolicy{Architecture: "arm64"}, wImage: "quay.io/coreos/etcd:v3.2.0-arm64"},
+		{ip: &EtcdImagePolicy{Repository: "example.com/etcd", Architecture: "arm64"}, wImage: "example.com/etcd:v3.2.0-arm64"},
+		{
+			ip:     &EtcdImagePolicy{Architecture: "arm64", ArchitectureRepositories: map[string]string{"arm64": "example.com/etcd-arm64"}},
+			wImage: "example.com/etcd-arm64:v3.2.0",
+		},
+		// no override for the architecture
+		{
+			ip:     &EtcdImagePolicy{Architecture: "ppc64le", ArchitectureRepositories: map[string]string{"arm64": "example.com/etcd-arm64"}},
+			wImage: "quay.io/coreos/etcd:v3.2.0-ppc64le",
+		},
+	}
+	for i, tt := range tests {
+		if image := tt.ip.ImageName("3.2.0"); image != tt.wImage {
+			t.Errorf("#%d: image get=%s, want=%s", i, image, tt.wImage)
+		}
+	}
+}
+
+func TestEtcdImageNameDigest(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("ab", 32)
+	tests := []struct {
+		ip     *EtcdImagePolicy
+		wImage string
+	}{
+		{ip: &EtcdImagePolicy{Digests: map[string]string{"3.2.0": digest}}, wImage: "quay.io/coreos/etcd@" + digest},
+		{ip: &EtcdImagePolicy{Digests: map[string]string{"3.1.8": digest}}, wImage: "quay.io/coreos/etcd:v3.2.0"},
+		{ip: &EtcdImagePolicy{Architecture: "arm64", Digests: map[string]string{"3.2.0": digest}}, wImage: "quay.io/coreos/etcd@" + digest},
+		{
+			ip: &EtcdImagePolicy{
+				Architecture:             "arm64",
+				ArchitectureRepositories: map[string]string{"arm64": "example.com/etcd-arm64"},
+				Digests:                  map[string]string{"3.2.0": digest},
+			},
+			wImage: "example.com/etcd-arm64@" + digest,
+		},
+	}
+	for i, tt := range tests {
+		if image := tt.ip.ImageName("3.2.0"); image != tt.wImage {
+			t.Errorf("#%d: image get=%s, want=%s", i, image, tt.wImage)
+		}
+	}
+}
+
+func TestValidateEtcdImage(t *testing.T) {
+	tests := []struct {
+		ip   EtcdImagePolicy
+		wErr bool
+	}{
+		{ip: EtcdImagePolicy{Architecture: "arm64"}, wErr: false},
+		{ip: EtcdImagePolicy{Architecture: "arm64", ArchitectureRepositories: map[string]string{"arm64": "example.com/etcd-arm64"}}, wErr: false},
+		{ip: EtcdImagePolicy{ArchitectureRepositories: map[string]string{"arm64": "example.com/etcd-arm64"}}, wErr: true},
+		{ip: EtcdImagePolicy{Digests: map[string]string{"3.2.0": "sha256:" + strings.Repeat("0f", 32)}}, wErr: false},
+		{ip: EtcdImagePolicy{Digests: map[string]string{"3.2.0": "sha256:abc"}}, wErr: true},
+		{ip: EtcdImagePolicy{Digests: map[string]string{"3.2.0": "v3.2.0"}}, wErr: true},
+	}
+	for i, tt := range tests {
+		err := tt.ip.Validate()
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import "errors"
+
+// InitialDataPolicy defines the keys the operator puts into a cluster once it is running.
+// Keys which already exist, e.g. from a restored backup, are not overwritten.
+type InitialDataPolicy struct {
+	// Data maps keys to their values.
+	Data map[string]string `json:"data,omitempty"`
+
+	// ConfigMap is the key of a config map in the namespace of the cluster which holds
+	// a JSON object of keys to their values. Its keys take precedence over Data.
+	ConfigMap *SnapshotKeySelector `json:"configMap,omitempty"`
+}
+
+func (ip *InitialDataPolicy) Validate() error {
+	if len(ip.Data) == 0 && ip.ConfigMap == nil {
+		return errors.New("spec: initial data needs data or a config map")
+	}
+	if cm := ip.ConfigMap; cm != nil && (len(cm.Name) == 0 || len(cm.Key) == 0) {
+		return errors.New("spec: initial data config map name and key must be set")
+	}
+	for k := range ip.Data {
+		if len(k) == 0 {
+			return errors.New("spec: initial data keys must not be empty")
+		}
+	}
+	return nil
+}
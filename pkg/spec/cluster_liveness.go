@@ -0,0 +1,47 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import "fmt"
+
+type LivenessFailurePolicy string
+
+const (
+	// LivenessFailureReplaceMember lets the etcd pod fail once its liveness probe fails.
+	// The operator then removes the member and adds a new one, or recreates the pod
+	// on the data of the member if it has a PVC.
+	LivenessFailureReplaceMember LivenessFailurePolicy = "ReplaceMember"
+	// LivenessFailureRestartContainer lets the kubelet restart the etcd container in place
+	// once its liveness probe fails. The member keeps its data volume and its identity.
+	LivenessFailureRestartContainer LivenessFailurePolicy = "RestartContainer"
+)
+
+// GetLivenessFailurePolicy returns what happens to an etcd pod whose liveness probe fails.
+// Default: "ReplaceMember"
+func (pp *PodPolicy) GetLivenessFailurePolicy() LivenessFailurePolicy {
+	if pp == nil || len(pp.LivenessFailurePolicy) == 0 {
+		return LivenessFailureReplaceMember
+	}
+	return pp.LivenessFailurePolicy
+}
+
+func (pp *PodPolicy) validateLivenessFailurePolicy() error {
+	switch pp.GetLivenessFailurePolicy() {
+	case LivenessFailureReplaceMember, LivenessFailureRestartContainer:
+		return nil
+	default:
+		return fmt.Errorf("spec: unknown liveness failure policy (%s)", pp.LivenessFailurePolicy)
+	}
+}
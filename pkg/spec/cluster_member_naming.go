@@ -0,0 +1,45 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import "fmt"
+
+type MemberNamingScheme string
+
+const (
+	// MemberNamingOrdinal names members "${cluster-name}-${counter}", e.g. "example-0003".
+	// The counter is kept in status.memberCounter, so that names are never reused.
+	MemberNamingOrdinal MemberNamingScheme = "Ordinal"
+	// MemberNamingRandom names members "${cluster-name}-${random-suffix}", e.g. "example-x7kbq".
+	MemberNamingRandom MemberNamingScheme = "Random"
+)
+
+// GetMemberNaming returns the naming scheme of new members.
+// Default: "Ordinal"
+func (c *ClusterSpec) GetMemberNaming() MemberNamingScheme {
+	if len(c.MemberNaming) == 0 {
+		return MemberNamingOrdinal
+	}
+	return c.MemberNaming
+}
+
+func (c *ClusterSpec) validateMemberNaming() error {
+	switch c.GetMemberNaming() {
+	case MemberNamingOrdinal, MemberNamingRandom:
+	default:
+		return fmt.Errorf("spec: unknown member naming scheme (%s)", c.MemberNaming)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MirrorPolicy defines the continuous replication of the keys of the cluster
+// to another etcd cluster with `etcdctl make-mirror`.
+// Exactly one of DestinationCluster and DestinationEndpoint must be set.
+type MirrorPolicy struct {
+	// DestinationCluster is the name of the etcd cluster in the same namespace
+	// the keys are mirrored to.
+	DestinationCluster string `json:"destinationCluster,omitempty"`
+	// DestinationEndpoint is the client URL of the external etcd cluster
+	// the keys are mirrored to, e.g. "https://etcd.example.com:2379".
+	DestinationEndpoint string `json:"destinationEndpoint,omitempty"`
+	// DestinationTLSSecret is the secret with the client certificate to talk to the
+	// destination over TLS. It has the same layout as the operator secret:
+	// etcd-crt.pem, etcd-key.pem and etcd-ca-crt.pem.
+	DestinationTLSSecret string `json:"destinationTLSSecret,omitempty"`
+
+	// Prefix restricts the mirror to the keys with the prefix.
+	// Default: all keys
+	Prefix string `json:"prefix,omitempty"`
+	// DestinationPrefix replaces Prefix in the keys written to the destination.
+	// It needs etcd 3.2 and above.
+	DestinationPrefix string `json:"destinationPrefix,omitempty"`
+
+	// HeartbeatKey opts into measuring the lag of the mirror: the operator writes the current time
+	// to the key on every reconcile and reads it back from the destination. It must start with Prefix,
+	// so that it is mirrored, and be reserved for the operator, since it is overwritten.
+	// Default: the lag is not measured
+	HeartbeatKey string `json:"heartbeatKey,omitempty"`
+
+	// Pod defines the policy to create the mirror pod.
+	Pod *PodPolicy `json:"pod,omitempty"`
+}
+
+func (mp *MirrorPolicy) Validate(version string) error {
+	if len(mp.DestinationCluster) == 0 && len(mp.DestinationEndpoint) == 0 {
+		return errors.New("spec: mirror needs either a destination cluster or a destination endpoint")
+	}
+	if len(mp.DestinationCluster) != 0 && len(mp.DestinationEndpoint) != 0 {
+		return errors.New("spec: mirror destination cluster and destination endpoint are mutually exclusive")
+	}
+	if len(mp.HeartbeatKey) != 0 && !strings.HasPrefix(mp.HeartbeatKey, mp.Prefix) {
+		return fmt.Errorf("spec: mirror heartbeat key (%s) must start with the prefix (%s)", mp.HeartbeatKey, mp.Prefix)
+	}
+	if len(mp.DestinationPrefix) != 0 && !versionAtLeast(version, "3.2.0") {
+		return fmt.Errorf("spec: mirror destination prefix needs etcd 3.2 or above, got version (%s)", version)
+	}
+	return nil
+}
+
+// DestinationKey returns the key the given source key is mirrored to.
+func (mp *MirrorPolicy) DestinationKey(key string) string {
+	if len(mp.DestinationPrefix) == 0 {
+		return key
+	}
+	return mp.DestinationPrefix + key[len(mp.Prefix):]
+}
@@ -0,0 +1,59 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+	"time"
+)
+
+// ServiceMonitorPolicy defines the ServiceMonitor of the Prometheus Operator
+// which the operator creates for the cluster, so that Prometheus scrapes the
+// metrics of all members.
+type ServiceMonitorPolicy struct {
+	// Labels are added to the ServiceMonitor, so that the serviceMonitorSelector
+	// of a Prometheus picks it up.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Interval is the scrape interval, e.g. "30s".
+	// If not set, the scrape interval of Prometheus is used.
+	Interval string `json:"interval,omitempty"`
+
+	// TLSSecretDir is the directory in the Prometheus pods where the operator secret
+	// of the cluster (TLS.static.operatorSecret) is mounted. It is only used if the
+	// cluster serves clients over TLS. If not set, it is the directory the Prometheus
+	// Operator mounts the secret in when it is listed in the `secrets` of the Prometheus.
+	TLSSecretDir string `json:"tlsSecretDir,omitempty"`
+}
+
+func (sp *ServiceMonitorPolicy) Validate() error {
+	if len(sp.Interval) == 0 {
+		return nil
+	}
+	if d, err := time.ParseDuration(sp.Interval); err != nil || d <= 0 {
+		return fmt.Errorf("spec: invalid service monitor interval (%s)", sp.Interval)
+	}
+	return nil
+}
+
+// PrometheusRulePolicy defines the PrometheusRule of the Prometheus Operator
+// which the operator creates for the cluster with the recommended etcd alerts:
+// no leader, high WAL fsync durations, database near the backend quota and,
+// if backup is enabled, stale backups.
+type PrometheusRulePolicy struct {
+	// Labels are added to the PrometheusRule, so that the ruleSelector of a
+	// Prometheus picks it up.
+	Labels map[string]string `json:"labels,omitempty"`
+}
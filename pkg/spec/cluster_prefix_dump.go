@@ -0,0 +1,91 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ExportPolicy defines the keys the operator dumps to the operator wide S3 bucket
+// when the cluster is annotated with TriggerExportAnnotation.
+// Only the keys and values are dumped: leases, revisions and the history of the keys are not.
+type ExportPolicy struct {
+	// Prefixes are the key prefixes whose keys are dumped, at one revision of the cluster.
+	Prefixes []string `json:"prefixes"`
+
+	// Object is the name of the dump in the bucket, e.g. "team-a/config".
+	// An earlier dump of the same name is replaced.
+	Object string `json:"object"`
+}
+
+func (ep *ExportPolicy) Validate() error {
+	if len(ep.Prefixes) == 0 {
+		return errors.New("spec: export needs at least one prefix")
+	}
+	if err := validatePrefixes(ep.Prefixes); err != nil {
+		return fmt.Errorf("spec: export: %v", err)
+	}
+	if err := validateDumpObject(ep.Object); err != nil {
+		return fmt.Errorf("spec: export: %v", err)
+	}
+	return nil
+}
+
+// ImportPolicy defines the dump the operator loads from the operator wide S3 bucket
+// when the cluster is annotated with TriggerImportAnnotation.
+// Keys deleted from the exporting cluster since the dump are not deleted.
+type ImportPolicy struct {
+	// Object is the name of the dump in the bucket, as set in spec.export.object of the exporting cluster.
+	Object string `json:"object"`
+
+	// Prefixes restrict the loaded keys to the ones under any of the prefixes, if set.
+	Prefixes []string `json:"prefixes,omitempty"`
+
+	// Overwrite replaces the values of keys which already exist in the cluster.
+	// By default, existing keys are kept, so that an import does not revert newer writes.
+	Overwrite bool `json:"overwrite,omitempty"`
+}
+
+func (ip *ImportPolicy) Validate() error {
+	if err := validatePrefixes(ip.Prefixes); err != nil {
+		return fmt.Errorf("spec: import: %v", err)
+	}
+	if err := validateDumpObject(ip.Object); err != nil {
+		return fmt.Errorf("spec: import: %v", err)
+	}
+	return nil
+}
+
+func validatePrefixes(prefixes []string) error {
+	for _, p := range prefixes {
+		if len(p) == 0 {
+			return errors.New("prefixes must not be empty")
+		}
+	}
+	return nil
+}
+
+func validateDumpObject(object string) error {
+	if len(object) == 0 {
+		return errors.New("object must be set")
+	}
+	if path.IsAbs(object) || path.Clean(object) != object || object == ".." || strings.HasPrefix(object, "../") {
+		return fmt.Errorf("object (%s) must be a clean relative path", object)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// ProfileSpecKey is the key of the cluster spec in the data of a profile config map.
+const ProfileSpecKey = "spec"
+
+// ApplyProfile fills the fields of the spec which the cluster does not set from the given profile spec
+// in YAML or JSON. Nested policies are merged field by field. Whether a field is set is read from the JSON
+// the cluster was decoded from, so that explicit zero values, e.g. "paused: false", win over the profile.
+// For a cluster which was not decoded, the fields with their zero value count as not set.
+func (c *Cluster) ApplyProfile(profile string) error {
+	var p map[string]interface{}
+	if err := yaml.NewYAMLOrJSONDecoder(strings.NewReader(profile), 4096).Decode(&p); err != nil {
+		return fmt.Errorf("invalid profile: %v", err)
+	}
+	// A profile cannot refer to another profile.
+	delete(p, "profile")
+
+	decoded := len(c.rawSpec) != 0
+	b := []byte(c.rawSpec)
+	if !decoded {
+		var err error
+		if b, err = json.Marshal(&c.Spec); err != nil {
+			return err
+		}
+	}
+	own := map[string]interface{}{}
+	if err := json.Unmarshal(b, &own); err != nil {
+		return err
+	}
+	if !decoded {
+		dropZeroJSONValues(own)
+	}
+	b, err := json.Marshal(mergeProfile(own, p))
+	if err != nil {
+		return err
+	}
+	merged := ClusterSpec{}
+	if err := json.Unmarshal(b, &merged); err != nil {
+		return fmt.Errorf("invalid profile: %v", err)
+	}
+	c.Spec = merged
+	c.rawSpec = b
+	return nil
+}
+
+// mergeProfile sets the keys which own does not set, or sets to null, to the values of profile.
+func mergeProfile(own, profile map[string]interface{}) map[string]interface{} {
+	for k, pv := range profile {
+		ov, ok := own[k]
+		if !ok || ov == nil {
+			own[k] = pv
+			continue
+		}
+		om, ok1 := ov.(map[string]interface{})
+		pm, ok2 := pv.(map[string]interface{})
+		if ok1 && ok2 {
+			own[k] = mergeProfile(om, pm)
+		}
+	}
+	return own
+}
+
+// dropZeroJSONValues deletes the keys with a zero value from m and its nested objects.
+func dropZeroJSONValues(m map[string]interface{}) {
+	for k, v := range m {
+		if nested, ok := v.(map[string]interface{}); ok {
+			dropZeroJSONValues(nested)
+		}
+		if isZeroJSONValue(v) {
+			delete(m, k)
+		}
+	}
+}
+
+func isZeroJSONValue(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	case float64:
+		return v == 0
+	case string:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
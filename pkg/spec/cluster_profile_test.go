@@ -0,0 +1,103 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestApplyProfile(t *testing.T) {
+	profile := `
+size: 5
+version: 3.1.10
+paused: true
+backup:
+  storageType: S3
+  maxBackups: 5
+  backupIntervalInSecond: 600
+pod:
+  nodeSelector:
+    pool: etcd
+`
+	tests := []struct {
+		sp string
+		w  ClusterSpec
+	}{{
+		sp: `{"profile": "golden"}`,
+		w: ClusterSpec{
+			Profile: "golden",
+			Size:    5,
+			Version: "3.1.10",
+			Paused:  true,
+			Backup:  &BackupPolicy{StorageType: BackupStorageTypeS3, MaxBackups: 5, BackupIntervalInSecond: 600},
+			Pod:     &PodPolicy{NodeSelector: map[string]string{"pool": "etcd"}},
+		},
+	}, {
+		// set fields and nested fields of the cluster win.
+		sp: `{"profile": "golden", "size": 3, "backup": {"maxBackups": 10}, "pod": {"nodeSelector": {"pool": "db"}}}`,
+		w: ClusterSpec{
+			Profile: "golden",
+			Size:    3,
+			Version: "3.1.10",
+			Paused:  true,
+			Backup:  &BackupPolicy{StorageType: BackupStorageTypeS3, MaxBackups: 10, BackupIntervalInSecond: 600},
+			Pod:     &PodPolicy{NodeSelector: map[string]string{"pool": "db"}},
+		},
+	}, {
+		// fields set to their zero value win too; null fields do not.
+		sp: `{"profile": "golden", "paused": false, "backup": {"maxBackups": 0}, "pod": null}`,
+		w: ClusterSpec{
+			Profile: "golden",
+			Size:    5,
+			Version: "3.1.10",
+			Backup:  &BackupPolicy{StorageType: BackupStorageTypeS3, BackupIntervalInSecond: 600},
+			Pod:     &PodPolicy{NodeSelector: map[string]string{"pool": "etcd"}},
+		},
+	}}
+	for i, tt := range tests {
+		cl := Cluster{}
+		if err := json.Unmarshal([]byte(`{"spec": `+tt.sp+`}`), &cl); err != nil {
+			t.Fatalf("#%d: failed to decode cluster: %v", i, err)
+		}
+		if err := cl.ApplyProfile(profile); err != nil {
+			t.Fatalf("#%d: unexpected error: %v", i, err)
+		}
+		if !reflect.DeepEqual(cl.Spec, tt.w) {
+			t.Errorf("#%d: spec get=%+v, want=%+v", i, cl.Spec, tt.w)
+		}
+	}
+}
+
+func TestApplyProfileNotDecoded(t *testing.T) {
+	cl := Cluster{Spec: ClusterSpec{Profile: "golden", Version: "3.2.0"}}
+	if err := cl.ApplyProfile("size: 5\nversion: 3.1.10\n"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w := ClusterSpec{Profile: "golden", Size: 5, Version: "3.2.0"}
+	if !reflect.DeepEqual(cl.Spec, w) {
+		t.Errorf("spec get=%+v, want=%+v", cl.Spec, w)
+	}
+}
+
+func TestApplyProfileInvalid(t *testing.T) {
+	for i, profile := range []string{"size: [", "size: three"} {
+		cl := Cluster{Spec: ClusterSpec{Profile: "broken"}}
+		if err := cl.ApplyProfile(profile); err == nil {
+			t.Errorf("#%d: expected error for profile %q", i, profile)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// SeedSnapshotPolicy defines the etcd v3 snapshot a new cluster is bootstrapped from.
+// Exactly one source must be set. It is meant for small seed datasets and air-gapped
+// environments without backup storage.
+type SeedSnapshotPolicy struct {
+	// Secret is the key of a secret in the namespace of the cluster which holds the snapshot.
+	Secret *SnapshotKeySelector `json:"secret,omitempty"`
+	// ConfigMap is the key of a config map in the namespace of the cluster which holds
+	// the base64 encoded snapshot, since config maps only hold text.
+	ConfigMap *SnapshotKeySelector `json:"configMap,omitempty"`
+	// URL is the http or https URL to download the snapshot from.
+	URL string `json:"url,omitempty"`
+
+	// SHA256 is the hex encoded SHA-256 checksum of the snapshot.
+	// If set, the seed member fails to start if the snapshot does not match it.
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// SnapshotKeySelector selects a key of a secret or config map.
+type SnapshotKeySelector struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+}
+
+func (sp *SeedSnapshotPolicy) Validate() error {
+	n := 0
+	if sp.Secret != nil {
+		n++
+		if len(sp.Secret.Name) == 0 || len(sp.Secret.Key) == 0 {
+			return errors.New("spec: seed snapshot secret name and key must be set")
+		}
+	}
+	if sp.ConfigMap != nil {
+		n++
+		if len(sp.ConfigMap.Name) == 0 || len(sp.ConfigMap.Key) == 0 {
+			return errors.New("spec: seed snapshot config map name and key must be set")
+		}
+	}
+	if len(sp.URL) != 0 {
+		n++
+		u, err := url.Parse(sp.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+			return errors.New("spec: seed snapshot URL must be an http or https URL")
+		}
+		if strings.ContainsAny(sp.URL, "'\"` \t\n") {
+			return errors.New("spec: seed snapshot URL must not contain quotes or whitespace")
+		}
+	}
+	if n != 1 {
+		return errors.New("spec: exactly one of seed snapshot secret, config map or URL must be set")
+	}
+	if len(sp.SHA256) != 0 {
+		if b, err := hex.DecodeString(sp.SHA256); err != nil || len(b) != 32 {
+			return errors.New("spec: seed snapshot sha256 must be a hex encoded SHA-256 checksum")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"errors"
+	"fmt"
+)
+
+type ServiceMeshType string
+
+const (
+	ServiceMeshIstio   ServiceMeshType = "Istio"
+	ServiceMeshLinkerd ServiceMeshType = "Linkerd"
+)
+
+// ServiceMeshPolicy defines how the etcd pods of the cluster get along with a service mesh
+// which injects sidecars into the pods of the namespace.
+// Raft peer traffic between the members must not be intercepted by the mesh sidecars:
+// the sidecars start after etcd and are stopped before it, and a sidecar holding the
+// peer connections of a member breaks the quorum of the cluster.
+type ServiceMeshPolicy struct {
+	// Type is the service mesh of the namespace: "Istio" or "Linkerd".
+	Type ServiceMeshType `json:"type"`
+
+	// DisableInjection keeps the mesh from injecting a sidecar into the etcd pods at all.
+	DisableInjection bool `json:"disableInjection,omitempty"`
+
+	// ExcludeClientTraffic lets client traffic on port 2379 bypass the sidecar as well,
+	// e.g. if the cluster serves clients over its own TLS.
+	// Clients inside the mesh then need to bypass their sidecars for port 2379 too.
+	// Peer traffic on port 2380 always bypasses the sidecar.
+	ExcludeClientTraffic bool `json:"excludeClientTraffic,omitempty"`
+}
+
+func (sp *ServiceMeshPolicy) Validate() error {
+	switch sp.Type {
+	case ServiceMeshIstio, ServiceMeshLinkerd:
+	default:
+		return fmt.Errorf("spec: unknown service mesh type (%s)", sp.Type)
+	}
+	if sp.DisableInjection && sp.ExcludeClientTraffic {
+		return errors.New("spec: service mesh excludeClientTraffic has no effect with disableInjection")
+	}
+	return nil
+}
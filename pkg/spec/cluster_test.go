@@ -0,0 +1,676 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+func TestValidatePodResources(t *testing.T) {
+	tests := []struct {
+		requests, limits string
+		wErr             bool
+	}{
+		{requests: "100Mi", limits: "200Mi", wErr: false},
+		{requests: "200Mi", limits: "200Mi", wErr: false},
+		{requests: "300Mi", limits: "200Mi", wErr: true},
+	}
+	for i, tt := range tests {
+		cs := &ClusterSpec{
+			Pod: &PodPolicy{
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceMemory: resource.MustParse(tt.requests)},
+					Limits:   v1.ResourceList{v1.ResourceMemory: resource.MustParse(tt.limits)},
+				},
+			},
+		}
+		err := cs.Validate()
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+		}
+	}
+}
+
+func TestValidatePodImagePullPolicy(t *testing.T) {
+	tests := []struct {
+		policy v1.PullPolicy
+		wErr   bool
+	}{
+		{policy: "", wErr: false},
+		{policy: v1.PullAlways, wErr: false},
+		{policy: v1.PullIfNotPresent, wErr: false},
+		{policy: v1.PullNever, wErr: false},
+		{policy: "Sometimes", wErr: true},
+	}
+	for i, tt := range tests {
+		cs := &ClusterSpec{Pod: &PodPolicy{ImagePullPolicy: tt.policy}}
+		err := cs.Validate()
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+		}
+	}
+}
+
+func TestValidatePodContainers(t *testing.T) {
+	tests := []struct {
+		initContainers []string
+		containers     []string
+		volumes        []string
+		wErr           bool
+	}{
+		{initContainers: []string{"wait-for-vault"}, containers: []string{"log-shipper"}, wErr: false},
+		{initContainers: []string{"check-dns"}, wErr: true},
+		{initContainers: []string{"log-shipper"}, containers: []string{"log-shipper"}, wErr: true},
+		{containers: []string{"log-shipper"}, volumes: []string{"shipper-config"}, wErr: false},
+		{containers: []string{"etcd"}, wErr: true},
+		{containers: []string{"log-shipper", "log-shipper"}, wErr: true},
+		{volumes: []string{"etcd-data"}, wErr: true},
+		{volumes: []string{"shipper-config", "shipper-config"}, wErr: true},
+	}
+	for i, tt := range tests {
+		pp := &PodPolicy{}
+		for _, n := range tt.initContainers {
+			pp.InitContainers = append(pp.InitContainers, v1.Container{Name: n})
+		}
+		for _, n := range tt.containers {
+			pp.Sidecars = append(pp.Sidecars, v1.Container{Name: n})
+		}
+		for _, n := range tt.volumes {
+			pp.SidecarVolumes = append(pp.SidecarVolumes, v1.Volume{Name: n})
+		}
+		err := (&ClusterSpec{Pod: pp}).Validate()
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+		}
+	}
+}
+
+func TestValidatePodEtcdEnv(t *testing.T) {
+	tests := []struct {
+		name string
+		wErr bool
+	}{
+		{name: "ETCD_AUTO_COMPACTION_RETENTION", wErr: false},
+		{name: "GODEBUG", wErr: false},
+		{name: "ETCD_INITIAL_CLUSTER", wErr: true},
+		{name: "ETCD_DATA_DIR", wErr: true},
+	}
+	for i, tt := range tests {
+		cs := &ClusterSpec{Pod: &PodPolicy{EtcdEnv: []v1.EnvVar{{Name: tt.name, Value: "1"}}}}
+		err := cs.Validate()
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+		}
+	}
+}
+
+func TestValidatePodEtcdEnvWithEtcdctlAuth(t *testing.T) {
+	tests := []struct {
+		name       string
+		authSecret string
+		wErr       bool
+	}{
+		{name: "ETCDCTL_USER", authSecret: "", wErr: false},
+		{name: "ETCDCTL_USER", authSecret: "etcd-probe-user", wErr: true},
+		{name: "ETCDCTL_PASSWORD", authSecret: "etcd-probe-user", wErr: true},
+		{name: "GODEBUG", authSecret: "etcd-probe-user", wErr: false},
+	}
+	for i, tt := range tests {
+		cs := &ClusterSpec{Pod: &PodPolicy{EtcdctlAuthSecret: tt.authSecret, EtcdEnv: []v1.EnvVar{{Name: tt.name, Value: "1"}}}}
+		err := cs.Validate()
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+		}
+	}
+}
+
+func TestValidatePodTerminationGracePeriod(t *testing.T) {
+	tests := []struct {
+		gracePeriod int64
+		wErr        bool
+	}{
+		{gracePeriod: 0, wErr: false},
+		{gracePeriod: 120, wErr: false},
+		{gracePeriod: -1, wErr: true},
+	}
+	for i, tt := range tests {
+		cs := &ClusterSpec{Pod: &PodPolicy{TerminationGracePeriodSeconds: &tt.gracePeriod}}
+		err := cs.Validate()
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+		}
+	}
+}
+
+func TestValidatePodPersistentVolumeClaimSpec(t *testing.T) {
+	storage := v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse("1Gi")}}
+	tests := []struct {
+		cs   ClusterSpec
+		wErr bool
+	}{
+		{cs: ClusterSpec{Pod: &PodPolicy{PersistentVolumeClaimSpec: &v1.PersistentVolumeClaimSpec{Resources: storage}}}, wErr: false},
+		{cs: ClusterSpec{Pod: &PodPolicy{PersistentVolumeClaimSpec: &v1.PersistentVolumeClaimSpec{}}}, wErr: true},
+		{cs: ClusterSpec{
+			Pod:        &PodPolicy{PersistentVolumeClaimSpec: &v1.PersistentVolumeClaimSpec{Resources: storage}},
+			SelfHosted: &SelfHostedPolicy{},
+		}, wErr: true},
+	}
+	for i, tt := range tests {
+		err := tt.cs.Validate()
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+		}
+	}
+}
+
+func TestSetDegradedCondition(t *testing.T) {
+	cs := &ClusterStatus{}
+	cs.SetReadyCondition()
+	cs.SetDegradedCondition("corrupted")
+	cs.SetDegradedCondition("corrupted")
+	if len(cs.Conditions) != 2 || !cs.IsDegraded() {
+		t.Fatalf("expect one degraded condition after ready, get=%v", cs.Conditions)
+	}
+	cs.SetReadyCondition()
+	if cs.IsDegraded() {
+		t.Errorf("expect cluster not degraded after ready, get=%v", cs.Conditions)
+	}
+}
+
+func TestSetThrottledCondition(t *testing.T) {
+	cs := &ClusterStatus{}
+	cs.SetThrottledCondition(3, 7)
+	cs.SetThrottledCondition(3, 7)
+	if len(cs.Conditions) != 1 {
+		t.Fatalf("expect one throttled condition at the same progress, get=%v", cs.Conditions)
+	}
+	cs.SetThrottledCondition(4, 7)
+	if len(cs.Conditions) != 2 || cs.Conditions[1].Reason != "pod creation throttled: "+scalingReason(4, 7) {
+		t.Errorf("expect a throttled condition for the new progress, get=%v", cs.Conditions)
+	}
+}
+
+func TestSetRestoreFailedCondition(t *testing.T) {
+	cs := &ClusterStatus{}
+	cs.SetRestoreFailedCondition("container restore-datadir: Error (exit code 1)")
+	cs.SetRestoreFailedCondition("container restore-datadir: Error (exit code 1)")
+	if len(cs.Conditions) != 1 || cs.Conditions[0].Type != ClusterConditionRestoreFailed {
+		t.Fatalf("expect one restore failed condition for the same reason, get=%v", cs.Conditions)
+	}
+	cs.SetRestoreFailedCondition("DeadlineExceeded")
+	if len(cs.Conditions) != 2 || cs.Conditions[1].Reason != "DeadlineExceeded" {
+		t.Errorf("expect a restore failed condition for the new reason, get=%v", cs.Conditions)
+	}
+}
+
+func TestMirrorDestinationKey(t *testing.T) {
+	tests := []struct {
+		mp   MirrorPolicy
+		key  string
+		wKey string
+	}{
+		{mp: MirrorPolicy{}, key: "/a/b", wKey: "/a/b"},
+		{mp: MirrorPolicy{Prefix: "/a/"}, key: "/a/b", wKey: "/a/b"},
+		{mp: MirrorPolicy{Prefix: "/a/", DestinationPrefix: "/c/"}, key: "/a/b", wKey: "/c/b"},
+		{mp: MirrorPolicy{DestinationPrefix: "/c"}, key: "/a/b", wKey: "/c/a/b"},
+	}
+	for i, tt := range tests {
+		if k := tt.mp.DestinationKey(tt.key); k != tt.wKey {
+			t.Errorf("#%d: destination key get=%s, want=%s", i, k, tt.wKey)
+		}
+	}
+}
+
+func TestValidateMirror(t *testing.T) {
+	tests := []struct {
+		mp      MirrorPolicy
+		version string
+		wErr    bool
+	}{
+		{mp: MirrorPolicy{DestinationCluster: "backup"}, version: "3.1.8", wErr: false},
+		{mp: MirrorPolicy{DestinationEndpoint: "https://etcd.example.com:2379"}, version: "3.1.8", wErr: false},
+		{mp: MirrorPolicy{DestinationCluster: "backup", Prefix: "/a/", HeartbeatKey: "/a/heartbeat"}, version: "3.1.8", wErr: false},
+		{mp: MirrorPolicy{DestinationCluster: "backup", Prefix: "/a/", HeartbeatKey: "/b/heartbeat"}, version: "3.1.8", wErr: true},
+		{mp: MirrorPolicy{}, version: "3.1.8", wErr: true},
+		{mp: MirrorPolicy{DestinationCluster: "backup", DestinationEndpoint: "http://etcd:2379"}, version: "3.1.8", wErr: true},
+		{mp: MirrorPolicy{DestinationCluster: "backup", DestinationPrefix: "/c/"}, version: "3.1.8", wErr: true},
+		{mp: MirrorPolicy{DestinationCluster: "backup", DestinationPrefix: "/c/"}, version: "3.2.0", wErr: false},
+	}
+	for i, tt := range tests {
+		err := tt.mp.Validate(tt.version)
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+		}
+	}
+}
+
+func TestValidatePrefixDumps(t *testing.T) {
+	tests := []struct {
+		ep   *ExportPolicy
+		ip   *ImportPolicy
+		wErr bool
+	}{
+		{ep: &ExportPolicy{Prefixes: []string{"/a/"}, Object: "team-a/config"}, wErr: false},
+		{ep: &ExportPolicy{Object: "team-a/config"}, wErr: true},
+		{ep: &ExportPolicy{Prefixes: []string{""}, Object: "team-a/config"}, wErr: true},
+		{ep: &ExportPolicy{Prefixes: []string{"/a/"}}, wErr: true},
+		{ep: &ExportPolicy{Prefixes: []string{"/a/"}, Object: "/team-a/config"}, wErr: true},
+		{ep: &ExportPolicy{Prefixes: []string{"/a/"}, Object: "../config"}, wErr: true},
+		{ip: &ImportPolicy{Object: "team-a/config"}, wErr: false},
+		{ip: &ImportPolicy{Object: "team-a/config", Prefixes: []string{"/a/b/"}, Overwrite: true}, wErr: false},
+		{ip: &ImportPolicy{Object: "team-a/config", Prefixes: []string{""}}, wErr: true},
+		{ip: &ImportPolicy{Object: "team-a/./config"}, wErr: true},
+	}
+	for i, tt := range tests {
+		var err error
+		if tt.ep != nil {
+			err = tt.ep.Validate()
+		} else {
+			err = tt.ip.Validate()
+		}
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+		}
+	}
+}
+
+func TestValidateV2Migration(t *testing.T) {
+	tests := []struct {
+		mp   V2MigrationPolicy
+		wErr bool
+	}{
+		{mp: V2MigrationPolicy{PersistentVolumeClaimName: "etcd2"}, wErr: false},
+		{mp: V2MigrationPolicy{PersistentVolumeClaimName: "etcd2", DataDir: "default.etcd"}, wErr: false},
+		{mp: V2MigrationPolicy{}, wErr: true},
+		{mp: V2MigrationPolicy{PersistentVolumeClaimName: "etcd2", DataDir: "/var/lib/etcd2"}, wErr: true},
+		{mp: V2MigrationPolicy{PersistentVolumeClaimName: "etcd2", DataDir: "../etcd2"}, wErr: true},
+	}
+	for i, tt := range tests {
+		err := tt.mp.Validate()
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+		}
+	}
+}
+
+func TestValidateSeedSnapshot(t *testing.T) {
+	sum := strings.Repeat("ab", 32)
+	tests := []struct {
+		sp   SeedSnapshotPolicy
+		wErr bool
+	}{
+		{sp: SeedSnapshotPolicy{Secret: &SnapshotKeySelector{Name: "seed", Key: "snapshot.db"}}, wErr: false},
+		{sp: SeedSnapshotPolicy{ConfigMap: &SnapshotKeySelector{Name: "seed", Key: "snapshot.db"}, SHA256: sum}, wErr: false},
+		{sp: SeedSnapshotPolicy{URL: "https://example.com/snapshot.db", SHA256: sum}, wErr: false},
+		{sp: SeedSnapshotPolicy{}, wErr: true},
+		{sp: SeedSnapshotPolicy{Secret: &SnapshotKeySelector{Name: "seed"}}, wErr: true},
+		{sp: SeedSnapshotPolicy{Secret: &SnapshotKeySelector{Name: "seed", Key: "snapshot.db"}, URL: "https://example.com/snapshot.db"}, wErr: true},
+		{sp: SeedSnapshotPolicy{URL: "s3://bucket/snapshot.db"}, wErr: true},
+		{sp: SeedSnapshotPolicy{URL: "https://example.com/snapshot.db'; rm -rf /var/etcd; '"}, wErr: true},
+		{sp: SeedSnapshotPolicy{URL: "https://example.com/snapshot.db", SHA256: "abcd"}, wErr: true},
+	}
+	for i, tt := range tests {
+		err := tt.sp.Validate()
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+		}
+	}
+}
+
+func TestValidateInitialData(t *testing.T) {
+	tests := []struct {
+		ip   InitialDataPolicy
+		wErr bool
+	}{
+		{ip: InitialDataPolicy{Data: map[string]string{"/app/mode": "prod"}}, wErr: false},
+		{ip: InitialDataPolicy{ConfigMap: &SnapshotKeySelector{Name: "app-config", Key: "data.json"}}, wErr: false},
+		{ip: InitialDataPolicy{}, wErr: true},
+		{ip: InitialDataPolicy{ConfigMap: &SnapshotKeySelector{Name: "app-config"}}, wErr: true},
+		{ip: InitialDataPolicy{Data: map[string]string{"": "prod"}}, wErr: true},
+	}
+	for i, tt := range tests {
+		err := tt.ip.Validate()
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+		}
+	}
+}
+
+func TestValidateNFSBackup(t *testing.T) {
+	tests := []struct {
+		nfs  *NFSSource
+		wErr bool
+	}{
+		{nfs: &NFSSource{Server: "nfs.example.com", Path: "/exports/etcd"}, wErr: false},
+		{nfs: nil, wErr: true},
+		{nfs: &NFSSource{Path: "/exports/etcd"}, wErr: true},
+		{nfs: &NFSSource{Server: "nfs.example.com", Path: "exports/etcd"}, wErr: true},
+	}
+	for i, tt := range tests {
+		bp := &BackupPolicy{StorageType: BackupStorageTypeNFS, StorageSource: StorageSource{NFS: tt.nfs}}
+		err := bp.Validate()
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+		}
+	}
+}
+
+func TestValidateS3Backup(t *testing.T) {
+	tests := []struct {
+		proxy *S3Proxy
+		wErr  bool
+	}{
+		{proxy: nil, wErr: false},
+		{proxy: &S3Proxy{HTTPSProxy: "http://proxy.example.com:3128", NoProxy: ".svc,.cluster.local"}, wErr: false},
+		{proxy: &S3Proxy{HTTPProxy: "proxy.example.com:3128"}, wErr: true},
+		{proxy: &S3Proxy{HTTPSProxy: "socks5://proxy.example.com:1080"}, wErr: true},
+	}
+	for i, tt := range tests {
+		bp := &BackupPolicy{StorageType: BackupStorageTypeS3, StorageSource: StorageSource{S3: &S3Source{Proxy: tt.proxy}}}
+		err := bp.Validate()
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+		}
+	}
+}
+
+func TestValidateClientService(t *testing.T) {
+	tests := []struct {
+		sp   ClientServicePolicy
+		wErr bool
+	}{
+		{sp: ClientServicePolicy{}, wErr: false},
+		{sp: ClientServicePolicy{SessionAffinity: v1.ServiceAffinityClientIP, TopologyAwareHints: true}, wErr: false},
+		{sp: ClientServicePolicy{SessionAffinity: "Sticky"}, wErr: true},
+		{sp: ClientServicePolicy{Type: v1.ServiceTypeLoadBalancer, ExternalDNSHostname: "etcd.example.com"}, wErr: false},
+		{sp: ClientServicePolicy{Type: v1.ServiceTypeNodePort}, wErr: false},
+		{sp: ClientServicePolicy{Type: v1.ServiceTypeExternalName}, wErr: true},
+		{sp: ClientServicePolicy{ExternalDNSHostname: "etcd.example.com"}, wErr: true},
+		{sp: ClientServicePolicy{Type: v1.ServiceTypeLoadBalancer, ExternalDNSHostname: "etcd_example.com"}, wErr: true},
+	}
+	for i, tt := range tests {
+		err := tt.sp.Validate()
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+		}
+	}
+}
+
+func TestValidateServiceMesh(t *testing.T) {
+	tests := []struct {
+		sp   ServiceMeshPolicy
+		wErr bool
+	}{
+		{sp: ServiceMeshPolicy{Type: ServiceMeshIstio}, wErr: false},
+		{sp: ServiceMeshPolicy{Type: ServiceMeshLinkerd, ExcludeClientTraffic: true}, wErr: false},
+		{sp: ServiceMeshPolicy{Type: ServiceMeshIstio, DisableInjection: true}, wErr: false},
+		{sp: ServiceMeshPolicy{}, wErr: true},
+		{sp: ServiceMeshPolicy{Type: "Consul"}, wErr: true},
+		{sp: ServiceMeshPolicy{Type: ServiceMeshLinkerd, DisableInjection: true, ExcludeClientTraffic: true}, wErr: true},
+	}
+	for i, tt := range tests {
+		err := tt.sp.Validate()
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+		}
+	}
+}
+
+func TestValidateUpgradeStrategy(t *testing.T) {
+	tests := []struct {
+		strategy UpgradeStrategyType
+		backup   *BackupPolicy
+		wErr     bool
+	}{
+		{strategy: "", wErr: false},
+		{strategy: UpgradeStrategyRolling, wErr: false},
+		{strategy: UpgradeStrategyManualApproval, wErr: false},
+		{strategy: UpgradeStrategyRecreateFromBackup, backup: &BackupPolicy{BackupIntervalInSecond: 60, MaxBackups: 5}, wErr: false},
+		// recreate without backup
+		{strategy: UpgradeStrategyRecreateFromBackup, wErr: true},
+		{strategy: "BlueGreen", wErr: true},
+	}
+	for i, tt := range tests {
+		cs := &ClusterSpec{UpgradeStrategy: tt.strategy, Backup: tt.backup}
+		err := cs.validateUpgradeStrategy()
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+		}
+	}
+}
+
+func TestGetMaxUnavailable(t *testing.T) {
+	tests := []struct {
+		maxUnavailable int
+		size           int
+		w              int
+	}{
+		{maxUnavailable: 0, size: 5, w: 1},
+		{maxUnavailable: 2, size: 5, w: 2},
+		{maxUnavailable: 3, size: 5, w: 2},
+		{maxUnavailable: 3, size: 7, w: 3},
+		{maxUnavailable: 2, size: 4, w: 1},
+		{maxUnavailable: 2, size: 1, w: 1},
+	}
+	for i, tt := range tests {
+		cs := &ClusterSpec{MaxUnavailable: tt.maxUnavailable}
+		if get := cs.GetMaxUnavailable(tt.size); get != tt.w {
+			t.Errorf("#%d: max unavailable get=%d, want=%d", i, get, tt.w)
+		}
+	}
+}
+
+func TestValidateDisruption(t *testing.T) {
+	tests := []struct {
+		cs   ClusterSpec
+		wErr bool
+	}{
+		{cs: ClusterSpec{MaxUnavailable: 2, ManageSafeToEvict: true}, wErr: false},
+		{cs: ClusterSpec{MaxUnavailable: -1}, wErr: true},
+		{cs: ClusterSpec{ManageSafeToEvict: true, Pod: &PodPolicy{Annotations: map[string]string{"team": "storage"}}}, wErr: false},
+		{cs: ClusterSpec{ManageSafeToEvict: true, Pod: &PodPolicy{Annotations: map[string]string{SafeToEvictAnnotation: "true"}}}, wErr: true},
+		{cs: ClusterSpec{Pod: &PodPolicy{Annotations: map[string]string{SafeToEvictAnnotation: "true"}}}, wErr: false},
+		{cs: ClusterSpec{EvacuateNodes: true}, wErr: false},
+		{cs: ClusterSpec{EvacuateNodes: true, SelfHosted: &SelfHostedPolicy{}}, wErr: true},
+		{cs: ClusterSpec{RebalanceZones: true, Pod: &PodPolicy{SpreadAcrossZones: true}}, wErr: false},
+		{cs: ClusterSpec{RebalanceZones: true}, wErr: true},
+	}
+	for i, tt := range tests {
+		err := tt.cs.validateDisruption()
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+		}
+	}
+}
+
+func TestValidateMemberNaming(t *testing.T) {
+	tests := []struct {
+		naming MemberNamingScheme
+		wErr   bool
+	}{
+		{naming: "", wErr: false},
+		{naming: MemberNamingOrdinal, wErr: false},
+		{naming: MemberNamingRandom, wErr: false},
+		{naming: "Sequential", wErr: true},
+	}
+	for i, tt := range tests {
+		cs := &ClusterSpec{MemberNaming: tt.naming}
+		err := cs.validateMemberNaming()
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+		}
+	}
+}
+
+func TestValidateDisasterRecovery(t *testing.T) {
+	tests := []struct {
+		policy DisasterRecoveryPolicyType
+		wErr   bool
+	}{
+		{policy: "", wErr: false},
+		{policy: DisasterRecoveryRestoreFromBackup, wErr: false},
+		{policy: DisasterRecoveryManualApproval, wErr: false},
+		{policy: "ReplaceMembers", wErr: true},
+	}
+	for i, tt := range tests {
+		cs := &ClusterSpec{DisasterRecovery: tt.policy}
+		err := cs.validateDisasterRecovery()
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+		}
+	}
+}
+
+func TestValidateAntiAffinityMode(t *testing.T) {
+	tests := []struct {
+		mode      AntiAffinityMode
+		preferred bool
+		wErr      bool
+	}{
+		{mode: "", wErr: false},
+		{mode: "", preferred: true, wErr: false},
+		{mode: AntiAffinityRequired, wErr: false},
+		{mode: AntiAffinityPreferred, preferred: true, wErr: false},
+		{mode: AntiAffinityDisabled, wErr: false},
+		{mode: AntiAffinityDisabled, preferred: true, wErr: true},
+		{mode: "Soft", wErr: true},
+	}
+	for i, tt := range tests {
+		pp := &PodPolicy{AntiAffinityMode: tt.mode, PreferredAntiAffinity: tt.preferred}
+		err := pp.validateAntiAffinityMode()
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+		}
+	}
+}
+
+func TestValidateLivenessFailurePolicy(t *testing.T) {
+	tests := []struct {
+		policy LivenessFailurePolicy
+		wErr   bool
+	}{
+		{policy: "", wErr: false},
+		{policy: LivenessFailureReplaceMember, wErr: false},
+		{policy: LivenessFailureRestartContainer, wErr: false},
+		{policy: "Ignore", wErr: true},
+	}
+	for i, tt := range tests {
+		pp := &PodPolicy{LivenessFailurePolicy: tt.policy}
+		err := pp.validateLivenessFailurePolicy()
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+		}
+	}
+}
+
+func TestValidateHibernated(t *testing.T) {
+	backup := &BackupPolicy{BackupIntervalInSecond: 60, MaxBackups: 5}
+	pvc := &PodPolicy{PersistentVolumeClaimSpec: &v1.PersistentVolumeClaimSpec{
+		Resources: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse("1Gi")}},
+	}}
+	tests := []struct {
+		backup     *BackupPolicy
+		pod        *PodPolicy
+		selfHosted *SelfHostedPolicy
+		wErr       bool
+	}{
+		{backup: backup, wErr: false},
+		{backup: nil, wErr: true},
+		{backup: nil, pod: pvc, wErr: false},
+		{backup: backup, selfHosted: &SelfHostedPolicy{}, wErr: true},
+	}
+	for i, tt := range tests {
+		cs := &ClusterSpec{Hibernated: true, Backup: tt.backup, Pod: tt.pod, SelfHosted: tt.selfHosted}
+		err := cs.Validate()
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+		}
+	}
+}
+
+func TestRevertImmutableFields(t *testing.T) {
+	pvc := &v1.PersistentVolumeClaimSpec{
+		Resources: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse("1Gi")}},
+	}
+	tests := []struct {
+		old, new *PodPolicy
+		wFields  int
+	}{
+		{old: nil, new: &PodPolicy{Labels: map[string]string{"a": "b"}}, wFields: 0},
+		{old: &PodPolicy{PersistentVolumeClaimSpec: pvc}, new: &PodPolicy{PersistentVolumeClaimSpec: pvc}, wFields: 0},
+		{old: nil, new: &PodPolicy{PersistentVolumeClaimSpec: pvc}, wFields: 1},
+		{old: &PodPolicy{PersistentVolumeClaimSpec: pvc}, new: nil, wFields: 1},
+	}
+	for i, tt := range tests {
+		cs := ClusterSpec{Pod: tt.new}
+		fields := cs.RevertImmutableFields(ClusterSpec{Pod: tt.old})
+		if len(fields) != tt.wFields {
+			t.Errorf("#%d: reverted fields get=%v, want %d field(s)", i, fields, tt.wFields)
+		}
+		var oldPVC, newPVC *v1.PersistentVolumeClaimSpec
+		if tt.old != nil {
+			oldPVC = tt.old.PersistentVolumeClaimSpec
+		}
+		if cs.Pod != nil {
+			newPVC = cs.Pod.PersistentVolumeClaimSpec
+		}
+		if oldPVC != newPVC {
+			t.Errorf("#%d: pvc spec get=%v, want=%v", i, newPVC, oldPVC)
+		}
+	}
+}
+
+func TestClientEndpoint(t *testing.T) {
+	tests := []struct {
+		sp *ClientServicePolicy
+		w  string
+	}{
+		{sp: nil, w: ""},
+		{sp: &ClientServicePolicy{Type: v1.ServiceTypeLoadBalancer}, w: ""},
+		{sp: &ClientServicePolicy{Type: v1.ServiceTypeLoadBalancer, ExternalDNSHostname: "etcd.example.com"}, w: "etcd.example.com:2379"},
+		{sp: &ClientServicePolicy{Type: v1.ServiceTypeNodePort, ExternalDNSHostname: "etcd.example.com"}, w: "etcd.example.com:30379"},
+	}
+	for i, tt := range tests {
+		if get := tt.sp.ClientEndpoint(30379); get != tt.w {
+			t.Errorf("#%d: client endpoint get=%s, want=%s", i, get, tt.w)
+		}
+	}
+}
+
+func TestValidateGRPCProxy(t *testing.T) {
+	tls := &TLSPolicy{Static: &StaticTLS{OperatorSecret: "op-tls", Member: &MemberSecret{ClientSecret: "client-tls"}}}
+	tests := []struct {
+		gp      GRPCProxyPolicy
+		version string
+		tls     *TLSPolicy
+		wErr    bool
+	}{
+		{gp: GRPCProxyPolicy{Size: 1}, version: "3.1.8"},
+		{gp: GRPCProxyPolicy{Size: 0}, version: "3.1.8", wErr: true},
+		{gp: GRPCProxyPolicy{Size: 1, ServerSecret: "proxy-tls"}, version: "3.1.8", tls: tls, wErr: true},
+		{gp: GRPCProxyPolicy{Size: 1}, version: "3.2.0", tls: tls, wErr: true},
+		{gp: GRPCProxyPolicy{Size: 1, ServerSecret: "proxy-tls"}, version: "3.2.0", tls: tls},
+	}
+	for i, tt := range tests {
+		err := tt.gp.Validate(tt.version, tt.tls)
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: validate get=%v, want error=%v", i, err, tt.wErr)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+// Cluster annotations which trigger one-off operations.
+// The operator removes the annotation, whatever its value, before it runs the operation once,
+// and posts an event with the result.
+const (
+	// TriggerBackupAnnotation makes a backup of the cluster. It needs spec.backup.
+	TriggerBackupAnnotation = "etcd.coreos.com/trigger-backup"
+	// TriggerCompactionAnnotation compacts the keyspace of the cluster to its latest revision.
+	TriggerCompactionAnnotation = "etcd.coreos.com/trigger-compaction"
+	// TriggerDefragAnnotation defragments all members one at a time, the leader last.
+	TriggerDefragAnnotation = "etcd.coreos.com/trigger-defrag"
+	// TriggerExportAnnotation dumps the keys under the prefixes of spec.export to S3. It needs spec.export.
+	TriggerExportAnnotation = "etcd.coreos.com/trigger-export"
+	// TriggerImportAnnotation loads the dump of spec.import from S3 into the cluster. It needs spec.import.
+	TriggerImportAnnotation = "etcd.coreos.com/trigger-import"
+)
@@ -0,0 +1,64 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"errors"
+	"fmt"
+)
+
+type UpgradeStrategyType string
+
+const (
+	// UpgradeStrategyRolling upgrades one member at a time.
+	UpgradeStrategyRolling UpgradeStrategyType = "Rolling"
+	// UpgradeStrategyRecreateFromBackup backs the cluster up, deletes all members
+	// and recreates the cluster from the backup at the new version.
+	// It is meant for version jumps a rolling upgrade does not support.
+	// The cluster is unavailable until the seed member is running.
+	UpgradeStrategyRecreateFromBackup UpgradeStrategyType = "RecreateFromBackup"
+	// UpgradeStrategyManualApproval upgrades one member at a time like UpgradeStrategyRolling,
+	// but only after the user approved the member with the ApproveUpgradeAnnotation.
+	UpgradeStrategyManualApproval UpgradeStrategyType = "ManualApproval"
+
+	// ApproveUpgradeAnnotation is the cluster annotation to approve the upgrade
+	// of the member named by its value.
+	ApproveUpgradeAnnotation = "etcd.coreos.com/approve-upgrade"
+)
+
+// GetUpgradeStrategy returns the upgrade strategy of the cluster.
+// Default: "Rolling"
+func (c *ClusterSpec) GetUpgradeStrategy() UpgradeStrategyType {
+	if len(c.UpgradeStrategy) == 0 {
+		return UpgradeStrategyRolling
+	}
+	return c.UpgradeStrategy
+}
+
+func (c *ClusterSpec) validateUpgradeStrategy() error {
+	switch c.GetUpgradeStrategy() {
+	case UpgradeStrategyRolling, UpgradeStrategyManualApproval:
+	case UpgradeStrategyRecreateFromBackup:
+		if c.Backup == nil {
+			return errors.New("spec: recreate from backup upgrade strategy needs a backup policy")
+		}
+		if c.SelfHosted != nil {
+			return errors.New("spec: recreate from backup upgrade strategy is not allowed for self-hosted clusters")
+		}
+	default:
+		return fmt.Errorf("spec: unknown upgrade strategy (%s)", c.UpgradeStrategy)
+	}
+	return nil
+}
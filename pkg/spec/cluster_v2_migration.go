@@ -0,0 +1,43 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+// V2MigrationPolicy defines the etcd2 data dir a new cluster is bootstrapped from.
+// The seed member migrates a copy of the v2 data to the v3 API with `etcdctl migrate`
+// and restores its data dir from the result. The v2 data is not changed.
+type V2MigrationPolicy struct {
+	// PersistentVolumeClaimName is the name of the PVC in the namespace of the cluster
+	// which holds the data dir of a stopped etcd2 member.
+	PersistentVolumeClaimName string `json:"persistentVolumeClaimName"`
+	// DataDir is the path of the etcd2 data dir inside the volume.
+	// Default: the root of the volume
+	DataDir string `json:"dataDir,omitempty"`
+}
+
+func (mp *V2MigrationPolicy) Validate() error {
+	if len(mp.PersistentVolumeClaimName) == 0 {
+		return errors.New("spec: v2 migration persistent volume claim name must be set")
+	}
+	if filepath.IsAbs(mp.DataDir) || strings.HasPrefix(filepath.Clean(mp.DataDir), "..") {
+		return errors.New("spec: v2 migration data dir must be a relative path inside the volume")
+	}
+	return nil
+}
@@ -46,6 +46,14 @@ func (c *Cluster) UnmarshalJSON(data []byte) error {
 		return err
 	}
 	tmp2 := Cluster(tmp)
+	// Keep the JSON of the spec, so that ApplyProfile can tell the fields the cluster sets.
+	var raw struct {
+		Spec json.RawMessage `json:"spec"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	tmp2.rawSpec = raw.Spec
 	*c = tmp2
 	return nil
 }
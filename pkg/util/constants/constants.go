@@ -20,6 +20,7 @@ const (
 	DefaultDialTimeout      = 5 * time.Second
 	DefaultRequestTimeout   = 5 * time.Second
 	DefaultSnapshotInterval = 1800 * time.Second
+	DefaultDefragTimeout    = 60 * time.Second
 
 	DefaultBackupPodHTTPPort = 19999
 
@@ -0,0 +1,62 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cronutil
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseInvalid(t *testing.T) {
+	for i, expr := range []string{"", "* * * *", "* * * * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *",
+		"* * * * 8", "5-1 * * * *", "*/0 * * * *", "a * * * *", "1-x * * * *"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("#%d: expected error for schedule %q", i, expr)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	// 2017-06-14 is a Wednesday.
+	from := time.Date(2017, 6, 14, 10, 30, 15, 0, time.UTC)
+	tests := []struct {
+		expr string
+		w    time.Time
+	}{
+		{expr: "* * * * *", w: time.Date(2017, 6, 14, 10, 31, 0, 0, time.UTC)},
+		{expr: "30 10 * * *", w: time.Date(2017, 6, 15, 10, 30, 0, 0, time.UTC)},
+		{expr: "0 3 * * *", w: time.Date(2017, 6, 15, 3, 0, 0, 0, time.UTC)},
+		{expr: "*/20 * * * *", w: time.Date(2017, 6, 14, 10, 40, 0, 0, time.UTC)},
+		{expr: "15/20 * * * *", w: time.Date(2017, 6, 14, 10, 35, 0, 0, time.UTC)},
+		{expr: "0 8-18/4 * * *", w: time.Date(2017, 6, 14, 12, 0, 0, 0, time.UTC)},
+		{expr: "0 0 * * 6", w: time.Date(2017, 6, 17, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 * * 7", w: time.Date(2017, 6, 18, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 1 * *", w: time.Date(2017, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 1,20 * *", w: time.Date(2017, 6, 20, 0, 0, 0, 0, time.UTC)},
+		// both day fields are restricted: either matches.
+		{expr: "0 0 1 * 5", w: time.Date(2017, 6, 16, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 29 2 *", w: time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 30 2 *", w: time.Time{}},
+	}
+	for i, tt := range tests {
+		s, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("#%d: unexpected error: %v", i, err)
+		}
+		if get := s.Next(from); !get.Equal(tt.w) {
+			t.Errorf("#%d: next of %q get=%v, want=%v", i, tt.expr, get, tt.w)
+		}
+	}
+}
@@ -20,6 +20,8 @@ import (
 
 	"github.com/coreos/etcd-operator/pkg/util/constants"
 	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/etcdserver/api/v3rpc/rpctypes"
+	pb "github.com/coreos/etcd/etcdserver/etcdserverpb"
 
 	"golang.org/x/net/context"
 )
@@ -79,3 +81,131 @@ func CheckHealth(url string, tc *tls.Config) (bool, error) {
 	}
 	return true, nil
 }
+
+// MemberStatus returns the status of the etcd member serving on the given client URL.
+func MemberStatus(url string, tc *tls.Config) (*clientv3.StatusResponse, error) {
+	cfg := clientv3.Config{
+		Endpoints:   []string{url},
+		DialTimeout: constants.DefaultDialTimeout,
+		TLS:         tc,
+	}
+	etcdcli, err := clientv3.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create etcd client for %s: %v", url, err)
+	}
+	defer etcdcli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), constants.DefaultRequestTimeout)
+	resp, err := etcdcli.Status(ctx, url)
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status of %s: %v", url, err)
+	}
+	return resp, nil
+}
+
+// DefragmentMember defragments the backend database of the etcd member serving on the given client URL.
+// The member does not serve requests until defragmentation finishes.
+func DefragmentMember(url string, tc *tls.Config) error {
+	cfg := clientv3.Config{
+		Endpoints:   []string{url},
+		DialTimeout: constants.DefaultDialTimeout,
+		TLS:         tc,
+	}
+	etcdcli, err := clientv3.New(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create etcd client for %s: %v", url, err)
+	}
+	defer etcdcli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), constants.DefaultDefragTimeout)
+	_, err = etcdcli.Defragment(ctx, url)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to defragment %s: %v", url, err)
+	}
+	return nil
+}
+
+// AlarmTypeCorrupt is the alarm etcd 3.3 and above raises on data inconsistency
+// between members. The vendored etcd API predates it.
+const AlarmTypeCorrupt = pb.AlarmType(2)
+
+// ListAlarms returns the alarms of the given type raised in the etcd cluster.
+// For pb.AlarmType_NONE, it returns the alarms of all types.
+func ListAlarms(clientURLs []string, tc *tls.Config, t pb.AlarmType) ([]*pb.AlarmMember, error) {
+	cfg := clientv3.Config{
+		Endpoints:   clientURLs,
+		DialTimeout: constants.DefaultDialTimeout,
+		TLS:         tc,
+	}
+	etcdcli, err := clientv3.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer etcdcli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), constants.DefaultRequestTimeout)
+	resp, err := etcdcli.AlarmList(ctx)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	var alarms []*pb.AlarmMember
+	for _, a := range resp.Alarms {
+		if t == pb.AlarmType_NONE || a.Alarm == t {
+			alarms = append(alarms, a)
+		}
+	}
+	return alarms, nil
+}
+
+// DisarmAlarm disarms the given alarm in the etcd cluster.
+func DisarmAlarm(clientURLs []string, tc *tls.Config, alarm *pb.AlarmMember) error {
+	cfg := clientv3.Config{
+		Endpoints:   clientURLs,
+		DialTimeout: constants.DefaultDialTimeout,
+		TLS:         tc,
+	}
+	etcdcli, err := clientv3.New(cfg)
+	if err != nil {
+		return err
+	}
+	defer etcdcli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), constants.DefaultRequestTimeout)
+	_, err = etcdcli.AlarmDisarm(ctx, (*clientv3.AlarmMember)(alarm))
+	cancel()
+	return err
+}
+
+// CompactToLatest compacts the key space of the etcd cluster to the latest revision
+// and returns the revision.
+func CompactToLatest(clientURLs []string, tc *tls.Config) (int64, error) {
+	cfg := clientv3.Config{
+		Endpoints:   clientURLs,
+		DialTimeout: constants.DefaultDialTimeout,
+		TLS:         tc,
+	}
+	etcdcli, err := clientv3.New(cfg)
+	if err != nil {
+		return 0, err
+	}
+	defer etcdcli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), constants.DefaultRequestTimeout)
+	resp, err := etcdcli.Get(ctx, "/", clientv3.WithCountOnly())
+	cancel()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get latest revision: %v", err)
+	}
+	rev := resp.Header.Revision
+
+	ctx, cancel = context.WithTimeout(context.Background(), constants.DefaultDefragTimeout)
+	_, err = etcdcli.Compact(ctx, rev, clientv3.WithCompactPhysical())
+	cancel()
+	if err != nil && err != rpctypes.ErrCompacted {
+		return 0, fmt.Errorf("failed to compact to revision %d: %v", rev, err)
+	}
+	return rev, nil
+}
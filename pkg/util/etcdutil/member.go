@@ -15,6 +15,7 @@
 package etcdutil
 
 import (
+	"crypto/rand"
 	"errors"
 	"fmt"
 	"net/url"
@@ -36,12 +37,13 @@ type Member struct {
 	SecureClient bool
 }
 
-func (m *Member) fqdn() string {
+// Addr returns the DNS name of the member.
+func (m *Member) Addr() string {
 	return fmt.Sprintf("%s.%s.%s.svc.cluster.local", m.Name, clusterNameFromMemberName(m.Name), m.Namespace)
 }
 
 func (m *Member) ClientAddr() string {
-	return fmt.Sprintf("%s://%s:2379", m.clientScheme(), m.fqdn())
+	return fmt.Sprintf("%s://%s:2379", m.clientScheme(), m.Addr())
 }
 
 func (m *Member) clientScheme() string {
@@ -66,7 +68,7 @@ func (m *Member) ListenPeerURL() string {
 }
 
 func (m *Member) PeerURL() string {
-	return fmt.Sprintf("%s://%s:2380", m.peerScheme(), m.fqdn())
+	return fmt.Sprintf("%s://%s:2380", m.peerScheme(), m.Addr())
 }
 
 type MemberSet map[string]*Member
@@ -180,6 +182,45 @@ func CreateMemberName(clusterName string, member int) string {
 	return fmt.Sprintf("%s-%04d", clusterName, member)
 }
 
+// randomMemberSuffixChars leaves out vowels to not spell words, and 0, 1 and 3 which look like letters.
+const randomMemberSuffixChars = "bcdfghjklmnpqrstvwxz2456789"
+
+const randomMemberSuffixLen = 5
+
+// CreateRandomMemberName returns a member name with a random suffix, e.g. "example-x7kbq".
+// The suffix always contains a letter, so that it is never taken for the counter of an ordinal name.
+func CreateRandomMemberName(clusterName string) (string, error) {
+	b := make([]byte, randomMemberSuffixLen)
+	for {
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		for i := range b {
+			b[i] = randomMemberSuffixChars[int(b[i])%len(randomMemberSuffixChars)]
+		}
+		if IsRandomMemberSuffix(string(b)) {
+			return clusterName + "-" + string(b), nil
+		}
+	}
+}
+
+// IsRandomMemberSuffix tells whether s is the suffix of a name created by CreateRandomMemberName.
+func IsRandomMemberSuffix(s string) bool {
+	if len(s) != randomMemberSuffixLen {
+		return false
+	}
+	hasLetter := false
+	for _, r := range s {
+		if !strings.ContainsRune(randomMemberSuffixChars, r) {
+			return false
+		}
+		if r >= 'a' && r <= 'z' {
+			hasLetter = true
+		}
+	}
+	return hasLetter
+}
+
 func clusterNameFromMemberName(mn string) string {
 	i := strings.LastIndex(mn, "-")
 	if i == -1 {
@@ -54,3 +54,36 @@ func TestMemberSetIsEqual(t *testing.T) {
 		}
 	}
 }
+
+func TestIsRandomMemberSuffix(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{s: "x7kbq", want: true},
+		{s: "24567", want: false},
+		{s: "0001", want: false},
+		{s: "x7kb", want: false},
+		{s: "x7kba", want: false},
+	}
+	for i, tt := range tests {
+		if get := IsRandomMemberSuffix(tt.s); get != tt.want {
+			t.Errorf("#%d: get=%v, want=%v", i, get, tt.want)
+		}
+	}
+}
+
+func TestCreateRandomMemberName(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		name, err := CreateRandomMemberName("example")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if clusterNameFromMemberName(name) != "example" {
+			t.Fatalf("#%d: name (%s) does not belong to the cluster", i, name)
+		}
+		if _, err := GetCounterFromMemberName(name); err == nil {
+			t.Fatalf("#%d: name (%s) is taken for an ordinal name", i, name)
+		}
+	}
+}
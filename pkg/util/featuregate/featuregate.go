@@ -0,0 +1,102 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package featuregate lets experimental operator behavior ship disabled by
+// default and be turned on per operator deployment via `--feature-gates`.
+package featuregate
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Feature string
+
+const (
+	// SelfHosted lets the operator manage self-hosted clusters, i.e. clusters with
+	// spec.selfHosted which back the Kubernetes control plane the operator runs on.
+	SelfHosted Feature = "SelfHosted"
+)
+
+// knownFeatures maps every feature the operator knows about to its default state.
+// New experimental features should be added here, disabled by default.
+var knownFeatures = map[Feature]bool{
+	SelfHosted: false,
+}
+
+// FeatureGate tells whether a feature is enabled.
+type FeatureGate map[Feature]bool
+
+// Parse parses a comma separated list of "Feature=true|false" pairs, e.g.
+// "FeatureA=true,FeatureB=false". Features not in the list keep their defaults.
+func Parse(s string) (FeatureGate, error) {
+	return parse(knownFeatures, s)
+}
+
+func parse(known map[Feature]bool, s string) (FeatureGate, error) {
+	fg := FeatureGate{}
+	for f, enabled := range known {
+		fg[f] = enabled
+	}
+
+	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		return fg, nil
+	}
+	for _, kv := range strings.Split(s, ",") {
+		arr := strings.SplitN(strings.TrimSpace(kv), "=", 2)
+		if len(arr) != 2 {
+			return nil, fmt.Errorf("invalid feature gate (%s): must be in the form of Feature=true|false", kv)
+		}
+		f := Feature(strings.TrimSpace(arr[0]))
+		if _, ok := known[f]; !ok {
+			return nil, fmt.Errorf("unknown feature gate (%s): known features are %v", f, knownList(known))
+		}
+		enabled, err := strconv.ParseBool(strings.TrimSpace(arr[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for feature gate (%s): %v", f, err)
+		}
+		fg[f] = enabled
+	}
+	return fg, nil
+}
+
+// Enabled returns true if the given feature is enabled.
+// A nil FeatureGate has every feature disabled.
+func (fg FeatureGate) Enabled(f Feature) bool {
+	return fg[f]
+}
+
+// String returns the enabled features, sorted by name.
+func (fg FeatureGate) String() string {
+	var enabled []string
+	for f, ok := range fg {
+		if ok {
+			enabled = append(enabled, string(f))
+		}
+	}
+	sort.Strings(enabled)
+	return strings.Join(enabled, ",")
+}
+
+func knownList(known map[Feature]bool) []string {
+	var l []string
+	for f := range known {
+		l = append(l, string(f))
+	}
+	sort.Strings(l)
+	return l
+}
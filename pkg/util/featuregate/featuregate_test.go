@@ -0,0 +1,47 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuregate
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	known := map[Feature]bool{"A": false, "B": true}
+	tests := []struct {
+		s   string
+		wA  bool
+		wB  bool
+		wok bool
+	}{
+		{s: "", wA: false, wB: true, wok: true},
+		{s: "A=true", wA: true, wB: true, wok: true},
+		{s: "A=true, B=false", wA: true, wB: false, wok: true},
+		{s: "C=true", wok: false},
+		{s: "A", wok: false},
+		{s: "A=yes", wok: false},
+	}
+	for i, tt := range tests {
+		fg, err := parse(known, tt.s)
+		if (err == nil) != tt.wok {
+			t.Errorf("#%d: err=%v, want ok=%v", i, err, tt.wok)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if fg.Enabled("A") != tt.wA || fg.Enabled("B") != tt.wB {
+			t.Errorf("#%d: get A=%v B=%v, want A=%v B=%v", i, fg.Enabled("A"), fg.Enabled("B"), tt.wA, tt.wB)
+		}
+	}
+}
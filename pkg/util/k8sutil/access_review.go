@@ -0,0 +1,48 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"k8s.io/client-go/kubernetes"
+	authzv1beta1 "k8s.io/client-go/pkg/apis/authorization/v1beta1"
+)
+
+// ResourceAccess is an action on a kind of resource.
+// Namespace is empty for cluster scoped resources.
+type ResourceAccess struct {
+	Namespace string
+	Group     string
+	Resource  string
+	Verb      string
+}
+
+// CanI asks the apiserver whether the caller is allowed the given access.
+func CanI(kubecli kubernetes.Interface, ra ResourceAccess) (bool, error) {
+	review := &authzv1beta1.SelfSubjectAccessReview{
+		Spec: authzv1beta1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authzv1beta1.ResourceAttributes{
+				Namespace: ra.Namespace,
+				Group:     ra.Group,
+				Resource:  ra.Resource,
+				Verb:      ra.Verb,
+			},
+		},
+	}
+	res, err := kubecli.AuthorizationV1beta1().SelfSubjectAccessReviews().Create(review)
+	if err != nil {
+		return false, err
+	}
+	return res.Status.Allowed, nil
+}
@@ -0,0 +1,42 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+// AuditLogKey is the key in the audit ConfigMap holding the audit records,
+// one JSON encoded record per line.
+const AuditLogKey = "audit.log"
+
+func AuditConfigMapName(clusterName string) string {
+	return clusterName + "-audit"
+}
+
+func NewAuditConfigMapManifest(clusterName string, owner metav1.OwnerReference) *v1.ConfigMap {
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   AuditConfigMapName(clusterName),
+			Labels: LabelsForCluster(clusterName),
+		},
+		Data: map[string]string{
+			AuditLogKey: "",
+		},
+	}
+	addOwnerRefToObject(cm.GetObjectMeta(), owner)
+	return cm
+}
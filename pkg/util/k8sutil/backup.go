@@ -43,6 +43,8 @@ const (
 	awsConfigDir              = "/root/.aws/config/"
 	awsSecretVolName          = "secret-aws"
 	awsConfigVolName          = "config-aws"
+	s3CABundleDir             = "/etc/etcd-operator/s3-ca/"
+	s3CABundleVolName         = "s3-ca-bundle"
 	fromDirMountDir           = "/mnt/backup/from"
 
 	PVBackupV1 = "v1" // TODO: refactor and combine this with pkg/backup.PVBackupV1
@@ -61,7 +63,9 @@ func CreateStorageClass(kubecli kubernetes.Interface, pvProvisioner string) erro
 	return err
 }
 
-func CreateAndWaitPVC(kubecli kubernetes.Interface, clusterName, ns, pvProvisioner string, volumeSizeInMB int) error {
+// CreateAndWaitPVC creates the backup PVC of the cluster and waits for it to be bound.
+// If owner is not nil, the PVC is garbage collected with its owner.
+func CreateAndWaitPVC(kubecli kubernetes.Interface, clusterName, ns, pvProvisioner string, volumeSizeInMB int, owner *metav1.OwnerReference) error {
 	name := makePVCName(clusterName)
 	storageClassName := storageClassPrefix + "-" + path.Base(pvProvisioner)
 	claim := &v1.PersistentVolumeClaim{
@@ -86,6 +90,9 @@ func CreateAndWaitPVC(kubecli kubernetes.Interface, clusterName, ns, pvProvision
 			},
 		},
 	}
+	if owner != nil {
+		addOwnerRefToObject(claim.GetObjectMeta(), *owner)
+	}
 	_, err := kubecli.CoreV1().PersistentVolumeClaims(ns).Create(claim)
 	if err != nil {
 		return err
@@ -146,6 +153,38 @@ func AttachS3ToPodSpec(ps *v1.PodSpec, ss spec.S3Source) {
 		Name:  backupenv.AWSS3Bucket,
 		Value: ss.S3Bucket,
 	})
+
+	if len(ss.CABundleSecret) != 0 {
+		ps.Containers[0].VolumeMounts = append(ps.Containers[0].VolumeMounts, v1.VolumeMount{
+			Name:      s3CABundleVolName,
+			MountPath: s3CABundleDir,
+			ReadOnly:  true,
+		})
+		ps.Volumes = append(ps.Volumes, v1.Volume{
+			Name: s3CABundleVolName,
+			VolumeSource: v1.VolumeSource{
+				Secret: &v1.SecretVolumeSource{
+					SecretName: ss.CABundleSecret,
+				},
+			},
+		})
+		ps.Containers[0].Env = append(ps.Containers[0].Env, v1.EnvVar{
+			Name:  backupenv.AWSCABundle,
+			Value: path.Join(s3CABundleDir, spec.S3CABundleFileName),
+		})
+	}
+	if p := ss.Proxy; p != nil {
+		// The S3 client of the backup sidecar uses the proxies of the environment.
+		for _, e := range []v1.EnvVar{
+			{Name: "HTTP_PROXY", Value: p.HTTPProxy},
+			{Name: "HTTPS_PROXY", Value: p.HTTPSProxy},
+			{Name: "NO_PROXY", Value: p.NoProxy},
+		} {
+			if len(e.Value) != 0 {
+				ps.Containers[0].Env = append(ps.Containers[0].Env, e)
+			}
+		}
+	}
 }
 
 func AttachOperatorS3ToPodSpec(ps *v1.PodSpec, s3Ctx s3config.S3Context) {
@@ -273,7 +312,7 @@ func DeletePVC(kubecli kubernetes.Interface, clusterName, ns string) error {
 	return nil
 }
 
-func CopyVolume(kubecli kubernetes.Interface, fromClusterName, toClusterName, ns string) error {
+func CopyVolume(kubecli kubernetes.Interface, fromClusterName, toClusterName, ns string, owner metav1.OwnerReference) error {
 	from := path.Join(fromDirMountDir, PVBackupV1, fromClusterName)
 	to := path.Join(constants.BackupMountDir, PVBackupV1, toClusterName)
 
@@ -288,7 +327,7 @@ func CopyVolume(kubecli kubernetes.Interface, fromClusterName, toClusterName, ns
 			Containers: []v1.Container{
 				{
 					Name:  "copy-backup",
-					Image: "alpine",
+					Image: AlpineImage,
 					Command: []string{
 						"/bin/sh",
 						"-ec",
@@ -322,6 +361,13 @@ func CopyVolume(kubecli kubernetes.Interface, fromClusterName, toClusterName, ns
 			}},
 		},
 	}
+	addOwnerRefToObject(pod.GetObjectMeta(), owner)
+	return runBackupPod(kubecli, ns, pod)
+}
+
+// runBackupPod creates the pod and waits for it to succeed.
+// It deletes the pod afterwards to detach its volumes from the node.
+func runBackupPod(kubecli kubernetes.Interface, ns string, pod *v1.Pod) error {
 	if _, err := kubecli.CoreV1().Pods(ns).Create(pod); err != nil {
 		return err
 	}
@@ -342,12 +388,12 @@ func CopyVolume(kubecli kubernetes.Interface, fromClusterName, toClusterName, ns
 			if len(pod.Status.ContainerStatuses) > 0 {
 				termReason = pod.Status.ContainerStatuses[0].LastTerminationState.Terminated.Reason
 			}
-			return false, fmt.Errorf("backup copy pod (%s) failed: %v, %v", pod.Name, pod.Status.Reason, termReason)
+			return false, fmt.Errorf("backup pod (%s) failed: %v, %v", pod.Name, pod.Status.Reason, termReason)
 		}
 		return false, nil
 	})
 	if err != nil {
-		return fmt.Errorf("failed to wait backup copy pod (%s, phase: %s) to succeed: %v", pod.Name, phase, err)
+		return fmt.Errorf("failed to wait backup pod (%s, phase: %s) to succeed: %v", pod.Name, phase, err)
 	}
 	// Delete the pod to detach the volume from the node
 	return kubecli.CoreV1().Pods(ns).Delete(pod.Name, metav1.NewDeleteOptions(0))
@@ -361,8 +407,10 @@ func makePVCName(clusterName string) string {
 	return fmt.Sprintf("%s-pvc", clusterName)
 }
 
-func BackupServiceAddr(clusterName string) string {
-	return fmt.Sprintf("%s:%d", BackupSidecarName(clusterName), constants.DefaultBackupPodHTTPPort)
+// BackupServiceAddr returns the address of the backup service of the cluster in the given namespace.
+// It includes the namespace, so that it resolves from pods in other namespaces, e.g. the operator.
+func BackupServiceAddr(clusterName, ns string) string {
+	return fmt.Sprintf("%s.%s.svc.cluster.local:%d", BackupSidecarName(clusterName), ns, constants.DefaultBackupPodHTTPPort)
 }
 
 func BackupSidecarName(clusterName string) string {
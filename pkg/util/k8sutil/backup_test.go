@@ -0,0 +1,70 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"testing"
+
+	backupenv "github.com/coreos/etcd-operator/pkg/backup/env"
+	"github.com/coreos/etcd-operator/pkg/spec"
+
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+func TestAttachS3ToPodSpec(t *testing.T) {
+	tests := []struct {
+		ss        spec.S3Source
+		wVolumes  int
+		wCABundle string
+		wProxy    string
+		wNoProxy  string
+	}{
+		{ss: spec.S3Source{AWSSecret: "aws"}, wVolumes: 1},
+		{
+			ss: spec.S3Source{
+				AWSSecret:      "aws",
+				CABundleSecret: "s3-ca",
+				Proxy:          &spec.S3Proxy{HTTPSProxy: "http://proxy:3128", NoProxy: ".svc"},
+			},
+			wVolumes:  2,
+			wCABundle: "/etc/etcd-operator/s3-ca/ca-bundle.crt",
+			wProxy:    "http://proxy:3128",
+			wNoProxy:  ".svc",
+		},
+	}
+	for i, tt := range tests {
+		ps := &v1.PodSpec{Containers: []v1.Container{{Name: "backup"}}}
+		AttachS3ToPodSpec(ps, tt.ss)
+		if len(ps.Volumes) != tt.wVolumes {
+			t.Errorf("#%d: volumes get=%d, want=%d", i, len(ps.Volumes), tt.wVolumes)
+		}
+		env := map[string]string{}
+		for _, e := range ps.Containers[0].Env {
+			env[e.Name] = e.Value
+		}
+		if env[backupenv.AWSCABundle] != tt.wCABundle {
+			t.Errorf("#%d: CA bundle get=%s, want=%s", i, env[backupenv.AWSCABundle], tt.wCABundle)
+		}
+		if env["HTTPS_PROXY"] != tt.wProxy {
+			t.Errorf("#%d: https proxy get=%s, want=%s", i, env["HTTPS_PROXY"], tt.wProxy)
+		}
+		if env["NO_PROXY"] != tt.wNoProxy {
+			t.Errorf("#%d: no proxy get=%s, want=%s", i, env["NO_PROXY"], tt.wNoProxy)
+		}
+		if _, ok := env["HTTP_PROXY"]; ok {
+			t.Errorf("#%d: unexpected empty HTTP_PROXY", i)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"reflect"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+const (
+	// TopologyAwareHintsAnnotation enables topology aware hints on a service in Kubernetes 1.21 and above.
+	TopologyAwareHintsAnnotation = "service.kubernetes.io/topology-aware-hints"
+	// ExternalDNSHostnameAnnotation is the DNS name external-dns publishes for a service.
+	ExternalDNSHostnameAnnotation = "external-dns.alpha.kubernetes.io/hostname"
+)
+
+// UpdateClientServicePolicy applies the routing options to the existing client service of the cluster,
+// and exposes the metrics port only while the service is not published, see clientServicePorts.
+func UpdateClientServicePolicy(kubecli kubernetes.Interface, clusterName, ns string, metricsPort int, sp *spec.ClientServicePolicy) error {
+	svc, err := kubecli.CoreV1().Services(ns).Get(ClientServiceName(clusterName), metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	old := svc.Spec.SessionAffinity
+	oldType := svc.Spec.Type
+	oldPorts := svc.Spec.Ports
+	svc.Spec.Ports = keepNodePorts(clientServicePorts(metricsPort, sp), svc)
+	oldAnnotations := make(map[string]string, len(svc.Annotations))
+	for k, v := range svc.Annotations {
+		oldAnnotations[k] = v
+	}
+	applyClientServicePolicy(svc, sp)
+	if old == svc.Spec.SessionAffinity && oldType == svc.Spec.Type && reflect.DeepEqual(oldPorts, svc.Spec.Ports) &&
+		reflect.DeepEqual(oldAnnotations, svc.Annotations) {
+		return nil
+	}
+	_, err = kubecli.CoreV1().Services(ns).Update(svc)
+	return err
+}
+
+// applyClientServicePolicy sets the routing and publishing options on the client service.
+// A nil policy resets them to the defaults.
+func applyClientServicePolicy(svc *v1.Service, sp *spec.ClientServicePolicy) {
+	if sp == nil {
+		sp = &spec.ClientServicePolicy{}
+	}
+	svc.Spec.SessionAffinity = sp.SessionAffinity
+	if len(svc.Spec.SessionAffinity) == 0 {
+		svc.Spec.SessionAffinity = v1.ServiceAffinityNone
+	}
+	if sp.TopologyAwareHints {
+		if svc.Annotations == nil {
+			svc.Annotations = map[string]string{}
+		}
+		svc.Annotations[TopologyAwareHintsAnnotation] = "auto"
+	} else {
+		delete(svc.Annotations, TopologyAwareHintsAnnotation)
+	}
+
+	svc.Spec.Type = sp.Type
+	if len(svc.Spec.Type) == 0 {
+		svc.Spec.Type = v1.ServiceTypeClusterIP
+	}
+	if svc.Spec.Type == v1.ServiceTypeClusterIP {
+		// The apiserver rejects node ports on ClusterIP services.
+		for i := range svc.Spec.Ports {
+			svc.Spec.Ports[i].NodePort = 0
+		}
+	}
+	if len(sp.ExternalDNSHostname) != 0 {
+		if svc.Annotations == nil {
+			svc.Annotations = map[string]string{}
+		}
+		svc.Annotations[ExternalDNSHostnameAnnotation] = sp.ExternalDNSHostname
+	} else {
+		delete(svc.Annotations, ExternalDNSHostnameAnnotation)
+	}
+}
+
+// ClientServiceNodePort returns the node port the client port of the cluster is published on,
+// or 0 if the client service has none.
+func ClientServiceNodePort(kubecli kubernetes.Interface, clusterName, ns string) (int, error) {
+	svc, err := kubecli.CoreV1().Services(ns).Get(ClientServiceName(clusterName), metav1.GetOptions{})
+	if err != nil {
+		return 0, err
+	}
+	for _, p := range svc.Spec.Ports {
+		if p.Name == "client" {
+			return int(p.NodePort), nil
+		}
+	}
+	return 0, nil
+}
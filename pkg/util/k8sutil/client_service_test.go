@@ -0,0 +1,123 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"testing"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+func TestApplyClientServicePolicy(t *testing.T) {
+	tests := []struct {
+		sp *spec.ClientServicePolicy
+		// oldHints sets the annotation before applying the policy.
+		oldHints  bool
+		wAffinity v1.ServiceAffinity
+		wHints    bool
+	}{
+		{sp: nil, wAffinity: v1.ServiceAffinityNone},
+		{sp: nil, oldHints: true, wAffinity: v1.ServiceAffinityNone},
+		{sp: &spec.ClientServicePolicy{SessionAffinity: v1.ServiceAffinityClientIP}, wAffinity: v1.ServiceAffinityClientIP},
+		{sp: &spec.ClientServicePolicy{TopologyAwareHints: true}, wAffinity: v1.ServiceAffinityNone, wHints: true},
+	}
+	for i, tt := range tests {
+		svc := newEtcdServiceManifest("test-client", "test", "", clientServicePorts(0, nil))
+		if tt.oldHints {
+			svc.Annotations = map[string]string{TopologyAwareHintsAnnotation: "auto"}
+		}
+		applyClientServicePolicy(svc, tt.sp)
+		if svc.Spec.SessionAffinity != tt.wAffinity {
+			t.Errorf("#%d: session affinity get=%s, want=%s", i, svc.Spec.SessionAffinity, tt.wAffinity)
+		}
+		if _, ok := svc.Annotations[TopologyAwareHintsAnnotation]; ok != tt.wHints {
+			t.Errorf("#%d: topology aware hints get=%v, want=%v", i, ok, tt.wHints)
+		}
+	}
+}
+
+func TestApplyClientServicePolicyPublishing(t *testing.T) {
+	tests := []struct {
+		sp *spec.ClientServicePolicy
+		// oldNodePort sets a node port before applying the policy.
+		oldNodePort int32
+		wType       v1.ServiceType
+		wNodePort   int32
+		wHostname   string
+	}{
+		{sp: nil, wType: v1.ServiceTypeClusterIP},
+		{sp: &spec.ClientServicePolicy{Type: v1.ServiceTypeNodePort}, oldNodePort: 30379, wType: v1.ServiceTypeNodePort, wNodePort: 30379},
+		{sp: &spec.ClientServicePolicy{}, oldNodePort: 30379, wType: v1.ServiceTypeClusterIP},
+		{
+			sp:        &spec.ClientServicePolicy{Type: v1.ServiceTypeLoadBalancer, ExternalDNSHostname: "etcd.example.com"},
+			wType:     v1.ServiceTypeLoadBalancer,
+			wHostname: "etcd.example.com",
+		},
+	}
+	for i, tt := range tests {
+		svc := newEtcdServiceManifest("test-client", "test", "", clientServicePorts(0, nil))
+		svc.Annotations = map[string]string{ExternalDNSHostnameAnnotation: "old.example.com"}
+		svc.Spec.Ports[0].NodePort = tt.oldNodePort
+		applyClientServicePolicy(svc, tt.sp)
+		if svc.Spec.Type != tt.wType {
+			t.Errorf("#%d: type get=%s, want=%s", i, svc.Spec.Type, tt.wType)
+		}
+		if svc.Spec.Ports[0].NodePort != tt.wNodePort {
+			t.Errorf("#%d: node port get=%d, want=%d", i, svc.Spec.Ports[0].NodePort, tt.wNodePort)
+		}
+		if h := svc.Annotations[ExternalDNSHostnameAnnotation]; h != tt.wHostname {
+			t.Errorf("#%d: external-dns hostname get=%s, want=%s", i, h, tt.wHostname)
+		}
+	}
+}
+
+func TestClientServicePorts(t *testing.T) {
+	tests := []struct {
+		sp     *spec.ClientServicePolicy
+		wPorts int
+	}{
+		{sp: nil, wPorts: 2},
+		{sp: &spec.ClientServicePolicy{Type: v1.ServiceTypeClusterIP}, wPorts: 2},
+		// the metrics port is not published outside of Kubernetes
+		{sp: &spec.ClientServicePolicy{Type: v1.ServiceTypeNodePort}, wPorts: 1},
+		{sp: &spec.ClientServicePolicy{Type: v1.ServiceTypeLoadBalancer}, wPorts: 1},
+	}
+	for i, tt := range tests {
+		if ports := clientServicePorts(2381, tt.sp); len(ports) != tt.wPorts {
+			t.Errorf("#%d: ports get=%v, want %d ports", i, ports, tt.wPorts)
+		}
+	}
+}
+
+func TestKeepNodePorts(t *testing.T) {
+	svc := newEtcdServiceManifest("test-client", "test", "", clientServicePorts(2381, nil))
+	svc.Spec.Type = v1.ServiceTypeNodePort
+	svc.Spec.Ports[0].NodePort = 30379
+	ports := keepNodePorts(clientServicePorts(0, &spec.ClientServicePolicy{Type: v1.ServiceTypeNodePort}), svc)
+	if len(ports) != 1 || ports[0].NodePort != 30379 {
+		t.Errorf("expect the node port of the client port to be kept, get=%v", ports)
+	}
+}
+
+func TestPeerServicePorts(t *testing.T) {
+	if ports := peerServicePorts(0); len(ports) != 1 || ports[0].Port != 2380 {
+		t.Errorf("expect only the peer port without a metrics port, get=%v", ports)
+	}
+	if ports := peerServicePorts(2381); len(ports) != 2 || ports[1].Name != metricsPortName || ports[1].Port != 2381 {
+		t.Errorf("expect the metrics port, get=%v", ports)
+	}
+}
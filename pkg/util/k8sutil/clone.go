@@ -0,0 +1,69 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"fmt"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+const (
+	cloneSourceTLSDir    = "/etc/etcdtls/clone/source-tls"
+	cloneSourceTLSVolume = "clone-source-tls"
+)
+
+// CloneSourceURL returns the client URL of the source cluster of the clone.
+func CloneSourceURL(ns string, cp *spec.ClonePolicy) string {
+	scheme := "http"
+	if len(cp.SourceTLSSecret) != 0 {
+		scheme = "https"
+	}
+	if len(cp.SourceNamespace) != 0 {
+		ns = cp.SourceNamespace
+	}
+	return fmt.Sprintf("%s://%s.%s.svc.cluster.local:2379", scheme, ClientServiceName(cp.SourceCluster), ns)
+}
+
+// AddCloneToPod makes the seed member pod take a snapshot of the source cluster
+// and restore its data dir from it before etcd starts.
+func AddCloneToPod(pod *v1.Pod, ns, token string, m *etcdutil.Member, cs spec.ClusterSpec) {
+	cp := cs.Clone
+	cmd := fmt.Sprintf("ETCDCTL_API=3 etcdctl --endpoints=%s", CloneSourceURL(ns, cp))
+	mounts := etcdVolumeMounts()
+	if len(cp.SourceTLSSecret) != 0 {
+		cmd += fmt.Sprintf(" --cert=%[1]s/%[2]s --key=%[1]s/%[3]s --cacert=%[1]s/%[4]s",
+			cloneSourceTLSDir, etcdutil.CliCertFile, etcdutil.CliKeyFile, etcdutil.CliCAFile)
+		mounts = append(mounts, v1.VolumeMount{Name: cloneSourceTLSVolume, MountPath: cloneSourceTLSDir})
+		pod.Spec.Volumes = append(pod.Spec.Volumes, v1.Volume{Name: cloneSourceTLSVolume, VolumeSource: v1.VolumeSource{
+			Secret: &v1.SecretVolumeSource{SecretName: cp.SourceTLSSecret},
+		}})
+	}
+	cmd += " snapshot save " + backupFile
+
+	ics := []v1.Container{
+		{
+			Name:         "snapshot-source",
+			Image:        EtcdImageName(cs),
+			Command:      []string{"/bin/sh", "-ec", cmd},
+			VolumeMounts: mounts,
+		},
+		restoreDatadirContainer(token, cs, m, false),
+	}
+	pod.Spec.InitContainers = append(ics, pod.Spec.InitContainers...)
+}
@@ -0,0 +1,61 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+func TestAddCloneToPod(t *testing.T) {
+	tests := []struct {
+		cp       *spec.ClonePolicy
+		wURL     string
+		wVolumes int
+	}{
+		{
+			cp:   &spec.ClonePolicy{SourceCluster: "prod"},
+			wURL: "http://prod-client.staging.svc.cluster.local:2379",
+		},
+		{
+			cp:       &spec.ClonePolicy{SourceCluster: "prod", SourceNamespace: "production", SourceTLSSecret: "prod-tls"},
+			wURL:     "https://prod-client.production.svc.cluster.local:2379",
+			wVolumes: 1,
+		},
+	}
+	for i, tt := range tests {
+		if u := CloneSourceURL("staging", tt.cp); u != tt.wURL {
+			t.Errorf("#%d: source url get=%s, want=%s", i, u, tt.wURL)
+		}
+		pod := &v1.Pod{}
+		m := &etcdutil.Member{Name: "test-0000", Namespace: "staging"}
+		AddCloneToPod(pod, "staging", "token", m, spec.ClusterSpec{Version: "3.1.8", Clone: tt.cp})
+		ics := pod.Spec.InitContainers
+		if len(ics) != 2 || ics[0].Name != "snapshot-source" || ics[1].Name != "restore-datadir" {
+			t.Fatalf("#%d: unexpected init containers (%v)", i, ics)
+		}
+		if cmd := ics[0].Command[2]; !strings.Contains(cmd, "--endpoints="+tt.wURL) {
+			t.Errorf("#%d: snapshot command (%s) does not use source url (%s)", i, cmd, tt.wURL)
+		}
+		if len(pod.Spec.Volumes) != tt.wVolumes {
+			t.Errorf("#%d: volumes get=%d, want=%d", i, len(pod.Spec.Volumes), tt.wVolumes)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"fmt"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/pkg/api/v1"
+	appsv1beta1 "k8s.io/client-go/pkg/apis/apps/v1beta1"
+)
+
+const debugAppLabel = "etcd-debug"
+
+// debugCommand keeps the debug containers running until they are terminated.
+var debugCommand = []string{"/bin/sh", "-c", "trap 'exit 0' TERM; while true; do sleep 1; done"}
+
+func DebugName(clusterName string) string {
+	return clusterName + "-debug"
+}
+
+// DebugLabels are the labels of the debug pod. They differ from the labels
+// of the member pods, so that the debug pod is not taken for a member.
+func DebugLabels(clusterName string) map[string]string {
+	return map[string]string{
+		"app":          debugAppLabel,
+		"etcd_cluster": clusterName,
+	}
+}
+
+// CreateOrUpdateDebug makes sure the etcd cluster has a debug Deployment matching its spec.
+func CreateOrUpdateDebug(kubecli kubernetes.Interface, clusterName, ns string, cs spec.ClusterSpec, owner metav1.OwnerReference) error {
+	d := NewDebugDeploymentManifest(clusterName, ns, cs, owner)
+	_, err := kubecli.AppsV1beta1().Deployments(ns).Create(d)
+	if err != nil {
+		if !IsKubernetesResourceAlreadyExistError(err) {
+			return err
+		}
+		return PatchDeployment(kubecli, ns, d.Name, func(cur *appsv1beta1.Deployment) {
+			cur.Spec = d.Spec
+		})
+	}
+	return nil
+}
+
+func DeleteDebug(kubecli kubernetes.Interface, clusterName, ns string) error {
+	err := kubecli.AppsV1beta1().Deployments(ns).Delete(DebugName(clusterName), CascadeDeleteOptions(0))
+	if err != nil && !IsKubernetesResourceNotFoundError(err) {
+		return err
+	}
+	return nil
+}
+
+// NewDebugDeploymentManifest returns a Deployment of a single pod with an etcdctl container of the cluster version
+// and a curl container. Both are set up to talk to the client service of the cluster,
+// with the operator client certificates if the cluster uses client TLS.
+func NewDebugDeploymentManifest(clusterName, ns string, cs spec.ClusterSpec, owner metav1.OwnerReference) *appsv1beta1.Deployment {
+	secure := cs.TLS.IsSecureClient()
+	scheme := "http"
+	if secure {
+		scheme = "https"
+	}
+	env := []v1.EnvVar{
+		{Name: "ETCDCTL_API", Value: "3"},
+		{Name: "ETCDCTL_ENDPOINTS", Value: fmt.Sprintf("%s://%s.%s.svc.cluster.local:2379", scheme, ClientServiceName(clusterName), ns)},
+	}
+	var (
+		mounts  []v1.VolumeMount
+		volumes []v1.Volume
+	)
+	if secure {
+		env = append(env,
+			v1.EnvVar{Name: "ETCDCTL_CACERT", Value: operatorEtcdTLSDir + "/" + etcdutil.CliCAFile},
+			v1.EnvVar{Name: "ETCDCTL_CERT", Value: operatorEtcdTLSDir + "/" + etcdutil.CliCertFile},
+			v1.EnvVar{Name: "ETCDCTL_KEY", Value: operatorEtcdTLSDir + "/" + etcdutil.CliKeyFile},
+		)
+		mounts = []v1.VolumeMount{{Name: operatorEtcdTLSVolume, MountPath: operatorEtcdTLSDir}}
+		volumes = []v1.Volume{{Name: operatorEtcdTLSVolume, VolumeSource: v1.VolumeSource{
+			Secret: &v1.SecretVolumeSource{SecretName: cs.TLS.Static.OperatorSecret},
+		}}}
+	}
+
+	pl := v1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: DebugLabels(clusterName),
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{{
+				Name:         "etcdctl",
+				Image:        EtcdImageName(cs),
+				Command:      debugCommand,
+				Env:          env,
+				VolumeMounts: mounts,
+			}, {
+				Name:         "curl",
+				Image:        CurlImage,
+				Command:      debugCommand,
+				Env:          env,
+				VolumeMounts: mounts,
+			}},
+			Volumes: volumes,
+		},
+	}
+	applyPodPolicyToPodTemplateSpec(clusterName, &pl, cs.Pod)
+	podSpecWithArchitecture(&pl.Spec, cs.EtcdImage.GetArchitecture())
+
+	replicas := int32(1)
+	d := &appsv1beta1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   DebugName(clusterName),
+			Labels: LabelsForCluster(clusterName),
+		},
+		Spec: appsv1beta1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: DebugLabels(clusterName)},
+			Template: pl,
+		},
+	}
+	addOwnerRefToObject(d.GetObjectMeta(), owner)
+	return d
+}
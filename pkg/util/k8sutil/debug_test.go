@@ -0,0 +1,81 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"testing"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+func TestNewDebugDeploymentManifest(t *testing.T) {
+	tls := &spec.TLSPolicy{Static: &spec.StaticTLS{OperatorSecret: "op-tls", Member: &spec.MemberSecret{ClientSecret: "client-tls"}}}
+	tests := []struct {
+		cs         spec.ClusterSpec
+		wEndpoints string
+		wEnvs      int
+		wVolumes   int
+	}{
+		{
+			cs:         spec.ClusterSpec{Version: "3.1.8"},
+			wEndpoints: "http://test-client.default.svc.cluster.local:2379",
+			wEnvs:      2,
+		},
+		{
+			cs:         spec.ClusterSpec{Version: "3.2.0", TLS: tls},
+			wEndpoints: "https://test-client.default.svc.cluster.local:2379",
+			wEnvs:      5,
+			wVolumes:   1,
+		},
+	}
+	for i, tt := range tests {
+		d := NewDebugDeploymentManifest("test", "default", tt.cs, metav1.OwnerReference{})
+		ps := d.Spec.Template.Spec
+		if len(ps.Containers) != 2 {
+			t.Fatalf("#%d: containers get=%d, want=2", i, len(ps.Containers))
+		}
+		if img, w := ps.Containers[0].Image, EtcdImageName(tt.cs); img != w {
+			t.Errorf("#%d: etcdctl image get=%s, want=%s", i, img, w)
+		}
+		for _, c := range ps.Containers {
+			if len(c.Env) != tt.wEnvs {
+				t.Errorf("#%d: %s envs get=%d, want=%d", i, c.Name, len(c.Env), tt.wEnvs)
+			}
+			if ep := envValue(c.Env, "ETCDCTL_ENDPOINTS"); ep != tt.wEndpoints {
+				t.Errorf("#%d: %s endpoints get=%s, want=%s", i, c.Name, ep, tt.wEndpoints)
+			}
+		}
+		if len(ps.Volumes) != tt.wVolumes {
+			t.Errorf("#%d: volumes get=%d, want=%d", i, len(ps.Volumes), tt.wVolumes)
+		}
+		// The debug pod must not be listed as an etcd member of the cluster.
+		if labels.SelectorFromSet(LabelsForCluster("test")).Matches(labels.Set(d.Spec.Template.Labels)) {
+			t.Errorf("#%d: debug pod labels (%v) match the member labels", i, d.Spec.Template.Labels)
+		}
+	}
+}
+
+func envValue(env []v1.EnvVar, name string) string {
+	for _, e := range env {
+		if e.Name == name {
+			return e.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,54 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+const (
+	etcdAuthUsernameKey = "username"
+	etcdAuthPasswordKey = "password"
+)
+
+// EtcdAuthData is the etcd user the operator authenticates as.
+type EtcdAuthData struct {
+	Username string
+	Password string
+}
+
+// GetEtcdAuthDataFromSecret reads the etcd user of spec.pod.etcdctlAuthSecret.
+func GetEtcdAuthDataFromSecret(kubecli kubernetes.Interface, ns, se string) (*EtcdAuthData, error) {
+	secret, err := kubecli.CoreV1().Secrets(ns).Get(se, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return etcdAuthDataFromSecret(secret)
+}
+
+func etcdAuthDataFromSecret(secret *v1.Secret) (*EtcdAuthData, error) {
+	d := &EtcdAuthData{
+		Username: string(secret.Data[etcdAuthUsernameKey]),
+		Password: string(secret.Data[etcdAuthPasswordKey]),
+	}
+	if len(d.Username) == 0 {
+		return nil, fmt.Errorf("secret (%s) has no %s", secret.Name, etcdAuthUsernameKey)
+	}
+	return d, nil
+}
@@ -0,0 +1,48 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+func TestEtcdAuthDataFromSecret(t *testing.T) {
+	tests := []struct {
+		data map[string][]byte
+
+		w *EtcdAuthData
+	}{
+		{
+			data: map[string][]byte{"username": []byte("probe"), "password": []byte("secret")},
+			w:    &EtcdAuthData{Username: "probe", Password: "secret"},
+		},
+		{data: map[string][]byte{"username": []byte("probe")}, w: &EtcdAuthData{Username: "probe"}},
+		{data: map[string][]byte{"password": []byte("secret")}, w: nil},
+	}
+	for i, tt := range tests {
+		secret := &v1.Secret{Data: tt.data}
+		secret.Name = "etcd-probe-user"
+		get, err := etcdAuthDataFromSecret(secret)
+		if (err == nil) != (tt.w != nil) {
+			t.Errorf("#%d: err get=%v, want error=%v", i, err, tt.w == nil)
+		}
+		if !reflect.DeepEqual(get, tt.w) {
+			t.Errorf("#%d: get=%+v, want=%+v", i, get, tt.w)
+		}
+	}
+}
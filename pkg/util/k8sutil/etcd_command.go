@@ -0,0 +1,132 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+
+	"github.com/coreos/go-semver/semver"
+)
+
+// etcdFlagMinVersions are the lowest etcd versions which accept the flags.
+// Flags which are not listed are accepted by all supported etcd versions.
+var etcdFlagMinVersions = map[string]*semver.Version{
+	"auto-compaction-mode":               semver.New("3.3.0"),
+	"experimental-initial-corrupt-check": semver.New("3.3.0"),
+	"experimental-corrupt-check-time":    semver.New("3.3.0"),
+	"listen-metrics-urls":                semver.New("3.3.0"),
+	"metrics":                            semver.New("3.3.0"),
+}
+
+// etcdCommand builds the etcd command line for an etcd version.
+// Flags the version does not accept are skipped, since etcd refuses
+// to start on unknown flags.
+type etcdCommand struct {
+	// version is nil if the etcd version is unknown. All flags are added then.
+	version *semver.Version
+	flags   []string
+}
+
+func newEtcdCommand(version string) *etcdCommand {
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		v = nil
+	}
+	return &etcdCommand{version: v}
+}
+
+// add adds the flag with the given value if the etcd version accepts it.
+func (c *etcdCommand) add(name string, value interface{}) {
+	if !c.accepts(name) {
+		return
+	}
+	c.flags = append(c.flags, fmt.Sprintf("--%s=%v", name, value))
+}
+
+func (c *etcdCommand) accepts(name string) bool {
+	min, ok := etcdFlagMinVersions[name]
+	if !ok || c.version == nil {
+		return true
+	}
+	return !c.version.LessThan(*min)
+}
+
+func (c *etcdCommand) String() string {
+	return "/usr/local/bin/etcd " + strings.Join(c.flags, " ")
+}
+
+// newMemberEtcdCommand returns the etcd command of the given member.
+func newMemberEtcdCommand(m *etcdutil.Member, dataDir string, initialCluster []string, state, token string, cs spec.ClusterSpec) *etcdCommand {
+	c := newEtcdCommand(cs.Version)
+	c.add("data-dir", dataDir)
+	c.add("name", m.Name)
+	c.add("initial-advertise-peer-urls", m.PeerURL())
+	c.add("listen-peer-urls", m.ListenPeerURL())
+	c.add("listen-client-urls", m.ListenClientURL())
+	c.add("advertise-client-urls", m.ClientAddr())
+	c.add("initial-cluster", strings.Join(initialCluster, ","))
+	c.add("initial-cluster-state", state)
+	if m.SecurePeer {
+		c.add("peer-client-cert-auth", true)
+		c.add("peer-trusted-ca-file", peerTLSDir+"/peer-ca-crt.pem")
+		c.add("peer-cert-file", peerTLSDir+"/peer-crt.pem")
+		c.add("peer-key-file", peerTLSDir+"/peer-key.pem")
+	}
+	if m.SecureClient {
+		c.add("client-cert-auth", true)
+		c.add("trusted-ca-file", clientTLSDir+"/client-ca-crt.pem")
+		c.add("cert-file", clientTLSDir+"/client-crt.pem")
+		c.add("key-file", clientTLSDir+"/client-key.pem")
+	}
+	if state == "new" {
+		c.add("initial-cluster-token", token)
+	}
+
+	if ep := cs.Etcd; ep != nil {
+		if len(ep.AutoCompactionMode) != 0 {
+			c.add("auto-compaction-mode", ep.AutoCompactionMode)
+		}
+		if len(ep.AutoCompactionRetention) != 0 {
+			c.add("auto-compaction-retention", ep.AutoCompactionRetention)
+		}
+		if ep.QuotaBackendBytes != 0 {
+			c.add("quota-backend-bytes", ep.QuotaBackendBytes)
+		}
+		if len(ep.CorruptCheckTime) != 0 {
+			c.add("experimental-initial-corrupt-check", true)
+			c.add("experimental-corrupt-check-time", ep.CorruptCheckTime)
+		}
+		if ep.SnapshotCount != 0 {
+			c.add("snapshot-count", ep.SnapshotCount)
+		}
+		if ep.HeartbeatIntervalInMs != 0 {
+			c.add("heartbeat-interval", ep.HeartbeatIntervalInMs)
+		}
+		if ep.ElectionTimeoutInMs != 0 {
+			c.add("election-timeout", ep.ElectionTimeoutInMs)
+		}
+		if ep.MetricsPort != 0 {
+			c.add("listen-metrics-urls", fmt.Sprintf("http://0.0.0.0:%d", ep.MetricsPort))
+		}
+		if len(ep.MetricsLevel) != 0 {
+			c.add("metrics", ep.MetricsLevel)
+		}
+	}
+	return c
+}
@@ -0,0 +1,78 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+)
+
+func TestMemberEtcdCommandVersionFlags(t *testing.T) {
+	ep := &spec.EtcdPolicy{AutoCompactionMode: spec.AutoCompactionModePeriodic, AutoCompactionRetention: "1"}
+	tests := []struct {
+		version string
+		wMode   bool
+	}{
+		{version: "3.1.8", wMode: false},
+		{version: "3.2.9", wMode: false},
+		{version: "3.3.0", wMode: true},
+		{version: "3.5.0", wMode: true},
+		// unknown versions get all flags.
+		{version: "", wMode: true},
+	}
+	m := &etcdutil.Member{Name: "test-0000", Namespace: "default"}
+	for i, tt := range tests {
+		cmd := newMemberEtcdCommand(m, dataDir, nil, "new", "token", spec.ClusterSpec{Version: tt.version, Etcd: ep}).String()
+		if get := strings.Contains(cmd, "--auto-compaction-mode="); get != tt.wMode {
+			t.Errorf("#%d: auto compaction mode flag get=%v, want=%v (%s)", i, get, tt.wMode, cmd)
+		}
+		if !strings.Contains(cmd, "--auto-compaction-retention=1") {
+			t.Errorf("#%d: expect auto compaction retention flag, get=%s", i, cmd)
+		}
+		if !strings.HasPrefix(cmd, "/usr/local/bin/etcd --data-dir=") || !strings.Contains(cmd, "--initial-cluster-token=token") {
+			t.Errorf("#%d: expect member flags, get=%s", i, cmd)
+		}
+	}
+}
+
+func TestMemberEtcdCommandRaftTiming(t *testing.T) {
+	ep := &spec.EtcdPolicy{SnapshotCount: 50000, HeartbeatIntervalInMs: 250, ElectionTimeoutInMs: 2500}
+	m := &etcdutil.Member{Name: "test-0000", Namespace: "default"}
+	cmd := newMemberEtcdCommand(m, dataDir, nil, "new", "token", spec.ClusterSpec{Version: "3.1.8", Etcd: ep}).String()
+	for _, f := range []string{"--snapshot-count=50000", "--heartbeat-interval=250", "--election-timeout=2500"} {
+		if !strings.Contains(cmd, f) {
+			t.Errorf("expect flag %s, get=%s", f, cmd)
+		}
+	}
+
+	cmd = newMemberEtcdCommand(m, dataDir, nil, "new", "token", spec.ClusterSpec{Version: "3.1.8"}).String()
+	if strings.Contains(cmd, "--heartbeat-interval") || strings.Contains(cmd, "--election-timeout") {
+		t.Errorf("expect no raft timing flags by default, get=%s", cmd)
+	}
+}
+
+func TestMemberEtcdCommandMetrics(t *testing.T) {
+	ep := &spec.EtcdPolicy{MetricsPort: 2381, MetricsLevel: spec.MetricsLevelExtensive}
+	m := &etcdutil.Member{Name: "test-0000", Namespace: "default"}
+	cmd := newMemberEtcdCommand(m, dataDir, nil, "new", "token", spec.ClusterSpec{Version: "3.3.0", Etcd: ep}).String()
+	for _, f := range []string{"--listen-metrics-urls=http://0.0.0.0:2381", "--metrics=extensive"} {
+		if !strings.Contains(cmd, f) {
+			t.Errorf("expect flag %s, get=%s", f, cmd)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+const eventSourceComponent = "etcd-operator"
+
+func OperatorUpgradedEvent(cl *spec.Cluster, from, to string) *v1.Event {
+	event := newClusterEvent(cl)
+	event.Type = v1.EventTypeNormal
+	event.Reason = "OperatorUpgraded"
+	event.Message = fmt.Sprintf("Cluster was last reconciled by operator version %q, now managed by %q", from, to)
+	return event
+}
+
+func OperatorMigrationEvent(cl *spec.Cluster, step string, err error) *v1.Event {
+	event := newClusterEvent(cl)
+	if err != nil {
+		event.Type = v1.EventTypeWarning
+		event.Reason = "OperatorMigrationFailed"
+		event.Message = fmt.Sprintf("Migration step (%s) failed: %v", step, err)
+		return event
+	}
+	event.Type = v1.EventTypeNormal
+	event.Reason = "OperatorMigrated"
+	event.Message = fmt.Sprintf("Migration step (%s) finished", step)
+	return event
+}
+
+func MemberDefragmentedEvent(cl *spec.Cluster, memberName string, err error) *v1.Event {
+	event := newClusterEvent(cl)
+	if err != nil {
+		event.Type = v1.EventTypeWarning
+		event.Reason = "MemberDefragmentationFailed"
+		event.Message = fmt.Sprintf("Failed to defragment member %s: %v", memberName, err)
+		return event
+	}
+	event.Type = v1.EventTypeNormal
+	event.Reason = "MemberDefragmented"
+	event.Message = fmt.Sprintf("Member %s defragmented", memberName)
+	return event
+}
+
+func InitialDataLoadedEvent(cl *spec.Cluster, put, total int, err error) *v1.Event {
+	event := newClusterEvent(cl)
+	if err != nil {
+		event.Type = v1.EventTypeWarning
+		event.Reason = "InitialDataLoadFailed"
+		event.Message = fmt.Sprintf("Failed to load initial data after putting %d key(s): %v", put, err)
+		return event
+	}
+	event.Type = v1.EventTypeNormal
+	event.Reason = "InitialDataLoaded"
+	event.Message = fmt.Sprintf("Put %d of %d initial key(s), the others existed already", put, total)
+	return event
+}
+
+func NoSpaceRemediationEvent(cl *spec.Cluster, step string, err error) *v1.Event {
+	event := newClusterEvent(cl)
+	if err != nil {
+		event.Type = v1.EventTypeWarning
+		event.Reason = "NoSpaceRemediationFailed"
+		event.Message = fmt.Sprintf("NOSPACE alarm remediation step (%s) failed: %v", step, err)
+		return event
+	}
+	event.Type = v1.EventTypeNormal
+	event.Reason = "NoSpaceRemediated"
+	event.Message = fmt.Sprintf("NOSPACE alarm remediation step (%s) finished", step)
+	return event
+}
+
+func DataCorruptionEvent(cl *spec.Cluster, reason string) *v1.Event {
+	event := newClusterEvent(cl)
+	event.Type = v1.EventTypeWarning
+	event.Reason = "DataCorruption"
+	event.Message = reason
+	return event
+}
+
+func UpgradePausedEvent(cl *spec.Cluster, reason string) *v1.Event {
+	event := newClusterEvent(cl)
+	event.Type = v1.EventTypeWarning
+	event.Reason = "UpgradePaused"
+	event.Message = fmt.Sprintf("Upgrade to %s paused: %s", cl.Spec.Version, reason)
+	return event
+}
+
+func RecoveryPendingEvent(cl *spec.Cluster, reason string) *v1.Event {
+	event := newClusterEvent(cl)
+	event.Type = v1.EventTypeWarning
+	event.Reason = "RecoveryPending"
+	event.Message = reason
+	return event
+}
+
+func ImageDigestMismatchEvent(cl *spec.Cluster, memberName, version, recorded, running string) *v1.Event {
+	event := newClusterEvent(cl)
+	event.Type = v1.EventTypeWarning
+	event.Reason = "ImageDigestMismatch"
+	event.Message = fmt.Sprintf("Member %s runs etcd %s image %s, but %s was recorded for the version", memberName, version, running, recorded)
+	return event
+}
+
+func MemberDrainingEvent(cl *spec.Cluster, memberName, nodeName string) *v1.Event {
+	event := newClusterEvent(cl)
+	event.Type = v1.EventTypeNormal
+	event.Reason = "MemberDraining"
+	event.Message = fmt.Sprintf("Member %s on node %s is being evicted or its node is cordoned, holding upgrades and defragmentation", memberName, nodeName)
+	return event
+}
+
+func ZoneUnavailableEvent(cl *spec.Cluster, zone string) *v1.Event {
+	event := newClusterEvent(cl)
+	event.Type = v1.EventTypeWarning
+	event.Reason = "ZoneUnavailable"
+	event.Message = fmt.Sprintf("Zone %s of members has no ready node", zone)
+	return event
+}
+
+func ZoneRebalancedEvent(cl *spec.Cluster, reason string) *v1.Event {
+	event := newClusterEvent(cl)
+	event.Type = v1.EventTypeNormal
+	event.Reason = "ZoneRebalanced"
+	event.Message = reason
+	return event
+}
+
+func ZoneRebalanceStoppedEvent(cl *spec.Cluster, reason string) *v1.Event {
+	event := newClusterEvent(cl)
+	event.Type = v1.EventTypeWarning
+	event.Reason = "ZoneRebalanceStopped"
+	event.Message = reason
+	return event
+}
+
+func EvacuationBlockedEvent(cl *spec.Cluster, reason string) *v1.Event {
+	event := newClusterEvent(cl)
+	event.Type = v1.EventTypeWarning
+	event.Reason = "EvacuationBlocked"
+	event.Message = reason
+	return event
+}
+
+func SelfHostedUnmanagedEvent(cl *spec.Cluster) *v1.Event {
+	event := newClusterEvent(cl)
+	event.Type = v1.EventTypeWarning
+	event.Reason = "SelfHostedUnmanaged"
+	event.Message = "Self-hosted cluster is not reconciled: the operator runs without --feature-gates=SelfHosted=true"
+	return event
+}
+
+func AutoscaledEvent(cl *spec.Cluster, reason string) *v1.Event {
+	event := newClusterEvent(cl)
+	event.Type = v1.EventTypeNormal
+	event.Reason = "Autoscaled"
+	event.Message = reason
+	return event
+}
+
+func QuorumGuardEvent(cl *spec.Cluster, reason string) *v1.Event {
+	event := newClusterEvent(cl)
+	event.Type = v1.EventTypeWarning
+	event.Reason = "QuorumGuard"
+	event.Message = reason
+	return event
+}
+
+func TriggeredOperationEvent(cl *spec.Cluster, operation, result string, err error) *v1.Event {
+	event := newClusterEvent(cl)
+	if err != nil {
+		event.Type = v1.EventTypeWarning
+		event.Reason = "TriggeredOperationFailed"
+		event.Message = fmt.Sprintf("Triggered %s failed: %v", operation, err)
+		return event
+	}
+	event.Type = v1.EventTypeNormal
+	event.Reason = "TriggeredOperationFinished"
+	event.Message = fmt.Sprintf("Triggered %s finished: %s", operation, result)
+	return event
+}
+
+func SpecUpdateRejectedEvent(cl *spec.Cluster, fields []string) *v1.Event {
+	event := newClusterEvent(cl)
+	event.Type = v1.EventTypeWarning
+	event.Reason = "SpecUpdateRejected"
+	event.Message = fmt.Sprintf("Reverted the update of %s, which cannot be updated once the cluster is created", strings.Join(fields, ", "))
+	return event
+}
+
+func newClusterEvent(cl *spec.Cluster) *v1.Event {
+	t := time.Now()
+	return &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: cl.Metadata.Name + "-",
+			Namespace:    cl.Metadata.Namespace,
+		},
+		InvolvedObject: v1.ObjectReference{
+			APIVersion:      cl.APIVersion,
+			Kind:            cl.Kind,
+			Name:            cl.Metadata.Name,
+			Namespace:       cl.Metadata.Namespace,
+			UID:             cl.Metadata.UID,
+			ResourceVersion: cl.Metadata.ResourceVersion,
+		},
+		Source: v1.EventSource{
+			Component: eventSourceComponent,
+		},
+		// Each cluster event is unique so it should not be collapsed with other events.
+		FirstTimestamp: metav1.Time{Time: t},
+		LastTimestamp:  metav1.Time{Time: t},
+		Count:          int32(1),
+	}
+}
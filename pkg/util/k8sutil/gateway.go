@@ -0,0 +1,130 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/pkg/api/v1"
+	extensionsv1beta1 "k8s.io/client-go/pkg/apis/extensions/v1beta1"
+)
+
+const gatewayAppLabel = "etcd-gateway"
+
+func GatewayName(clusterName string) string {
+	return clusterName + "-gateway"
+}
+
+// GatewayLabels are the labels of the gateway pods. They differ from the labels
+// of the member pods, so that the gateways are not taken for members.
+func GatewayLabels(clusterName string) map[string]string {
+	return map[string]string{
+		"app":          gatewayAppLabel,
+		"etcd_cluster": clusterName,
+	}
+}
+
+// CreateOrUpdateGateway makes sure the etcd cluster has a gateway DaemonSet matching its spec
+// and forwarding to the given member endpoints, see NewGatewayDaemonSetManifest.
+// Changes of the spec or the members roll out to the gateways one node at a time.
+func CreateOrUpdateGateway(kubecli kubernetes.Interface, clusterName, ns string, cs spec.ClusterSpec, endpoints []string, owner metav1.OwnerReference) error {
+	ds := NewGatewayDaemonSetManifest(clusterName, ns, cs, endpoints, owner)
+	_, err := kubecli.ExtensionsV1beta1().DaemonSets(ns).Create(ds)
+	if err == nil || !IsKubernetesResourceAlreadyExistError(err) {
+		return err
+	}
+	cur, err := kubecli.ExtensionsV1beta1().DaemonSets(ns).Get(ds.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	cur.Spec = ds.Spec
+	_, err = kubecli.ExtensionsV1beta1().DaemonSets(ns).Update(cur)
+	return err
+}
+
+func DeleteGateway(kubecli kubernetes.Interface, clusterName, ns string) error {
+	err := kubecli.ExtensionsV1beta1().DaemonSets(ns).Delete(GatewayName(clusterName), CascadeDeleteOptions(0))
+	if err != nil && !IsKubernetesResourceNotFoundError(err) {
+		return err
+	}
+	return nil
+}
+
+// NewGatewayDaemonSetManifest returns the gateway DaemonSet of the cluster. The gateways balance the connections
+// over the given member endpoints ("host:port") and fail over between them, or forward to the client service
+// if no endpoints are given, e.g. before the operator knows the members.
+// The port is declared as host port, so that the scheduler does not place two gateways on the same port of a node.
+func NewGatewayDaemonSetManifest(clusterName, ns string, cs spec.ClusterSpec, endpoints []string, owner metav1.OwnerReference) *extensionsv1beta1.DaemonSet {
+	gp := cs.Gateway
+	automountServiceAccountToken := false
+	if len(endpoints) == 0 {
+		endpoints = []string{fmt.Sprintf("%s.%s.svc.cluster.local:2379", ClientServiceName(clusterName), ns)}
+	}
+	port := int32(gp.GetPort())
+	// The gateway forwards TCP connections as is. Clients talk TLS to the members if the cluster uses client TLS.
+	c := v1.Container{
+		Name:    "gateway",
+		Image:   EtcdImageName(cs),
+		Command: []string{"/usr/local/bin/etcd", "gateway", "start"},
+		Args: []string{
+			"--endpoints=" + strings.Join(endpoints, ","),
+			fmt.Sprintf("--listen-addr=127.0.0.1:%d", port),
+		},
+		Ports: []v1.ContainerPort{{
+			Name:          "client",
+			ContainerPort: port,
+			HostPort:      port,
+			Protocol:      v1.ProtocolTCP,
+		}},
+	}
+	pl := v1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: GatewayLabels(clusterName),
+		},
+		Spec: v1.PodSpec{
+			Containers:  []v1.Container{c},
+			HostNetwork: true,
+			DNSPolicy:   v1.DNSClusterFirstWithHostNet,
+			// The gateway does not access the Kubernetes API.
+			AutomountServiceAccountToken: &automountServiceAccountToken,
+		},
+	}
+	if gp.Pod != nil {
+		pl.Spec.Containers[0] = containerWithRequirements(pl.Spec.Containers[0], gp.Pod.Resources)
+	}
+	applyPodPolicyToPodTemplateSpec(clusterName, &pl, gp.Pod)
+	podSpecWithArchitecture(&pl.Spec, cs.EtcdImage.GetArchitecture())
+
+	ds := &extensionsv1beta1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   GatewayName(clusterName),
+			Labels: LabelsForCluster(clusterName),
+		},
+		Spec: extensionsv1beta1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: GatewayLabels(clusterName)},
+			Template: pl,
+			UpdateStrategy: extensionsv1beta1.DaemonSetUpdateStrategy{
+				Type: extensionsv1beta1.RollingUpdateDaemonSetStrategyType,
+			},
+		},
+	}
+	addOwnerRefToObject(ds.GetObjectMeta(), owner)
+	return ds
+}
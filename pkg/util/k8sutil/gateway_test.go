@@ -0,0 +1,78 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"testing"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestNewGatewayDaemonSetManifest(t *testing.T) {
+	tests := []struct {
+		gp          *spec.GatewayPolicy
+		wListenAddr string
+	}{
+		{
+			gp:          &spec.GatewayPolicy{},
+			wListenAddr: "--listen-addr=127.0.0.1:23790",
+		},
+		{
+			gp:          &spec.GatewayPolicy{Port: 12379},
+			wListenAddr: "--listen-addr=127.0.0.1:12379",
+		},
+	}
+	for i, tt := range tests {
+		cs := spec.ClusterSpec{Version: "3.1.8", Gateway: tt.gp}
+		ds := NewGatewayDaemonSetManifest("test", "default", cs, nil, metav1.OwnerReference{})
+		c := ds.Spec.Template.Spec.Containers[0]
+		if c.Args[1] != tt.wListenAddr {
+			t.Errorf("#%d: listen addr flag get=%s, want=%s", i, c.Args[1], tt.wListenAddr)
+		}
+		if p := c.Ports[0]; int(p.HostPort) != tt.gp.GetPort() || p.ContainerPort != p.HostPort {
+			t.Errorf("#%d: gateway port get=%d/%d, want host port=%d", i, p.ContainerPort, p.HostPort, tt.gp.GetPort())
+		}
+		if !ds.Spec.Template.Spec.HostNetwork {
+			t.Errorf("#%d: gateway pods must run in the host network", i)
+		}
+		// Gateway pods must not be listed as etcd members of the cluster.
+		if labels.SelectorFromSet(LabelsForCluster("test")).Matches(labels.Set(ds.Spec.Template.Labels)) {
+			t.Errorf("#%d: gateway pod labels (%v) match the member labels", i, ds.Spec.Template.Labels)
+		}
+	}
+}
+
+func TestNewGatewayDaemonSetManifestEndpoints(t *testing.T) {
+	tests := []struct {
+		endpoints  []string
+		wEndpoints string
+	}{
+		{endpoints: nil, wEndpoints: "--endpoints=test-client.default.svc.cluster.local:2379"},
+		{
+			endpoints:  []string{"test-0000.test.default.svc.cluster.local:2379", "test-0001.test.default.svc.cluster.local:2379"},
+			wEndpoints: "--endpoints=test-0000.test.default.svc.cluster.local:2379,test-0001.test.default.svc.cluster.local:2379",
+		},
+	}
+	for i, tt := range tests {
+		cs := spec.ClusterSpec{Version: "3.1.8", Gateway: &spec.GatewayPolicy{}}
+		ds := NewGatewayDaemonSetManifest("test", "default", cs, tt.endpoints, metav1.OwnerReference{})
+		if get := ds.Spec.Template.Spec.Containers[0].Args[0]; get != tt.wEndpoints {
+			t.Errorf("#%d: endpoints flag get=%s, want=%s", i, get, tt.wEndpoints)
+		}
+	}
+}
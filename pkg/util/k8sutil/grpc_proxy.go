@@ -0,0 +1,183 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"fmt"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/pkg/api/v1"
+	appsv1beta1 "k8s.io/client-go/pkg/apis/apps/v1beta1"
+)
+
+const grpcProxyAppLabel = "etcd-grpc-proxy"
+
+func GRPCProxyName(clusterName string) string {
+	return clusterName + "-grpc-proxy"
+}
+
+// GRPCProxyLabels are the labels of the proxy pods. They differ from the labels
+// of the member pods, so that the proxies are not taken for members.
+func GRPCProxyLabels(clusterName string) map[string]string {
+	return map[string]string{
+		"app":          grpcProxyAppLabel,
+		"etcd_cluster": clusterName,
+	}
+}
+
+// CreateOrUpdateGRPCProxy makes sure the etcd cluster has a gRPC proxy Deployment
+// and Service matching its spec.
+func CreateOrUpdateGRPCProxy(kubecli kubernetes.Interface, clusterName, ns string, cs spec.ClusterSpec, owner metav1.OwnerReference) error {
+	d := NewGRPCProxyDeploymentManifest(clusterName, ns, cs, owner)
+	_, err := kubecli.AppsV1beta1().Deployments(ns).Create(d)
+	if err != nil {
+		if !IsKubernetesResourceAlreadyExistError(err) {
+			return err
+		}
+		err = PatchDeployment(kubecli, ns, d.Name, func(cur *appsv1beta1.Deployment) {
+			cur.Spec = d.Spec
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	svc := NewGRPCProxyServiceManifest(clusterName, owner)
+	_, err = kubecli.CoreV1().Services(ns).Create(svc)
+	if err != nil && !IsKubernetesResourceAlreadyExistError(err) {
+		return err
+	}
+	return nil
+}
+
+func DeleteGRPCProxy(kubecli kubernetes.Interface, clusterName, ns string) error {
+	name := GRPCProxyName(clusterName)
+	err := kubecli.AppsV1beta1().Deployments(ns).Delete(name, CascadeDeleteOptions(0))
+	if err != nil && !IsKubernetesResourceNotFoundError(err) {
+		return err
+	}
+	err = kubecli.CoreV1().Services(ns).Delete(name, nil)
+	if err != nil && !IsKubernetesResourceNotFoundError(err) {
+		return err
+	}
+	return nil
+}
+
+func NewGRPCProxyDeploymentManifest(clusterName, ns string, cs spec.ClusterSpec, owner metav1.OwnerReference) *appsv1beta1.Deployment {
+	secure := cs.TLS.IsSecureClient()
+	scheme := "http"
+	if secure {
+		scheme = "https"
+	}
+	flags := []string{
+		fmt.Sprintf("--endpoints=%s://%s.%s.svc.cluster.local:2379", scheme, ClientServiceName(clusterName), ns),
+		"--listen-addr=0.0.0.0:2379",
+	}
+	if secure {
+		flags = append(flags,
+			fmt.Sprintf("--cert=%s/%s", operatorEtcdTLSDir, etcdutil.CliCertFile),
+			fmt.Sprintf("--key=%s/%s", operatorEtcdTLSDir, etcdutil.CliKeyFile),
+			fmt.Sprintf("--cacert=%s/%s", operatorEtcdTLSDir, etcdutil.CliCAFile),
+			fmt.Sprintf("--cert-file=%s/client-crt.pem", clientTLSDir),
+			fmt.Sprintf("--key-file=%s/client-key.pem", clientTLSDir),
+			fmt.Sprintf("--trusted-ca-file=%s/client-ca-crt.pem", clientTLSDir),
+		)
+	}
+
+	c := v1.Container{
+		Name:    "grpc-proxy",
+		Image:   EtcdImageName(cs),
+		Command: []string{"/usr/local/bin/etcd", "grpc-proxy", "start"},
+		Args:    flags,
+		Ports: []v1.ContainerPort{{
+			Name:          "client",
+			ContainerPort: 2379,
+			Protocol:      v1.ProtocolTCP,
+		}},
+		ReadinessProbe: &v1.Probe{
+			Handler: v1.Handler{
+				TCPSocket: &v1.TCPSocketAction{Port: intstr.FromInt(2379)},
+			},
+			InitialDelaySeconds: 5,
+			PeriodSeconds:       10,
+		},
+	}
+	var volumes []v1.Volume
+	if secure {
+		c.VolumeMounts = []v1.VolumeMount{
+			{Name: clientTLSVolume, MountPath: clientTLSDir},
+			{Name: operatorEtcdTLSVolume, MountPath: operatorEtcdTLSDir},
+		}
+		volumes = []v1.Volume{
+			{Name: clientTLSVolume, VolumeSource: v1.VolumeSource{
+				Secret: &v1.SecretVolumeSource{SecretName: cs.GRPCProxy.ServerSecret},
+			}},
+			{Name: operatorEtcdTLSVolume, VolumeSource: v1.VolumeSource{
+				Secret: &v1.SecretVolumeSource{SecretName: cs.TLS.Static.OperatorSecret},
+			}},
+		}
+	}
+
+	gp := cs.GRPCProxy
+	pl := v1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: GRPCProxyLabels(clusterName),
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{c},
+			Volumes:    volumes,
+		},
+	}
+	if gp.Pod != nil {
+		pl.Spec.Containers[0] = containerWithRequirements(pl.Spec.Containers[0], gp.Pod.Resources)
+	}
+	applyPodPolicyToPodTemplateSpec(clusterName, &pl, gp.Pod)
+	podSpecWithArchitecture(&pl.Spec, cs.EtcdImage.GetArchitecture())
+
+	replicas := int32(gp.Size)
+	d := &appsv1beta1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   GRPCProxyName(clusterName),
+			Labels: LabelsForCluster(clusterName),
+		},
+		Spec: appsv1beta1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: GRPCProxyLabels(clusterName)},
+			Template: pl,
+		},
+	}
+	addOwnerRefToObject(d.GetObjectMeta(), owner)
+	return d
+}
+
+func NewGRPCProxyServiceManifest(clusterName string, owner metav1.OwnerReference) *v1.Service {
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   GRPCProxyName(clusterName),
+			Labels: LabelsForCluster(clusterName),
+		},
+		Spec: v1.ServiceSpec{
+			Ports:    []v1.ServicePort{newServicePort("client", 2379)},
+			Selector: GRPCProxyLabels(clusterName),
+		},
+	}
+	addOwnerRefToObject(svc.GetObjectMeta(), owner)
+	return svc
+}
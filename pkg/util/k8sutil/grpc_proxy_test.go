@@ -0,0 +1,68 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestNewGRPCProxyDeploymentManifest(t *testing.T) {
+	tls := &spec.TLSPolicy{Static: &spec.StaticTLS{OperatorSecret: "op-tls", Member: &spec.MemberSecret{ClientSecret: "client-tls"}}}
+	tests := []struct {
+		cs        spec.ClusterSpec
+		wEndpoint string
+		wVolumes  int
+	}{
+		{
+			cs:        spec.ClusterSpec{Version: "3.1.8", GRPCProxy: &spec.GRPCProxyPolicy{Size: 2}},
+			wEndpoint: "--endpoints=http://test-client.default.svc.cluster.local:2379",
+		},
+		{
+			cs:        spec.ClusterSpec{Version: "3.2.0", GRPCProxy: &spec.GRPCProxyPolicy{Size: 2, ServerSecret: "proxy-tls"}, TLS: tls},
+			wEndpoint: "--endpoints=https://test-client.default.svc.cluster.local:2379",
+			wVolumes:  2,
+		},
+	}
+	for i, tt := range tests {
+		d := NewGRPCProxyDeploymentManifest("test", "default", tt.cs, metav1.OwnerReference{})
+		if *d.Spec.Replicas != 2 {
+			t.Errorf("#%d: replicas get=%d, want=2", i, *d.Spec.Replicas)
+		}
+		c := d.Spec.Template.Spec.Containers[0]
+		if c.Args[0] != tt.wEndpoint {
+			t.Errorf("#%d: endpoint flag get=%s, want=%s", i, c.Args[0], tt.wEndpoint)
+		}
+		vols := d.Spec.Template.Spec.Volumes
+		if len(vols) != tt.wVolumes {
+			t.Errorf("#%d: volumes get=%d, want=%d", i, len(vols), tt.wVolumes)
+		}
+		if len(vols) != 0 && vols[0].Secret.SecretName != "proxy-tls" {
+			t.Errorf("#%d: proxy serving secret get=%s, want=proxy-tls", i, vols[0].Secret.SecretName)
+		}
+		// Proxy pods must not be listed as etcd members of the cluster.
+		if labels.SelectorFromSet(LabelsForCluster("test")).Matches(labels.Set(d.Spec.Template.Labels)) {
+			t.Errorf("#%d: proxy pod labels (%v) match the member labels", i, d.Spec.Template.Labels)
+		}
+		if !reflect.DeepEqual(d.Labels, LabelsForCluster("test")) {
+			t.Errorf("#%d: deployment labels get=%v, want=%v", i, d.Labels, LabelsForCluster("test"))
+		}
+	}
+}
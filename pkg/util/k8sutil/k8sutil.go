@@ -19,7 +19,7 @@ import (
 	"fmt"
 	"net"
 	"os"
-	"strings"
+	"reflect"
 	"time"
 
 	"github.com/coreos/etcd-operator/pkg/backup/backupapi"
@@ -55,6 +55,19 @@ const (
 	clientTLSVolume          = "member-client-tls"
 	operatorEtcdTLSDir       = "/etc/etcdtls/operator/etcd-tls"
 	operatorEtcdTLSVolume    = "operator-etcd-tls"
+	metricsPortName          = "metrics"
+
+	// TolerateUnreadyEndpointsAnnotation makes a service publish the DNS records
+	// of its endpoints before they pass readiness checks.
+	TolerateUnreadyEndpointsAnnotation = "service.alpha.kubernetes.io/tolerate-unready-endpoints"
+)
+
+// Images of the utility containers in the pods the operator creates.
+// They can be overridden, e.g. with the mirrors of an air-gapped registry.
+var (
+	BusyboxImage = "busybox:1.26.2"
+	AlpineImage  = "alpine"
+	CurlImage    = "tutum/curl"
 )
 
 func GetEtcdVersion(pod *v1.Pod) string {
@@ -76,66 +89,157 @@ func GetPodNames(pods []*v1.Pod) []string {
 	return res
 }
 
-func makeRestoreInitContainerSpec(backupAddr, token, version string, m *etcdutil.Member) string {
-	spec := []v1.Container{
+// makeRestoreInitContainers restores the member from the latest backup compatible with backupVersion
+// using etcdctl of the cluster version.
+func makeRestoreInitContainers(backupAddr, token, backupVersion string, cs spec.ClusterSpec, m *etcdutil.Member) []v1.Container {
+	restore := restoreDatadirContainer(token, cs, m, false)
+	restore.ImagePullPolicy = initContainerPullPolicy(cs)
+	return []v1.Container{
 		{
 			Name:  "fetch-backup",
-			Image: "tutum/curl",
-			Command: []string{
-				"/bin/sh", "-ec",
-				fmt.Sprintf("curl -o %s %s", backupFile, backupapi.NewBackupURL("http", backupAddr, version, -1)),
-			},
-			VolumeMounts: etcdVolumeMounts(),
-		},
-		{
-			Name:  "restore-datadir",
-			Image: EtcdImageName(version),
+			Image: BusyboxImage,
 			Command: []string{
 				"/bin/sh", "-ec",
-				fmt.Sprintf("ETCDCTL_API=3 etcdctl snapshot restore %[1]s"+
-					" --name %[2]s"+
-					" --initial-cluster %[2]s=%[3]s"+
-					" --initial-cluster-token %[4]s"+
-					" --initial-advertise-peer-urls %[3]s"+
-					" --data-dir %[5]s", backupFile, m.Name, m.PeerURL(), token, dataDir),
+				fmt.Sprintf("wget -O %s %s", backupFile, backupapi.NewBackupURL("http", backupAddr, backupVersion, -1)),
 			},
-			VolumeMounts: etcdVolumeMounts(),
+			ImagePullPolicy: initContainerPullPolicy(cs),
+			VolumeMounts:    etcdVolumeMounts(),
 		},
+		restore,
 	}
-	b, err := json.Marshal(spec)
-	if err != nil {
-		panic(err)
+}
+
+// restoreDatadirContainer restores the data dir of the member from the snapshot in backupFile.
+// The hash check must be skipped for a db file copied from a data dir instead of saved by `etcdctl snapshot save`.
+func restoreDatadirContainer(token string, cs spec.ClusterSpec, m *etcdutil.Member, skipHashCheck bool) v1.Container {
+	cmd := fmt.Sprintf("ETCDCTL_API=3 etcdctl snapshot restore %[1]s"+
+		" --name %[2]s"+
+		" --initial-cluster %[2]s=%[3]s"+
+		" --initial-cluster-token %[4]s"+
+		" --initial-advertise-peer-urls %[3]s"+
+		" --data-dir %[5]s", backupFile, m.Name, m.PeerURL(), token, dataDir)
+	if skipHashCheck {
+		cmd += " --skip-hash-check"
+	}
+	return v1.Container{
+		Name:         "restore-datadir",
+		Image:        EtcdImageName(cs),
+		Command:      []string{"/bin/sh", "-ec", cmd},
+		VolumeMounts: etcdVolumeMounts(),
 	}
-	return string(b)
 }
 
-func EtcdImageName(version string) string {
-	return fmt.Sprintf("quay.io/coreos/etcd:v%v", version)
+// EtcdImageName returns the etcd image of the cluster version.
+func EtcdImageName(cs spec.ClusterSpec) string {
+	return cs.EtcdImage.ImageName(cs.Version)
 }
 func PodWithNodeSelector(p *v1.Pod, ns map[string]string) *v1.Pod {
 	p.Spec.NodeSelector = ns
 	return p
 }
 
-func CreateClientService(kubecli kubernetes.Interface, clusterName, ns string, owner metav1.OwnerReference) error {
-	return createService(kubecli, ClientServiceName(clusterName), clusterName, ns, "", 2379, owner)
+// CreateClientService creates the client service of the cluster. If metricsPort
+// is not 0, the service also exposes the metrics port of the members, unless it is published.
+func CreateClientService(kubecli kubernetes.Interface, clusterName, ns string, metricsPort int, sp *spec.ClientServicePolicy, owner metav1.OwnerReference) error {
+	svc := newEtcdServiceManifest(ClientServiceName(clusterName), clusterName, "", clientServicePorts(metricsPort, sp))
+	applyClientServicePolicy(svc, sp)
+	return createService(kubecli, ns, svc, owner)
 }
 
 func ClientServiceName(clusterName string) string {
 	return clusterName + "-client"
 }
 
-func CreatePeerService(kubecli kubernetes.Interface, clusterName, ns string, owner metav1.OwnerReference) error {
-	return createService(kubecli, clusterName, clusterName, ns, v1.ClusterIPNone, 2380, owner)
+// CreatePeerService creates the headless service of the cluster. Besides the peer port,
+// it exposes the metrics of each member on the metrics port if metricsPort is not 0.
+func CreatePeerService(kubecli kubernetes.Interface, clusterName, ns string, metricsPort int, owner metav1.OwnerReference) error {
+	svc := newEtcdServiceManifest(clusterName, clusterName, v1.ClusterIPNone, peerServicePorts(metricsPort))
+	// Members resolve each other through the DNS records of this service.
+	// A new member is not ready until it has joined the cluster, which needs
+	// the existing members to reach it by its DNS name first.
+	svc.Annotations = map[string]string{
+		TolerateUnreadyEndpointsAnnotation: "true",
+	}
+	return createService(kubecli, ns, svc, owner)
 }
 
-func createService(kubecli kubernetes.Interface, svcName, clusterName, ns, clusterIP string, port int32, owner metav1.OwnerReference) error {
-	svc := newEtcdServiceManifest(svcName, clusterName, clusterIP, port)
+func createService(kubecli kubernetes.Interface, ns string, svc *v1.Service, owner metav1.OwnerReference) error {
 	addOwnerRefToObject(svc.GetObjectMeta(), owner)
 	_, err := kubecli.CoreV1().Services(ns).Create(svc)
 	return err
 }
 
+// UpdateServicePorts updates the ports of the client and peer services of the cluster
+// to expose the given metrics port.
+func UpdateServicePorts(kubecli kubernetes.Interface, clusterName, ns string, metricsPort int, sp *spec.ClientServicePolicy) error {
+	err := updateServicePorts(kubecli, ns, ClientServiceName(clusterName), clientServicePorts(metricsPort, sp))
+	if err != nil {
+		return err
+	}
+	return updateServicePorts(kubecli, ns, clusterName, peerServicePorts(metricsPort))
+}
+
+func updateServicePorts(kubecli kubernetes.Interface, ns, svcName string, ports []v1.ServicePort) error {
+	svc, err := kubecli.CoreV1().Services(ns).Get(svcName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	ports = keepNodePorts(ports, svc)
+	if reflect.DeepEqual(svc.Spec.Ports, ports) {
+		return nil
+	}
+	svc.Spec.Ports = ports
+	_, err = kubecli.CoreV1().Services(ns).Update(svc)
+	return err
+}
+
+// keepNodePorts returns the ports with the node ports the service already has for ports of the same name,
+// so that updates of a NodePort or LoadBalancer service do not move the node ports clients connect to.
+func keepNodePorts(ports []v1.ServicePort, svc *v1.Service) []v1.ServicePort {
+	if svc.Spec.Type != v1.ServiceTypeNodePort && svc.Spec.Type != v1.ServiceTypeLoadBalancer {
+		return ports
+	}
+	res := make([]v1.ServicePort, len(ports))
+	for i, p := range ports {
+		for _, old := range svc.Spec.Ports {
+			if old.Name == p.Name {
+				p.NodePort = old.NodePort
+			}
+		}
+		res[i] = p
+	}
+	return res
+}
+
+// clientServicePorts returns the ports of the client service. The metrics port is only exposed
+// if metricsPort is not 0 and the service is not published outside of Kubernetes.
+func clientServicePorts(metricsPort int, sp *spec.ClientServicePolicy) []v1.ServicePort {
+	ports := []v1.ServicePort{newServicePort("client", 2379)}
+	if metricsPort != 0 && !sp.IsPublished() {
+		ports = append(ports, newServicePort(metricsPortName, metricsPort))
+	}
+	return ports
+}
+
+func peerServicePorts(metricsPort int) []v1.ServicePort {
+	// The peer port was named "client" by earlier versions. Keep the name
+	// so that existing services are not changed.
+	ports := []v1.ServicePort{newServicePort("client", 2380)}
+	if metricsPort != 0 {
+		ports = append(ports, newServicePort(metricsPortName, metricsPort))
+	}
+	return ports
+}
+
+func newServicePort(name string, port int) v1.ServicePort {
+	return v1.ServicePort{
+		Name:       name,
+		Port:       int32(port),
+		TargetPort: intstr.FromInt(port),
+		Protocol:   v1.ProtocolTCP,
+	}
+}
+
 // CreateAndWaitPod is a workaround for self hosted and util for testing.
 // We should eventually get rid of this in critical code path and move it to test util.
 func CreateAndWaitPod(kubecli kubernetes.Interface, ns string, pod *v1.Pod, timeout time.Duration) (*v1.Pod, error) {
@@ -164,7 +268,7 @@ func CreateAndWaitPod(kubecli kubernetes.Interface, ns string, pod *v1.Pod, time
 	return retPod, err
 }
 
-func newEtcdServiceManifest(svcName, clusterName string, clusterIP string, port int32) *v1.Service {
+func newEtcdServiceManifest(svcName, clusterName string, clusterIP string, ports []v1.ServicePort) *v1.Service {
 	labels := map[string]string{
 		"app":          "etcd",
 		"etcd_cluster": clusterName,
@@ -175,14 +279,7 @@ func newEtcdServiceManifest(svcName, clusterName string, clusterIP string, port
 			Labels: labels,
 		},
 		Spec: v1.ServiceSpec{
-			Ports: []v1.ServicePort{
-				{
-					Name:       "client",
-					Port:       port,
-					TargetPort: intstr.FromInt(int(port)),
-					Protocol:   v1.ProtocolTCP,
-				},
-			},
+			Ports:     ports,
 			Selector:  labels,
 			ClusterIP: clusterIP,
 		},
@@ -190,9 +287,11 @@ func newEtcdServiceManifest(svcName, clusterName string, clusterIP string, port
 	return svc
 }
 
-func AddRecoveryToPod(pod *v1.Pod, clusterName, token string, m *etcdutil.Member, cs spec.ClusterSpec) {
-	pod.Annotations[v1.PodInitContainersBetaAnnotationKey] =
-		makeRestoreInitContainerSpec(BackupServiceAddr(clusterName), token, cs.Version, m)
+// AddRecoveryToPod restores the member from a backup taken at backupVersion.
+// The backup version differs from the cluster version if the cluster is recreated from a backup to upgrade it.
+func AddRecoveryToPod(pod *v1.Pod, clusterName, token, backupVersion string, m *etcdutil.Member, cs spec.ClusterSpec) {
+	ics := makeRestoreInitContainers(BackupServiceAddr(clusterName, m.Namespace), token, backupVersion, cs, m)
+	pod.Spec.InitContainers = append(ics, pod.Spec.InitContainers...)
 }
 
 func addOwnerRefToObject(o metav1.Object, r metav1.OwnerReference) {
@@ -200,19 +299,7 @@ func addOwnerRefToObject(o metav1.Object, r metav1.OwnerReference) {
 }
 
 func NewEtcdPod(m *etcdutil.Member, initialCluster []string, clusterName, state, token string, cs spec.ClusterSpec, owner metav1.OwnerReference) *v1.Pod {
-	commands := fmt.Sprintf("/usr/local/bin/etcd --data-dir=%s --name=%s --initial-advertise-peer-urls=%s "+
-		"--listen-peer-urls=%s --listen-client-urls=%s --advertise-client-urls=%s "+
-		"--initial-cluster=%s --initial-cluster-state=%s",
-		dataDir, m.Name, m.PeerURL(), m.ListenPeerURL(), m.ListenClientURL(), m.ClientAddr(), strings.Join(initialCluster, ","), state)
-	if m.SecurePeer {
-		commands += fmt.Sprintf(" --peer-client-cert-auth=true --peer-trusted-ca-file=%[1]s/peer-ca-crt.pem --peer-cert-file=%[1]s/peer-crt.pem --peer-key-file=%[1]s/peer-key.pem", peerTLSDir)
-	}
-	if m.SecureClient {
-		commands += fmt.Sprintf(" --client-cert-auth=true --trusted-ca-file=%[1]s/client-ca-crt.pem --cert-file=%[1]s/client-crt.pem --key-file=%[1]s/client-key.pem", clientTLSDir)
-	}
-	if state == "new" {
-		commands = fmt.Sprintf("%s --initial-cluster-token=%s", commands, token)
-	}
+	commands := newMemberEtcdCommand(m, dataDir, initialCluster, state, token, cs).String()
 
 	labels := map[string]string{
 		"app":          "etcd",
@@ -220,16 +307,27 @@ func NewEtcdPod(m *etcdutil.Member, initialCluster []string, clusterName, state,
 		"etcd_cluster": clusterName,
 	}
 
-	// TODO: fix "sleep 5".
-	// Without waiting some time, there is high rate of flakes in DNS setup.
-	commands = fmt.Sprintf("sleep 5; %s", commands)
-	container := containerWithLivenessProbe(etcdContainer(commands, cs.Version), etcdLivenessProbe(cs.TLS.IsSecureClient()))
+	container := containerWithLivenessProbe(etcdContainer(commands, cs), etcdLivenessProbe(cs.TLS.IsSecureClient()))
+	container = containerWithReadinessProbe(container, etcdReadinessProbe(cs.TLS.IsSecureClient(), cs.Version))
+	if p := cs.Etcd.GetMetricsPort(); p != 0 {
+		container.Ports = append(container.Ports, v1.ContainerPort{
+			Name:          metricsPortName,
+			ContainerPort: int32(p),
+			Protocol:      v1.ProtocolTCP,
+		})
+	}
+	if supportsLeaderTransfer(cs.Version) {
+		container.Lifecycle = &v1.Lifecycle{PreStop: etcdLeaderTransferHandler(cs.TLS.IsSecureClient())}
+	}
 	if cs.Pod != nil {
 		container = containerWithRequirements(container, cs.Pod.Resources)
+		if len(cs.Pod.EtcdctlAuthSecret) != 0 {
+			container.Env = append(container.Env, etcdctlAuthEnv(cs.Pod.EtcdctlAuthSecret, cs.Version)...)
+		}
 	}
 
 	volumes := []v1.Volume{
-		{Name: "etcd-data", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}},
+		memberDataVolume(m, cs),
 	}
 
 	if m.SecurePeer {
@@ -256,6 +354,7 @@ func NewEtcdPod(m *etcdutil.Member, initialCluster []string, clusterName, state,
 		}})
 	}
 
+	automountServiceAccountToken := false
 	pod := &v1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        m.Name,
@@ -263,18 +362,29 @@ func NewEtcdPod(m *etcdutil.Member, initialCluster []string, clusterName, state,
 			Annotations: map[string]string{},
 		},
 		Spec: v1.PodSpec{
-			Containers:    []v1.Container{container},
-			RestartPolicy: v1.RestartPolicyNever,
-			Volumes:       volumes,
+			// Without waiting for its own DNS record, etcd fails to start
+			// because it cannot resolve its advertised peer URL.
+			InitContainers: []v1.Container{checkDNSInitContainer(m)},
+			Containers:     []v1.Container{container},
+			RestartPolicy:  v1.RestartPolicyNever,
+			Volumes:        volumes,
 			// DNS A record: [m.Name].[clusterName].Namespace.svc.cluster.local.
 			// For example, etcd-0000 in default namesapce will have DNS name
 			// `etcd-0000.etcd.default.svc.cluster.local`.
 			Hostname:  m.Name,
 			Subdomain: clusterName,
+			// etcd does not access the Kubernetes API.
+			AutomountServiceAccountToken: &automountServiceAccountToken,
 		},
 	}
 
+	// Members of the same cluster must not share a node by default: losing
+	// a node would otherwise take down multiple members at once.
+	pod = PodWithAntiAffinity(pod, clusterName)
+
 	applyPodPolicy(clusterName, pod, cs.Pod)
+	applyServiceMeshPolicy(pod, cs.ServiceMesh)
+	podSpecWithArchitecture(&pod.Spec, cs.EtcdImage.GetArchitecture())
 
 	SetEtcdVersion(pod, cs.Version)
 
@@ -303,7 +413,12 @@ func InClusterConfig() (*rest.Config, error) {
 	if len(os.Getenv("KUBERNETES_SERVICE_PORT")) == 0 {
 		os.Setenv("KUBERNETES_SERVICE_PORT", "443")
 	}
-	return rest.InClusterConfig()
+	cfg, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg.WrapTransport = instrumentTransport
+	return cfg, nil
 }
 
 func NewTPRClient() (*rest.RESTClient, error) {
@@ -402,12 +517,12 @@ func CascadeDeleteOptions(gracePeriodSeconds int64) *metav1.DeleteOptions {
 	}
 }
 
-// mergeLables merges l2 into l1. Conflicting label will be skipped.
-func mergeLabels(l1, l2 map[string]string) {
-	for k, v := range l2 {
-		if _, ok := l1[k]; ok {
+// mergeStringMaps adds the entries of m2 to m1 without overwriting existing keys.
+func mergeStringMaps(m1, m2 map[string]string) {
+	for k, v := range m2 {
+		if _, ok := m1[k]; ok {
 			continue
 		}
-		l1[k] = v
+		m1[k] = v
 	}
 }
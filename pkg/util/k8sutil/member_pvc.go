@@ -0,0 +1,91 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+// MemberPVCName returns the name of the PVC the member keeps its data dir on.
+func MemberPVCName(memberName string) string {
+	return "etcd-data-" + memberName
+}
+
+// HasMemberPVC tells whether the members of the cluster keep their data dir on PVCs.
+func HasMemberPVC(cs spec.ClusterSpec) bool {
+	return cs.Pod != nil && cs.Pod.PersistentVolumeClaimSpec != nil
+}
+
+func NewMemberPVCManifest(m *etcdutil.Member, clusterName string, cs spec.ClusterSpec, owner metav1.OwnerReference) *v1.PersistentVolumeClaim {
+	pvc := &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: MemberPVCName(m.Name),
+			Labels: map[string]string{
+				"app":          "etcd",
+				"etcd_node":    m.Name,
+				"etcd_cluster": clusterName,
+			},
+		},
+		Spec: *cs.Pod.PersistentVolumeClaimSpec,
+	}
+	if len(pvc.Spec.AccessModes) == 0 {
+		pvc.Spec.AccessModes = []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce}
+	}
+	addOwnerRefToObject(pvc.GetObjectMeta(), owner)
+	return pvc
+}
+
+// CreateMemberPVC creates the PVC of the member unless it exists already.
+func CreateMemberPVC(kubecli kubernetes.Interface, ns string, pvc *v1.PersistentVolumeClaim) error {
+	_, err := kubecli.CoreV1().PersistentVolumeClaims(ns).Create(pvc)
+	if err != nil && !IsKubernetesResourceAlreadyExistError(err) {
+		return err
+	}
+	return nil
+}
+
+// MemberPVCExists tells whether the PVC of the member exists and is not being deleted.
+func MemberPVCExists(kubecli kubernetes.Interface, ns, memberName string) (bool, error) {
+	pvc, err := kubecli.CoreV1().PersistentVolumeClaims(ns).Get(MemberPVCName(memberName), metav1.GetOptions{})
+	if err != nil {
+		if IsKubernetesResourceNotFoundError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return pvc.DeletionTimestamp == nil, nil
+}
+
+func DeleteMemberPVC(kubecli kubernetes.Interface, ns, memberName string) error {
+	err := kubecli.CoreV1().PersistentVolumeClaims(ns).Delete(MemberPVCName(memberName), nil)
+	if err != nil && !IsKubernetesResourceNotFoundError(err) {
+		return err
+	}
+	return nil
+}
+
+func memberDataVolume(m *etcdutil.Member, cs spec.ClusterSpec) v1.Volume {
+	if HasMemberPVC(cs) {
+		return v1.Volume{Name: "etcd-data", VolumeSource: v1.VolumeSource{
+			PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: MemberPVCName(m.Name)},
+		}}
+	}
+	return v1.Volume{Name: "etcd-data", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}}
+}
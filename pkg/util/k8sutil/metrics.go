@@ -0,0 +1,78 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var apiserverRequestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "etcd_operator",
+	Subsystem: "apiserver",
+	Name:      "request_errors_total",
+	Help:      "Total number of failed requests to the Kubernetes apiserver by verb and status code",
+},
+	[]string{"Verb", "Code"},
+)
+
+func init() {
+	prometheus.MustRegister(apiserverRequestErrors)
+}
+
+// instrumentedTransport counts the requests to the apiserver which fail with a transport error,
+// a server error or throttling. Client errors like not found or conflict are part of
+// the normal operation of the operator and are not counted.
+type instrumentedTransport struct {
+	rt http.RoundTripper
+}
+
+func instrumentTransport(rt http.RoundTripper) http.RoundTripper {
+	return &instrumentedTransport{rt: rt}
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.rt.RoundTrip(req)
+	switch {
+	case err != nil:
+		apiserverRequestErrors.WithLabelValues(requestVerb(req), "error").Inc()
+	case resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests:
+		apiserverRequestErrors.WithLabelValues(requestVerb(req), strconv.Itoa(resp.StatusCode)).Inc()
+	}
+	return resp, err
+}
+
+// requestVerb returns the Kubernetes API verb of the request. Gets and lists are not told apart.
+func requestVerb(req *http.Request) string {
+	switch req.Method {
+	case http.MethodGet:
+		if req.URL.Query().Get("watch") == "true" || strings.Contains(req.URL.Path, "/watch/") {
+			return "watch"
+		}
+		return "get"
+	case http.MethodPost:
+		return "create"
+	case http.MethodPut:
+		return "update"
+	case http.MethodPatch:
+		return "patch"
+	case http.MethodDelete:
+		return "delete"
+	}
+	return strings.ToLower(req.Method)
+}
@@ -0,0 +1,45 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRequestVerb(t *testing.T) {
+	tests := []struct {
+		method string
+		url    string
+		wVerb  string
+	}{
+		{method: "GET", url: "https://10.0.0.1/api/v1/namespaces/default/pods/etcd-0000", wVerb: "get"},
+		{method: "GET", url: "https://10.0.0.1/apis/etcd.coreos.com/v1beta1/namespaces/default/clusters?watch=true&resourceVersion=1", wVerb: "watch"},
+		{method: "GET", url: "https://10.0.0.1/api/v1/watch/namespaces/default/pods", wVerb: "watch"},
+		{method: "POST", url: "https://10.0.0.1/api/v1/namespaces/default/pods", wVerb: "create"},
+		{method: "PUT", url: "https://10.0.0.1/apis/etcd.coreos.com/v1beta1/namespaces/default/clusters/etcd", wVerb: "update"},
+		{method: "PATCH", url: "https://10.0.0.1/api/v1/namespaces/default/pods/etcd-0000", wVerb: "patch"},
+		{method: "DELETE", url: "https://10.0.0.1/api/v1/namespaces/default/pods/etcd-0000", wVerb: "delete"},
+	}
+	for i, tt := range tests {
+		req, err := http.NewRequest(tt.method, tt.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if verb := requestVerb(req); verb != tt.wVerb {
+			t.Errorf("#%d: verb get=%s, want=%s", i, verb, tt.wVerb)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"fmt"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/pkg/api/v1"
+	appsv1beta1 "k8s.io/client-go/pkg/apis/apps/v1beta1"
+)
+
+const (
+	mirrorAppLabel = "etcd-mirror"
+
+	mirrorDestinationTLSDir    = "/etc/etcdtls/mirror/destination-tls"
+	mirrorDestinationTLSVolume = "mirror-destination-tls"
+)
+
+func MirrorName(clusterName string) string {
+	return clusterName + "-mirror"
+}
+
+// MirrorLabels are the labels of the mirror pod. They differ from the labels
+// of the member pods, so that the mirror is not taken for a member.
+func MirrorLabels(clusterName string) map[string]string {
+	return map[string]string{
+		"app":          mirrorAppLabel,
+		"etcd_cluster": clusterName,
+	}
+}
+
+// MirrorDestinationURL returns the client URL of the destination of the mirror.
+func MirrorDestinationURL(ns string, mp *spec.MirrorPolicy) string {
+	if len(mp.DestinationEndpoint) != 0 {
+		return mp.DestinationEndpoint
+	}
+	scheme := "http"
+	if len(mp.DestinationTLSSecret) != 0 {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s.%s.svc.cluster.local:2379", scheme, ClientServiceName(mp.DestinationCluster), ns)
+}
+
+// CreateOrUpdateMirror makes sure the etcd cluster has a mirror Deployment matching its spec.
+func CreateOrUpdateMirror(kubecli kubernetes.Interface, clusterName, ns string, cs spec.ClusterSpec, owner metav1.OwnerReference) error {
+	d := NewMirrorDeploymentManifest(clusterName, ns, cs, owner)
+	_, err := kubecli.AppsV1beta1().Deployments(ns).Create(d)
+	if err == nil || !IsKubernetesResourceAlreadyExistError(err) {
+		return err
+	}
+	return PatchDeployment(kubecli, ns, d.Name, func(cur *appsv1beta1.Deployment) {
+		cur.Spec = d.Spec
+	})
+}
+
+func DeleteMirror(kubecli kubernetes.Interface, clusterName, ns string) error {
+	err := kubecli.AppsV1beta1().Deployments(ns).Delete(MirrorName(clusterName), CascadeDeleteOptions(0))
+	if err != nil && !IsKubernetesResourceNotFoundError(err) {
+		return err
+	}
+	return nil
+}
+
+func NewMirrorDeploymentManifest(clusterName, ns string, cs spec.ClusterSpec, owner metav1.OwnerReference) *appsv1beta1.Deployment {
+	mp := cs.Mirror
+	secure := cs.TLS.IsSecureClient()
+	scheme := "http"
+	if secure {
+		scheme = "https"
+	}
+	args := []string{
+		"make-mirror",
+		fmt.Sprintf("--endpoints=%s://%s.%s.svc.cluster.local:2379", scheme, ClientServiceName(clusterName), ns),
+	}
+	var mounts []v1.VolumeMount
+	var volumes []v1.Volume
+	if secure {
+		args = append(args,
+			fmt.Sprintf("--cert=%s/%s", operatorEtcdTLSDir, etcdutil.CliCertFile),
+			fmt.Sprintf("--key=%s/%s", operatorEtcdTLSDir, etcdutil.CliKeyFile),
+			fmt.Sprintf("--cacert=%s/%s", operatorEtcdTLSDir, etcdutil.CliCAFile),
+		)
+		mounts = append(mounts, v1.VolumeMount{Name: operatorEtcdTLSVolume, MountPath: operatorEtcdTLSDir})
+		volumes = append(volumes, v1.Volume{Name: operatorEtcdTLSVolume, VolumeSource: v1.VolumeSource{
+			Secret: &v1.SecretVolumeSource{SecretName: cs.TLS.Static.OperatorSecret},
+		}})
+	}
+	if len(mp.DestinationTLSSecret) != 0 {
+		args = append(args,
+			fmt.Sprintf("--dest-cert=%s/%s", mirrorDestinationTLSDir, etcdutil.CliCertFile),
+			fmt.Sprintf("--dest-key=%s/%s", mirrorDestinationTLSDir, etcdutil.CliKeyFile),
+			fmt.Sprintf("--dest-cacert=%s/%s", mirrorDestinationTLSDir, etcdutil.CliCAFile),
+		)
+		mounts = append(mounts, v1.VolumeMount{Name: mirrorDestinationTLSVolume, MountPath: mirrorDestinationTLSDir})
+		volumes = append(volumes, v1.Volume{Name: mirrorDestinationTLSVolume, VolumeSource: v1.VolumeSource{
+			Secret: &v1.SecretVolumeSource{SecretName: mp.DestinationTLSSecret},
+		}})
+	}
+	if len(mp.Prefix) != 0 {
+		args = append(args, "--prefix="+mp.Prefix)
+	}
+	if len(mp.DestinationPrefix) != 0 {
+		args = append(args, "--dest-prefix="+mp.DestinationPrefix)
+	}
+	args = append(args, MirrorDestinationURL(ns, mp))
+
+	c := v1.Container{
+		Name:         "mirror",
+		Image:        EtcdImageName(cs),
+		Command:      []string{"/usr/local/bin/etcdctl"},
+		Args:         args,
+		Env:          []v1.EnvVar{{Name: "ETCDCTL_API", Value: "3"}},
+		VolumeMounts: mounts,
+	}
+	pl := v1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: MirrorLabels(clusterName),
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{c},
+			Volumes:    volumes,
+		},
+	}
+	if mp.Pod != nil {
+		pl.Spec.Containers[0] = containerWithRequirements(pl.Spec.Containers[0], mp.Pod.Resources)
+	}
+	applyPodPolicyToPodTemplateSpec(clusterName, &pl, mp.Pod)
+	podSpecWithArchitecture(&pl.Spec, cs.EtcdImage.GetArchitecture())
+
+	// Two mirrors would write the same keys concurrently, e.g. during a rolling update.
+	replicas := int32(1)
+	d := &appsv1beta1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   MirrorName(clusterName),
+			Labels: LabelsForCluster(clusterName),
+		},
+		Spec: appsv1beta1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: MirrorLabels(clusterName)},
+			Template: pl,
+			Strategy: appsv1beta1.DeploymentStrategy{
+				Type: appsv1beta1.RecreateDeploymentStrategyType,
+			},
+		},
+	}
+	addOwnerRefToObject(d.GetObjectMeta(), owner)
+	return d
+}
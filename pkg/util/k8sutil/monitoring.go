@@ -0,0 +1,74 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"encoding/json"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+// The Prometheus Operator is not vendored. Its resources are managed through
+// the raw REST client, like the cluster TPRs.
+const monitoringAPIVersion = "monitoring.coreos.com/v1"
+
+func monitoringObjectURI(ns, resource, name string) string {
+	uri := fmt.Sprintf("/apis/%s/namespaces/%s/%s", monitoringAPIVersion, ns, resource)
+	if len(name) != 0 {
+		uri += "/" + name
+	}
+	return uri
+}
+
+// createOrUpdateMonitoringObject creates the given Prometheus Operator object, or
+// replaces the existing one. meta is the metadata of obj.
+func createOrUpdateMonitoringObject(restcli rest.Interface, ns, resource string, meta *metav1.ObjectMeta, obj interface{}) error {
+	b, err := restcli.Get().RequestURI(monitoringObjectURI(ns, resource, meta.Name)).DoRaw()
+	if err != nil {
+		if !IsKubernetesResourceNotFoundError(err) {
+			return err
+		}
+		body, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		_, err = restcli.Post().RequestURI(monitoringObjectURI(ns, resource, "")).Body(body).DoRaw()
+		return err
+	}
+
+	cur := &struct {
+		Metadata metav1.ObjectMeta `json:"metadata"`
+	}{}
+	if err := json.Unmarshal(b, cur); err != nil {
+		return err
+	}
+	meta.ResourceVersion = cur.Metadata.ResourceVersion
+	body, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	_, err = restcli.Put().RequestURI(monitoringObjectURI(ns, resource, meta.Name)).Body(body).DoRaw()
+	return err
+}
+
+func deleteMonitoringObject(restcli rest.Interface, ns, resource, name string) error {
+	_, err := restcli.Delete().RequestURI(monitoringObjectURI(ns, resource, name)).DoRaw()
+	if err != nil && !IsKubernetesResourceNotFoundError(err) {
+		return err
+	}
+	return nil
+}
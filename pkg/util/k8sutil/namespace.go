@@ -0,0 +1,45 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+// namespaceTerminatingMsg is part of the error the namespace lifecycle admission
+// returns on creating objects in a terminating namespace.
+const namespaceTerminatingMsg = "because it is being terminated"
+
+// IsNamespaceTerminatingError returns whether err is the rejection of
+// a create call in a terminating namespace. err might be wrapped.
+func IsNamespaceTerminatingError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), namespaceTerminatingMsg)
+}
+
+// IsNamespaceTerminating returns whether the namespace is being terminated or already gone.
+func IsNamespaceTerminating(kubecli kubernetes.Interface, ns string) (bool, error) {
+	n, err := kubecli.CoreV1().Namespaces().Get(ns, metav1.GetOptions{})
+	if err != nil {
+		if IsKubernetesResourceNotFoundError(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	return n.Status.Phase == v1.NamespaceTerminating || n.DeletionTimestamp != nil, nil
+}
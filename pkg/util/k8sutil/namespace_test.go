@@ -0,0 +1,74 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+// TestAddressesInClusterNamespace checks that clusters of the same name in different
+// namespaces address their own members and services.
+func TestAddressesInClusterNamespace(t *testing.T) {
+	for _, ns := range []string{"default", "team-a", "team-b"} {
+		svcSuffix := "." + ns + ".svc.cluster.local"
+		m := &etcdutil.Member{Name: "test-0000", Namespace: ns}
+
+		if addr := m.Addr(); addr != "test-0000.test"+svcSuffix {
+			t.Errorf("%s: member addr get=%s, want=%s", ns, addr, "test-0000.test"+svcSuffix)
+		}
+		if addr, w := BackupServiceAddr("test", ns), "test-backup-sidecar"+svcSuffix+":19999"; addr != w {
+			t.Errorf("%s: backup service addr get=%s, want=%s", ns, addr, w)
+		}
+
+		pod := &v1.Pod{}
+		AddRecoveryToPod(pod, "test", "token", "3.1.8", m, spec.ClusterSpec{Version: "3.1.8"})
+		if cmd := pod.Spec.InitContainers[0].Command[2]; !strings.Contains(cmd, "test-backup-sidecar"+svcSuffix) {
+			t.Errorf("%s: fetch backup command (%s) does not use the backup service of the namespace", ns, cmd)
+		}
+
+		cs := spec.ClusterSpec{Version: "3.1.8", GRPCProxy: &spec.GRPCProxyPolicy{Size: 1}}
+		d := NewGRPCProxyDeploymentManifest("test", ns, cs, metav1.OwnerReference{})
+		if ep := d.Spec.Template.Spec.Containers[0].Args[0]; !strings.Contains(ep, "test-client"+svcSuffix) {
+			t.Errorf("%s: grpc proxy endpoints (%s) do not use the client service of the namespace", ns, ep)
+		}
+	}
+}
+
+func TestIsNamespaceTerminatingError(t *testing.T) {
+	terminating := errors.New(`pods "test-0000" is forbidden: unable to create new content in namespace team-a because it is being terminated.`)
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: nil, want: false},
+		{err: errors.New(`pods "test-0000" is forbidden: exceeded quota`), want: false},
+		{err: terminating, want: true},
+		{err: fmt.Errorf("fail to create seed member: %v", terminating), want: true},
+	}
+	for i, tt := range tests {
+		if get := IsNamespaceTerminatingError(tt.err); get != tt.want {
+			t.Errorf("#%d: get=%v, want=%v", i, get, tt.want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/constants"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+const backupNFSVolName = "etcd-backup-nfs"
+
+// PodSpecWithNFS mounts the directory of the namespace on the NFS export at the backup mount dir,
+// which gives the backup sidecar the same layout as a backup PV.
+func PodSpecWithNFS(ps *v1.PodSpec, nfs spec.NFSSource, ns string) {
+	ps.Containers[0].VolumeMounts = []v1.VolumeMount{nfsVolumeMount(ns)}
+	ps.Volumes = []v1.Volume{nfsVolume(nfs)}
+}
+
+func nfsVolumeMount(ns string) v1.VolumeMount {
+	return v1.VolumeMount{
+		Name:      backupNFSVolName,
+		MountPath: constants.BackupMountDir,
+		SubPath:   ns,
+	}
+}
+
+func nfsVolume(nfs spec.NFSSource) v1.Volume {
+	return v1.Volume{
+		Name: backupNFSVolName,
+		VolumeSource: v1.VolumeSource{
+			NFS: &v1.NFSVolumeSource{
+				Server: nfs.Server,
+				Path:   nfs.Path,
+			},
+		},
+	}
+}
+
+// CopyNFSBackups copies the backups of fromClusterName to toClusterName on the NFS export.
+func CopyNFSBackups(kubecli kubernetes.Interface, nfs spec.NFSSource, fromClusterName, toClusterName, ns string, owner metav1.OwnerReference) error {
+	from := path.Join(constants.BackupMountDir, PVBackupV1, fromClusterName)
+	to := path.Join(constants.BackupMountDir, PVBackupV1, toClusterName)
+	pod := newNFSBackupPod(copyVolumePodName(toClusterName), nfs, ns,
+		fmt.Sprintf("mkdir -p %[2]s; cp -r %[1]s/* %[2]s/", from, to))
+	pod.Labels = map[string]string{"etcd_cluster": toClusterName}
+	addOwnerRefToObject(pod.GetObjectMeta(), owner)
+	return runBackupPod(kubecli, ns, pod)
+}
+
+// DeleteNFSBackups deletes the backups of the cluster on the NFS export.
+// The pod has no owner and no cluster label, since the cluster is usually deleted already
+// and the pod must not be garbage collected before it finishes.
+func DeleteNFSBackups(kubecli kubernetes.Interface, nfs spec.NFSSource, clusterName, ns string) error {
+	dir := path.Join(constants.BackupMountDir, PVBackupV1, clusterName)
+	pod := newNFSBackupPod(clusterName+"-deletebackups", nfs, ns, "rm -rf "+dir)
+	return runBackupPod(kubecli, ns, pod)
+}
+
+func newNFSBackupPod(name string, nfs spec.NFSSource, ns, cmd string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{
+					Name:         "nfs-backup",
+					Image:        AlpineImage,
+					Command:      []string{"/bin/sh", "-ec", cmd},
+					VolumeMounts: []v1.VolumeMount{nfsVolumeMount(ns)},
+				},
+			},
+			RestartPolicy: v1.RestartPolicyNever,
+			Volumes:       []v1.Volume{nfsVolume(nfs)},
+		},
+	}
+}
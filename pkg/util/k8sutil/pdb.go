@@ -0,0 +1,81 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	policyv1beta1 "k8s.io/client-go/pkg/apis/policy/v1beta1"
+)
+
+func PDBName(clusterName string) string {
+	return clusterName
+}
+
+// CreateOrReplacePDB makes sure the etcd cluster has a PodDisruptionBudget which allows
+// one voluntary disruption at a time, or maxUnavailable if greater than 0, see NewEtcdPDBManifest.
+// PodDisruptionBudget spec is immutable, so an existing budget with a different
+// minAvailable is deleted and recreated.
+func CreateOrReplacePDB(kubecli kubernetes.Interface, clusterName, ns string, size, maxUnavailable int, owner metav1.OwnerReference) error {
+	pdbcli := kubecli.PolicyV1beta1().PodDisruptionBudgets(ns)
+	want := NewEtcdPDBManifest(clusterName, size, maxUnavailable, owner)
+
+	cur, err := pdbcli.Get(want.Name, metav1.GetOptions{})
+	if err != nil {
+		if !IsKubernetesResourceNotFoundError(err) {
+			return err
+		}
+		_, err = pdbcli.Create(want)
+		return err
+	}
+	if cur.Spec.MinAvailable == want.Spec.MinAvailable {
+		return nil
+	}
+	err = pdbcli.Delete(want.Name, nil)
+	if err != nil && !IsKubernetesResourceNotFoundError(err) {
+		return err
+	}
+	_, err = pdbcli.Create(want)
+	return err
+}
+
+// NewEtcdPDBManifest returns a PodDisruptionBudget which keeps all but maxUnavailable members
+// of the given cluster size available, or all but one if maxUnavailable is 0, and never less than a quorum.
+// Clusters of one or two members cannot lose any member without losing their quorum,
+// so their budget allows no eviction at all.
+func NewEtcdPDBManifest(clusterName string, size, maxUnavailable int, owner metav1.OwnerReference) *policyv1beta1.PodDisruptionBudget {
+	if maxUnavailable <= 0 {
+		maxUnavailable = 1
+	}
+	minAvailable := size - maxUnavailable
+	if quorum := size/2 + 1; minAvailable < quorum {
+		minAvailable = quorum
+	}
+	pdb := &policyv1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   PDBName(clusterName),
+			Labels: LabelsForCluster(clusterName),
+		},
+		Spec: policyv1beta1.PodDisruptionBudgetSpec{
+			MinAvailable: intstr.FromInt(minAvailable),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: LabelsForCluster(clusterName),
+			},
+		},
+	}
+	addOwnerRefToObject(pdb.GetObjectMeta(), owner)
+	return pdb
+}
@@ -0,0 +1,47 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewEtcdPDBManifestMinAvailable(t *testing.T) {
+	tests := []struct {
+		size           int
+		maxUnavailable int
+		wMinAvailable  int
+	}{
+		// one and two members allow no eviction
+		{size: 1, wMinAvailable: 1},
+		{size: 2, wMinAvailable: 2},
+		{size: 3, wMinAvailable: 2},
+		{size: 4, wMinAvailable: 3},
+		{size: 5, wMinAvailable: 4},
+		{size: 7, wMinAvailable: 6},
+		{size: 5, maxUnavailable: 1, wMinAvailable: 4},
+		{size: 7, maxUnavailable: 2, wMinAvailable: 5},
+		// never less than a quorum
+		{size: 5, maxUnavailable: 3, wMinAvailable: 3},
+	}
+	for i, tt := range tests {
+		pdb := NewEtcdPDBManifest("test", tt.size, tt.maxUnavailable, metav1.OwnerReference{})
+		if ma := pdb.Spec.MinAvailable.IntValue(); ma != tt.wMinAvailable {
+			t.Errorf("#%d: minAvailable get=%d, want=%d", i, ma, tt.wMinAvailable)
+		}
+	}
+}
@@ -20,12 +20,20 @@ import (
 	"github.com/coreos/etcd-operator/pkg/spec"
 	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
 
+	"github.com/coreos/go-semver/semver"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/pkg/api/v1"
 )
 
 const (
 	etcdVolumeName = "etcd-data"
+
+	// ZoneLabelKey is the node label that identifies the availability zone of a node.
+	ZoneLabelKey = "failure-domain.beta.kubernetes.io/zone"
+	// TopologyZoneLabelKey replaces ZoneLabelKey from Kubernetes 1.17 on.
+	TopologyZoneLabelKey = "topology.kubernetes.io/zone"
+	// ArchLabelKey is the node label that identifies the CPU architecture of a node.
+	ArchLabelKey = "beta.kubernetes.io/arch"
 )
 
 func etcdVolumeMounts() []v1.VolumeMount {
@@ -34,11 +42,11 @@ func etcdVolumeMounts() []v1.VolumeMount {
 	}
 }
 
-func etcdContainer(commands, version string) v1.Container {
+func etcdContainer(commands string, cs spec.ClusterSpec) v1.Container {
 	c := v1.Container{
 		Command: []string{"/bin/sh", "-ec", commands},
 		Name:    "etcd",
-		Image:   EtcdImageName(version),
+		Image:   EtcdImageName(cs),
 		Ports: []v1.ContainerPort{
 			{
 				Name:          "server",
@@ -57,6 +65,30 @@ func etcdContainer(commands, version string) v1.Container {
 	return c
 }
 
+// checkDNSInitContainer returns an init container which waits until the DNS
+// record of the member resolves.
+func checkDNSInitContainer(m *etcdutil.Member) v1.Container {
+	return v1.Container{
+		Name:  "check-dns",
+		Image: BusyboxImage,
+		Command: []string{"/bin/sh", "-c", fmt.Sprintf(`
+			while ( ! nslookup %s )
+			do
+				sleep 1
+			done`, m.Addr())},
+		ImagePullPolicy: v1.PullIfNotPresent,
+	}
+}
+
+// initContainerPullPolicy returns the pull policy of the init containers the operator adds to etcd pods:
+// spec.pod.imagePullPolicy if set, or IfNotPresent since their images have pinned tags.
+func initContainerPullPolicy(cs spec.ClusterSpec) v1.PullPolicy {
+	if cs.Pod != nil && len(cs.Pod.ImagePullPolicy) != 0 {
+		return cs.Pod.ImagePullPolicy
+	}
+	return v1.PullIfNotPresent
+}
+
 func containerWithLivenessProbe(c v1.Container, lp *v1.Probe) v1.Container {
 	c.LivenessProbe = lp
 	return c
@@ -67,17 +99,33 @@ func containerWithRequirements(c v1.Container, r v1.ResourceRequirements) v1.Con
 	return c
 }
 
-func etcdLivenessProbe(isSecure bool) *v1.Probe {
-	// etcd pod is alive only if a linearizable get succeeds.
-	cmd := "ETCDCTL_API=3 etcdctl get foo"
+func containerWithReadinessProbe(c v1.Container, rp *v1.Probe) v1.Container {
+	c.ReadinessProbe = rp
+	return c
+}
+
+// etcdctlCommand returns the etcdctl v3 command that talks to the given endpoints,
+// or to the local etcd member if endpoints is empty.
+func etcdctlCommand(isSecure bool, endpoints, args string) string {
+	flags := ""
 	if isSecure {
-		tlsFlags := fmt.Sprintf("--cert=%[1]s/%[2]s --key=%[1]s/%[3]s --cacert=%[1]s/%[4]s", operatorEtcdTLSDir, etcdutil.CliCertFile, etcdutil.CliKeyFile, etcdutil.CliCAFile)
-		cmd = fmt.Sprintf("ETCDCTL_API=3 etcdctl --endpoints=https://localhost:2379 %s get foo", tlsFlags)
+		if len(endpoints) == 0 {
+			endpoints = "https://localhost:2379"
+		}
+		flags = fmt.Sprintf(" --cert=%[1]s/%[2]s --key=%[1]s/%[3]s --cacert=%[1]s/%[4]s", operatorEtcdTLSDir, etcdutil.CliCertFile, etcdutil.CliKeyFile, etcdutil.CliCAFile)
 	}
+	if len(endpoints) != 0 {
+		flags = " --endpoints=" + endpoints + flags
+	}
+	return fmt.Sprintf("ETCDCTL_API=3 etcdctl%s %s", flags, args)
+}
+
+func etcdLivenessProbe(isSecure bool) *v1.Probe {
+	// etcd pod is alive only if a linearizable get succeeds.
 	return &v1.Probe{
 		Handler: v1.Handler{
 			Exec: &v1.ExecAction{
-				Command: []string{"/bin/sh", "-ec", cmd},
+				Command: []string{"/bin/sh", "-ec", etcdctlCommand(isSecure, "", "get foo")},
 			},
 		},
 		InitialDelaySeconds: 10,
@@ -87,6 +135,118 @@ func etcdLivenessProbe(isSecure bool) *v1.Probe {
 	}
 }
 
+// supportsLeaderTransfer returns true if etcdctl of the given etcd version can move the leadership.
+func supportsLeaderTransfer(version string) bool {
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false
+	}
+	return !v.LessThan(*semver.New("3.3.0"))
+}
+
+// etcdctlTakesPassword returns true if etcdctl of the given etcd version accepts the password
+// separately from the user name.
+func etcdctlTakesPassword(version string) bool {
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false
+	}
+	return !v.LessThan(*semver.New("3.4.0"))
+}
+
+// etcdctlAuthEnv returns the environment variables which let etcdctl in the etcd container
+// authenticate with the username and password in the given secret.
+// etcdctl before 3.4 only takes them together in ETCDCTL_USER, as "username:password".
+// Unknown versions get that form too, since later versions still accept it.
+func etcdctlAuthEnv(secret, version string) []v1.EnvVar {
+	secretEnv := func(name, key string) v1.EnvVar {
+		return v1.EnvVar{Name: name, ValueFrom: &v1.EnvVarSource{
+			SecretKeyRef: &v1.SecretKeySelector{
+				LocalObjectReference: v1.LocalObjectReference{Name: secret},
+				Key:                  key,
+			},
+		}}
+	}
+	if etcdctlTakesPassword(version) {
+		return []v1.EnvVar{
+			secretEnv("ETCDCTL_USER", etcdAuthUsernameKey),
+			secretEnv("ETCDCTL_PASSWORD", etcdAuthPasswordKey),
+		}
+	}
+	return []v1.EnvVar{
+		secretEnv("AUTH_USERNAME", etcdAuthUsernameKey),
+		secretEnv("AUTH_PASSWORD", etcdAuthPasswordKey),
+		{Name: "ETCDCTL_USER", Value: "$(AUTH_USERNAME):$(AUTH_PASSWORD)"},
+	}
+}
+
+// etcdLeaderTransferHandler returns a handler which transfers the leadership away
+// from the local member if it is the leader, so that clients do not stall on
+// a leader election when the member stops.
+func etcdLeaderTransferHandler(isSecure bool) *v1.Handler {
+	script := fmt.Sprintf(`
+		status=$(%s) || exit 0
+		id=$(echo "$status" | awk -F ', ' '{print $2}')
+		leader=$(echo "$status" | awk -F ', ' '{print $5}')
+		[ "$leader" = "true" ] || exit 0
+		target=$(%s | awk -F ', ' -v id="$id" '$1 != id && $2 == "started" {print $1; exit}')
+		[ -n "$target" ] || exit 0
+		%s "$target" || true`,
+		etcdctlCommand(isSecure, "", "endpoint status"),
+		etcdctlCommand(isSecure, "", "member list"),
+		etcdctlCommand(isSecure, "", "move-leader"))
+	return &v1.Handler{
+		Exec: &v1.ExecAction{
+			Command: []string{"/bin/sh", "-c", script},
+		},
+	}
+}
+
+// readinessMaxRaftIndexLag is the maximum number of raft entries a member can fall behind the leader
+// and still be ready. It matches the lag up to which the operator considers a member in sync.
+const readinessMaxRaftIndexLag = 1000
+
+// raftIndexField returns the field of the raft index in the output of "etcdctl endpoint status"
+// of the given etcd version. etcdctl 3.4 added the "is learner" field before it.
+func raftIndexField(version string) int {
+	v, err := semver.NewVersion(version)
+	if err != nil || v.LessThan(*semver.New("3.4.0")) {
+		return 7
+	}
+	return 8
+}
+
+func etcdReadinessProbe(isSecure bool, version string) *v1.Probe {
+	// etcd pod is ready to serve clients only if the member is part of a healthy cluster
+	// and in sync with the leader, so that clients are not routed to a member which is still
+	// catching up, e.g. after it joined or restarted, and serves stale serializable reads.
+	// Unlike the liveness probe, it fails fast so that the client service stops
+	// routing to a member soon after it becomes unhealthy.
+	script := fmt.Sprintf(`
+		%s
+		index=$(%s | awk -F ', ' '{print $%[3]d}')
+		eps=$(%s | awk -F ', ' '$2 == "started" {printf "%%s%%s", sep, $5; sep=","}')
+		leader=$(%s | awk -F ', ' '$5 == "true" {print $%[3]d; exit}')
+		[ -n "$leader" ] && [ $((leader - index)) -le %[6]d ]`,
+		etcdctlCommand(isSecure, "", "endpoint health"),
+		etcdctlCommand(isSecure, "", "endpoint status"),
+		raftIndexField(version),
+		etcdctlCommand(isSecure, "", "member list"),
+		etcdctlCommand(isSecure, `"$eps"`, "endpoint status"),
+		readinessMaxRaftIndexLag)
+	return &v1.Probe{
+		Handler: v1.Handler{
+			Exec: &v1.ExecAction{
+				Command: []string{"/bin/sh", "-ec", script},
+			},
+		},
+		InitialDelaySeconds: 5,
+		TimeoutSeconds:      5,
+		PeriodSeconds:       10,
+		FailureThreshold:    3,
+	}
+}
+
 func PodWithAntiAffinity(pod *v1.Pod, clusterName string) *v1.Pod {
 	// set pod anti-affinity with the pods that belongs to the same etcd cluster
 	ls := &metav1.LabelSelector{MatchLabels: map[string]string{
@@ -95,6 +255,65 @@ func PodWithAntiAffinity(pod *v1.Pod, clusterName string) *v1.Pod {
 	return podWithAntiAffinity(pod, ls)
 }
 
+// PodWithPreferredAntiAffinity is like PodWithAntiAffinity, but only asks the scheduler to
+// spread the pods of the same etcd cluster onto different nodes if possible.
+func PodWithPreferredAntiAffinity(pod *v1.Pod, clusterName string) *v1.Pod {
+	ls := &metav1.LabelSelector{MatchLabels: map[string]string{
+		"etcd_cluster": clusterName,
+	}}
+	affinity := &v1.Affinity{
+		PodAntiAffinity: &v1.PodAntiAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []v1.WeightedPodAffinityTerm{
+				{
+					Weight: 100,
+					PodAffinityTerm: v1.PodAffinityTerm{
+						LabelSelector: ls,
+						TopologyKey:   "kubernetes.io/hostname",
+					},
+				},
+			},
+		},
+	}
+
+	pod.Spec.Affinity = affinity
+	return pod
+}
+
+// podWithZoneSpread asks the scheduler to spread the pods of the same etcd cluster
+// across zones, by either zone label of the nodes, see NodeZone. The spreading is preferred
+// rather than required since clusters commonly have more members than there are zones.
+func podWithZoneSpread(pod *v1.Pod, clusterName string) *v1.Pod {
+	if pod.Spec.Affinity == nil {
+		pod.Spec.Affinity = &v1.Affinity{}
+	}
+	if pod.Spec.Affinity.PodAntiAffinity == nil {
+		pod.Spec.Affinity.PodAntiAffinity = &v1.PodAntiAffinity{}
+	}
+	paa := pod.Spec.Affinity.PodAntiAffinity
+	for _, key := range []string{ZoneLabelKey, TopologyZoneLabelKey} {
+		term := v1.WeightedPodAffinityTerm{
+			Weight: 100,
+			PodAffinityTerm: v1.PodAffinityTerm{
+				LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{
+					"etcd_cluster": clusterName,
+				}},
+				TopologyKey: key,
+			},
+		}
+		paa.PreferredDuringSchedulingIgnoredDuringExecution = append(paa.PreferredDuringSchedulingIgnoredDuringExecution, term)
+	}
+	return pod
+}
+
+// NodeZone returns the zone of the node from its ZoneLabelKey label, or its TopologyZoneLabelKey label
+// on clusters which only set the latter.
+func NodeZone(node *v1.Node) string {
+	if zone := node.Labels[ZoneLabelKey]; len(zone) != 0 {
+		return zone
+	}
+	return node.Labels[TopologyZoneLabelKey]
+}
+
 func podWithAntiAffinity(pod *v1.Pod, ls *metav1.LabelSelector) *v1.Pod {
 	affinity := &v1.Affinity{
 		PodAntiAffinity: &v1.PodAntiAffinity{
@@ -116,8 +335,20 @@ func applyPodPolicy(clusterName string, pod *v1.Pod, policy *spec.PodPolicy) {
 		return
 	}
 
-	if policy.AntiAffinity {
-		pod = PodWithAntiAffinity(pod, clusterName)
+	switch {
+	case policy.Affinity != nil:
+		pod.Spec.Affinity = policy.Affinity
+	case policy.GetAntiAffinityMode() == spec.AntiAffinityPreferred:
+		pod = PodWithPreferredAntiAffinity(pod, clusterName)
+	case policy.GetAntiAffinityMode() == spec.AntiAffinityDisabled:
+		pod.Spec.Affinity = nil
+	}
+	if policy.Affinity == nil && policy.SpreadAcrossZones {
+		pod = podWithZoneSpread(pod, clusterName)
+	}
+
+	if policy.GetLivenessFailurePolicy() == spec.LivenessFailureRestartContainer {
+		pod.Spec.RestartPolicy = v1.RestartPolicyAlways
 	}
 
 	if len(policy.NodeSelector) != 0 {
@@ -127,7 +358,30 @@ func applyPodPolicy(clusterName string, pod *v1.Pod, policy *spec.PodPolicy) {
 		pod.Spec.Tolerations = policy.Tolerations
 	}
 
-	mergeLabels(pod.Labels, policy.Labels)
+	if len(policy.ImagePullSecrets) != 0 {
+		pod.Spec.ImagePullSecrets = policy.ImagePullSecrets
+	}
+	pod.Spec.InitContainers = append(pod.Spec.InitContainers, policy.InitContainers...)
+	if len(policy.ImagePullPolicy) != 0 {
+		for i := range pod.Spec.InitContainers {
+			pod.Spec.InitContainers[i].ImagePullPolicy = policy.ImagePullPolicy
+		}
+		for i := range pod.Spec.Containers {
+			pod.Spec.Containers[i].ImagePullPolicy = policy.ImagePullPolicy
+		}
+	}
+
+	pod.Spec.Containers = append(pod.Spec.Containers, policy.Sidecars...)
+	pod.Spec.Volumes = append(pod.Spec.Volumes, policy.SidecarVolumes...)
+	if policy.AutomountServiceAccountToken != nil {
+		pod.Spec.AutomountServiceAccountToken = policy.AutomountServiceAccountToken
+	}
+	if policy.TerminationGracePeriodSeconds != nil {
+		pod.Spec.TerminationGracePeriodSeconds = policy.TerminationGracePeriodSeconds
+	}
+
+	mergeStringMaps(pod.Labels, policy.Labels)
+	mergeStringMaps(pod.Annotations, policy.Annotations)
 
 	for i := range pod.Spec.Containers {
 		if pod.Spec.Containers[i].Name == "etcd" {
@@ -136,7 +390,47 @@ func applyPodPolicy(clusterName string, pod *v1.Pod, policy *spec.PodPolicy) {
 	}
 }
 
-// only used for backup pod.
+// podSpecWithArchitecture requires the pod to run on nodes of the given architecture, if any.
+// The requirement is added to every node selector term of the node affinity, since the terms are ORed.
+// The affinity is copied, since it might be the one of the pod policy.
+func podSpecWithArchitecture(ps *v1.PodSpec, arch string) {
+	if len(arch) == 0 {
+		return
+	}
+	req := v1.NodeSelectorRequirement{
+		Key:      ArchLabelKey,
+		Operator: v1.NodeSelectorOpIn,
+		Values:   []string{arch},
+	}
+
+	affinity := v1.Affinity{}
+	if ps.Affinity != nil {
+		affinity = *ps.Affinity
+	}
+	na := v1.NodeAffinity{}
+	if affinity.NodeAffinity != nil {
+		na = *affinity.NodeAffinity
+	}
+	sel := v1.NodeSelector{}
+	if na.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+		sel = *na.RequiredDuringSchedulingIgnoredDuringExecution
+	}
+
+	terms := make([]v1.NodeSelectorTerm, 0, len(sel.NodeSelectorTerms))
+	for _, t := range sel.NodeSelectorTerms {
+		exprs := append(append([]v1.NodeSelectorRequirement{}, t.MatchExpressions...), req)
+		terms = append(terms, v1.NodeSelectorTerm{MatchExpressions: exprs})
+	}
+	if len(terms) == 0 {
+		terms = []v1.NodeSelectorTerm{{MatchExpressions: []v1.NodeSelectorRequirement{req}}}
+	}
+	sel.NodeSelectorTerms = terms
+	na.RequiredDuringSchedulingIgnoredDuringExecution = &sel
+	affinity.NodeAffinity = &na
+	ps.Affinity = &affinity
+}
+
+// only used for backup, debug, grpc proxy, gateway, mirror and restore pods.
 func applyPodPolicyToPodTemplateSpec(clusterName string, pod *v1.PodTemplateSpec, policy *spec.PodPolicy) {
 	if policy == nil {
 		return
@@ -150,8 +444,22 @@ func applyPodPolicyToPodTemplateSpec(clusterName string, pod *v1.PodTemplateSpec
 	if len(policy.Tolerations) != 0 {
 		pod.Spec.Tolerations = policy.Tolerations
 	}
+	if len(policy.ImagePullSecrets) != 0 {
+		pod.Spec.ImagePullSecrets = policy.ImagePullSecrets
+	}
+	if len(policy.ImagePullPolicy) != 0 {
+		for i := range pod.Spec.Containers {
+			pod.Spec.Containers[i].ImagePullPolicy = policy.ImagePullPolicy
+		}
+	}
 
-	mergeLabels(pod.Labels, policy.Labels)
+	mergeStringMaps(pod.Labels, policy.Labels)
+	if len(policy.Annotations) != 0 {
+		if pod.Annotations == nil {
+			pod.Annotations = map[string]string{}
+		}
+		mergeStringMaps(pod.Annotations, policy.Annotations)
+	}
 }
 
 // IsPodReady returns false if the Pod Status is nil
@@ -0,0 +1,463 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+func newTestEtcdPod(cs spec.ClusterSpec) *v1.Pod {
+	m := &etcdutil.Member{Name: "test-0000", Namespace: "default"}
+	return NewEtcdPod(m, []string{"test-0000=http://test-0000.test.default.svc.cluster.local:2380"}, "test", "new", "token", cs, metav1.OwnerReference{})
+}
+
+func TestNewEtcdPodWithNodeSelector(t *testing.T) {
+	ns := map[string]string{"disk": "nvme"}
+	pod := newTestEtcdPod(spec.ClusterSpec{Version: "3.1.8", Pod: &spec.PodPolicy{NodeSelector: ns}})
+	if !reflect.DeepEqual(pod.Spec.NodeSelector, ns) {
+		t.Errorf("node selector get=%v, want=%v", pod.Spec.NodeSelector, ns)
+	}
+
+	pod = newTestEtcdPod(spec.ClusterSpec{Version: "3.1.8"})
+	if len(pod.Spec.NodeSelector) != 0 {
+		t.Errorf("expect no node selector, get=%v", pod.Spec.NodeSelector)
+	}
+}
+
+func TestNewBackupPodTemplateWithNodeSelector(t *testing.T) {
+	ns := map[string]string{"disk": "nvme"}
+	cs := spec.ClusterSpec{
+		Version: "3.1.8",
+		Backup:  &spec.BackupPolicy{Pod: &spec.PodPolicy{NodeSelector: ns}},
+	}
+	pt := NewBackupPodTemplate("test", "default", cs)
+	if !reflect.DeepEqual(pt.Spec.NodeSelector, ns) {
+		t.Errorf("node selector get=%v, want=%v", pt.Spec.NodeSelector, ns)
+	}
+}
+
+func TestNewEtcdPodAffinity(t *testing.T) {
+	pod := newTestEtcdPod(spec.ClusterSpec{Version: "3.1.8"})
+	paa := pod.Spec.Affinity.PodAntiAffinity
+	if paa == nil || len(paa.RequiredDuringSchedulingIgnoredDuringExecution) != 1 {
+		t.Fatalf("expect required pod anti-affinity by default, get=%v", pod.Spec.Affinity)
+	}
+
+	pod = newTestEtcdPod(spec.ClusterSpec{Version: "3.1.8", Pod: &spec.PodPolicy{PreferredAntiAffinity: true}})
+	paa = pod.Spec.Affinity.PodAntiAffinity
+	if len(paa.RequiredDuringSchedulingIgnoredDuringExecution) != 0 || len(paa.PreferredDuringSchedulingIgnoredDuringExecution) != 1 {
+		t.Errorf("expect preferred pod anti-affinity, get=%v", pod.Spec.Affinity)
+	}
+
+	pod = newTestEtcdPod(spec.ClusterSpec{Version: "3.1.8", Pod: &spec.PodPolicy{AntiAffinityMode: spec.AntiAffinityPreferred}})
+	paa = pod.Spec.Affinity.PodAntiAffinity
+	if len(paa.RequiredDuringSchedulingIgnoredDuringExecution) != 0 || len(paa.PreferredDuringSchedulingIgnoredDuringExecution) != 1 {
+		t.Errorf("expect preferred pod anti-affinity, get=%v", pod.Spec.Affinity)
+	}
+
+	pod = newTestEtcdPod(spec.ClusterSpec{Version: "3.1.8", Pod: &spec.PodPolicy{AntiAffinityMode: spec.AntiAffinityDisabled}})
+	if pod.Spec.Affinity != nil {
+		t.Errorf("expect no affinity, get=%v", pod.Spec.Affinity)
+	}
+
+	af := &v1.Affinity{NodeAffinity: &v1.NodeAffinity{}}
+	pod = newTestEtcdPod(spec.ClusterSpec{Version: "3.1.8", Pod: &spec.PodPolicy{Affinity: af}})
+	if pod.Spec.Affinity != af {
+		t.Errorf("expect affinity override, get=%v", pod.Spec.Affinity)
+	}
+}
+
+func TestNewEtcdPodSpreadAcrossZones(t *testing.T) {
+	pod := newTestEtcdPod(spec.ClusterSpec{Version: "3.1.8", Pod: &spec.PodPolicy{SpreadAcrossZones: true}})
+	paa := pod.Spec.Affinity.PodAntiAffinity
+	if len(paa.RequiredDuringSchedulingIgnoredDuringExecution) != 1 {
+		t.Errorf("expect node anti-affinity to be kept, get=%v", pod.Spec.Affinity)
+	}
+	pterms := paa.PreferredDuringSchedulingIgnoredDuringExecution
+	if len(pterms) != 2 || pterms[0].PodAffinityTerm.TopologyKey != ZoneLabelKey || pterms[1].PodAffinityTerm.TopologyKey != TopologyZoneLabelKey {
+		t.Errorf("expect preferred zone anti-affinity, get=%v", pod.Spec.Affinity)
+	}
+}
+
+func TestNodeZone(t *testing.T) {
+	tests := []struct {
+		labels map[string]string
+		w      string
+	}{
+		{labels: nil, w: ""},
+		{labels: map[string]string{ZoneLabelKey: "us-east-1a"}, w: "us-east-1a"},
+		{labels: map[string]string{TopologyZoneLabelKey: "us-east-1b"}, w: "us-east-1b"},
+		{labels: map[string]string{ZoneLabelKey: "us-east-1a", TopologyZoneLabelKey: "us-east-1b"}, w: "us-east-1a"},
+	}
+	for i, tt := range tests {
+		node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Labels: tt.labels}}
+		if get := NodeZone(node); get != tt.w {
+			t.Errorf("#%d: zone get=%s, want=%s", i, get, tt.w)
+		}
+	}
+}
+
+func TestNewEtcdPodWithArchitecture(t *testing.T) {
+	archTerm := v1.NodeSelectorRequirement{Key: ArchLabelKey, Operator: v1.NodeSelectorOpIn, Values: []string{"arm64"}}
+	diskTerm := v1.NodeSelectorRequirement{Key: "disk", Operator: v1.NodeSelectorOpIn, Values: []string{"nvme"}}
+	tests := []struct {
+		pp     *spec.PodPolicy
+		wTerms []v1.NodeSelectorTerm
+	}{
+		{pp: nil, wTerms: []v1.NodeSelectorTerm{{MatchExpressions: []v1.NodeSelectorRequirement{archTerm}}}},
+		// user node affinity is kept
+		{
+			pp: &spec.PodPolicy{Affinity: &v1.Affinity{NodeAffinity: &v1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+					NodeSelectorTerms: []v1.NodeSelectorTerm{{MatchExpressions: []v1.NodeSelectorRequirement{diskTerm}}},
+				},
+			}}},
+			wTerms: []v1.NodeSelectorTerm{{MatchExpressions: []v1.NodeSelectorRequirement{diskTerm, archTerm}}},
+		},
+	}
+	for i, tt := range tests {
+		cs := spec.ClusterSpec{Version: "3.2.0", Pod: tt.pp, EtcdImage: &spec.EtcdImagePolicy{Architecture: "arm64"}}
+		// The pod policy must not be modified by creating pods.
+		newTestEtcdPod(cs)
+		pod := newTestEtcdPod(cs)
+		if image := pod.Spec.Containers[0].Image; image != "quay.io/coreos/etcd:v3.2.0-arm64" {
+			t.Errorf("#%d: image get=%s, want=quay.io/coreos/etcd:v3.2.0-arm64", i, image)
+		}
+		terms := pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+		if !reflect.DeepEqual(terms, tt.wTerms) {
+			t.Errorf("#%d: node selector terms get=%v, want=%v", i, terms, tt.wTerms)
+		}
+	}
+
+	pod := newTestEtcdPod(spec.ClusterSpec{Version: "3.2.0"})
+	if pod.Spec.Affinity.NodeAffinity != nil {
+		t.Errorf("expect no node affinity without architecture, get=%v", pod.Spec.Affinity.NodeAffinity)
+	}
+}
+
+func TestNewEtcdPodWithTolerations(t *testing.T) {
+	tolerations := []v1.Toleration{{
+		Key:      "dedicated",
+		Operator: v1.TolerationOpEqual,
+		Value:    "etcd",
+		Effect:   v1.TaintEffectNoSchedule,
+	}}
+	pod := newTestEtcdPod(spec.ClusterSpec{Version: "3.1.8", Pod: &spec.PodPolicy{Tolerations: tolerations}})
+	if !reflect.DeepEqual(pod.Spec.Tolerations, tolerations) {
+		t.Errorf("tolerations get=%v, want=%v", pod.Spec.Tolerations, tolerations)
+	}
+}
+
+func TestNewEtcdPodLivenessFailurePolicy(t *testing.T) {
+	tests := []struct {
+		policy         spec.LivenessFailurePolicy
+		wRestartPolicy v1.RestartPolicy
+	}{
+		{policy: "", wRestartPolicy: v1.RestartPolicyNever},
+		{policy: spec.LivenessFailureReplaceMember, wRestartPolicy: v1.RestartPolicyNever},
+		{policy: spec.LivenessFailureRestartContainer, wRestartPolicy: v1.RestartPolicyAlways},
+	}
+	for i, tt := range tests {
+		pod := newTestEtcdPod(spec.ClusterSpec{Version: "3.1.8", Pod: &spec.PodPolicy{LivenessFailurePolicy: tt.policy}})
+		if pod.Spec.RestartPolicy != tt.wRestartPolicy {
+			t.Errorf("#%d: restart policy get=%v, want=%v", i, pod.Spec.RestartPolicy, tt.wRestartPolicy)
+		}
+	}
+}
+
+func TestNewEtcdPodProbes(t *testing.T) {
+	pod := newTestEtcdPod(spec.ClusterSpec{Version: "3.1.8"})
+	c := pod.Spec.Containers[0]
+	if c.LivenessProbe == nil || c.ReadinessProbe == nil {
+		t.Fatalf("expect liveness and readiness probes, get liveness=%v, readiness=%v", c.LivenessProbe, c.ReadinessProbe)
+	}
+	cmd := c.ReadinessProbe.Exec.Command[2]
+	if strings.Contains(cmd, "--cacert") {
+		t.Errorf("expect no TLS flags for insecure client, get=%s", cmd)
+	}
+	if !strings.Contains(cmd, "endpoint health") || !strings.Contains(cmd, "{print $7; exit}") || !strings.Contains(cmd, "-le 1000") {
+		t.Errorf("expect readiness to check health and the raft index lag behind the leader, get=%s", cmd)
+	}
+
+	cs := spec.ClusterSpec{
+		Version: "3.1.8",
+		TLS: &spec.TLSPolicy{Static: &spec.StaticTLS{
+			Member:         &spec.MemberSecret{ClientSecret: "client"},
+			OperatorSecret: "operator",
+		}},
+	}
+	m := &etcdutil.Member{Name: "test-0000", Namespace: "default", SecureClient: true}
+	pod = NewEtcdPod(m, nil, "test", "new", "token", cs, metav1.OwnerReference{})
+	cmd = pod.Spec.Containers[0].ReadinessProbe.Exec.Command[2]
+	if !strings.Contains(cmd, "--endpoints=https://localhost:2379") || !strings.Contains(cmd, "--cacert") {
+		t.Errorf("expect TLS flags for secure client, get=%s", cmd)
+	}
+	for _, line := range strings.Split(cmd, "\n") {
+		if strings.Contains(line, "etcdctl") && strings.Count(line, "--endpoints=") != 1 {
+			t.Errorf("expect exactly one --endpoints per etcdctl command, get=%s", line)
+		}
+	}
+	if !strings.Contains(cmd, `--endpoints="$eps" --cert=`) {
+		t.Errorf("expect the status of all members to be read from $eps, get=%s", cmd)
+	}
+}
+
+func TestRaftIndexField(t *testing.T) {
+	tests := []struct {
+		version string
+		w       int
+	}{
+		{version: "3.1.8", w: 7},
+		{version: "3.3.10", w: 7},
+		{version: "3.4.0", w: 8},
+		{version: "3.5.2", w: 8},
+		{version: "invalid", w: 7},
+	}
+	for i, tt := range tests {
+		if get := raftIndexField(tt.version); get != tt.w {
+			t.Errorf("#%d: raft index field get=%d, want=%d", i, get, tt.w)
+		}
+	}
+}
+
+func TestNewEtcdPodWithImagePullPolicy(t *testing.T) {
+	secrets := []v1.LocalObjectReference{{Name: "registry-key"}}
+	pp := &spec.PodPolicy{ImagePullSecrets: secrets, ImagePullPolicy: v1.PullAlways}
+	pod := newTestEtcdPod(spec.ClusterSpec{Version: "3.1.8", Pod: pp})
+	if !reflect.DeepEqual(pod.Spec.ImagePullSecrets, secrets) {
+		t.Errorf("image pull secrets get=%v, want=%v", pod.Spec.ImagePullSecrets, secrets)
+	}
+	if p := pod.Spec.Containers[0].ImagePullPolicy; p != v1.PullAlways {
+		t.Errorf("image pull policy get=%v, want=%v", p, v1.PullAlways)
+	}
+
+	pt := NewBackupPodTemplate("test", "default", spec.ClusterSpec{Version: "3.1.8", Backup: &spec.BackupPolicy{Pod: pp}})
+	if !reflect.DeepEqual(pt.Spec.ImagePullSecrets, secrets) {
+		t.Errorf("backup image pull secrets get=%v, want=%v", pt.Spec.ImagePullSecrets, secrets)
+	}
+}
+
+func TestNewEtcdPodWithSidecars(t *testing.T) {
+	sidecar := v1.Container{Name: "log-shipper", Image: "fluent/fluent-bit"}
+	volume := v1.Volume{Name: "shipper-config", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}}
+	pp := &spec.PodPolicy{Sidecars: []v1.Container{sidecar}, SidecarVolumes: []v1.Volume{volume}}
+	pod := newTestEtcdPod(spec.ClusterSpec{Version: "3.1.8", Pod: pp})
+	cs := pod.Spec.Containers
+	if len(cs) != 2 || cs[0].Name != "etcd" || cs[1].Name != sidecar.Name {
+		t.Errorf("expect etcd container followed by sidecar, get=%v", cs)
+	}
+	vs := pod.Spec.Volumes
+	if vs[len(vs)-1].Name != volume.Name {
+		t.Errorf("expect sidecar volume, get=%v", vs)
+	}
+}
+
+func TestNewEtcdPodInitContainers(t *testing.T) {
+	ic := v1.Container{Name: "wait-for-vault", Image: "busybox"}
+	pod := newTestEtcdPod(spec.ClusterSpec{Version: "3.1.8", Pod: &spec.PodPolicy{InitContainers: []v1.Container{ic}}})
+	ics := pod.Spec.InitContainers
+	if len(ics) != 2 || ics[0].Name != "check-dns" || ics[1].Name != ic.Name {
+		t.Fatalf("expect DNS check followed by user init container, get=%v", ics)
+	}
+	if cmd := ics[0].Command[2]; !strings.Contains(cmd, "test-0000.test.default.svc.cluster.local") {
+		t.Errorf("expect DNS check of member address, get=%s", cmd)
+	}
+
+	m := &etcdutil.Member{Name: "test-0000", Namespace: "default"}
+	AddRecoveryToPod(pod, "test", "token", "3.1.8", m, spec.ClusterSpec{Version: "3.1.8"})
+	ics = pod.Spec.InitContainers
+	if len(ics) != 4 || ics[0].Name != "fetch-backup" || ics[1].Name != "restore-datadir" {
+		t.Fatalf("expect restore init containers first, get=%v", ics)
+	}
+	if ics[0].Image != BusyboxImage {
+		t.Errorf("fetch backup image get=%s, want=%s", ics[0].Image, BusyboxImage)
+	}
+	for _, ic := range ics[:3] {
+		if ic.ImagePullPolicy != v1.PullIfNotPresent {
+			t.Errorf("%s pull policy get=%s, want=%s", ic.Name, ic.ImagePullPolicy, v1.PullIfNotPresent)
+		}
+	}
+
+	pod = &v1.Pod{}
+	AddRecoveryToPod(pod, "test", "token", "3.1.8", m, spec.ClusterSpec{Version: "3.1.8", Pod: &spec.PodPolicy{ImagePullPolicy: v1.PullAlways}})
+	for _, ic := range pod.Spec.InitContainers {
+		if ic.ImagePullPolicy != v1.PullAlways {
+			t.Errorf("%s pull policy get=%s, want=%s", ic.Name, ic.ImagePullPolicy, v1.PullAlways)
+		}
+	}
+}
+
+func TestAddRecoveryToPodFromOlderBackup(t *testing.T) {
+	m := &etcdutil.Member{Name: "test-0000", Namespace: "default"}
+	pod := &v1.Pod{}
+	AddRecoveryToPod(pod, "test", "token", "3.0.17", m, spec.ClusterSpec{Version: "3.2.0"})
+	ics := pod.Spec.InitContainers
+	if cmd := ics[0].Command[2]; !strings.Contains(cmd, "etcdVersion=3.0.17") {
+		t.Errorf("expect backup of version 3.0.17 to be fetched, get=%s", cmd)
+	}
+	if get, want := ics[1].Image, EtcdImageName(spec.ClusterSpec{Version: "3.2.0"}); get != want {
+		t.Errorf("restore image get=%s, want=%s", get, want)
+	}
+}
+
+func TestNewEtcdPodWithEtcdEnv(t *testing.T) {
+	env := []v1.EnvVar{{Name: "GODEBUG", Value: "gctrace=1"}}
+	pp := &spec.PodPolicy{EtcdEnv: env, Sidecars: []v1.Container{{Name: "log-shipper"}}}
+	pod := newTestEtcdPod(spec.ClusterSpec{Version: "3.1.8", Pod: pp})
+	if get := pod.Spec.Containers[0].Env; !reflect.DeepEqual(get, env) {
+		t.Errorf("etcd env get=%v, want=%v", get, env)
+	}
+	if get := pod.Spec.Containers[1].Env; len(get) != 0 {
+		t.Errorf("expect no env in sidecar, get=%v", get)
+	}
+}
+
+func TestNewEtcdPodWithEtcdctlAuth(t *testing.T) {
+	tests := []struct {
+		version string
+		wUser   string // value of ETCDCTL_USER, empty if it comes from the secret
+		wEnv    []string
+	}{
+		{version: "3.3.0", wUser: "$(AUTH_USERNAME):$(AUTH_PASSWORD)", wEnv: []string{"AUTH_USERNAME", "AUTH_PASSWORD", "ETCDCTL_USER"}},
+		{version: "3.4.3", wEnv: []string{"ETCDCTL_USER", "ETCDCTL_PASSWORD"}},
+	}
+	for i, tt := range tests {
+		pod := newTestEtcdPod(spec.ClusterSpec{Version: tt.version, Pod: &spec.PodPolicy{EtcdctlAuthSecret: "etcd-probe-user"}})
+		env := pod.Spec.Containers[0].Env
+		var names []string
+		for _, e := range env {
+			names = append(names, e.Name)
+			if e.ValueFrom != nil && e.ValueFrom.SecretKeyRef.Name != "etcd-probe-user" {
+				t.Errorf("#%d: env (%s) secret get=%s, want=etcd-probe-user", i, e.Name, e.ValueFrom.SecretKeyRef.Name)
+			}
+			if e.Name == "ETCDCTL_USER" && e.Value != tt.wUser {
+				t.Errorf("#%d: ETCDCTL_USER get=%q, want=%q", i, e.Value, tt.wUser)
+			}
+		}
+		if !reflect.DeepEqual(names, tt.wEnv) {
+			t.Errorf("#%d: etcd env get=%v, want=%v", i, names, tt.wEnv)
+		}
+	}
+
+	pod := newTestEtcdPod(spec.ClusterSpec{Version: "3.4.3"})
+	if get := pod.Spec.Containers[0].Env; len(get) != 0 {
+		t.Errorf("expect no etcd env without etcdctl auth secret, get=%v", get)
+	}
+}
+
+func TestNewEtcdPodWithAnnotations(t *testing.T) {
+	pp := &spec.PodPolicy{Annotations: map[string]string{
+		"prometheus.io/scrape":   "true",
+		etcdVersionAnnotationKey: "0.0.0",
+	}}
+	pod := newTestEtcdPod(spec.ClusterSpec{Version: "3.1.8", Pod: pp})
+	if v := pod.Annotations["prometheus.io/scrape"]; v != "true" {
+		t.Errorf("annotation get=%v, want=true", v)
+	}
+	if v := GetEtcdVersion(pod); v != "3.1.8" {
+		t.Errorf("expect operator annotations to take precedence, version get=%v, want=3.1.8", v)
+	}
+
+	pt := NewBackupPodTemplate("test", "default", spec.ClusterSpec{Version: "3.1.8", Backup: &spec.BackupPolicy{Pod: pp}})
+	if v := pt.Annotations["prometheus.io/scrape"]; v != "true" {
+		t.Errorf("backup annotation get=%v, want=true", v)
+	}
+}
+
+func TestNewEtcdPodLeaderTransfer(t *testing.T) {
+	tests := []struct {
+		version  string
+		wPreStop bool
+	}{
+		{version: "3.1.8", wPreStop: false},
+		{version: "3.2.9", wPreStop: false},
+		{version: "3.3.0", wPreStop: true},
+		{version: "3.4.3", wPreStop: true},
+	}
+	for i, tt := range tests {
+		pod := newTestEtcdPod(spec.ClusterSpec{Version: tt.version})
+		lc := pod.Spec.Containers[0].Lifecycle
+		if get := lc != nil && lc.PreStop != nil; get != tt.wPreStop {
+			t.Errorf("#%d: pre-stop hook get=%v, want=%v", i, get, tt.wPreStop)
+		}
+	}
+}
+
+func TestNewEtcdPodAutomountServiceAccountToken(t *testing.T) {
+	pod := newTestEtcdPod(spec.ClusterSpec{Version: "3.1.8"})
+	if a := pod.Spec.AutomountServiceAccountToken; a == nil || *a {
+		t.Errorf("expect no service account token by default, get=%v", a)
+	}
+
+	automount := true
+	pod = newTestEtcdPod(spec.ClusterSpec{Version: "3.1.8", Pod: &spec.PodPolicy{AutomountServiceAccountToken: &automount}})
+	if a := pod.Spec.AutomountServiceAccountToken; a == nil || !*a {
+		t.Errorf("expect service account token override, get=%v", a)
+	}
+}
+
+func TestNewEtcdPodWithTerminationGracePeriod(t *testing.T) {
+	gracePeriod := int64(120)
+	pod := newTestEtcdPod(spec.ClusterSpec{Version: "3.1.8", Pod: &spec.PodPolicy{TerminationGracePeriodSeconds: &gracePeriod}})
+	if g := pod.Spec.TerminationGracePeriodSeconds; g == nil || *g != gracePeriod {
+		t.Errorf("termination grace period get=%v, want=%d", g, gracePeriod)
+	}
+}
+
+func TestNewEtcdPodWithAutoCompaction(t *testing.T) {
+	ep := &spec.EtcdPolicy{AutoCompactionMode: spec.AutoCompactionModeRevision, AutoCompactionRetention: "10000"}
+	pod := newTestEtcdPod(spec.ClusterSpec{Version: "3.3.0", Etcd: ep})
+	cmd := pod.Spec.Containers[0].Command[2]
+	if !strings.Contains(cmd, "--auto-compaction-mode=revision --auto-compaction-retention=10000") {
+		t.Errorf("expect auto compaction flags, get=%s", cmd)
+	}
+}
+
+func TestNewEtcdPodWithQuotaBackendBytes(t *testing.T) {
+	pod := newTestEtcdPod(spec.ClusterSpec{Version: "3.1.8", Etcd: &spec.EtcdPolicy{QuotaBackendBytes: 4294967296}})
+	if cmd := pod.Spec.Containers[0].Command[2]; !strings.Contains(cmd, "--quota-backend-bytes=4294967296") {
+		t.Errorf("expect quota flag, get=%s", cmd)
+	}
+}
+
+func TestNewEtcdPodWithPersistentVolumeClaim(t *testing.T) {
+	pvcSpec := &v1.PersistentVolumeClaimSpec{
+		Resources: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse("1Gi")}},
+	}
+	cs := spec.ClusterSpec{Version: "3.1.8", Pod: &spec.PodPolicy{PersistentVolumeClaimSpec: pvcSpec}}
+	pod := newTestEtcdPod(cs)
+	vs := pod.Spec.Volumes[0].VolumeSource
+	if vs.PersistentVolumeClaim == nil || vs.PersistentVolumeClaim.ClaimName != "etcd-data-test-0000" {
+		t.Errorf("expect data volume on PVC etcd-data-test-0000, get=%+v", vs)
+	}
+	pvc := NewMemberPVCManifest(&etcdutil.Member{Name: "test-0000"}, "test", cs, metav1.OwnerReference{})
+	if pvc.Name != "etcd-data-test-0000" || len(pvc.Spec.AccessModes) != 1 || pvc.Spec.AccessModes[0] != v1.ReadWriteOnce {
+		t.Errorf("unexpected member PVC (%+v)", pvc)
+	}
+
+	pod = newTestEtcdPod(spec.ClusterSpec{Version: "3.1.8"})
+	if pod.Spec.Volumes[0].EmptyDir == nil {
+		t.Errorf("expect data volume on emptyDir, get=%+v", pod.Spec.Volumes[0].VolumeSource)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/constants"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	prometheusRuleKind = "PrometheusRule"
+	prometheusRules    = "prometheusrules"
+)
+
+type prometheusRule struct {
+	APIVersion string             `json:"apiVersion"`
+	Kind       string             `json:"kind"`
+	Metadata   metav1.ObjectMeta  `json:"metadata"`
+	Spec       prometheusRuleSpec `json:"spec"`
+}
+
+type prometheusRuleSpec struct {
+	Groups []prometheusRuleGroup `json:"groups"`
+}
+
+type prometheusRuleGroup struct {
+	Name  string         `json:"name"`
+	Rules []alertingRule `json:"rules"`
+}
+
+type alertingRule struct {
+	Alert       string            `json:"alert"`
+	Expr        string            `json:"expr"`
+	For         string            `json:"for,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+func PrometheusRuleName(clusterName string) string {
+	return clusterName
+}
+
+// CreateOrUpdatePrometheusRule makes sure the etcd cluster has a PrometheusRule
+// with the recommended etcd alerts.
+func CreateOrUpdatePrometheusRule(restcli rest.Interface, clusterName, ns string, cs spec.ClusterSpec, owner metav1.OwnerReference) error {
+	pr := newPrometheusRuleManifest(clusterName, ns, cs, owner)
+	return createOrUpdateMonitoringObject(restcli, ns, prometheusRules, &pr.Metadata, pr)
+}
+
+func DeletePrometheusRule(restcli rest.Interface, clusterName, ns string) error {
+	return deleteMonitoringObject(restcli, ns, prometheusRules, PrometheusRuleName(clusterName))
+}
+
+func newPrometheusRuleManifest(clusterName, ns string, cs spec.ClusterSpec, owner metav1.OwnerReference) *prometheusRule {
+	labels := LabelsForCluster(clusterName)
+	mergeStringMaps(labels, cs.PrometheusRule.Labels)
+	pr := &prometheusRule{
+		APIVersion: monitoringAPIVersion,
+		Kind:       prometheusRuleKind,
+		Metadata: metav1.ObjectMeta{
+			Name:      PrometheusRuleName(clusterName),
+			Namespace: ns,
+			Labels:    labels,
+		},
+		Spec: prometheusRuleSpec{
+			Groups: []prometheusRuleGroup{{
+				Name:  "etcd-" + clusterName,
+				Rules: etcdAlertingRules(clusterName, ns, cs),
+			}},
+		},
+	}
+	addOwnerRefToObject(&pr.Metadata, owner)
+	return pr
+}
+
+// etcdAlertingRules returns the recommended alerts of the cluster. Member metrics are
+// selected by the labels of the targets of the cluster ServiceMonitor.
+func etcdAlertingRules(clusterName, ns string, cs spec.ClusterSpec) []alertingRule {
+	sel := fmt.Sprintf(`job="%s",namespace="%s"`, ClientServiceName(clusterName), ns)
+	quota := cs.Etcd.GetQuotaBackendBytes()
+
+	rules := []alertingRule{{
+		Alert: "EtcdNoLeader",
+		Expr:  fmt.Sprintf(`etcd_server_has_leader{%s} == 0`, sel),
+		For:   "1m",
+		Labels: map[string]string{
+			"severity": "critical",
+		},
+		Annotations: map[string]string{
+			"message": fmt.Sprintf("etcd member {{ $labels.pod }} of cluster %s has no leader.", clusterName),
+		},
+	}, {
+		Alert: "EtcdHighFsyncDurations",
+		Expr:  fmt.Sprintf(`histogram_quantile(0.99, sum(rate(etcd_disk_wal_fsync_duration_seconds_bucket{%s}[5m])) by (pod, le)) > 0.5`, sel),
+		For:   "10m",
+		Labels: map[string]string{
+			"severity": "warning",
+		},
+		Annotations: map[string]string{
+			"message": fmt.Sprintf("99th percentile WAL fsync duration of etcd member {{ $labels.pod }} of cluster %s is {{ $value }}s.", clusterName),
+		},
+	}, {
+		Alert: "EtcdDatabaseNearQuota",
+		// etcd 3.4 renamed the db size metric.
+		Expr: fmt.Sprintf(`max(etcd_mvcc_db_total_size_in_bytes{%[1]s} or etcd_debugging_mvcc_db_total_size_in_bytes{%[1]s}) by (pod) > %[2]d`, sel, quota*8/10),
+		For:  "10m",
+		Labels: map[string]string{
+			"severity": "warning",
+		},
+		Annotations: map[string]string{
+			"message": fmt.Sprintf("Database of etcd member {{ $labels.pod }} of cluster %s is above 80%% of the backend quota (%d bytes).", clusterName, quota),
+		},
+	}}
+
+	if cs.Backup != nil {
+		interval := constants.DefaultSnapshotInterval
+		if cs.Backup.BackupIntervalInSecond != 0 {
+			interval = time.Duration(cs.Backup.BackupIntervalInSecond) * time.Second
+		}
+		rules = append(rules, alertingRule{
+			Alert: "EtcdBackupStale",
+			// Exported by the operator.
+			Expr: fmt.Sprintf(`time() - etcd_operator_cluster_last_backup_timestamp_seconds{ClusterName="%s"} > %d`, clusterName, int64(3*interval/time.Second)),
+			For:  "5m",
+			Labels: map[string]string{
+				"severity": "warning",
+			},
+			Annotations: map[string]string{
+				"message": fmt.Sprintf("Last backup of etcd cluster %s is older than 3 backup intervals.", clusterName),
+			},
+		})
+	}
+	return rules
+}
@@ -0,0 +1,65 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewPrometheusRuleManifest(t *testing.T) {
+	tests := []struct {
+		cs      spec.ClusterSpec
+		wAlerts []string
+	}{{
+		cs:      spec.ClusterSpec{PrometheusRule: &spec.PrometheusRulePolicy{}},
+		wAlerts: []string{"EtcdNoLeader", "EtcdHighFsyncDurations", "EtcdDatabaseNearQuota"},
+	}, {
+		cs:      spec.ClusterSpec{PrometheusRule: &spec.PrometheusRulePolicy{}, Backup: &spec.BackupPolicy{BackupIntervalInSecond: 600}},
+		wAlerts: []string{"EtcdNoLeader", "EtcdHighFsyncDurations", "EtcdDatabaseNearQuota", "EtcdBackupStale"},
+	}}
+	for i, tt := range tests {
+		pr := newPrometheusRuleManifest("test", "default", tt.cs, metav1.OwnerReference{})
+		rules := pr.Spec.Groups[0].Rules
+		if len(rules) != len(tt.wAlerts) {
+			t.Errorf("#%d: alerts get=%v, want=%v", i, rules, tt.wAlerts)
+			continue
+		}
+		for j, r := range rules {
+			if r.Alert != tt.wAlerts[j] {
+				t.Errorf("#%d: alert %d get=%s, want=%s", i, j, r.Alert, tt.wAlerts[j])
+			}
+		}
+	}
+}
+
+func TestPrometheusRuleThresholds(t *testing.T) {
+	cs := spec.ClusterSpec{
+		PrometheusRule: &spec.PrometheusRulePolicy{},
+		Etcd:           &spec.EtcdPolicy{QuotaBackendBytes: 1000},
+		Backup:         &spec.BackupPolicy{BackupIntervalInSecond: 600},
+	}
+	rules := etcdAlertingRules("test", "default", cs)
+	if !strings.Contains(rules[2].Expr, `{job="test-client",namespace="default"}) by (pod) > 800`) {
+		t.Errorf("db size alert get=%s, want threshold 800", rules[2].Expr)
+	}
+	if !strings.HasSuffix(rules[3].Expr, "> 1800") {
+		t.Errorf("backup alert get=%s, want threshold 1800", rules[3].Expr)
+	}
+}
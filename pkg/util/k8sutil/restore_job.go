@@ -0,0 +1,257 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+	"github.com/coreos/etcd-operator/pkg/util/retryutil"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/pkg/api/v1"
+	batchv1 "k8s.io/client-go/pkg/apis/batch/v1"
+)
+
+const (
+	restoreJobAppLabel = "etcd-restore"
+
+	// restoreJobFinishedAnnotation records when the operator saw the restore Job finish,
+	// which the TTL of finished restore Jobs counts from.
+	restoreJobFinishedAnnotation = "etcd.coreos.com/restore-finished"
+)
+
+func RestoreJobName(memberName string) string {
+	return memberName + "-restore"
+}
+
+// RestoreJobLabels are the labels of the restore Jobs and their pods. They differ from the labels
+// of the member pods, so that a restore pod is not taken for a member.
+func RestoreJobLabels(clusterName string) map[string]string {
+	return map[string]string{
+		"app":          restoreJobAppLabel,
+		"etcd_cluster": clusterName,
+	}
+}
+
+// NewRestoreJobManifest returns a Job which restores the data dir of the member on its PVC
+// with the restore init containers added to the given pod, e.g. by AddRecoveryToPod.
+// The last of them restores the data dir and runs as the container of the Job, the others as its init containers.
+// The Job fails once it runs for longer than deadline.
+func NewRestoreJobManifest(m *etcdutil.Member, clusterName string, restore *v1.Pod, deadline time.Duration, cs spec.ClusterSpec, owner metav1.OwnerReference) *batchv1.Job {
+	ics := restore.Spec.InitContainers
+	automountServiceAccountToken := false
+	pl := v1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: RestoreJobLabels(clusterName),
+		},
+		Spec: v1.PodSpec{
+			InitContainers: ics[:len(ics)-1],
+			Containers:     ics[len(ics)-1:],
+			Volumes:        append([]v1.Volume{memberDataVolume(m, cs)}, restore.Spec.Volumes...),
+			RestartPolicy:  v1.RestartPolicyNever,
+			// The restore does not access the Kubernetes API.
+			AutomountServiceAccountToken: &automountServiceAccountToken,
+		},
+	}
+	applyPodPolicyToPodTemplateSpec(clusterName, &pl, cs.Pod)
+	podSpecWithArchitecture(&pl.Spec, cs.EtcdImage.GetArchitecture())
+
+	one := int32(1)
+	deadlineSeconds := int64(deadline / time.Second)
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        RestoreJobName(m.Name),
+			Labels:      RestoreJobLabels(clusterName),
+			Annotations: map[string]string{},
+		},
+		Spec: batchv1.JobSpec{
+			Parallelism:           &one,
+			Completions:           &one,
+			ActiveDeadlineSeconds: &deadlineSeconds,
+			Template:              pl,
+		},
+	}
+	addOwnerRefToObject(job.GetObjectMeta(), owner)
+	return job
+}
+
+// WaitRestoreJob waits until the restore Job completes or fails, and returns why it failed, or "" if it completed.
+func WaitRestoreJob(kubecli kubernetes.Interface, ns, name string, timeout time.Duration) (string, error) {
+	interval := 5 * time.Second
+	var failure string
+	err := retryutil.Retry(interval, int(timeout/interval), func() (bool, error) {
+		job, err := kubecli.BatchV1().Jobs(ns).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		if jobHasCondition(job, batchv1.JobComplete) {
+			return true, nil
+		}
+		var pods []v1.Pod
+		if job.Status.Failed != 0 {
+			pods, err = listRestoreJobPods(kubecli, ns, job)
+			if err != nil {
+				return false, err
+			}
+		}
+		failure = RestoreJobFailure(job, pods)
+		return len(failure) != 0, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to wait for restore job (%s): %v", name, err)
+	}
+	return failure, nil
+}
+
+// RestoreJobFailure returns why the restore Job failed, or "" if it has not failed.
+// The Job controller would replace a failed pod, but a failed restore leaves a data dir behind
+// which `etcdctl snapshot restore` refuses to overwrite. So the Job fails with its first failed pod.
+func RestoreJobFailure(job *batchv1.Job, pods []v1.Pod) string {
+	for _, c := range job.Status.Conditions {
+		if c.Type == batchv1.JobFailed && c.Status == v1.ConditionTrue {
+			if len(c.Message) == 0 {
+				return c.Reason
+			}
+			return fmt.Sprintf("%s: %s", c.Reason, c.Message)
+		}
+	}
+	if job.Status.Failed == 0 {
+		return ""
+	}
+	for i := range pods {
+		if pods[i].Status.Phase == v1.PodFailed {
+			return podFailure(&pods[i])
+		}
+	}
+	return "restore pod failed"
+}
+
+// podFailure describes the first container of the failed pod which exited with an error.
+func podFailure(pod *v1.Pod) string {
+	statuses := append(append([]v1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, s := range statuses {
+		t := s.State.Terminated
+		if t == nil || t.ExitCode == 0 {
+			continue
+		}
+		reason := fmt.Sprintf("container %s: %s (exit code %d)", s.Name, t.Reason, t.ExitCode)
+		if len(t.Message) != 0 {
+			reason += ": " + t.Message
+		}
+		return reason
+	}
+	if len(pod.Status.Reason) != 0 {
+		return fmt.Sprintf("pod %s: %s", pod.Name, pod.Status.Reason)
+	}
+	return fmt.Sprintf("pod %s failed", pod.Name)
+}
+
+// FinishRestoreJob records that the restore Job finished, for the TTL of finished restore Jobs.
+// A completed Job has its pods deleted to detach the PVC from their node before the member pod uses it.
+// A failed Job keeps its pods to inspect them, and is stopped from creating more.
+func FinishRestoreJob(kubecli kubernetes.Interface, ns, name string, failed bool, now time.Time) error {
+	job, err := kubecli.BatchV1().Jobs(ns).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if job.Annotations == nil {
+		job.Annotations = map[string]string{}
+	}
+	job.Annotations[restoreJobFinishedAnnotation] = now.Format(time.RFC3339)
+	if failed {
+		zero := int32(0)
+		job.Spec.Parallelism = &zero
+	}
+	job, err = kubecli.BatchV1().Jobs(ns).Update(job)
+	if err != nil || failed {
+		return err
+	}
+	pods, err := listRestoreJobPods(kubecli, ns, job)
+	if err != nil {
+		return err
+	}
+	for _, pod := range pods {
+		err := kubecli.CoreV1().Pods(ns).Delete(pod.Name, metav1.NewDeleteOptions(0))
+		if err != nil && !IsKubernetesResourceNotFoundError(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteExpiredRestoreJobs deletes the restore Jobs of the cluster which finished more than ttl ago,
+// and returns their names.
+func DeleteExpiredRestoreJobs(kubecli kubernetes.Interface, clusterName, ns string, ttl time.Duration, now time.Time) ([]string, error) {
+	opts := metav1.ListOptions{LabelSelector: labels.SelectorFromSet(RestoreJobLabels(clusterName)).String()}
+	jobs, err := kubecli.BatchV1().Jobs(ns).List(opts)
+	if err != nil {
+		return nil, err
+	}
+	var deleted []string
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if !restoreJobExpired(job, ttl, now) {
+			continue
+		}
+		err := kubecli.BatchV1().Jobs(ns).Delete(job.Name, CascadeDeleteOptions(0))
+		if err != nil && !IsKubernetesResourceNotFoundError(err) {
+			return deleted, err
+		}
+		deleted = append(deleted, job.Name)
+	}
+	return deleted, nil
+}
+
+// restoreJobExpired returns whether the Job finished more than ttl ago. A Job the operator did not see finish,
+// e.g. since it restarted meanwhile, finished at its completion time, or at the latest at its deadline.
+func restoreJobExpired(job *batchv1.Job, ttl time.Duration, now time.Time) bool {
+	var finished time.Time
+	if t, err := time.Parse(time.RFC3339, job.Annotations[restoreJobFinishedAnnotation]); err == nil {
+		finished = t
+	} else if job.Status.CompletionTime != nil {
+		finished = job.Status.CompletionTime.Time
+	} else if d := job.Spec.ActiveDeadlineSeconds; d != nil {
+		finished = job.CreationTimestamp.Add(time.Duration(*d) * time.Second)
+	} else {
+		return false
+	}
+	return now.Sub(finished) > ttl
+}
+
+func listRestoreJobPods(kubecli kubernetes.Interface, ns string, job *batchv1.Job) ([]v1.Pod, error) {
+	sel, err := metav1.LabelSelectorAsSelector(job.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := kubecli.CoreV1().Pods(ns).List(metav1.ListOptions{LabelSelector: sel.String()})
+	if err != nil {
+		return nil, err
+	}
+	return pods.Items, nil
+}
+
+func jobHasCondition(job *batchv1.Job, typ batchv1.JobConditionType) bool {
+	for _, c := range job.Status.Conditions {
+		if c.Type == typ && c.Status == v1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
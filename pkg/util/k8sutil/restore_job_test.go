@@ -0,0 +1,158 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/pkg/api/v1"
+	batchv1 "k8s.io/client-go/pkg/apis/batch/v1"
+)
+
+func TestNewRestoreJobManifest(t *testing.T) {
+	m := &etcdutil.Member{Name: "test-0000", Namespace: "default"}
+	cs := spec.ClusterSpec{
+		Version: "3.1.8",
+		SeedSnapshot: &spec.SeedSnapshotPolicy{
+			Secret: &spec.SnapshotKeySelector{Name: "seed", Key: "snapshot.db"},
+		},
+		Pod: &spec.PodPolicy{
+			PersistentVolumeClaimSpec: &v1.PersistentVolumeClaimSpec{
+				Resources: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse("1Gi")}},
+			},
+		},
+	}
+	restore := &v1.Pod{}
+	AddSeedSnapshotToPod(restore, "token", m, cs)
+	job := NewRestoreJobManifest(m, "test", restore, 10*time.Minute, cs, metav1.OwnerReference{Name: "test"})
+
+	if job.Name != "test-0000-restore" || len(job.OwnerReferences) != 1 {
+		t.Errorf("expect job test-0000-restore owned by the cluster, get=%s owned by %v", job.Name, job.OwnerReferences)
+	}
+	if d := job.Spec.ActiveDeadlineSeconds; d == nil || *d != 600 {
+		t.Errorf("expect a deadline of 600s, get=%v", d)
+	}
+	ps := job.Spec.Template.Spec
+	if len(ps.InitContainers) != 1 || ps.InitContainers[0].Name != "fetch-seed-snapshot" {
+		t.Errorf("expect the fetch as init container, get=%v", ps.InitContainers)
+	}
+	if len(ps.Containers) != 1 || ps.Containers[0].Name != "restore-datadir" {
+		t.Errorf("expect the restore as container, get=%v", ps.Containers)
+	}
+	if ps.RestartPolicy != v1.RestartPolicyNever {
+		t.Errorf("restart policy get=%s, want=%s", ps.RestartPolicy, v1.RestartPolicyNever)
+	}
+	if len(ps.Volumes) != 2 || ps.Volumes[0].PersistentVolumeClaim == nil || ps.Volumes[0].PersistentVolumeClaim.ClaimName != MemberPVCName(m.Name) {
+		t.Errorf("expect the PVC of the member and the seed snapshot volume, get=%v", ps.Volumes)
+	}
+	// The restore pod must not be listed as an etcd member of the cluster.
+	if labels.SelectorFromSet(LabelsForCluster("test")).Matches(labels.Set(job.Spec.Template.Labels)) {
+		t.Errorf("restore pod labels (%v) match the member labels", job.Spec.Template.Labels)
+	}
+}
+
+func TestRestoreJobFailure(t *testing.T) {
+	failedPod := v1.Pod{Status: v1.PodStatus{
+		Phase: v1.PodFailed,
+		InitContainerStatuses: []v1.ContainerStatus{{
+			Name:  "fetch-backup",
+			State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 0, Reason: "Completed"}},
+		}},
+		ContainerStatuses: []v1.ContainerStatus{{
+			Name:  "restore-datadir",
+			State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 1, Reason: "Error"}},
+		}},
+	}}
+	tests := []struct {
+		status batchv1.JobStatus
+		pods   []v1.Pod
+
+		w string
+	}{
+		{status: batchv1.JobStatus{Active: 1}, w: ""},
+		{status: batchv1.JobStatus{Succeeded: 1}, w: ""},
+		{status: batchv1.JobStatus{Failed: 1}, pods: []v1.Pod{failedPod}, w: "container restore-datadir: Error (exit code 1)"},
+		{
+			status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{{
+				Type:    batchv1.JobFailed,
+				Status:  v1.ConditionTrue,
+				Reason:  "DeadlineExceeded",
+				Message: "Job was active longer than specified deadline",
+			}}},
+			w: "DeadlineExceeded: Job was active longer than specified deadline",
+		},
+	}
+	for i, tt := range tests {
+		if get := RestoreJobFailure(&batchv1.Job{Status: tt.status}, tt.pods); get != tt.w {
+			t.Errorf("#%d: failure get=%q, want=%q", i, get, tt.w)
+		}
+	}
+}
+
+func TestRestoreJobExpired(t *testing.T) {
+	now := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	ttl := 24 * time.Hour
+	deadline := int64(600)
+	tests := []struct {
+		job *batchv1.Job
+
+		w bool
+	}{
+		{job: &batchv1.Job{}, w: false},
+		{
+			job: &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+				restoreJobFinishedAnnotation: now.Add(-time.Hour).Format(time.RFC3339),
+			}}},
+			w: false,
+		},
+		{
+			job: &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+				restoreJobFinishedAnnotation: now.Add(-25 * time.Hour).Format(time.RFC3339),
+			}}},
+			w: true,
+		},
+		{
+			job: &batchv1.Job{Status: batchv1.JobStatus{CompletionTime: &metav1.Time{Time: now.Add(-25 * time.Hour)}}},
+			w:   true,
+		},
+		// not seen finishing, the Job ended at its deadline at the latest
+		{
+			job: &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.Time{Time: now.Add(-24 * time.Hour)}},
+				Spec:       batchv1.JobSpec{ActiveDeadlineSeconds: &deadline},
+			},
+			w: false,
+		},
+		{
+			job: &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.Time{Time: now.Add(-25 * time.Hour)}},
+				Spec:       batchv1.JobSpec{ActiveDeadlineSeconds: &deadline},
+			},
+			w: true,
+		},
+	}
+	for i, tt := range tests {
+		if get := restoreJobExpired(tt.job, ttl, now); get != tt.w {
+			t.Errorf("#%d: expired get=%v, want=%v", i, get, tt.w)
+		}
+	}
+}
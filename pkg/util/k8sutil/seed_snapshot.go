@@ -0,0 +1,93 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+const (
+	seedSnapshotVolume   = "seed-snapshot"
+	seedSnapshotMountDir = "/var/etcd-seed"
+	seedSnapshotFile     = "snapshot"
+)
+
+// SeedSnapshotSource describes where the seed snapshot is read from.
+func SeedSnapshotSource(sp *spec.SeedSnapshotPolicy) string {
+	switch {
+	case sp.Secret != nil:
+		return fmt.Sprintf("secret %s/%s", sp.Secret.Name, sp.Secret.Key)
+	case sp.ConfigMap != nil:
+		return fmt.Sprintf("config map %s/%s", sp.ConfigMap.Name, sp.ConfigMap.Key)
+	default:
+		return sp.URL
+	}
+}
+
+// AddSeedSnapshotToPod makes the seed member pod fetch the seed snapshot, verify its checksum if given,
+// and restore its data dir from it before etcd starts.
+func AddSeedSnapshotToPod(pod *v1.Pod, token string, m *etcdutil.Member, cs spec.ClusterSpec) {
+	sp := cs.SeedSnapshot
+	mounts := etcdVolumeMounts()
+	image := EtcdImageName(cs)
+	src := seedSnapshotMountDir + "/" + seedSnapshotFile
+	var cmd string
+	// args are passed to the command as "$1", ..., so that user input is not parsed by the shell.
+	var args []string
+	switch {
+	case sp.Secret != nil:
+		cmd = fmt.Sprintf("cp %s %s", src, backupFile)
+		mounts = append(mounts, v1.VolumeMount{Name: seedSnapshotVolume, MountPath: seedSnapshotMountDir, ReadOnly: true})
+		pod.Spec.Volumes = append(pod.Spec.Volumes, v1.Volume{Name: seedSnapshotVolume, VolumeSource: v1.VolumeSource{
+			Secret: &v1.SecretVolumeSource{
+				SecretName: sp.Secret.Name,
+				Items:      []v1.KeyToPath{{Key: sp.Secret.Key, Path: seedSnapshotFile}},
+			},
+		}})
+	case sp.ConfigMap != nil:
+		cmd = fmt.Sprintf("base64 -d %s > %s", src, backupFile)
+		mounts = append(mounts, v1.VolumeMount{Name: seedSnapshotVolume, MountPath: seedSnapshotMountDir, ReadOnly: true})
+		pod.Spec.Volumes = append(pod.Spec.Volumes, v1.Volume{Name: seedSnapshotVolume, VolumeSource: v1.VolumeSource{
+			ConfigMap: &v1.ConfigMapVolumeSource{
+				LocalObjectReference: v1.LocalObjectReference{Name: sp.ConfigMap.Name},
+				Items:                []v1.KeyToPath{{Key: sp.ConfigMap.Key, Path: seedSnapshotFile}},
+			},
+		}})
+	default:
+		image = CurlImage
+		cmd = fmt.Sprintf(`curl -fsSL -o %s "$1"`, backupFile)
+		args = []string{sp.URL}
+	}
+	if len(sp.SHA256) != 0 {
+		cmd += fmt.Sprintf(" && echo '%s  %s' | sha256sum -c -", strings.ToLower(sp.SHA256), backupFile)
+	}
+
+	ics := []v1.Container{
+		{
+			Name:         "fetch-seed-snapshot",
+			Image:        image,
+			Command:      append([]string{"/bin/sh", "-ec", cmd, "fetch-seed-snapshot"}, args...),
+			VolumeMounts: mounts,
+		},
+		restoreDatadirContainer(token, cs, m, false),
+	}
+	pod.Spec.InitContainers = append(ics, pod.Spec.InitContainers...)
+}
@@ -0,0 +1,72 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+func TestAddSeedSnapshotToPod(t *testing.T) {
+	sum := strings.Repeat("ab", 32)
+	tests := []struct {
+		sp       *spec.SeedSnapshotPolicy
+		wCmd     string
+		wArgs    []string
+		wVolumes int
+	}{
+		{
+			sp:       &spec.SeedSnapshotPolicy{Secret: &spec.SnapshotKeySelector{Name: "seed", Key: "snapshot.db"}},
+			wCmd:     "cp ",
+			wVolumes: 1,
+		},
+		{
+			sp:       &spec.SeedSnapshotPolicy{ConfigMap: &spec.SnapshotKeySelector{Name: "seed", Key: "snapshot.db"}},
+			wCmd:     "base64 -d ",
+			wVolumes: 1,
+		},
+		{
+			sp:    &spec.SeedSnapshotPolicy{URL: "https://example.com/snapshot.db", SHA256: sum},
+			wCmd:  "curl -fsSL -o " + backupFile + ` "$1" && echo '` + sum + "  " + backupFile + "' | sha256sum -c -",
+			wArgs: []string{"https://example.com/snapshot.db"},
+		},
+	}
+	for i, tt := range tests {
+		pod := &v1.Pod{}
+		m := &etcdutil.Member{Name: "test-0000", Namespace: "default"}
+		AddSeedSnapshotToPod(pod, "token", m, spec.ClusterSpec{Version: "3.1.8", SeedSnapshot: tt.sp})
+		ics := pod.Spec.InitContainers
+		if len(ics) != 2 || ics[0].Name != "fetch-seed-snapshot" || ics[1].Name != "restore-datadir" {
+			t.Fatalf("#%d: unexpected init containers (%v)", i, ics)
+		}
+		if cmd := ics[0].Command[2]; !strings.HasPrefix(cmd, tt.wCmd) {
+			t.Errorf("#%d: fetch command get=%s, want prefix=%s", i, cmd, tt.wCmd)
+		}
+		if tt.wArgs != nil && ics[0].Image != CurlImage {
+			t.Errorf("#%d: fetch image get=%s, want=%s", i, ics[0].Image, CurlImage)
+		}
+		if args := ics[0].Command[4:]; strings.Join(args, " ") != strings.Join(tt.wArgs, " ") {
+			t.Errorf("#%d: fetch command args get=%v, want=%v", i, args, tt.wArgs)
+		}
+		if len(pod.Spec.Volumes) != tt.wVolumes {
+			t.Errorf("#%d: volumes get=%d, want=%d", i, len(pod.Spec.Volumes), tt.wVolumes)
+		}
+	}
+}
@@ -39,19 +39,7 @@ func selfHostedDataDir(ns, name string) string {
 
 func NewSelfHostedEtcdPod(m *etcdutil.Member, initialCluster, endpoints []string, clusterName, state, token string, cs spec.ClusterSpec, owner metav1.OwnerReference) *v1.Pod {
 	hostDataDir := selfHostedDataDir(m.Namespace, m.Name)
-	commands := fmt.Sprintf("/usr/local/bin/etcd --data-dir=%s --name=%s --initial-advertise-peer-urls=%s "+
-		"--listen-peer-urls=%s --listen-client-urls=%s --advertise-client-urls=%s "+
-		"--initial-cluster=%s --initial-cluster-state=%s",
-		hostDataDir, m.Name, m.PeerURL(), m.ListenPeerURL(), m.ListenClientURL(), m.ClientAddr(), strings.Join(initialCluster, ","), state)
-	if m.SecurePeer {
-		commands += fmt.Sprintf(" --peer-client-cert-auth=true --peer-trusted-ca-file=%[1]s/peer-ca-crt.pem --peer-cert-file=%[1]s/peer-crt.pem --peer-key-file=%[1]s/peer-key.pem", peerTLSDir)
-	}
-	if m.SecureClient {
-		commands += fmt.Sprintf(" --client-cert-auth=true --trusted-ca-file=%[1]s/client-ca-crt.pem --cert-file=%[1]s/client-crt.pem --key-file=%[1]s/client-key.pem", clientTLSDir)
-	}
-	if state == "new" {
-		commands += fmt.Sprintf(" --initial-cluster-token=%s", token)
-	}
+	commands := newMemberEtcdCommand(m, hostDataDir, initialCluster, state, token, cs).String()
 
 	labels := map[string]string{
 		"app":          "etcd",
@@ -69,7 +57,7 @@ func NewSelfHostedEtcdPod(m *etcdutil.Member, initialCluster, endpoints []string
 	}
 
 	commands = fmt.Sprintf("sleep 5; flock %s -c \"%s\"", etcdLockPath, commands)
-	c := etcdContainer(commands, cs.Version)
+	c := etcdContainer(commands, cs)
 	// On node reboot, there will be two copies of etcd pod: scheduled and checkpointed one.
 	// Checkpointed one will start first. But then the scheduler will detect host port conflict,
 	// and set the pod (in APIServer) failed. This further affects etcd service by removing the endpoints.
@@ -118,6 +106,7 @@ func NewSelfHostedEtcdPod(m *etcdutil.Member, initialCluster, endpoints []string
 		}})
 	}
 
+	automountServiceAccountToken := false
 	pod := &v1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:   m.Name,
@@ -136,6 +125,8 @@ func NewSelfHostedEtcdPod(m *etcdutil.Member, initialCluster, endpoints []string
 			DNSPolicy:     v1.DNSClusterFirstWithHostNet,
 			Hostname:      m.Name,
 			Subdomain:     clusterName,
+			// etcd does not access the Kubernetes API.
+			AutomountServiceAccountToken: &automountServiceAccountToken,
 		},
 	}
 
@@ -144,6 +135,7 @@ func NewSelfHostedEtcdPod(m *etcdutil.Member, initialCluster, endpoints []string
 	applyPodPolicy(clusterName, pod, cs.Pod)
 	// overwrites the antiAffinity setting for self hosted cluster.
 	pod = selfHostedPodWithAntiAffinity(pod)
+	podSpecWithArchitecture(&pod.Spec, cs.EtcdImage.GetArchitecture())
 	applyAppendHostsInitContainer(pod)
 	addOwnerRefToObject(pod.GetObjectMeta(), owner)
 	return pod
@@ -154,20 +146,21 @@ func applyAppendHostsInitContainer(p *v1.Pod) {
 	containerSpec := []v1.Container{
 		{
 			Name:  "append-hosts",
-			Image: "busybox",
+			Image: BusyboxImage,
 			Command: []string{
 				// Init container would be re-executed on restart. We are taking the datadir as a signal of restart.
 				// If restart happens and hosts checkpoint exists, we will append it to /etc/hosts.
 				"/bin/sh", "-c",
 				fmt.Sprintf("[ -f %[1]s ] && (cat %[1]s >> /etc/hosts) || true", etcdHostsFile),
 			},
+			ImagePullPolicy: v1.PullIfNotPresent,
 			VolumeMounts: []v1.VolumeMount{
 				{Name: etcdVolumeName, MountPath: etcdVolumeMountDir},
 			},
 		},
 	}
 
-	p.Spec.InitContainers = containerSpec
+	p.Spec.InitContainers = append(containerSpec, p.Spec.InitContainers...)
 }
 
 func selfHostedPodWithAntiAffinity(pod *v1.Pod) *v1.Pod {
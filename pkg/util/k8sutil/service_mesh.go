@@ -0,0 +1,62 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"github.com/coreos/etcd-operator/pkg/spec"
+
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+const (
+	istioInjectAnnotation               = "sidecar.istio.io/inject"
+	istioExcludeInboundPortsAnnotation  = "traffic.sidecar.istio.io/excludeInboundPorts"
+	istioExcludeOutboundPortsAnnotation = "traffic.sidecar.istio.io/excludeOutboundPorts"
+
+	linkerdInjectAnnotation            = "linkerd.io/inject"
+	linkerdSkipInboundPortsAnnotation  = "config.linkerd.io/skip-inbound-ports"
+	linkerdSkipOutboundPortsAnnotation = "config.linkerd.io/skip-outbound-ports"
+)
+
+// applyServiceMeshPolicy sets the sidecar injection annotations of the service mesh on an etcd pod.
+// They take precedence over the annotations of the pod policy.
+func applyServiceMeshPolicy(pod *v1.Pod, sp *spec.ServiceMeshPolicy) {
+	if sp == nil {
+		return
+	}
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	ports := "2380"
+	if sp.ExcludeClientTraffic {
+		ports = "2379,2380"
+	}
+	switch sp.Type {
+	case spec.ServiceMeshIstio:
+		if sp.DisableInjection {
+			pod.Annotations[istioInjectAnnotation] = "false"
+			return
+		}
+		pod.Annotations[istioExcludeInboundPortsAnnotation] = ports
+		pod.Annotations[istioExcludeOutboundPortsAnnotation] = ports
+	case spec.ServiceMeshLinkerd:
+		if sp.DisableInjection {
+			pod.Annotations[linkerdInjectAnnotation] = "disabled"
+			return
+		}
+		pod.Annotations[linkerdSkipInboundPortsAnnotation] = ports
+		pod.Annotations[linkerdSkipOutboundPortsAnnotation] = ports
+	}
+}
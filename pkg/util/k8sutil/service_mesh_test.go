@@ -0,0 +1,63 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+func TestApplyServiceMeshPolicy(t *testing.T) {
+	tests := []struct {
+		sp           *spec.ServiceMeshPolicy
+		wAnnotations map[string]string
+	}{
+		{sp: nil, wAnnotations: map[string]string{}},
+		{
+			sp: &spec.ServiceMeshPolicy{Type: spec.ServiceMeshIstio},
+			wAnnotations: map[string]string{
+				istioExcludeInboundPortsAnnotation:  "2380",
+				istioExcludeOutboundPortsAnnotation: "2380",
+			},
+		},
+		{
+			sp: &spec.ServiceMeshPolicy{Type: spec.ServiceMeshLinkerd, ExcludeClientTraffic: true},
+			wAnnotations: map[string]string{
+				linkerdSkipInboundPortsAnnotation:  "2379,2380",
+				linkerdSkipOutboundPortsAnnotation: "2379,2380",
+			},
+		},
+		{
+			sp:           &spec.ServiceMeshPolicy{Type: spec.ServiceMeshIstio, DisableInjection: true},
+			wAnnotations: map[string]string{istioInjectAnnotation: "false"},
+		},
+		{
+			sp:           &spec.ServiceMeshPolicy{Type: spec.ServiceMeshLinkerd, DisableInjection: true},
+			wAnnotations: map[string]string{linkerdInjectAnnotation: "disabled"},
+		},
+	}
+	for i, tt := range tests {
+		pod := &v1.Pod{}
+		pod.Annotations = map[string]string{}
+		applyServiceMeshPolicy(pod, tt.sp)
+		if !reflect.DeepEqual(pod.Annotations, tt.wAnnotations) {
+			t.Errorf("#%d: annotations get=%v, want=%v", i, pod.Annotations, tt.wAnnotations)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	serviceMonitorKind = "ServiceMonitor"
+	serviceMonitors    = "servicemonitors"
+
+	// prometheusSecretsDir is where the Prometheus Operator mounts the secrets
+	// listed in the `secrets` of a Prometheus.
+	prometheusSecretsDir = "/etc/prometheus/secrets"
+)
+
+type serviceMonitor struct {
+	APIVersion string             `json:"apiVersion"`
+	Kind       string             `json:"kind"`
+	Metadata   metav1.ObjectMeta  `json:"metadata"`
+	Spec       serviceMonitorSpec `json:"spec"`
+}
+
+type serviceMonitorSpec struct {
+	Selector          metav1.LabelSelector            `json:"selector"`
+	NamespaceSelector serviceMonitorNamespaceSelector `json:"namespaceSelector"`
+	Endpoints         []serviceMonitorEndpoint        `json:"endpoints"`
+}
+
+type serviceMonitorNamespaceSelector struct {
+	MatchNames []string `json:"matchNames"`
+}
+
+type serviceMonitorEndpoint struct {
+	Port        string                   `json:"port"`
+	Path        string                   `json:"path"`
+	Scheme      string                   `json:"scheme"`
+	Interval    string                   `json:"interval,omitempty"`
+	TLSConfig   *serviceMonitorTLSConfig `json:"tlsConfig,omitempty"`
+	Relabelings []serviceMonitorRelabel  `json:"relabelings,omitempty"`
+}
+
+type serviceMonitorTLSConfig struct {
+	CAFile     string `json:"caFile"`
+	CertFile   string `json:"certFile"`
+	KeyFile    string `json:"keyFile"`
+	ServerName string `json:"serverName"`
+}
+
+type serviceMonitorRelabel struct {
+	SourceLabels []string `json:"sourceLabels"`
+	Regex        string   `json:"regex"`
+	Action       string   `json:"action"`
+}
+
+func ServiceMonitorName(clusterName string) string {
+	return clusterName
+}
+
+// CreateOrUpdateServiceMonitor makes sure the etcd cluster has a ServiceMonitor
+// which scrapes the metrics of its members.
+func CreateOrUpdateServiceMonitor(restcli rest.Interface, clusterName, ns string, cs spec.ClusterSpec, owner metav1.OwnerReference) error {
+	sm := newServiceMonitorManifest(clusterName, ns, cs, owner)
+	return createOrUpdateMonitoringObject(restcli, ns, serviceMonitors, &sm.Metadata, sm)
+}
+
+func DeleteServiceMonitor(restcli rest.Interface, clusterName, ns string) error {
+	return deleteMonitoringObject(restcli, ns, serviceMonitors, ServiceMonitorName(clusterName))
+}
+
+func newServiceMonitorManifest(clusterName, ns string, cs spec.ClusterSpec, owner metav1.OwnerReference) *serviceMonitor {
+	sp := cs.ServiceMonitor
+	ep := serviceMonitorEndpoint{
+		Port:     "client",
+		Path:     "/metrics",
+		Scheme:   "http",
+		Interval: sp.Interval,
+		// The client and peer services select the same pods.
+		// Only keep the targets of the client service.
+		Relabelings: []serviceMonitorRelabel{{
+			SourceLabels: []string{"__meta_kubernetes_service_name"},
+			Regex:        ClientServiceName(clusterName),
+			Action:       "keep",
+		}},
+	}
+	if cs.Etcd.GetMetricsPort() != 0 {
+		// The metrics port serves plain HTTP.
+		ep.Port = metricsPortName
+		if cs.ClientService.IsPublished() {
+			// A published client service does not expose the metrics port, the peer service does.
+			ep.Relabelings[0].Regex = clusterName
+		}
+	} else if cs.TLS.IsSecureClient() {
+		dir := sp.TLSSecretDir
+		if len(dir) == 0 {
+			dir = path.Join(prometheusSecretsDir, cs.TLS.Static.OperatorSecret)
+		}
+		ep.Scheme = "https"
+		ep.TLSConfig = &serviceMonitorTLSConfig{
+			CAFile:   path.Join(dir, etcdutil.CliCAFile),
+			CertFile: path.Join(dir, etcdutil.CliCertFile),
+			KeyFile:  path.Join(dir, etcdutil.CliKeyFile),
+			// Targets are scraped by pod IP. The client service name is in the
+			// SAN of the member certificates.
+			ServerName: fmt.Sprintf("%s.%s.svc.cluster.local", ClientServiceName(clusterName), ns),
+		}
+	}
+
+	labels := LabelsForCluster(clusterName)
+	mergeStringMaps(labels, sp.Labels)
+	sm := &serviceMonitor{
+		APIVersion: monitoringAPIVersion,
+		Kind:       serviceMonitorKind,
+		Metadata: metav1.ObjectMeta{
+			Name:      ServiceMonitorName(clusterName),
+			Namespace: ns,
+			Labels:    labels,
+		},
+		Spec: serviceMonitorSpec{
+			Selector: metav1.LabelSelector{
+				MatchLabels: LabelsForCluster(clusterName),
+			},
+			NamespaceSelector: serviceMonitorNamespaceSelector{
+				MatchNames: []string{ns},
+			},
+			Endpoints: []serviceMonitorEndpoint{ep},
+		},
+	}
+	addOwnerRefToObject(&sm.Metadata, owner)
+	return sm
+}
@@ -0,0 +1,107 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"testing"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+func TestNewServiceMonitorManifest(t *testing.T) {
+	tls := &spec.TLSPolicy{Static: &spec.StaticTLS{OperatorSecret: "op-tls", Member: &spec.MemberSecret{ClientSecret: "client-tls"}}}
+	tests := []struct {
+		cs          spec.ClusterSpec
+		wPort       string
+		wScheme     string
+		wCAFile     string
+		wServerName string
+		wService    string
+	}{
+		{
+			cs:      spec.ClusterSpec{ServiceMonitor: &spec.ServiceMonitorPolicy{}},
+			wPort:   "client",
+			wScheme: "http",
+		},
+		{
+			cs:      spec.ClusterSpec{ServiceMonitor: &spec.ServiceMonitorPolicy{}, TLS: tls, Etcd: &spec.EtcdPolicy{MetricsPort: 2381}},
+			wPort:   "metrics",
+			wScheme: "http",
+		},
+		// a published client service does not expose the metrics port
+		{
+			cs: spec.ClusterSpec{
+				ServiceMonitor: &spec.ServiceMonitorPolicy{},
+				Etcd:           &spec.EtcdPolicy{MetricsPort: 2381},
+				ClientService:  &spec.ClientServicePolicy{Type: v1.ServiceTypeLoadBalancer},
+			},
+			wPort:    "metrics",
+			wScheme:  "http",
+			wService: "test",
+		},
+		{
+			cs:          spec.ClusterSpec{ServiceMonitor: &spec.ServiceMonitorPolicy{}, TLS: tls},
+			wPort:       "client",
+			wScheme:     "https",
+			wCAFile:     "/etc/prometheus/secrets/op-tls/etcd-ca-crt.pem",
+			wServerName: "test-client.default.svc.cluster.local",
+		},
+		{
+			cs:          spec.ClusterSpec{ServiceMonitor: &spec.ServiceMonitorPolicy{TLSSecretDir: "/tls"}, TLS: tls},
+			wPort:       "client",
+			wScheme:     "https",
+			wCAFile:     "/tls/etcd-ca-crt.pem",
+			wServerName: "test-client.default.svc.cluster.local",
+		},
+	}
+	for i, tt := range tests {
+		sm := newServiceMonitorManifest("test", "default", tt.cs, metav1.OwnerReference{})
+		ep := sm.Spec.Endpoints[0]
+		if ep.Port != tt.wPort {
+			t.Errorf("#%d: port get=%s, want=%s", i, ep.Port, tt.wPort)
+		}
+		if ep.Scheme != tt.wScheme {
+			t.Errorf("#%d: scheme get=%s, want=%s", i, ep.Scheme, tt.wScheme)
+		}
+		var caFile, serverName string
+		if ep.TLSConfig != nil {
+			caFile, serverName = ep.TLSConfig.CAFile, ep.TLSConfig.ServerName
+		}
+		if caFile != tt.wCAFile || serverName != tt.wServerName {
+			t.Errorf("#%d: tls config get=(%s, %s), want=(%s, %s)", i, caFile, serverName, tt.wCAFile, tt.wServerName)
+		}
+		wService := tt.wService
+		if len(wService) == 0 {
+			wService = "test-client"
+		}
+		if svc := ep.Relabelings[0].Regex; svc != wService {
+			t.Errorf("#%d: scraped service get=%s, want=%s", i, svc, wService)
+		}
+	}
+}
+
+func TestNewServiceMonitorManifestLabels(t *testing.T) {
+	cs := spec.ClusterSpec{ServiceMonitor: &spec.ServiceMonitorPolicy{Labels: map[string]string{"prometheus": "main", "app": "other"}}}
+	sm := newServiceMonitorManifest("test", "default", cs, metav1.OwnerReference{})
+	if sm.Metadata.Labels["prometheus"] != "main" {
+		t.Errorf("expect user label on service monitor, get=%v", sm.Metadata.Labels)
+	}
+	if sm.Metadata.Labels["app"] != "etcd" || sm.Spec.Selector.MatchLabels["app"] != "etcd" {
+		t.Errorf("expect cluster labels not to be overridden, get=%v", sm.Metadata.Labels)
+	}
+}
@@ -15,6 +15,7 @@
 package k8sutil
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -24,6 +25,7 @@ import (
 	"github.com/coreos/etcd-operator/pkg/util/retryutil"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/rest"
 )
 
@@ -33,9 +35,14 @@ import (
 // updating a Cluster TPR.
 type ClusterTPRUpdateFunc func(*spec.Cluster)
 
-func WatchClusters(host, ns string, httpClient *http.Client, resourceVersion string) (*http.Response, error) {
-	return httpClient.Get(fmt.Sprintf("%s/apis/%s/%s/namespaces/%s/clusters?watch=true&resourceVersion=%s",
-		host, spec.TPRGroup, spec.TPRVersion, ns, resourceVersion))
+// WatchClusters watches the clusters in ns, or in all namespaces if ns is metav1.NamespaceAll,
+// from resourceVersion until ctx is done.
+func WatchClusters(ctx context.Context, host, ns string, httpClient *http.Client, resourceVersion string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s%s?watch=true&resourceVersion=%s", host, listClustersURI(ns), resourceVersion), nil)
+	if err != nil {
+		return nil, err
+	}
+	return httpClient.Do(req.WithContext(ctx))
 }
 
 func GetClusterList(restcli rest.Interface, ns string) (*spec.ClusterList, error) {
@@ -64,7 +71,11 @@ func WaitEtcdTPRReady(restcli rest.Interface, interval, timeout time.Duration, n
 	})
 }
 
+// listClustersURI returns the URI of the clusters of the namespace, or of all namespaces if ns is metav1.NamespaceAll.
 func listClustersURI(ns string) string {
+	if ns == metav1.NamespaceAll {
+		return fmt.Sprintf("/apis/%s/%s/clusters", spec.TPRGroup, spec.TPRVersion)
+	}
 	return fmt.Sprintf("/apis/%s/%s/namespaces/%s/clusters", spec.TPRGroup, spec.TPRVersion, ns)
 }
 
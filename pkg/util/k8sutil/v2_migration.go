@@ -0,0 +1,60 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+const (
+	v2DataVolume   = "etcd-v2-data"
+	v2DataMountDir = "/var/etcd-v2"
+	// v2MigrationDir is where the copy of the v2 data dir is migrated, next to the member data dir.
+	v2MigrationDir = etcdVolumeMountDir + "/v2-migration"
+)
+
+// AddV2MigrationToPod makes the seed member pod migrate a copy of the etcd2 data dir
+// to the v3 API and restore its data dir from the migrated backend before etcd starts.
+func AddV2MigrationToPod(pod *v1.Pod, token string, m *etcdutil.Member, cs spec.ClusterSpec) {
+	mp := cs.V2Migration
+	src := path.Join(v2DataMountDir, mp.DataDir)
+	// `etcdctl migrate` rewrites the data dir it runs on, so it runs on a copy.
+	cmd := fmt.Sprintf("rm -rf %[2]s && cp -r %[1]s %[2]s"+
+		" && ETCDCTL_API=3 etcdctl migrate --data-dir=%[2]s"+
+		" && cp %[2]s/member/snap/db %[3]s"+
+		" && rm -rf %[2]s", src, v2MigrationDir, backupFile)
+
+	mounts := append(etcdVolumeMounts(), v1.VolumeMount{Name: v2DataVolume, MountPath: v2DataMountDir, ReadOnly: true})
+	pod.Spec.Volumes = append(pod.Spec.Volumes, v1.Volume{Name: v2DataVolume, VolumeSource: v1.VolumeSource{
+		PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: mp.PersistentVolumeClaimName, ReadOnly: true},
+	}})
+
+	ics := []v1.Container{
+		{
+			Name:         "migrate-v2",
+			Image:        EtcdImageName(cs),
+			Command:      []string{"/bin/sh", "-ec", cmd},
+			VolumeMounts: mounts,
+		},
+		restoreDatadirContainer(token, cs, m, true),
+	}
+	pod.Spec.InitContainers = append(ics, pod.Spec.InitContainers...)
+}
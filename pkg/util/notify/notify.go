@@ -0,0 +1,78 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package notify sends notifications about critical conditions of etcd clusters
+// to external receivers.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// The types of notifications.
+const (
+	TypeDegraded      = "Degraded"
+	TypeFailed        = "Failed"
+	TypeQuorumLost    = "QuorumLost"
+	TypeBackupsMissed = "BackupsMissed"
+)
+
+// Notification is a critical condition of a cluster.
+type Notification struct {
+	Cluster   string `json:"cluster"`
+	Namespace string `json:"namespace"`
+	// Type is the type of the condition, e.g. TypeDegraded.
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	// Time is when the operator observed the condition in RFC3339 format.
+	Time string `json:"time"`
+}
+
+// Notifier sends notifications to a receiver.
+type Notifier interface {
+	Notify(n Notification) error
+}
+
+const webhookTimeout = 10 * time.Second
+
+type webhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook returns a notifier which posts each notification as a JSON object to the URL.
+// The receiver must respond with a 2xx status code.
+func NewWebhook(url string) Notifier {
+	return &webhook{url: url, client: &http.Client{Timeout: webhookTimeout}}
+}
+
+func (w *webhook) Notify(n Notification) error {
+	b, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
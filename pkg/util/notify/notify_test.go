@@ -0,0 +1,52 @@
+// Copyright 2017 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestWebhook(t *testing.T) {
+	n := Notification{Cluster: "example", Namespace: "default", Type: TypeDegraded, Message: "data corruption detected", Time: "2017-06-01T12:00:00Z"}
+	tests := []struct {
+		code int
+		wErr bool
+	}{
+		{code: http.StatusOK, wErr: false},
+		{code: http.StatusNoContent, wErr: false},
+		{code: http.StatusInternalServerError, wErr: true},
+	}
+	for i, tt := range tests {
+		var got Notification
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+				t.Errorf("#%d: failed to decode notification: %v", i, err)
+			}
+			w.WriteHeader(tt.code)
+		}))
+		err := NewWebhook(srv.URL).Notify(n)
+		srv.Close()
+		if (err != nil) != tt.wErr {
+			t.Errorf("#%d: err=%v, want error=%v", i, err, tt.wErr)
+		}
+		if !reflect.DeepEqual(got, n) {
+			t.Errorf("#%d: notification get=%v, want=%v", i, got, n)
+		}
+	}
+}
@@ -94,7 +94,7 @@ func (f *Framework) setup() error {
 
 func (f *Framework) SetupEtcdOperator() error {
 	// TODO: unify this and the yaml file in example/
-	cmd := []string{"/usr/local/bin/etcd-operator", "--analytics=false"}
+	cmd := []string{"/usr/local/bin/etcd-operator", "--analytics=false", "--feature-gates=SelfHosted=true"}
 	if os.Getenv("AWS_TEST_ENABLED") == "true" {
 		cmd = append(cmd, "--backup-aws-secret=aws",
 			"--backup-aws-config=aws",